@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -11,7 +12,7 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
-func listUnique(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func listUnique(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Convert log retention to int
 	logRetention, err := strconv.Atoi(os.Getenv("LogRetentionDays"))
 	if err != nil {
@@ -28,7 +29,7 @@ func listUnique(event events.APIGatewayProxyRequest) (events.APIGatewayProxyResp
 	}
 
 	// Initialize api gateway
-	api, request := helpers.InitAPIGateway(event, config)
+	api, request := helpers.InitAPIGateway(ctx, event, config)
 
 	// List the data
 	data, err := api.ListUniqueValues(request, os.Getenv("UniqueKey"))
@@ -49,17 +50,17 @@ func local() {
 		UserAgent: "Fake",
 	}
 
-	context := events.APIGatewayProxyRequestContext{
+	requestContext := events.APIGatewayProxyRequestContext{
 		Identity: identity,
 	}
 
 	event := events.APIGatewayProxyRequest{
 		Path:           "/items",
 		HTTPMethod:     "GET",
-		RequestContext: context,
+		RequestContext: requestContext,
 	}
 
-	resp, err := listUnique(event)
+	resp, err := listUnique(context.Background(), event)
 	if err != nil {
 		panic(err)
 	}
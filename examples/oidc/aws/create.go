@@ -31,7 +31,7 @@ func create(w http.ResponseWriter, req *http.Request, params httprouter.Params)
 	}
 
 	// Create the item
-	err = api.Create(request, body, validation, nil)
+	err = api.Create(request, body, validation, nil, "CREATE")
 
 	// Check for errors in the response
 	if helpers.HTTPErrorHandler(err, w) {
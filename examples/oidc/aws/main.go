@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"net/http"
 	"strings"
@@ -43,7 +44,7 @@ func main() {
 
 	awsConfig := aws.NewConfig()
 	awsConfig.Region = "us-east-1"
-	api := dynamo.NewDynamoAPI(conf, *awsConfig)
+	api := dynamo.NewDynamoAPI(context.Background(), conf, *awsConfig)
 
 	// Initialize http server
 	router, err := helpers.InitHTTPServer(api, "/items/")
@@ -51,11 +52,12 @@ func main() {
 		panic(err)
 	}
 
-	// Add get/create/update endpoints
+	// Add create/update/delete endpoints
 	router.POST("/item/", create)
-	router.GET("/item/:id", get)
-	router.PUT("/item/:id", update)
-	router.DELETE("/item/:id", delete)
+	router.PUT("/item/:pk/", update)
+	router.PUT("/item/:pk/:sk/", update)
+	router.DELETE("/item/:pk/", delete)
+	router.DELETE("/item/:pk/:sk/", delete)
 	router.GET("/types/", listTypes)
 
 	// Start the server
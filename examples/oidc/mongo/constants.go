@@ -1,33 +1,35 @@
 package main
 
-// import (
-// 	"fmt"
+import (
+	"fmt"
 
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/mongo"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/utils"
-// )
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/mongo"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/utils"
+)
 
-// var api mongo.MongoAPI
-// var validation map[string]utils.FieldValidation
+var api mongo.MongoAPI
+var validation map[string]types.FieldValidation
 
-// func init() {
-// 	validation = map[string]utils.FieldValidation{
-// 		"value": func(input *types.ValidationInput, ch chan types.ValidationOutput) {
-// 			output := types.ValidationOutput{
-// 				Input: input,
-// 			}
+func init() {
+	options := []string{"a", "b", "c"}
 
-// 			if input.Value == "hello" {
-// 				output.Result = true
-// 				ch <- output
-// 				return
-// 			}
+	validation = map[string]types.FieldValidation{
+		"value": func(input *types.ValidationInput, ch chan types.ValidationOutput) {
+			if input.Value != "hello" {
+				ch <- types.ValidationOutput{
+					Input:   input,
+					Result:  false,
+					Message: fmt.Sprintf("Invalid value '%s' for attribute 'value'", input.Value),
+				}
+				return
+			}
 
-// 			output.Result = false
-// 			output.Message = fmt.Sprintf("Invalid value '%s' for attribute 'value'", input.Value)
-
-// 			ch <- output
-// 		},
-// 	}
-// }
+			ch <- types.ValidationOutput{
+				Input:  input,
+				Result: true,
+			}
+		},
+		"name": utils.ValueInArray(options, "letter", ""),
+	}
+}
@@ -1,77 +1,75 @@
 package main
 
-// import (
-// 	"flag"
-// 	"net/http"
+import (
+	"context"
+	"flag"
+	"net/http"
+	"strings"
 
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/helpers"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/mongo"
-// 	log "github.com/sirupsen/logrus"
-// )
+	scoutrConfig "github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/helpers"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/mongo"
+	log "github.com/sirupsen/logrus"
+)
 
-// func init() {
-// 	api = mongo.MongoAPI{
-// 		Scoutr: &base.Scoutr{
-// 			Config: config.Config{
-// 				PrimaryKey: "id",
-// 			},
-// 		},
-// 	}
-// }
+func main() {
+	// Command line arguments
+	var nameHeader string
+	var conf scoutrConfig.MongoConfig
 
-// func main() {
-// 	var address, database, username, password string
-// 	// Command line arguments
-// 	flag.StringVar(&api.Config.DataTable, "data-table", "", "Data table")
-// 	flag.StringVar(&api.Config.AuthTable, "auth-table", "", "Auth table")
-// 	flag.StringVar(&api.Config.GroupTable, "group-table", "", "Group table")
-// 	flag.StringVar(&api.Config.AuditTable, "audit-table", "", "Audit table")
-// 	flag.IntVar(&api.Config.LogRetentionDays, "log-retention-days", 30, "Days to retain read logs")
-// 	flag.StringVar(&api.Config.OIDCUsernameHeader, "oidc-username-header", "Oidc-Claim-Sub", "Username header from OIDC")
-// 	flag.StringVar(&api.Config.OIDCNameHeader, "oidc-name-header", "Oidc-Claim-Name", "Name header from OIDC")
-// 	flag.StringVar(&api.Config.OIDCEmailHeader, "oidc-email-header", "Oidc-Claim-Mail", "Email header from OIDC")
-// 	flag.StringVar(&api.Config.OIDCGroupHeader, "oidc-group-header", "", "Group header from OIDC")
-// 	flag.StringVar(&address, "address", "", "MongoDB server address formatted as HOST:PORT")
-// 	flag.StringVar(&database, "database", "", "Name of the database")
-// 	flag.StringVar(&username, "username", "", "MongoDB username to authenticate with")
-// 	flag.StringVar(&password, "password", "", "MongoDB password to authenticate with")
-// 	flag.Parse()
+	flag.StringVar(&conf.DataTable, "data-table", "", "Data table")
+	flag.StringVar(&conf.AuthTable, "auth-table", "", "Auth table")
+	flag.StringVar(&conf.GroupTable, "group-table", "", "Group table")
+	flag.StringVar(&conf.AuditTable, "audit-table", "", "Audit table")
+	flag.StringVar(&conf.PrimaryKey, "primary-key", "id", "Primary key of the data table")
+	flag.StringVar(&conf.SortKey, "sort-key", "", "Sort key of the data table, if it has one")
+	flag.IntVar(&conf.LogRetentionDays, "log-retention-days", 30, "Days to retain read logs")
+	flag.StringVar(&conf.OIDCUsernameHeader, "oidc-username-header", "Oidc-Claim-Sub", "Username header from OIDC")
+	flag.StringVar(&nameHeader, "oidc-name-header", "Oidc-Claim-Name", "Name header from OIDC")
+	flag.StringVar(&conf.OIDCEmailHeader, "oidc-email-header", "Oidc-Claim-Mail", "Email header from OIDC")
+	flag.StringVar(&conf.OIDCGroupHeader, "oidc-group-header", "", "Group header from OIDC")
+	flag.StringVar(&conf.ConnectionString, "connection-string", "", "MongoDB connection string")
+	flag.StringVar(&conf.Database, "database", "", "Name of the database")
+	flag.Parse()
 
-// 	// Make sure required fields are provided
-// 	if api.Config.DataTable == "" {
-// 		log.Fatalln("data-table argument is required")
-// 	}
-// 	if api.Config.AuthTable == "" {
-// 		log.Fatalln("auth-table argument is required")
-// 	}
-// 	if api.Config.GroupTable == "" {
-// 		log.Fatalln("group-table argument is required")
-// 	}
+	conf.OIDCNameHeader = strings.Split(nameHeader, ",")
 
-// 	// Initialize the client
-// 	api.Init(
-// 		address,
-// 		database,
-// 		username,
-// 		password,
-// 	)
-// 	defer api.Close()
+	// Make sure required fields are provided
+	if conf.DataTable == "" {
+		log.Fatalln("data-table argument is required")
+	}
+	if conf.AuthTable == "" {
+		log.Fatalln("auth-table argument is required")
+	}
+	if conf.GroupTable == "" {
+		log.Fatalln("group-table argument is required")
+	}
+	if conf.ConnectionString == "" {
+		log.Fatalln("connection-string argument is required")
+	}
 
-// 	// Initialize http server
-// 	router, err := helpers.InitHTTPServer(api, "/items/")
-// 	if err != nil {
-// 		panic(err)
-// 	}
+	// Initialize the client
+	var err error
+	api, err = mongo.NewMongoAPI(context.Background(), conf)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer api.Close()
 
-// 	// Add get/create/update endpoints
-// 	router.POST("/item/", create)
-// 	router.GET("/item/:id", get)
-// 	router.PUT("/item/:id", update)
-// 	router.DELETE("/item/:id", delete)
-// 	router.GET("/types/", listTypes)
+	// Initialize http server
+	router, err := helpers.InitHTTPServer(api, "/items/")
+	if err != nil {
+		panic(err)
+	}
 
-// 	// Start the server
-// 	log.Fatal(http.ListenAndServe(":8000", router))
-// }
+	// Add create/update/delete endpoints
+	router.POST("/item/", create)
+	router.PUT("/item/:pk/", update)
+	router.PUT("/item/:pk/:sk/", update)
+	router.DELETE("/item/:pk/", delete)
+	router.DELETE("/item/:pk/:sk/", delete)
+	router.GET("/types/", listTypes)
+
+	// Start the server
+	log.Fatal(http.ListenAndServe(":8000", router))
+}
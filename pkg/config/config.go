@@ -1,6 +1,10 @@
 package config
 
-import "github.com/MichaelPalmer1/scoutr-go/pkg/types"
+import (
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
 
 // Config : Various configuration
 type Config struct {
@@ -9,12 +13,64 @@ type Config struct {
 	AuditTable         string
 	GroupTable         string
 	PrimaryKey         string
+	SortKey            string
 	LogRetentionDays   int
 	OIDCUsernameHeader string
 	OIDCNameHeader     []string
 	OIDCEmailHeader    string
 	OIDCGroupHeader    string
 	ErrorFunc          func(req *types.Request, user *types.User, err error)
+
+	// AuditSinks : Destinations of audit logs - "table" (the audit table of the provider), "cloudtrail" (AWS
+	// CloudTrail Lake, DynamoDB provider only), "file" and "stdout". Logs are written to every sink and read from
+	// the first one that can be queried. Defaults to the audit table when AuditTable is set.
+	AuditSinks []string
+
+	// AuditFile : Path of the JSON lines file written by the file audit sink
+	AuditFile string
+
+	// CloudTrailChannelARN : ARN of the CloudTrail Lake channel written to by the cloudtrail audit sink
+	CloudTrailChannelARN string
+
+	// AuditAsync : Write audit logs from a background queue in batches, rather than on the request path
+	AuditAsync bool
+
+	// AuditQueueSize : Number of audit logs the background queue holds before new logs are spilled or dropped.
+	// Defaults to 1000.
+	AuditQueueSize int
+
+	// AuditBatchSize : Maximum number of audit logs written to a sink at once. Defaults to 25.
+	AuditBatchSize int
+
+	// AuditFlushInterval : How long queued audit logs wait for a batch to fill up. Defaults to 1 second.
+	AuditFlushInterval time.Duration
+
+	// AuditSpillDir : Directory that queued audit logs are saved to while a sink is unavailable or the queue is
+	// full. Saved logs are written to the sink once it recovers. Logs are dropped if this is not set.
+	AuditSpillDir string
+
+	// AuditStrict : Fail requests when their audit log cannot be written
+	AuditStrict bool
+
+	// AuditHMACKey : Secret key that signs audit logs. When set, every audit log carries a sequence number, the
+	// hash of the log before it and an HMAC over its own contents, so edited, removed or forged logs can be
+	// detected. Logs of read actions no longer expire, since removing them would break the chain.
+	AuditHMACKey []byte
+
+	// SoftDelete : Move deleted items to the trash by setting deleted_at and deleted_by, rather than removing them.
+	// Items in the trash are hidden from every other operation, and can be listed, restored or purged.
+	SoftDelete bool
+
+	// TrashRetentionDays : Number of days items stay in the trash before they are purged. Defaults to 30.
+	TrashRetentionDays int
+
+	// VersionField : Field holding the version of each item. Create sets it to 1 and every update, patch and
+	// delete increments it, so writes that expect an earlier version can be rejected. Disabled when empty.
+	VersionField string
+
+	// Schema : JSON Schema that items of the data table must satisfy. It is checked on create, update and patch,
+	// alongside any field validation, and each violation is reported under the JSON path of the field.
+	Schema *types.JSONSchema
 }
 
 // MongoConfig: Mongo-specific configuration
@@ -23,3 +79,30 @@ type MongoConfig struct {
 	ConnectionString string
 	Database         string
 }
+
+// FirestoreConfig: Firestore-specific configuration
+type FirestoreConfig struct {
+	Config
+	ProjectID string
+}
+
+// MemoryConfig: In-memory provider configuration
+type MemoryConfig struct {
+	Config
+
+	// KeySchemas : Attributes that make up the key of each table. The data table defaults to
+	// the primary key and sort key, the auth table to "id" and the group table to "group_id".
+	KeySchemas map[string][]string
+}
+
+// SQLConfig: SQL provider configuration
+type SQLConfig struct {
+	Config
+
+	// Driver : Name of the database/sql driver. The driver must be imported by the application.
+	Driver         string
+	DataSourceName string
+
+	// Dialect : SQL dialect of the database (sqlite or postgres). Defaults to the dialect of the driver.
+	Dialect string
+}
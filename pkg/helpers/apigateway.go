@@ -1,9 +1,11 @@
 package helpers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
 	dynamo "github.com/MichaelPalmer1/scoutr-go/pkg/providers/aws"
@@ -13,8 +15,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// InitAPIGateway : Initialize API Gateway
-func InitAPIGateway(event events.APIGatewayProxyRequest, config config.Config) (dynamo.DynamoAPI, types.Request) {
+// InitAPIGateway : Initialize API Gateway. The request is bound to the context of the Lambda invocation, so
+// backend calls are cancelled when the invocation runs out of time.
+func InitAPIGateway(ctx context.Context, event events.APIGatewayProxyRequest, config config.Config) (dynamo.DynamoAPI, types.Request) {
 	// Build request user
 	requestUser := types.RequestUser{
 		ID: event.RequestContext.Identity.APIKeyID,
@@ -32,6 +35,14 @@ func InitAPIGateway(event events.APIGatewayProxyRequest, config config.Config) (
 		User:        requestUser,
 	}
 
+	// Header names are not normalized by API Gateway
+	for name, value := range event.Headers {
+		if strings.EqualFold(name, "If-Match") {
+			request.IfMatch = value
+		}
+	}
+	request = request.WithContext(ctx)
+
 	// Make sure maps are initialized
 	if len(event.PathParameters) == 0 {
 		request.PathParams = make(map[string]string)
@@ -41,7 +52,7 @@ func InitAPIGateway(event events.APIGatewayProxyRequest, config config.Config) (
 	}
 
 	// Create API
-	api := dynamo.NewDynamoAPI(config, *aws.NewConfig())
+	api := dynamo.NewDynamoAPI(ctx, config, *aws.NewConfig())
 
 	return api, request
 }
@@ -55,13 +66,23 @@ func APIGatewayErrorHandler(err error) *events.APIGatewayProxyResponse {
 	}
 
 	if err != nil {
-		switch err.(type) {
+		// Failed transactions are reported with the code of the operation that failed
+		cause := err
+		if failed, ok := err.(*types.TransactionFailed); ok {
+			cause = failed.Err
+		}
+
+		switch cause.(type) {
 		case *types.Unauthorized:
 			response.StatusCode = http.StatusUnauthorized
 		case *types.BadRequest:
 			response.StatusCode = http.StatusBadRequest
 		case *types.NotFound:
 			response.StatusCode = http.StatusNotFound
+		case *types.PreconditionFailed:
+			response.StatusCode = http.StatusPreconditionFailed
+		case *types.Timeout:
+			response.StatusCode = http.StatusGatewayTimeout
 		default:
 			response.StatusCode = http.StatusInternalServerError
 		}
@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
@@ -23,17 +26,22 @@ func HTTPErrorHandler(err error, w http.ResponseWriter) bool {
 	if err != nil {
 		// Marshal the error
 		var errorString string
-		bs, err := json.Marshal(err)
-		if err != nil {
-			log.WithError(err).Error("Failed to marshal output")
+		bs, marshalErr := json.Marshal(err)
+		if marshalErr != nil {
+			log.WithError(marshalErr).Error("Failed to marshal output")
 			errorString = err.Error()
 		} else {
 			errorString = string(bs)
 		}
 
-		// Select the error code
+		// Select the error code. Failed transactions are reported with the code of the operation that failed.
+		cause := err
+		if failed, ok := err.(*types.TransactionFailed); ok {
+			cause = failed.Err
+		}
+
 		var errorCode int
-		switch err.(type) {
+		switch cause.(type) {
 		case *types.Unauthorized:
 			errorCode = http.StatusUnauthorized
 		case *types.Forbidden:
@@ -42,6 +50,10 @@ func HTTPErrorHandler(err error, w http.ResponseWriter) bool {
 			errorCode = http.StatusBadRequest
 		case *types.NotFound:
 			errorCode = http.StatusNotFound
+		case *types.PreconditionFailed:
+			errorCode = http.StatusPreconditionFailed
+		case *types.Timeout:
+			errorCode = http.StatusGatewayTimeout
 		default:
 			errorCode = http.StatusInternalServerError
 		}
@@ -63,9 +75,22 @@ func BuildHttpRequest(api base.ScoutrBase, r *http.Request, params httprouter.Pa
 	pathParams := make(map[string]string)
 	queryParams := make(map[string][]string)
 
-	// Parse query params
+	// Parse query params. The pagination params are not filters, so they are kept out of the query params
+	limit := 0
+	var next string
 	for key, values := range r.URL.Query() {
-		queryParams[key] = values
+		switch key {
+		case "limit":
+			// Invalid limits are passed through as a negative number so the provider rejects them
+			var err error
+			if limit, err = strconv.Atoi(values[0]); err != nil || limit < 1 {
+				limit = -1
+			}
+		case "next":
+			next = values[0]
+		default:
+			queryParams[key] = values
+		}
 	}
 
 	// Parse path params
@@ -90,9 +115,89 @@ func BuildHttpRequest(api base.ScoutrBase, r *http.Request, params httprouter.Pa
 		UserAgent:   r.UserAgent(),
 		PathParams:  pathParams,
 		QueryParams: queryParams,
+		Limit:       limit,
+		Next:        next,
+		IfMatch:     r.Header.Get("If-Match"),
 	}
 
-	return req
+	// Backend calls are cancelled when the client goes away or the server times out the request
+	return req.WithContext(r.Context())
+}
+
+// ItemKey : Build the key of the item addressed by the :pk and, for tables with a sort key, :sk path params
+func ItemKey(api base.ScoutrBase, params httprouter.Params) (types.Key, error) {
+	return api.ItemKey(params.ByName("pk"), params.ByName("sk"))
+}
+
+// isPaginated : Check if a request asked for a page of results rather than the full result set
+func isPaginated(r *http.Request) bool {
+	query := r.URL.Query()
+	return query.Has("limit") || query.Has("next")
+}
+
+// writePage : Write a page of results, with a Link header pointing at the following page if there is one
+func writePage[T any](w http.ResponseWriter, r *http.Request, page types.Page[T]) {
+	if page.Next != "" {
+		link := *r.URL
+		query := link.Query()
+		query.Set("next", page.Next)
+		link.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, link.RequestURI()))
+	}
+
+	// Marshal the response and write it to output
+	out, _ := json.Marshal(page)
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(out); err != nil {
+		log.Errorf("Error writing output: %v", err)
+	}
+}
+
+// SetETag : Set the ETag header to the version of an item, so clients can send it back in If-Match when they
+// modify the item. Nothing is set when items are not versioned.
+func SetETag(w http.ResponseWriter, api base.ScoutrBase, data interface{}) {
+	var record map[string]interface{}
+	switch value := data.(type) {
+	case types.Record:
+		record = value
+	case *types.Record:
+		if value != nil {
+			record = *value
+		}
+	case map[string]interface{}:
+		record = value
+	}
+
+	if tag := base.RecordETag(record, api.GetConfig().VersionField); tag != "" {
+		w.Header().Set("ETag", tag)
+	}
+}
+
+// HistoryPoint : Read a point in the history of an item from the "version" or "at" query parameter
+func HistoryPoint(query url.Values) (types.HistoryPoint, error) {
+	if value := query.Get("version"); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil || version < 1 {
+			return types.HistoryPoint{}, &types.BadRequest{
+				Message: fmt.Sprintf("Invalid version '%s'", value),
+			}
+		}
+		return types.HistoryPoint{Version: version}, nil
+	}
+
+	if value := query.Get("at"); value != "" {
+		at, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return types.HistoryPoint{}, &types.BadRequest{
+				Message: fmt.Sprintf("Invalid timestamp '%s', expected RFC 3339", value),
+			}
+		}
+		return types.HistoryPoint{Time: at}, nil
+	}
+
+	return types.HistoryPoint{}, &types.BadRequest{
+		Message: "Either the version or at query parameter is required",
+	}
 }
 
 // InitHTTPServer : Initialize the HTTP server
@@ -113,6 +218,15 @@ func InitHTTPServer(api base.ScoutrBase, primaryListEndpoint string) (*httproute
 		// Build request
 		request := BuildHttpRequest(api, req, params)
 
+		// List a single page of the table
+		if isPaginated(req) {
+			page, err := api.ListPage(request)
+			if !HTTPErrorHandler(err, w) {
+				writePage(w, req, page)
+			}
+			return
+		}
+
 		// List the table
 		data, err := api.List(request)
 
@@ -133,11 +247,31 @@ func InitHTTPServer(api base.ScoutrBase, primaryListEndpoint string) (*httproute
 	search := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
 		// Build request
 		request := BuildHttpRequest(api, req, params)
-		values, ok := request.Body.([]string)
+
+		// The body is a JSON list of values to search for
+		body, ok := request.Body.([]interface{})
 		if !ok {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
+		var values []string
+		for _, item := range body {
+			value, ok := item.(string)
+			if !ok {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			values = append(values, value)
+		}
+
+		// Search a single page of the table
+		if isPaginated(req) {
+			page, err := api.SearchPage(request, params.ByName("key"), values)
+			if !HTTPErrorHandler(err, w) {
+				writePage(w, req, page)
+			}
+			return
+		}
 
 		// Search the table
 		data, err := api.Search(request, params.ByName("key"), values)
@@ -156,6 +290,33 @@ func InitHTTPServer(api base.ScoutrBase, primaryListEndpoint string) (*httproute
 		}
 	}
 
+	get := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		// Build request
+		request := BuildHttpRequest(api, req, params)
+
+		key, err := ItemKey(api, params)
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Fetch the item
+		data, err := api.Get(request, key)
+
+		// Check for errors in the response
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Marshal the response and write it to output
+		out, _ := json.Marshal(data)
+		SetETag(w, api, data)
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		if err != nil {
+			log.Errorf("Error writing output: %v", err)
+		}
+	}
+
 	userInfo := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
 		// Lookup information about the user
 		user := GetUserFromOIDC(req, api)
@@ -186,8 +347,12 @@ func InitHTTPServer(api base.ScoutrBase, primaryListEndpoint string) (*httproute
 		request := BuildHttpRequest(api, req, params)
 
 		// Parse the request body
-		access, ok := request.Body.(userAccess)
-		if !ok {
+		var access userAccess
+		body, err := json.Marshal(request.Body)
+		if err == nil {
+			err = json.Unmarshal(body, &access)
+		}
+		if err != nil || access.Method == "" || access.Path == "" {
 			http.Error(w, "Invalid request", http.StatusBadRequest)
 			return
 		}
@@ -218,8 +383,45 @@ func InitHTTPServer(api base.ScoutrBase, primaryListEndpoint string) (*httproute
 		// Build request
 		request := BuildHttpRequest(api, req, params)
 
+		// Audit logs for a single item are found by the key of the resource
+		key, err := ItemKey(api, params)
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+		pathParams := key.AuditParams()
+
+		// List a single page of audit logs
+		if isPaginated(req) {
+			page, err := api.ListAuditLogsPage(request, pathParams, request.QueryParams)
+			if !HTTPErrorHandler(err, w) {
+				writePage(w, req, page)
+			}
+			return
+		}
+
 		// List the table
-		data, err := api.ListAuditLogs(request, request.PathParams, request.QueryParams)
+		data, err := api.ListAuditLogs(request, pathParams, request.QueryParams)
+
+		// Check for errors in the response
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Marshal the response and write it to output
+		out, _ := json.Marshal(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		if err != nil {
+			log.Errorf("Error writing output: %v", err)
+		}
+	}
+
+	auditVerify := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		// Build request
+		request := BuildHttpRequest(api, req, params)
+
+		// Verify the audit log chain
+		data, err := api.VerifyAuditLogs(request)
 
 		// Check for errors in the response
 		if HTTPErrorHandler(err, w) {
@@ -239,8 +441,216 @@ func InitHTTPServer(api base.ScoutrBase, primaryListEndpoint string) (*httproute
 		// Build request
 		request := BuildHttpRequest(api, req, params)
 
-		// List the table
-		data, err := api.History(request, "id", params.ByName("item"), request.QueryParams, []string{"CREATE", "UPDATE", "DELETE"})
+		key, err := ItemKey(api, params)
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Get the item as it was at a point in time, or list every change made to it
+		var data interface{}
+		if at := req.URL.Query().Get("at"); at != "" {
+			var timestamp time.Time
+			timestamp, err = time.Parse(time.RFC3339Nano, at)
+			if err != nil {
+				HTTPErrorHandler(&types.BadRequest{Message: fmt.Sprintf("Invalid timestamp '%s', expected RFC 3339", at)}, w)
+				return
+			}
+			delete(request.QueryParams, "at")
+			data, err = api.HistoryAt(request, key, timestamp)
+		} else {
+			data, err = api.History(request, key, request.QueryParams, nil)
+		}
+
+		// Check for errors in the response
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Marshal the response and write it to output
+		out, _ := json.Marshal(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		if err != nil {
+			log.Errorf("Error writing output: %v", err)
+		}
+	}
+
+	restore := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		// Build request
+		request := BuildHttpRequest(api, req, params)
+
+		key, err := ItemKey(api, params)
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		point, err := HistoryPoint(req.URL.Query())
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Roll the item back
+		data, err := api.Restore(request, key, point, nil)
+
+		// Check for errors in the response
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Marshal the response and write it to output
+		out, _ := json.Marshal(data)
+		SetETag(w, api, data)
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		if err != nil {
+			log.Errorf("Error writing output: %v", err)
+		}
+	}
+
+	trash := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		// Build request
+		request := BuildHttpRequest(api, req, params)
+
+		// List a single page of the trash
+		if isPaginated(req) {
+			page, err := api.ListTrashPage(request)
+			if !HTTPErrorHandler(err, w) {
+				writePage(w, req, page)
+			}
+			return
+		}
+
+		// List the trash
+		data, err := api.ListTrash(request)
+
+		// Check for errors in the response
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Marshal the response and write it to output
+		out, _ := json.Marshal(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		if err != nil {
+			log.Errorf("Error writing output: %v", err)
+		}
+	}
+
+	restoreFromTrash := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		// Build request
+		request := BuildHttpRequest(api, req, params)
+
+		key, err := ItemKey(api, params)
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Move the item out of the trash
+		data, err := api.RestoreFromTrash(request, key)
+
+		// Check for errors in the response
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Marshal the response and write it to output
+		out, _ := json.Marshal(data)
+		SetETag(w, api, data)
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		if err != nil {
+			log.Errorf("Error writing output: %v", err)
+		}
+	}
+
+	purgeTrash := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		// Build request
+		request := BuildHttpRequest(api, req, params)
+
+		// Remove the items that have been in the trash past the retention period
+		purged, err := api.PurgeTrash(request)
+
+		// Check for errors in the response
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Marshal the response and write it to output
+		out, _ := json.Marshal(map[string]int{"purged": purged})
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		if err != nil {
+			log.Errorf("Error writing output: %v", err)
+		}
+	}
+
+	batch := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		// Build request
+		request := BuildHttpRequest(api, req, params)
+
+		// The body is a JSON list of items, updates or keys, depending on the operation
+		body, err := json.Marshal(request.Body)
+		if err != nil || len(body) == 0 || body[0] != '[' {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var data []types.BatchResult
+		switch operation := params.ByName("operation"); operation {
+		case "create":
+			var items []map[string]interface{}
+			if err := json.Unmarshal(body, &items); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			data, err = api.BatchCreate(request, items, nil, nil, base.AuditActionCreate)
+		case "update":
+			var updates []types.BatchUpdate
+			if err := json.Unmarshal(body, &updates); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			data, err = api.BatchUpdate(request, updates, nil, nil, base.AuditActionUpdate)
+		case "delete":
+			var keys []types.Key
+			if err := json.Unmarshal(body, &keys); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			data, err = api.BatchDelete(request, keys)
+		default:
+			http.NotFound(w, req)
+			return
+		}
+
+		// Check for errors in the response
+		if HTTPErrorHandler(err, w) {
+			return
+		}
+
+		// Marshal the response and write it to output
+		out, _ := json.Marshal(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(out)
+		if err != nil {
+			log.Errorf("Error writing output: %v", err)
+		}
+	}
+
+	transaction := func(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		// Build request
+		request := BuildHttpRequest(api, req, params)
+
+		// The body is a JSON list of operations
+		var operations []types.TransactionOperation
+		body, err := json.Marshal(request.Body)
+		if err != nil || len(body) == 0 || body[0] != '[' || json.Unmarshal(body, &operations) != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		data, err := api.Transaction(request, operations, nil)
 
 		// Check for errors in the response
 		if HTTPErrorHandler(err, w) {
@@ -262,9 +672,22 @@ func InitHTTPServer(api base.ScoutrBase, primaryListEndpoint string) (*httproute
 	router.GET(primaryListEndpoint+":search_key/:search_value/", list)
 	router.GET("/user/", userInfo)
 	router.POST("/user/has-permission/", userHasPermission)
+	router.GET("/item/:pk/", get)
+	router.GET("/item/:pk/:sk/", get)
 	router.GET("/audit/", audit)
-	router.GET("/audit/:item/", audit)
-	router.GET("/history/:item/", history)
+	router.GET("/audit/:pk/", audit)
+	router.GET("/audit/:pk/:sk/", audit)
+	router.GET("/audit-verify/", auditVerify)
+	router.GET("/history/:pk/", history)
+	router.GET("/history/:pk/:sk/", history)
+	router.POST("/restore/:pk/", restore)
+	router.POST("/restore/:pk/:sk/", restore)
+	router.GET("/trash/", trash)
+	router.DELETE("/trash/", purgeTrash)
+	router.POST("/trash/:pk/", restoreFromTrash)
+	router.POST("/trash/:pk/:sk/", restoreFromTrash)
+	router.POST("/batch/:operation/", batch)
+	router.POST("/transaction/", transaction)
 	router.POST("/search/:key/", search)
 
 	return router, nil
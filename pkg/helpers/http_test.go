@@ -0,0 +1,592 @@
+package helpers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/helpers"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/memory"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/julienschmidt/httprouter"
+)
+
+func newServer(t *testing.T, options ...func(*config.Config)) (memory.MemoryAPI, *httprouter.Router) {
+	cfg := config.Config{
+		DataTable:          "data",
+		AuthTable:          "auth",
+		GroupTable:         "groups",
+		AuditTable:         "audit",
+		PrimaryKey:         "id",
+		OIDCUsernameHeader: "Oidc-Claim-Sub",
+		OIDCNameHeader:     []string{"Oidc-Claim-Given-Name", "Oidc-Claim-Family-Name"},
+		OIDCEmailHeader:    "Oidc-Claim-Mail",
+		OIDCGroupHeader:    "Oidc-Claim-Groups",
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	api := memory.NewMemoryAPI(config.MemoryConfig{Config: cfg})
+
+	user := types.User{
+		ID: "user1",
+		Permissions: types.Permissions{
+			PermittedEndpoints: []types.PermittedEndpoint{
+				{Endpoint: "^/items/.*", Method: "GET"},
+				{Endpoint: "^/items/$", Method: "POST"},
+				{Endpoint: "^/item/.*", Method: "GET"},
+				{Endpoint: "^/item/.*", Method: "PUT"},
+				{Endpoint: "^/item/.*", Method: "DELETE"},
+				{Endpoint: "^/search/.*", Method: "POST"},
+				{Endpoint: "^/batch/(create|update)/$", Method: "POST"},
+				{Endpoint: "^/transaction/$", Method: "POST"},
+				{Endpoint: "^/(audit|history)/.*", Method: "GET"},
+				{Endpoint: "^/restore/.*", Method: "POST"},
+				{Endpoint: "^/trash/.*", Method: "GET"},
+				{Endpoint: "^/trash/.*", Method: "POST"},
+				{Endpoint: "^/trash/$", Method: "DELETE"},
+			},
+			ReadFilters: []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}},
+		},
+	}
+	if err := api.PutItem("auth", user); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range []map[string]interface{}{
+		{"id": "1", "name": "alpha", "status": "active"},
+		{"id": "2", "name": "beta", "status": "locked"},
+		{"id": "3", "name": "gamma", "status": "active"},
+	} {
+		if err := api.PutItem("data", item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router, err := helpers.InitHTTPServer(api, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return api, router
+}
+
+func serve(router http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Oidc-Claim-Sub", "user1")
+	req.Header.Set("Oidc-Claim-Given-Name", "User")
+	req.Header.Set("Oidc-Claim-Family-Name", "One")
+	req.Header.Set("Oidc-Claim-Mail", "user1@example.com")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestInitHTTPServerInvalidEndpoint(t *testing.T) {
+	api := memory.NewMemoryAPI(config.MemoryConfig{})
+
+	if _, err := helpers.InitHTTPServer(api, "/items/:id"); err == nil {
+		t.Error("Expected an error for a path argument in the primary endpoint")
+	}
+}
+
+func TestHTTPList(t *testing.T) {
+	_, router := newServer(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{"All", "/items/", 2},
+		{"QueryParams", "/items/?name=alpha", 1},
+		{"SearchPath", "/items/name/gamma/", 1},
+		{"Filtered", "/items/?name=beta", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(router, "GET", test.path, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var records []types.Record
+			if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(records) != test.expected {
+				t.Errorf("Expected %d records, got %d", test.expected, len(records))
+			}
+		})
+	}
+}
+
+func TestHTTPPagination(t *testing.T) {
+	_, router := newServer(t)
+
+	// Follow the Link headers through every page
+	var ids []string
+	path := "/items/?limit=1"
+	for pages := 0; path != ""; pages++ {
+		if pages > 2 {
+			t.Fatal("Too many pages")
+		}
+
+		w := serve(router, "GET", path, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var page types.Page[types.Record]
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range page.Items {
+			ids = append(ids, record["id"].(string))
+		}
+
+		path = ""
+		if link := w.Header().Get("Link"); link != "" {
+			path = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+			if !strings.Contains(path, "next="+page.Next) || !strings.Contains(path, "limit=1") {
+				t.Errorf("Unexpected link %s", link)
+			}
+		} else if page.Next != "" {
+			t.Error("Expected a Link header for the next page")
+		}
+	}
+
+	// Filtered records are skipped without cutting pages short
+	if strings.Join(ids, ",") != "1,3" {
+		t.Errorf("Unexpected records %v", ids)
+	}
+
+	// Invalid pagination params are bad requests
+	for _, path := range []string{"/items/?limit=0", "/items/?limit=abc", "/items/?limit=5000", "/items/?next=%21"} {
+		if w := serve(router, "GET", path, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+		}
+	}
+
+	// Searches and audit logs are paginated the same way
+	w := serve(router, "POST", "/search/name/?limit=10", `["alpha", "gamma"]`)
+	var page types.Page[types.Record]
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 2 || page.Next != "" {
+		t.Errorf("Unexpected page %+v", page)
+	}
+
+	w = serve(router, "GET", "/audit/?limit=2", "")
+	var logs types.Page[types.AuditLog]
+	if err := json.Unmarshal(w.Body.Bytes(), &logs); err != nil {
+		t.Fatal(err)
+	} else if len(logs.Items) != 2 || logs.Next == "" {
+		t.Errorf("Unexpected page %+v", logs)
+	}
+}
+
+func TestHTTPErrors(t *testing.T) {
+	_, router := newServer(t)
+
+	// Invalid operators are bad requests
+	if w := serve(router, "GET", "/items/?name__bad=1", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	// Unknown users are unauthorized
+	req := httptest.NewRequest("GET", "/items/", nil)
+	req.Header.Set("Oidc-Claim-Sub", "unknown")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+
+	// Requests that run past their deadline time out
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	req = httptest.NewRequest("GET", "/items/", nil).WithContext(ctx)
+	req.Header.Set("Oidc-Claim-Sub", "user1")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", w.Code)
+	}
+}
+
+func TestHTTPSearch(t *testing.T) {
+	_, router := newServer(t)
+
+	w := serve(router, "POST", "/search/name/", `["alpha", "beta", "gamma"]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var records []types.Record
+	if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 {
+		t.Errorf("Expected 2 records, got %d", len(records))
+	}
+
+	if w := serve(router, "POST", "/search/name/", `{"name": "alpha"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHTTPHasPermission(t *testing.T) {
+	_, router := newServer(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected bool
+	}{
+		{"Permitted", `{"method": "GET", "path": "/items/"}`, true},
+		{"WrongMethod", `{"method": "DELETE", "path": "/items/"}`, false},
+		{"WrongPath", `{"method": "GET", "path": "/other/"}`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(router, "POST", "/user/has-permission/", test.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var output map[string]bool
+			if err := json.Unmarshal(w.Body.Bytes(), &output); err != nil {
+				t.Fatal(err)
+			}
+
+			if output["authorized"] != test.expected {
+				t.Errorf("Expected authorized to be %t", test.expected)
+			}
+		})
+	}
+
+	if w := serve(router, "POST", "/user/has-permission/", `[]`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHTTPAuditAndHistory(t *testing.T) {
+	api, router := newServer(t)
+
+	// Generate some history
+	req := types.Request{
+		User: types.RequestUser{
+			ID:   "user1",
+			Data: &types.UserData{Username: "user1", Name: "User One", Email: "user1@example.com"},
+		},
+		Method: "POST",
+		Path:   "/items/",
+		Body:   map[string]interface{}{"id": "4", "name": "delta", "status": "active"},
+	}
+	if err := api.Create(req, req.Body.(map[string]interface{}), nil, nil, base.AuditActionCreate); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().UTC()
+	req.Method, req.Path, req.Body = "PUT", "/item/4", nil
+	if _, err := api.Update(req, map[string]interface{}{"id": "4"}, map[string]interface{}{"name": "epsilon"}, nil, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Audit logs for all items
+	w := serve(router, "GET", "/audit/", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var logs []types.AuditLog
+	if err := json.Unmarshal(w.Body.Bytes(), &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("Expected 2 audit logs, got %d", len(logs))
+	}
+
+	// Audit logs for a single item
+	w = serve(router, "GET", "/audit/4/", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("Expected 2 audit logs, got %d", len(logs))
+	}
+
+	// Audit logs filtered by action
+	w = serve(router, "GET", "/audit/?action=CREATE", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Errorf("Expected 1 audit log, got %d", len(logs))
+	}
+
+	// History
+	w = serve(router, "GET", "/history/4/", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var history []types.History
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Data["name"] != "epsilon" {
+		t.Errorf("Unexpected history %+v", history)
+	}
+	if history[0].Action != base.AuditActionUpdate || history[0].User.ID != "user1" || history[0].Changes["name"].Old != "delta" {
+		t.Errorf("Unexpected history entry %+v", history[0])
+	}
+
+	// The item as it was before the update
+	w = serve(router, "GET", "/history/4/?at="+url.QueryEscape(created.Format(time.RFC3339Nano)), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var record types.Record
+	if err := json.Unmarshal(w.Body.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["name"] != "delta" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	// Before the item was created
+	w = serve(router, "GET", "/history/4/?at="+url.QueryEscape(created.Add(-time.Hour).Format(time.RFC3339)), "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(router, "GET", "/history/4/?at=yesterday", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Roll back to the first version
+	w = serve(router, "POST", "/restore/4/?version=1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if record, err := api.Get(req, types.Key{"id": "4"}); err != nil {
+		t.Fatal(err)
+	} else if record["name"] != "delta" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	for _, query := range []string{"", "?version=0", "?at=yesterday"} {
+		w = serve(router, "POST", "/restore/4/"+query, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for '%s', got %d: %s", query, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHTTPGet(t *testing.T) {
+	_, router := newServer(t)
+
+	w := serve(router, "GET", "/item/1/", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var record types.Record
+	if err := json.Unmarshal(w.Body.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	} else if record["name"] != "alpha" {
+		t.Errorf("Unexpected record %v", record)
+	}
+
+	// Items hidden by the read filters do not exist
+	if w := serve(router, "GET", "/item/2/", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+
+	// The table does not have a sort key
+	if w := serve(router, "GET", "/item/1/a/", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestHTTPTrash(t *testing.T) {
+	api, router := newServer(t, func(cfg *config.Config) { cfg.SoftDelete = true })
+
+	req := types.Request{
+		User:   types.RequestUser{ID: "user1", Data: &types.UserData{Username: "user1", Name: "User One", Email: "user1@example.com"}},
+		Method: "DELETE",
+		Path:   "/item/1",
+	}
+	if err := api.Delete(req, types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(router, "GET", "/trash/", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var records []types.Record
+	if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0]["id"] != "1" {
+		t.Errorf("Unexpected trash %+v", records)
+	}
+
+	// Nothing has been in the trash for long enough to be purged
+	w = serve(router, "DELETE", "/trash/", "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"purged":0}` {
+		t.Errorf("Unexpected purge response %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(router, "POST", "/trash/1/", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(router, "GET", "/item/1/", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHTTPVersions(t *testing.T) {
+	api, router := newServer(t, func(cfg *config.Config) { cfg.VersionField = "version" })
+
+	// Items without a version have no ETag until they are written
+	if w := serve(router, "GET", "/item/1/", ""); w.Header().Get("ETag") != "" {
+		t.Errorf("Unexpected ETag %s", w.Header().Get("ETag"))
+	}
+	r := httptest.NewRequest("PUT", "/item/1/", nil)
+	r.Header.Set("Oidc-Claim-Sub", "user1")
+	r.Header.Set("Oidc-Claim-Given-Name", "User")
+	r.Header.Set("Oidc-Claim-Family-Name", "One")
+	r.Header.Set("Oidc-Claim-Mail", "user1@example.com")
+	if _, err := api.Patch(helpers.BuildHttpRequest(api, r, nil), types.Key{"id": "1"}, map[string]interface{}{"name": "delta"}, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(router, "GET", "/item/1/", "")
+	if tag := w.Header().Get("ETag"); tag != `"1"` {
+		t.Fatalf("Expected ETag \"1\", got %s", tag)
+	}
+
+	// The ETag is sent back in If-Match, and writes to other versions fail with 412
+	r.Header.Set("If-Match", `"2"`)
+	request := helpers.BuildHttpRequest(api, r, nil)
+	if request.IfMatch != `"2"` {
+		t.Fatalf("Expected If-Match to be read, got %s", request.IfMatch)
+	}
+	_, err := api.Patch(request, types.Key{"id": "1"}, map[string]interface{}{"name": "epsilon"}, nil, base.AuditActionUpdate)
+	w = httptest.NewRecorder()
+	if !helpers.HTTPErrorHandler(err, w) || w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412, got %d: %v", w.Code, err)
+	}
+
+	r.Header.Set("If-Match", `"1"`)
+	if _, err := api.Patch(helpers.BuildHttpRequest(api, r, nil), types.Key{"id": "1"}, map[string]interface{}{"name": "epsilon"}, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restored items carry their new version
+	w = serve(router, "POST", "/restore/1/?version=1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	} else if tag := w.Header().Get("ETag"); tag != `"3"` {
+		t.Errorf("Expected ETag \"3\", got %s", tag)
+	}
+}
+
+func TestHTTPBatch(t *testing.T) {
+	_, router := newServer(t)
+
+	w := serve(router, "POST", "/batch/create/", `[{"id": "4", "status": "active"}, {"id": "1"}]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var results []types.BatchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	} else if len(results) != 2 || results[0].Status != types.BatchStatusCreated || results[1].Status != types.BatchStatusFailed || results[1].Message == "" {
+		t.Errorf("Unexpected results %s", w.Body.String())
+	}
+
+	w = serve(router, "POST", "/batch/update/", `[{"key": {"id": "4"}, "item": {"name": "delta"}}]`)
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	} else if len(results) != 1 || results[0].Status != types.BatchStatusUpdated {
+		t.Errorf("Unexpected results %s", w.Body.String())
+	}
+
+	// The body must be a list
+	if w := serve(router, "POST", "/batch/update/", `{"id": "4"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+
+	// Each operation has its own endpoint permission
+	if w := serve(router, "POST", "/batch/delete/", `[{"id": "4"}]`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+func TestHTTPTransaction(t *testing.T) {
+	_, router := newServer(t)
+
+	w := serve(router, "POST", "/transaction/", `[
+		{"action": "create", "item": {"id": "4", "status": "active"}},
+		{"action": "update", "key": {"id": "1"}, "item": {"name": "delta"}},
+		{"action": "check", "key": {"id": "3"}}
+	]`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var records []types.Record
+	if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	} else if len(records) != 3 || records[1]["name"] != "delta" {
+		t.Errorf("Unexpected records %s", w.Body.String())
+	}
+
+	// Failures are reported with the status of the operation that failed, and nothing is written
+	w = serve(router, "POST", "/transaction/", `[
+		{"action": "delete", "key": {"id": "3"}},
+		{"action": "check", "key": {"id": "2"}}
+	]`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var failed types.TransactionFailed
+	if err := json.Unmarshal(w.Body.Bytes(), &failed); err != nil {
+		t.Fatal(err)
+	} else if failed.Operation != 1 || failed.Message == "" {
+		t.Errorf("Unexpected error %s", w.Body.String())
+	}
+
+	if w := serve(router, "GET", "/item/3/", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected item 3 to be kept, got status %d", w.Code)
+	}
+
+	// The body must be a list
+	if w := serve(router, "POST", "/transaction/", `{"action": "check"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
@@ -22,68 +22,58 @@ type DynamoAPI struct {
 	*base.Scoutr
 	Client           types.DynamoClientAPI
 	filtering        DynamoFiltering
-	auditClient      *cloudtraildata.Client
 	cloudTrailClient *cloudtrail.Client
-	indices          map[string][]string
+	schema           *schemaCache
 }
 
-func NewDynamoAPI(scoutrConfig config.Config, awsConfig aws.Config) DynamoAPI {
+// NewDynamoAPI : Initialize the API, describing the data table with ctx
+func NewDynamoAPI(ctx context.Context, scoutrConfig config.Config, awsConfig aws.Config) DynamoAPI {
 	api := DynamoAPI{
 		Client:           dynamodb.NewFromConfig(awsConfig),
-		auditClient:      cloudtraildata.NewFromConfig(awsConfig),
 		cloudTrailClient: cloudtrail.NewFromConfig(awsConfig),
 		filtering:        NewFilter(),
 		Scoutr: &base.Scoutr{
 			Config: scoutrConfig,
 		},
 	}
-	api.ScoutrBase = api
 
-	// Learn about indices
-	if err := api.learnTables(); err != nil {
-		logrus.WithError(err).Fatal("Failed to learn tables")
+	// Learn the key schema of the data table and its indices
+	api.schema = &schemaCache{}
+	if err := api.RefreshSchema(ctx); err != nil {
+		logrus.WithError(err).Fatal("Failed to describe the data table")
 	}
 
-	return api
-}
-
-func (api DynamoAPI) learnTables() error {
-	output, err := api.Client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
-		TableName: &api.Config.DataTable,
-	})
-
+	// Select the audit sink
+	sink, err := base.NewAuditSink(api.Config, map[string]base.AuditSink{
+		base.AuditSinkTable:      api.AuditTableSink(),
+		base.AuditSinkCloudTrail: NewCloudTrailLakeSink(cloudtraildata.NewFromConfig(awsConfig), api.Config.CloudTrailChannelARN),
+	}, api.AuditChainStore())
 	if err != nil {
-		return err
+		logrus.WithError(err).Fatal("Failed to initialize the audit sink")
 	}
+	api.AuditSink = sink
 
-	for _, index := range output.Table.GlobalSecondaryIndexes {
-		var schema []string
-		for _, element := range index.KeySchema {
-			schema = append(schema, aws.ToString(element.AttributeName))
-		}
-		api.indices[aws.ToString(index.IndexName)] = schema
-	}
+	api.ScoutrBase = api
 
-	return nil
+	return api
 }
 
 // Init : Initialize the Dynamo client
 // func (api *DynamoAPI) Init(config aws.Config) {
 // 	api.Client = dynamodb.NewFromConfig(config)
-// 	api.auditClient = cloudtraildata.NewFromConfig(config)
 // 	api.cloudTrailClient = cloudtrail.NewFromConfig(config)
 // 	api.filtering = NewFilter()
 // 	api.ScoutrBase = api
 // }
 
-func Scan[T any](client types.DynamoClientAPI, input *dynamodb.ScanInput) ([]T, error) {
+func Scan[T any](ctx context.Context, client types.DynamoClientAPI, input *dynamodb.ScanInput) ([]T, error) {
 	var results []T
 	paginator := dynamodb.NewScanPaginator(client, input)
 
 	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(context.TODO())
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, err
+			return nil, base.ContextError(ctx, err)
 		}
 
 		var data []T
@@ -97,14 +87,14 @@ func Scan[T any](client types.DynamoClientAPI, input *dynamodb.ScanInput) ([]T,
 	return results, nil
 }
 
-func Query[T any](client types.DynamoClientAPI, input *dynamodb.QueryInput) ([]T, error) {
+func Query[T any](ctx context.Context, client types.DynamoClientAPI, input *dynamodb.QueryInput) ([]T, error) {
 	var results []T
 	paginator := dynamodb.NewQueryPaginator(client, input)
 
 	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(context.TODO())
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, err
+			return nil, base.ContextError(ctx, err)
 		}
 
 		var data []T
@@ -118,13 +108,13 @@ func Query[T any](client types.DynamoClientAPI, input *dynamodb.QueryInput) ([]T
 	return results, nil
 }
 
-func GetItem[T any](client types.DynamoClientAPI, input *dynamodb.GetItemInput) (*T, error) {
+func GetItem[T any](ctx context.Context, client types.DynamoClientAPI, input *dynamodb.GetItemInput) (*T, error) {
 	var output *T
 	var item map[string]dynamoTypes.AttributeValue
 
 	// Backoff operation
 	fn := func() error {
-		result, err := client.GetItem(context.TODO(), input)
+		result, err := client.GetItem(ctx, input)
 		if err != nil {
 			return err
 		}
@@ -134,9 +124,9 @@ func GetItem[T any](client types.DynamoClientAPI, input *dynamodb.GetItemInput)
 		return nil
 	}
 
-	// Perform exponential backoff
-	if err := backoff.Retry(fn, backoff.NewExponentialBackOff()); err != nil {
-		return nil, err
+	// Perform exponential backoff, giving up once the context is done
+	if err := backoff.Retry(fn, backoff.WithContext(backoff.NewExponentialBackOff(), ctx)); err != nil {
+		return nil, base.ContextError(ctx, err)
 	}
 
 	// Item does not exist
@@ -151,7 +141,7 @@ func GetItem[T any](client types.DynamoClientAPI, input *dynamodb.GetItemInput)
 	return output, nil
 }
 
-func (api *DynamoAPI) PutItem(table string, item interface{}, expr *expression.Expression) error {
+func (api *DynamoAPI) PutItem(ctx context.Context, table string, item interface{}, expr *expression.Expression) error {
 	av, err := attributevalue.MarshalMap(item)
 	if err != nil {
 		return err
@@ -168,14 +158,15 @@ func (api *DynamoAPI) PutItem(table string, item interface{}, expr *expression.E
 		input.ExpressionAttributeValues = expr.Values()
 	}
 
-	if _, err := api.Client.PutItem(context.TODO(), input); err != nil {
-		return err
+	if _, err := api.Client.PutItem(ctx, input); err != nil {
+		return base.ContextError(ctx, err)
 	}
 
 	return nil
 }
 
-func UpdateItem[T any](client types.DynamoClientAPI, table string, key map[string]dynamoTypes.AttributeValue, expr expression.Expression) (*T, error) {
+// UpdateItem : Apply an update expression to an item, returning the version of it selected by returnValues
+func UpdateItem[T any](ctx context.Context, client types.DynamoClientAPI, table string, key map[string]dynamoTypes.AttributeValue, expr expression.Expression, returnValues dynamoTypes.ReturnValue) (*T, error) {
 	var output *T
 
 	input := &dynamodb.UpdateItemInput{
@@ -185,11 +176,14 @@ func UpdateItem[T any](client types.DynamoClientAPI, table string, key map[strin
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		ConditionExpression:       expr.Condition(),
-		ReturnValues:              dynamoTypes.ReturnValueAllNew,
+		ReturnValues:              returnValues,
+
+		// The item is returned when the condition fails, so the reason can be reported
+		ReturnValuesOnConditionCheckFailure: dynamoTypes.ReturnValuesOnConditionCheckFailureAllOld,
 	}
 
-	if result, err := client.UpdateItem(context.TODO(), input); err != nil {
-		return nil, err
+	if result, err := client.UpdateItem(ctx, input); err != nil {
+		return nil, base.ContextError(ctx, err)
 	} else if err := attributevalue.UnmarshalMap(result.Attributes, &output); err != nil {
 		return nil, err
 	}
@@ -197,11 +191,15 @@ func UpdateItem[T any](client types.DynamoClientAPI, table string, key map[strin
 	return output, nil
 }
 
-func (api *DynamoAPI) DeleteItem(table string, key map[string]dynamoTypes.AttributeValue, expr *expression.Expression) error {
+// DeleteItem : Delete an item, returning the deleted version of it
+func (api *DynamoAPI) DeleteItem(ctx context.Context, table string, key map[string]dynamoTypes.AttributeValue, expr *expression.Expression) (types.Record, error) {
 	input := &dynamodb.DeleteItemInput{
 		TableName:    aws.String(table),
 		Key:          key,
 		ReturnValues: dynamoTypes.ReturnValueAllOld,
+
+		// The item is returned when the condition fails, so the reason can be reported
+		ReturnValuesOnConditionCheckFailure: dynamoTypes.ReturnValuesOnConditionCheckFailureAllOld,
 	}
 
 	if expr != nil {
@@ -210,9 +208,15 @@ func (api *DynamoAPI) DeleteItem(table string, key map[string]dynamoTypes.Attrib
 		input.ExpressionAttributeValues = expr.Values()
 	}
 
-	if _, err := api.Client.DeleteItem(context.TODO(), input); err != nil {
-		return err
+	result, err := api.Client.DeleteItem(ctx, input)
+	if err != nil {
+		return nil, base.ContextError(ctx, err)
 	}
 
-	return nil
+	var output types.Record
+	if err := attributevalue.UnmarshalMap(result.Attributes, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
 }
@@ -3,7 +3,10 @@ package aws
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
@@ -14,203 +17,299 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudtraildata"
 	cloudTrailDataTypes "github.com/aws/aws-sdk-go-v2/service/cloudtraildata/types"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
-// ListAuditLogs : List audit logs
-func (api DynamoAPI) ListAuditLogs(req types.Request, pathParams map[string]string, queryParams map[string][]string) ([]types.AuditLog, error) {
-	// Only fetch audit logs if the table is configured
-	if api.Config.AuditTable == "" {
-		return nil, &types.NotFound{
-			Message: "Audit logs are not enabled",
-		}
-	}
+const (
+	// auditTableBatchSize : Maximum number of items in a BatchWriteItem call
+	auditTableBatchSize = 25
+
+	// cloudTrailBatchSize : Maximum number of events in a PutAuditEvents call
+	cloudTrailBatchSize = 100
+
+	// chainHeadTime : Reserved key of the item in the audit table that holds the head of the audit log chain
+	chainHeadTime = "#chain-head"
+)
+
+// auditTable : Audit sink that stores audit logs in a Dynamo table
+type auditTable struct {
+	api  DynamoAPI
+	name string
+}
+
+// AuditTableSink : Sink that stores audit logs in the Dynamo audit table
+func (api DynamoAPI) AuditTableSink() base.AuditSink {
+	return auditTable{api: api, name: api.Config.AuditTable}
+}
 
-	// Get the user
-	_, err := api.InitializeRequest(req)
+// Write : Add an audit log to the table
+func (sink auditTable) Write(ctx context.Context, auditLog types.AuditLog) error {
+	// Marshal the audit log to Dynamo format
+	item, err := attributevalue.MarshalMap(auditLog)
 	if err != nil {
-		// Bad user - pass the error through
-		return nil, err
+		return err
 	}
 
-	input := dynamodb.ScanInput{
-		TableName: aws.String(api.Config.AuditTable),
-	}
+	// Add the record to dynamo
+	_, err = sink.api.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(sink.name),
+		Item:      item,
+	})
+
+	return base.ContextError(ctx, err)
+}
+
+// WriteBatch : Add a list of audit logs to the table, 25 at a time. Audit logs are keyed by time, so writing a
+// batch again after a partial failure does not duplicate the logs that succeeded.
+func (sink auditTable) WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error {
+	for start := 0; start < len(auditLogs); start += auditTableBatchSize {
+		end := start + auditTableBatchSize
+		if end > len(auditLogs) {
+			end = len(auditLogs)
+		}
+
+		requests := make([]dynamoTypes.WriteRequest, 0, end-start)
+		for _, auditLog := range auditLogs[start:end] {
+			item, err := attributevalue.MarshalMap(auditLog)
+			if err != nil {
+				return err
+			}
+			requests = append(requests, dynamoTypes.WriteRequest{
+				PutRequest: &dynamoTypes.PutRequest{Item: item},
+			})
+		}
+
+		output, err := sink.api.Client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]dynamoTypes.WriteRequest{sink.name: requests},
+		})
+		if err != nil {
+			return base.ContextError(ctx, err)
+		}
 
-	// Generate dynamic search
-	searchKey, hasSearchKey := pathParams["search_key"]
-	searchValue, hasSearchValue := pathParams["search_value"]
-	if hasSearchKey && hasSearchValue {
-		// Map the search key and value into path params
-		pathParams[searchKey] = searchValue
-		delete(pathParams, "search_key")
-		delete(pathParams, "search_value")
+		if unprocessed := len(output.UnprocessedItems[sink.name]); unprocessed > 0 {
+			return fmt.Errorf("%d audit logs were not processed", unprocessed)
+		}
 	}
 
-	// Merge pathParams into queryParams
-	for key, value := range pathParams {
-		queryParams[key] = append(queryParams[key], value)
+	return nil
+}
+
+// Query : List the audit logs in the table. Dynamo scans are unordered, so only the logs within each page are
+// sorted newest first. The head of the audit log chain is kept in the same table and is left out.
+func (sink auditTable) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	input := dynamodb.ScanInput{
+		TableName: aws.String(sink.name),
 	}
 
 	// Build filters
-	if rawConds, err := api.filtering.Filter(nil, queryParams, ""); err != nil {
+	conditions := expression.Name("time").NotEqual(expression.Value(chainHeadTime))
+	if rawConds, err := sink.api.filtering.Filter(nil, filters, ""); err != nil {
 		logrus.Errorln("Error encountered during filtering", err)
-		return nil, err
+		return types.Page[types.AuditLog]{}, err
 	} else if rawConds != nil {
-		conditions := rawConds.(expression.ConditionBuilder)
-		expr, err := expression.NewBuilder().WithFilter(conditions).Build()
-		if err != nil {
-			return nil, err
-		}
-
-		// Update scan input
-		input.FilterExpression = expr.Filter()
-		input.ExpressionAttributeNames = expr.Names()
-		input.ExpressionAttributeValues = expr.Values()
+		conditions = conditions.And(rawConds.(expression.ConditionBuilder))
 	}
 
-	// f, err := api.cloudTrailClient.StartQuery(context.TODO(), &cloudtrail.StartQueryInput{
-	// 	QueryStatement: aws.String("SELECT *"),
-	// })
-
-	// r, err := api.cloudTrailClient.DescribeQuery(context.TODO(), &cloudtrail.DescribeQueryInput{
-	// 	QueryId: f.QueryId,
-	// })
+	expr, err := expression.NewBuilder().WithFilter(conditions).Build()
+	if err != nil {
+		return types.Page[types.AuditLog]{}, err
+	}
 
-	// g, err := api.cloudTrailClient.GetQueryResults(context.TODO(), &cloudtrail.GetQueryResultsInput{
-	// 	QueryId: f.QueryId,
-	// })
+	// Update scan input
+	input.FilterExpression = expr.Filter()
+	input.ExpressionAttributeNames = expr.Names()
+	input.ExpressionAttributeValues = expr.Values()
 
 	// Download the data
-	data, err := Scan[types.AuditLog](api.Client, &input)
+	page, err := ScanPage[types.AuditLog](ctx, sink.api.Client, &input, limit, next)
 	if err != nil {
-		logrus.Errorln("Error while attempting to list records", err)
-		return nil, err
+		return types.Page[types.AuditLog]{}, err
 	}
 
 	// Sort the results
-	sort.Slice(data, func(i, j int) bool {
-		return data[i].Time > data[j].Time
+	sort.Slice(page.Items, func(i, j int) bool {
+		return page.Items[i].Time > page.Items[j].Time
 	})
 
-	return data, nil
+	return page, nil
+}
+
+// auditChain : Head of the audit log chain, kept in a reserved item of the audit table
+type auditChain struct {
+	api  DynamoAPI
+	name string
 }
 
-// auditLog : Creates an audit log
-func (api DynamoAPI) auditLog(action string, request types.Request, user *types.User, resource map[string]interface{}, changes map[string]interface{}) {
-	// Only send audit logs if the table is configured
+// AuditChainStore : Store that keeps the head of the audit log chain in the audit table. Returns nil if there is no
+// audit table.
+func (api DynamoAPI) AuditChainStore() base.AuditChainStore {
 	if api.Config.AuditTable == "" {
-		return
-	}
-
-	// Create audit log
-	now := time.Now().UTC()
-	auditLog := types.AuditLog{
-		Time: now.Format(time.RFC3339Nano),
-		User: types.AuditUser{
-			ID:        user.ID,
-			Name:      user.Name,
-			Username:  user.Username,
-			Email:     user.Email,
-			SourceIP:  request.SourceIP,
-			UserAgent: request.UserAgent,
-		},
-		Action: action,
-		Method: request.Method,
-		Path:   request.Path,
-	}
-
-	auditEvent := types.AuditEvent{
-		EventData: types.AuditEventData{
-			Version: "1.0",
-			UserIdentity: types.AuditEventUserIdentity{
-				Type:        "ScoutrUser",
-				PrincipalId: user.ID,
-				Details:     user,
-			},
-			UserAgent:         request.UserAgent,
-			SourceIPAddress:   request.SourceIP,
-			EventSource:       "scoutr",
-			EventName:         action,
-			EventTime:         time.Now().UTC(),
-			UID:               "RANDOM",
-			RequestParameters: resource,
-			AdditionalEventData: map[string]interface{}{
-				"request": request,
-				"changes": changes,
-			},
-		},
+		return nil
 	}
 
-	// Add expiry time for read events
-	if action == base.AuditActionGet || action == base.AuditActionList || action == base.AuditActionSearch {
-		auditLog.ExpireTime = now.AddDate(0, 0, api.Config.LogRetentionDays).Unix()
+	return auditChain{api: api, name: api.Config.AuditTable}
+}
+
+// Head : Read the head of the chain
+func (chain auditChain) Head(ctx context.Context) (base.AuditChainHead, error) {
+	output, err := chain.api.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(chain.name),
+		Key:            map[string]dynamoTypes.AttributeValue{"time": &dynamoTypes.AttributeValueMemberS{Value: chainHeadTime}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return base.AuditChainHead{}, base.ContextError(ctx, err)
 	}
 
-	// Add query params
-	if len(request.QueryParams) > 0 {
-		auditLog.QueryParams = request.QueryParams
+	var head base.AuditChainHead
+	if err := attributevalue.Unmarshal(output.Item["sequence"], &head.Sequence); err != nil {
+		return base.AuditChainHead{}, err
+	}
+	if err := attributevalue.Unmarshal(output.Item["hash"], &head.Hash); err != nil {
+		return base.AuditChainHead{}, err
 	}
 
-	// Add body
-	if request.Body != nil {
-		auditLog.Body = request.Body
-	} else if changes != nil {
-		auditLog.Body = changes
+	return head, nil
+}
+
+// Advance : Move the head of the chain on with a conditional put, if it has not moved since it was read
+func (chain auditChain) Advance(ctx context.Context, from base.AuditChainHead, to base.AuditChainHead) (bool, error) {
+	condition := expression.AttributeNotExists(expression.Name("time"))
+	if from != (base.AuditChainHead{}) {
+		condition = expression.Name("sequence").Equal(expression.Value(from.Sequence)).
+			And(expression.Name("hash").Equal(expression.Value(from.Hash)))
 	}
 
-	// Add resource
-	if resource != nil {
-		auditLog.Resource = resource
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return false, err
 	}
 
-	// Marshal the audit log to Dynamo format
-	item, err := attributevalue.MarshalMap(auditLog)
+	_, err = chain.api.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(chain.name),
+		Item: map[string]dynamoTypes.AttributeValue{
+			"time":     &dynamoTypes.AttributeValueMemberS{Value: chainHeadTime},
+			"sequence": &dynamoTypes.AttributeValueMemberN{Value: fmt.Sprint(to.Sequence)},
+			"hash":     &dynamoTypes.AttributeValueMemberS{Value: to.Hash},
+		},
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
 	if err != nil {
-		logrus.Errorln("Failed to marshal the audit log", err)
-		logrus.Infof("Failed audit log: '%v'", auditLog)
-		return
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ConditionalCheckFailedException" {
+			return false, nil
+		}
+
+		return false, base.ContextError(ctx, err)
 	}
 
-	// Generate the put item input
-	input := dynamodb.PutItemInput{
-		TableName: aws.String(api.Config.AuditTable),
-		Item:      item,
+	return true, nil
+}
+
+// CloudTrailLakeSink : Audit sink that sends audit logs to a CloudTrail Lake channel. CloudTrail Lake is queried
+// with SQL through its event data store, so the sink cannot list audit logs itself.
+type CloudTrailLakeSink struct {
+	Client     types.CloudTrailDataClientAPI
+	ChannelARN string
+}
+
+// NewCloudTrailLakeSink : Initialize a sink that sends audit logs to a CloudTrail Lake channel
+func NewCloudTrailLakeSink(client types.CloudTrailDataClientAPI, channelARN string) CloudTrailLakeSink {
+	return CloudTrailLakeSink{
+		Client:     client,
+		ChannelARN: channelARN,
 	}
+}
 
-	// Add the record to dynamo
-	_, err = api.Client.PutItem(context.TODO(), &input)
-	if err != nil {
-		logrus.Errorln("Failed to put audit log in Dynamo", err)
-		logrus.Infof("Failed audit log: '%v'", auditLog)
-		return
+// Write : Send an audit log to the channel as an audit event with a unique id
+func (sink CloudTrailLakeSink) Write(ctx context.Context, auditLog types.AuditLog) error {
+	return sink.WriteBatch(ctx, []types.AuditLog{auditLog})
+}
+
+// WriteBatch : Send a list of audit logs to the channel, 100 at a time
+func (sink CloudTrailLakeSink) WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error {
+	for start := 0; start < len(auditLogs); start += cloudTrailBatchSize {
+		end := start + cloudTrailBatchSize
+		if end > len(auditLogs) {
+			end = len(auditLogs)
+		}
+
+		events := make([]cloudTrailDataTypes.AuditEvent, 0, end-start)
+		for _, auditLog := range auditLogs[start:end] {
+			event, err := auditEvent(auditLog)
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+
+		result, err := sink.Client.PutAuditEvents(ctx, &cloudtraildata.PutAuditEventsInput{
+			ChannelArn:  aws.String(sink.ChannelARN),
+			AuditEvents: events,
+		})
+		if err != nil {
+			return base.ContextError(ctx, err)
+		}
+
+		var failures []string
+		for _, item := range result.Failed {
+			failures = append(failures, fmt.Sprintf("%s - %s: %s", aws.ToString(item.Id), aws.ToString(item.ErrorCode), aws.ToString(item.ErrorMessage)))
+		}
+		if len(failures) > 0 {
+			return errors.New("Failed to record audit events: " + strings.Join(failures, ", "))
+		}
 	}
 
-	bs, err := json.Marshal(auditEvent)
+	return nil
+}
+
+// auditEvent : Convert an audit log into a CloudTrail Lake audit event with a unique id
+func auditEvent(auditLog types.AuditLog) (cloudTrailDataTypes.AuditEvent, error) {
+	eventTime, err := time.Parse(time.RFC3339Nano, auditLog.Time)
 	if err != nil {
-		logrus.WithError(err).WithField("AuditEvent", auditEvent).Errorf("Failed to marshal audit event")
-		return
+		return cloudTrailDataTypes.AuditEvent{}, err
 	}
 
-	auditEvents := []cloudTrailDataTypes.AuditEvent{
-		{
-			Id:        aws.String("unique-id"),
-			EventData: aws.String(string(bs)),
+	id := uuid.NewString()
+	eventData := types.AuditEventData{
+		Version: "1.0",
+		UserIdentity: types.AuditEventUserIdentity{
+			Type:        "ScoutrUser",
+			PrincipalId: auditLog.User.ID,
+			Details:     auditLog.User,
+		},
+		UserAgent:         auditLog.User.UserAgent,
+		SourceIPAddress:   auditLog.User.SourceIP,
+		EventSource:       "scoutr",
+		EventName:         auditLog.Action,
+		EventTime:         eventTime,
+		UID:               id,
+		RequestParameters: auditLog.Resource,
+		AdditionalEventData: map[string]interface{}{
+			"method":       auditLog.Method,
+			"path":         auditLog.Path,
+			"query_params": auditLog.QueryParams,
+			"body":         auditLog.Body,
 		},
 	}
 
-	result, err := api.auditClient.PutAuditEvents(context.TODO(), &cloudtraildata.PutAuditEventsInput{
-		ChannelArn:  aws.String("channel-arn"),
-		AuditEvents: auditEvents,
-	})
-
+	bs, err := json.Marshal(eventData)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to put audit event to cloudtrail lake")
-		return
+		return cloudTrailDataTypes.AuditEvent{}, err
 	}
 
-	for _, item := range result.Failed {
-		logrus.Errorf("Failed to record event %s - %s: %s", aws.ToString(item.Id), aws.ToString(item.ErrorCode), aws.ToString(item.ErrorMessage))
-	}
+	return cloudTrailDataTypes.AuditEvent{
+		Id:        aws.String(id),
+		EventData: aws.String(string(bs)),
+	}, nil
+}
 
+// Query : CloudTrail Lake channels cannot be read back
+func (sink CloudTrailLakeSink) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	return types.Page[types.AuditLog]{}, base.ErrAuditQueryUnsupported
 }
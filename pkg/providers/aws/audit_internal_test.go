@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoAuditTable : Stores the items put in the audit table and serves them back to scans
+type mockDynamoAuditTable struct {
+	types.DynamoClientAPI
+	items *[]map[string]dynamoTypes.AttributeValue
+	scans *[]*dynamodb.ScanInput
+}
+
+func (m mockDynamoAuditTable) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	*m.items = append(*m.items, params.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m mockDynamoAuditTable) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	*m.scans = append(*m.scans, params)
+	return &dynamodb.ScanOutput{Items: *m.items}, nil
+}
+
+func TestAuditTableAttributes(t *testing.T) {
+	var items []map[string]dynamoTypes.AttributeValue
+	var scans []*dynamodb.ScanInput
+	api := DynamoAPI{
+		Client:    mockDynamoAuditTable{items: &items, scans: &scans},
+		filtering: NewFilter(),
+		Scoutr: &base.Scoutr{
+			Config: config.Config{
+				AuditTable: "audit",
+			},
+		},
+	}
+	sink := api.AuditTableSink()
+
+	auditLog := types.AuditLog{
+		ID:       "log-1",
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		User:     types.AuditUser{ID: "user-123", SourceIP: "10.0.0.1"},
+		Action:   "UPDATE",
+		Resource: map[string]interface{}{"id": "1"},
+		Previous: map[string]interface{}{"id": "1", "name": "alpha"},
+		Changes:  map[string]types.AuditChange{"name": {Old: "alpha", New: "beta"}},
+		Sequence: 1,
+		Hash:     "abc",
+	}
+	if err := sink.Write(context.Background(), auditLog); err != nil {
+		t.Fatal(err)
+	}
+
+	// Attributes are named as the JSON fields, which the table key and filters expect
+	item := items[0]
+	for _, name := range []string{"id", "time", "user", "action", "resource", "previous", "changes", "sequence", "hash"} {
+		if _, ok := item[name]; !ok {
+			t.Errorf("Expected attribute %s, got %v", name, item)
+		}
+	}
+	if user, ok := item["user"].(*dynamoTypes.AttributeValueMemberM); !ok || user.Value["source_ip"] == nil {
+		t.Errorf("Unexpected user attribute %+v", item["user"])
+	}
+	if changes, ok := item["changes"].(*dynamoTypes.AttributeValueMemberM); !ok {
+		t.Errorf("Unexpected changes attribute %+v", item["changes"])
+	} else if change, ok := changes.Value["name"].(*dynamoTypes.AttributeValueMemberM); !ok || change.Value["new"] == nil {
+		t.Errorf("Unexpected change %+v", changes.Value["name"])
+	}
+
+	// Logs are read back from the same attributes, filtered by resource
+	page, err := sink.Query(context.Background(), map[string][]string{"resource.id": {"1"}}, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 1 || page.Items[0].Time != auditLog.Time || page.Items[0].Changes["name"].New != "beta" {
+		t.Errorf("Unexpected audit logs %+v", page.Items)
+	}
+
+	var names []string
+	for _, name := range scans[0].ExpressionAttributeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "id,resource,time" {
+		t.Errorf("Unexpected attribute names in the filter %v", names)
+	}
+}
@@ -0,0 +1,133 @@
+package aws_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/aws"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	sdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtraildata"
+	cloudTrailDataTypes "github.com/aws/aws-sdk-go-v2/service/cloudtraildata/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+type mockCloudTrailData struct {
+	inputs *[]*cloudtraildata.PutAuditEventsInput
+	fail   bool
+}
+
+func (m mockCloudTrailData) PutAuditEvents(ctx context.Context, params *cloudtraildata.PutAuditEventsInput, optFns ...func(*cloudtraildata.Options)) (*cloudtraildata.PutAuditEventsOutput, error) {
+	*m.inputs = append(*m.inputs, params)
+
+	output := &cloudtraildata.PutAuditEventsOutput{}
+	if m.fail {
+		output.Failed = []cloudTrailDataTypes.ResultErrorEntry{
+			{Id: params.AuditEvents[0].Id, ErrorCode: sdk.String("InvalidChannelARN"), ErrorMessage: sdk.String("invalid")},
+		}
+	}
+
+	return output, nil
+}
+
+func TestCloudTrailLakeSink(t *testing.T) {
+	var inputs []*cloudtraildata.PutAuditEventsInput
+	sink := aws.NewCloudTrailLakeSink(mockCloudTrailData{inputs: &inputs}, "arn:aws:cloudtrail:us-east-1:123456789012:channel/abc")
+
+	auditLog := types.AuditLog{
+		Time:   time.Now().UTC().Format(time.RFC3339Nano),
+		User:   types.AuditUser{ID: "user-123"},
+		Action: "CREATE",
+	}
+	for i := 0; i < 2; i++ {
+		if err := sink.Write(context.Background(), auditLog); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Events are sent to the configured channel with a unique id
+	ids := make(map[string]bool)
+	for _, input := range inputs {
+		if sdk.ToString(input.ChannelArn) != sink.ChannelARN {
+			t.Errorf("Unexpected channel %s", sdk.ToString(input.ChannelArn))
+		}
+
+		id := sdk.ToString(input.AuditEvents[0].Id)
+		if _, err := uuid.Parse(id); err != nil {
+			t.Errorf("Expected a UUID, got %s", id)
+		}
+		ids[id] = true
+
+		var eventData types.AuditEventData
+		if err := json.Unmarshal([]byte(sdk.ToString(input.AuditEvents[0].EventData)), &eventData); err != nil {
+			t.Fatal(err)
+		} else if eventData.UID != id || eventData.EventName != "CREATE" || eventData.UserIdentity.PrincipalId != "user-123" {
+			t.Errorf("Unexpected event data %+v", eventData)
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 unique ids, got %v", ids)
+	}
+
+	// Rejected events are reported
+	sink.Client = mockCloudTrailData{inputs: &inputs, fail: true}
+	if err := sink.Write(context.Background(), auditLog); err == nil {
+		t.Error("Expected an error for a rejected event")
+	}
+
+	// The channel cannot be queried
+	if _, err := sink.Query(context.Background(), nil, 0, ""); err == nil {
+		t.Error("Expected an error when querying the channel")
+	}
+}
+
+type mockDynamoBatchWrite struct {
+	types.DynamoClientAPI
+	batches *[]int
+}
+
+func (m mockDynamoBatchWrite) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	requests := params.RequestItems["audit"]
+	*m.batches = append(*m.batches, len(requests))
+
+	// Leave the last item of a full batch unprocessed
+	output := &dynamodb.BatchWriteItemOutput{}
+	if len(requests) == 25 && len(*m.batches) > 2 {
+		output.UnprocessedItems = map[string][]dynamoTypes.WriteRequest{"audit": requests[24:]}
+	}
+
+	return output, nil
+}
+
+func TestAuditTableWriteBatch(t *testing.T) {
+	var batches []int
+	api := aws.DynamoAPI{
+		Client: mockDynamoBatchWrite{batches: &batches},
+		Scoutr: &base.Scoutr{
+			Config: config.Config{
+				AuditTable: "audit",
+			},
+		},
+	}
+
+	auditLogs := make([]types.AuditLog, 30)
+	sink := api.AuditTableSink().(base.BatchAuditSink)
+
+	// Logs are written 25 at a time
+	if err := sink.WriteBatch(context.Background(), auditLogs); err != nil {
+		t.Fatal(err)
+	} else if len(batches) != 2 || batches[0] != 25 || batches[1] != 5 {
+		t.Errorf("Expected batches of 25 and 5, got %v", batches)
+	}
+
+	// Unprocessed items are reported, so the batch can be retried
+	if err := sink.WriteBatch(context.Background(), auditLogs); err == nil {
+		t.Error("Expected an error for unprocessed items")
+	}
+}
@@ -0,0 +1,181 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// transactionLimit : Maximum number of items written by a single TransactWriteItems call
+const transactionLimit = 100
+
+// batchCreate : Item of a batch create that passed validation, waiting to be written
+type batchCreate struct {
+	index  int
+	key    types.Key
+	body   map[string]interface{}
+	record map[string]interface{}
+	item   map[string]dynamoTypes.AttributeValue
+}
+
+// BatchCreate : Create several items, authenticating the user once. Items that pass validation and the creation
+// filters are written with TransactWriteItems, up to 100 at a time. Items whose key is already in use are reported as
+// failed and the rest of the transaction is retried without them, so a single bad item does not fail the batch.
+func (api DynamoAPI) BatchCreate(req types.Request, items []map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) ([]types.BatchResult, error) {
+	req, err := api.PrepareBatch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get key schema
+	schema, err := api.Schema(req.Context())
+	if err != nil {
+		log.Errorln("Failed to describe table", err)
+		return nil, err
+	}
+
+	// Items are only created if their key is not in use
+	var conditions interface{}
+	for _, name := range schema.Keys() {
+		conditions = api.filtering.And(conditions, expression.Name(name).AttributeNotExists())
+	}
+	expr, err := expression.NewBuilder().WithCondition(conditions.(expression.ConditionBuilder)).Build()
+	if err != nil {
+		log.Errorln("Encountered error while building expression", err)
+		return nil, err
+	}
+
+	// Validate every item before writing any of them. A transaction cannot write an item twice.
+	results := make([]types.BatchResult, len(items))
+	var pending []batchCreate
+	seen := make(map[string]bool)
+	for i, item := range items {
+		key := make(types.Key)
+		var missing []string
+		for _, name := range schema.Keys() {
+			if value, ok := item[name]; ok {
+				key[name] = value
+			} else {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(missing) > 0 {
+			results[i] = types.NewBatchResult(key, "", nil, &types.BadRequest{
+				Message: fmt.Sprintf("Missing required key fields: %v", missing),
+			})
+			continue
+		}
+
+		if _, err := api.PrepareCreate(req, item, validation, requiredFields); err != nil {
+			results[i] = types.NewBatchResult(key, "", nil, err)
+			continue
+		}
+
+		id, err := api.ItemID(req.Context(), key)
+		if err != nil {
+			results[i] = types.NewBatchResult(key, "", nil, err)
+			continue
+		} else if seen[id] {
+			results[i] = types.NewBatchResult(key, "", nil, &types.BadRequest{
+				Message: "Item appears more than once in the batch",
+			})
+			continue
+		}
+		seen[id] = true
+
+		record := api.InitialVersion(item)
+		av, err := attributevalue.MarshalMap(record)
+		if err != nil {
+			results[i] = types.NewBatchResult(key, "", nil, err)
+			continue
+		}
+
+		pending = append(pending, batchCreate{index: i, key: key, body: item, record: record, item: av})
+	}
+
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(pending); start += transactionLimit {
+		end := start + transactionLimit
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		api.createTransaction(req, user, expr, auditAction, pending[start:end], results)
+	}
+
+	return results, nil
+}
+
+// createTransaction : Write a set of new items in a transaction, recording the result of each. Items that cancel the
+// transaction are dropped from it until it succeeds. Each item is audited once written.
+func (api DynamoAPI) createTransaction(req types.Request, user *types.User, expr expression.Expression, auditAction string, pending []batchCreate, results []types.BatchResult) {
+	for len(pending) > 0 {
+		input := &dynamodb.TransactWriteItemsInput{}
+		for _, item := range pending {
+			input.TransactItems = append(input.TransactItems, dynamoTypes.TransactWriteItem{
+				Put: &dynamoTypes.Put{
+					TableName:                 aws.String(api.Config.DataTable),
+					Item:                      item.item,
+					ConditionExpression:       expr.Condition(),
+					ExpressionAttributeNames:  expr.Names(),
+					ExpressionAttributeValues: expr.Values(),
+				},
+			})
+		}
+
+		_, err := api.Client.TransactWriteItems(req.Context(), input)
+
+		// Drop the items that cancelled the transaction and try again with the rest
+		var cancelled *dynamoTypes.TransactionCanceledException
+		if errors.As(err, &cancelled) && len(cancelled.CancellationReasons) == len(pending) {
+			var retry []batchCreate
+			for i, reason := range cancelled.CancellationReasons {
+				item := pending[i]
+				switch aws.ToString(reason.Code) {
+				case "", "None":
+					retry = append(retry, item)
+				case "ConditionalCheckFailed":
+					results[item.index] = types.NewBatchResult(item.key, "", nil, &types.BadRequest{
+						Message: "Item already exists or you do not have permission to create it",
+					})
+				default:
+					results[item.index] = types.NewBatchResult(item.key, "", nil, fmt.Errorf("%s: %s", aws.ToString(reason.Code), aws.ToString(reason.Message)))
+				}
+			}
+
+			if len(retry) < len(pending) {
+				pending = retry
+				continue
+			}
+		}
+
+		if err != nil {
+			log.WithError(err).Errorln("Encountered error while attempting to create records")
+			err = base.ContextError(req.Context(), err)
+			for _, item := range pending {
+				results[item.index] = types.NewBatchResult(item.key, "", nil, err)
+			}
+			return
+		}
+
+		// Create audit logs, recording every field as a change. Each log only holds its own item of the batch.
+		for _, item := range pending {
+			err := api.WriteChangeAuditLog(auditAction, req.WithBody(item.body), user, item.key, nil, nil, item.record)
+			results[item.index] = types.NewBatchResult(item.key, types.BatchStatusCreated, nil, err)
+		}
+		return
+	}
+}
@@ -0,0 +1,146 @@
+package aws
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoTransact : Serves the auth table and records TransactWriteItems calls, cancelling transactions that
+// write an item whose key is already in use
+type mockDynamoTransact struct {
+	types.DynamoClientAPI
+	user     map[string]dynamoTypes.AttributeValue
+	existing string
+	inputs   *[]*dynamodb.TransactWriteItemsInput
+	getItems *int
+}
+
+func (m mockDynamoTransact) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{
+		Table: &dynamoTypes.TableDescription{
+			KeySchema: []dynamoTypes.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: dynamoTypes.KeyTypeHash}},
+		},
+	}, nil
+}
+
+func (m mockDynamoTransact) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	*m.getItems++
+	return &dynamodb.GetItemOutput{Item: m.user}, nil
+}
+
+func (m mockDynamoTransact) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	*m.inputs = append(*m.inputs, params)
+
+	reasons := make([]dynamoTypes.CancellationReason, len(params.TransactItems))
+	cancelled := false
+	for i, item := range params.TransactItems {
+		reasons[i].Code = aws.String("None")
+		if id, ok := item.Put.Item["id"].(*dynamoTypes.AttributeValueMemberS); ok && id.Value == m.existing {
+			reasons[i].Code = aws.String("ConditionalCheckFailed")
+			cancelled = true
+		}
+	}
+
+	if cancelled {
+		return nil, &dynamoTypes.TransactionCanceledException{CancellationReasons: reasons}
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func TestBatchCreate(t *testing.T) {
+	user, err := attributevalue.MarshalMap(types.User{
+		ID:       "user1",
+		Username: "user1",
+		Name:     "User One",
+		Email:    "user1@example.com",
+		Permissions: types.Permissions{
+			PermittedEndpoints: []types.PermittedEndpoint{{Endpoint: ".*", Method: "POST"}},
+			CreateFilters:      []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := &[]*dynamodb.TransactWriteItemsInput{}
+	getItems := 0
+	api := DynamoAPI{
+		Client:    mockDynamoTransact{user: user, existing: "1", inputs: inputs, getItems: &getItems},
+		filtering: NewFilter(),
+		Scoutr: &base.Scoutr{
+			Config: config.Config{
+				AuthTable: "auth",
+				DataTable: "data",
+			},
+		},
+	}
+	api.ScoutrBase = api
+	sink := base.NewFileAuditSink(filepath.Join(t.TempDir(), "audit.log"))
+	api.AuditSink = sink
+
+	items := []map[string]interface{}{
+		{"id": "1", "owner": "a"},
+		{"id": "2", "owner": "a"},
+		{"id": "3", "owner": "b"},
+		{"id": "2", "owner": "a"},
+		{"owner": "a"},
+		{"id": "4", "owner": "a"},
+	}
+	req := types.Request{User: types.RequestUser{ID: "user1"}, Method: "POST", Path: "/batch/create/", Body: items}
+	results, err := api.BatchCreate(req, items, nil, nil, base.AuditActionCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Items that fail validation are never written, and items that cancel the transaction are dropped from it
+	for i, status := range []string{
+		types.BatchStatusFailed,
+		types.BatchStatusCreated,
+		types.BatchStatusFailed,
+		types.BatchStatusFailed,
+		types.BatchStatusFailed,
+		types.BatchStatusCreated,
+	} {
+		if results[i].Status != status {
+			t.Errorf("Expected item %d to be %s, got %+v", i, status, results[i])
+		}
+	}
+	if results[0].Message != "Item already exists or you do not have permission to create it" {
+		t.Errorf("Unexpected result %+v", results[0])
+	}
+
+	if len(*inputs) != 2 || len((*inputs)[0].TransactItems) != 3 || len((*inputs)[1].TransactItems) != 2 {
+		t.Errorf("Expected a transaction of 3 items retried with 2, got %d transactions", len(*inputs))
+	}
+	if condition := aws.ToString((*inputs)[1].TransactItems[0].Put.ConditionExpression); condition == "" {
+		t.Error("Expected items to be created only if their key is not in use")
+	}
+
+	// The user is only fetched once for the whole batch
+	if getItems != 1 {
+		t.Errorf("Expected the user to be fetched once, got %d", getItems)
+	}
+
+	// Each audit log only holds its own item of the batch
+	page, err := sink.Query(context.Background(), nil, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 2 {
+		t.Fatalf("Expected 2 audit logs, got %d", len(page.Items))
+	}
+	for _, auditLog := range page.Items {
+		if body, ok := auditLog.Body.(map[string]interface{}); !ok || body["id"] != auditLog.Resource["id"] {
+			t.Errorf("Unexpected body %+v of the audit log of item %v", auditLog.Body, auditLog.Resource)
+		}
+	}
+}
@@ -1,21 +1,16 @@
 package aws
 
 import (
-	"context"
 	"errors"
 
-	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/smithy-go"
 	log "github.com/sirupsen/logrus"
 )
 
 // Create : Create an item
-func (api DynamoAPI) Create(req types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string) error {
+func (api DynamoAPI) Create(req types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) error {
 	var conditions interface{}
 
 	// Get the user
@@ -25,22 +20,21 @@ func (api DynamoAPI) Create(req types.Request, item map[string]interface{}, vali
 		return err
 	}
 
+	// New items start at their first version
+	item = api.InitialVersion(item)
+
 	// Get key schema
-	output, err := api.Client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
-		TableName: aws.String(api.Config.DataTable),
-	})
+	schema, err := api.Schema(req.Context())
 	if err != nil {
 		log.Errorln("Failed to describe table", err)
 		return err
 	}
 
 	// Append key schema conditions
-	partitionKey := ""
-	for _, schema := range output.Table.KeySchema {
-		if schema.KeyType == dynamoTypes.KeyTypeHash {
-			partitionKey = *schema.AttributeName
-		}
-		conditions = api.filtering.And(conditions, expression.Name(*schema.AttributeName).AttributeNotExists())
+	key := make(map[string]interface{})
+	for _, name := range schema.Keys() {
+		key[name] = item[name]
+		conditions = api.filtering.And(conditions, expression.Name(name).AttributeNotExists())
 	}
 
 	// Build expression
@@ -51,7 +45,7 @@ func (api DynamoAPI) Create(req types.Request, item map[string]interface{}, vali
 	}
 
 	// Put the item into the table
-	if err := api.PutItem(api.Config.DataTable, item, &expr); err != nil {
+	if err := api.PutItem(req.Context(), api.Config.DataTable, item, &expr); err != nil {
 		log.WithError(err).Errorln("Encountered error while attempting to create record")
 
 		// Check if this was a conditional check failure
@@ -65,8 +59,6 @@ func (api DynamoAPI) Create(req types.Request, item map[string]interface{}, vali
 		return err
 	}
 
-	// Create audit log
-	api.auditLog(base.AuditActionCreate, req, user, map[string]interface{}{partitionKey: item[partitionKey]}, nil)
-
-	return nil
+	// Create audit log, recording every field as a change
+	return api.WriteChangeAuditLog(auditAction, req, user, key, nil, nil, item)
 }
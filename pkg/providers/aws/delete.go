@@ -1,21 +1,18 @@
 package aws
 
 import (
-	"context"
 	"errors"
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/smithy-go"
 	"github.com/sirupsen/logrus"
 )
 
 // Delete : Delete an item
-func (api DynamoAPI) Delete(request types.Request, partitionKey map[string]interface{}) error {
+func (api DynamoAPI) Delete(request types.Request, partitionKey types.Key) error {
 	// Get the user
 	user, err := api.InitializeRequest(request)
 	if err != nil {
@@ -23,8 +20,15 @@ func (api DynamoAPI) Delete(request types.Request, partitionKey map[string]inter
 		return err
 	}
 
+	// Get key schema
+	schema, err := api.Schema(request.Context())
+	if err != nil {
+		logrus.Errorln("Failed to describe table", err)
+		return err
+	}
+
 	// Build partition key
-	dynamoKeyParts, err := attributevalue.MarshalMap(partitionKey)
+	dynamoKeyParts, err := schema.marshalKey(partitionKey)
 	if err != nil {
 		logrus.Errorln("Failed to marshal partition key", err)
 		return err
@@ -38,46 +42,69 @@ func (api DynamoAPI) Delete(request types.Request, partitionKey map[string]inter
 		return err
 	}
 
-	// Get key schema
-	keySchema, err := api.Client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
-		TableName: aws.String(api.Config.DataTable),
-	})
-	if err != nil {
-		logrus.Errorln("Failed to describe table", err)
+	// Append key schema conditions
+	for _, name := range schema.Keys() {
+		conditions = api.filtering.And(conditions, expression.Name(name).AttributeExists())
+	}
+	if conditions, err = api.versionCondition(request, conditions); err != nil {
 		return err
 	}
 
-	// Append key schema conditions
-	for _, schema := range keySchema.Table.KeySchema {
-		conditions = api.filtering.And(conditions, expression.Name(*schema.AttributeName).AttributeExists())
+	// Move the item to the trash instead when soft delete is enabled
+	builder := expression.NewBuilder().WithCondition(conditions.(expression.ConditionBuilder))
+	fields := base.TrashFields(user)
+	softDelete := api.SoftDeletes(request)
+	if softDelete {
+		var updateExpr expression.UpdateBuilder
+		for key, value := range fields {
+			updateExpr = updateExpr.Set(expression.Name(key), expression.Value(value))
+		}
+		if api.Versioned() {
+			updateExpr = updateExpr.Add(expression.Name(api.Config.VersionField), expression.Value(1))
+		}
+		builder = builder.WithUpdate(updateExpr)
 	}
 
 	// Build expression
-	if conditions != nil {
-		expr, err = expression.NewBuilder().WithCondition(conditions.(expression.ConditionBuilder)).Build()
-		if err != nil {
-			logrus.Errorln("Encountered error while building expression", err)
-			return err
-		}
+	expr, err = builder.Build()
+	if err != nil {
+		logrus.Errorln("Encountered error while building expression", err)
+		return err
 	}
 
-	// Delete the item from dynamo
-	if err := api.DeleteItem(api.Config.DataTable, dynamoKeyParts, &expr); err != nil {
+	// Delete the item from dynamo, or mark it as deleted
+	var previous, output types.Record
+	if softDelete {
+		var old *types.Record
+		if old, err = UpdateItem[types.Record](request.Context(), api.Client, api.Config.DataTable, dynamoKeyParts, expr, dynamoTypes.ReturnValueAllOld); old != nil {
+			previous = *old
+		}
+	} else {
+		previous, err = api.DeleteItem(request.Context(), api.Config.DataTable, dynamoKeyParts, &expr)
+	}
+	if err != nil {
 		logrus.Errorln("Error while attempting to delete item in dynamo", err)
 
 		// Check if this was a conditional check failure
 		var apiErr smithy.APIError
 		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ConditionalCheckFailedException" {
-			return &types.BadRequest{
-				Message: "Item does not exist or you do not have permission to delete it",
-			}
+			return api.conditionFailure(request, user, base.FilterActionDelete, err, "Item does not exist or you do not have permission to delete it")
 		}
 
 		return err
 	}
 
-	// Create audit log
-	api.auditLog(base.AuditActionDelete, request, user, partitionKey, nil)
+	if softDelete {
+		output = make(types.Record, len(previous)+len(fields))
+		for key, value := range previous {
+			output[key] = value
+		}
+		for key, value := range fields {
+			output[key] = value
+		}
+		api.IncrementVersion(output)
+	}
 
-	return nil
+	// Create audit log
+	return api.WriteChangeAuditLog(base.DeleteAuditAction(request), request, user, partitionKey, nil, previous, output)
 }
@@ -0,0 +1,85 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoDelete : Serves the auth table of a data table keyed by a number, recording DeleteItem calls
+type mockDynamoDelete struct {
+	types.DynamoClientAPI
+	user   map[string]dynamoTypes.AttributeValue
+	inputs *[]*dynamodb.DeleteItemInput
+}
+
+func (m mockDynamoDelete) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{
+		Table: &dynamoTypes.TableDescription{
+			KeySchema: []dynamoTypes.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: dynamoTypes.KeyTypeHash}},
+			AttributeDefinitions: []dynamoTypes.AttributeDefinition{
+				{AttributeName: aws.String("id"), AttributeType: dynamoTypes.ScalarAttributeTypeN},
+			},
+		},
+	}, nil
+}
+
+func (m mockDynamoDelete) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: m.user}, nil
+}
+
+func (m mockDynamoDelete) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	*m.inputs = append(*m.inputs, params)
+
+	return &dynamodb.DeleteItemOutput{Attributes: params.Key}, nil
+}
+
+func TestDeleteNumericKey(t *testing.T) {
+	user, err := attributevalue.MarshalMap(types.User{
+		ID:       "user1",
+		Username: "user1",
+		Name:     "User One",
+		Email:    "user1@example.com",
+		Permissions: types.Permissions{
+			PermittedEndpoints: []types.PermittedEndpoint{{Endpoint: ".*", Method: "DELETE"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := &[]*dynamodb.DeleteItemInput{}
+	api := DynamoAPI{
+		Client:    mockDynamoDelete{user: user, inputs: inputs},
+		filtering: NewFilter(),
+		Scoutr: &base.Scoutr{
+			Config: config.Config{
+				AuthTable: "auth",
+				DataTable: "data",
+			},
+		},
+	}
+	api.ScoutrBase = api
+
+	// Keys taken from the path are strings, and only the key attributes are sent
+	req := types.Request{User: types.RequestUser{ID: "user1"}, Method: "DELETE", Path: "/item/1"}
+	if err := api.Delete(req, types.Key{"id": "1", "name": "alpha"}); err != nil {
+		t.Fatal(err)
+	}
+
+	key := (*inputs)[0].Key
+	if value, ok := key["id"].(*dynamoTypes.AttributeValueMemberN); !ok || value.Value != "1" || len(key) != 1 {
+		t.Errorf("Unexpected key %+v", key)
+	}
+
+	if err := api.Delete(req, types.Key{"id": "one"}); err == nil {
+		t.Error("Expected error deleting with a key that is not a number")
+	}
+}
@@ -2,20 +2,24 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	log "github.com/sirupsen/logrus"
 )
 
-// Get : Get an item from the table
-func (api DynamoAPI) Get(req types.Request, id string) (types.Record, error) {
-	var partitionKey string
-
+// Get : Get an item from the table. When the key contains every key attribute of the table, the item is fetched
+// with GetItem. Tables with a sort key can also be looked up by partition key alone, in which case the partition
+// must contain a single item.
+func (api DynamoAPI) Get(req types.Request, key types.Key) (types.Record, error) {
 	// Get the user
 	user, err := api.InitializeRequest(req)
 	if err != nil {
@@ -23,71 +27,165 @@ func (api DynamoAPI) Get(req types.Request, id string) (types.Record, error) {
 		return nil, err
 	}
 
-	// Lookup the partition key
-	tableInfo, err := api.Client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
-		TableName: aws.String(api.Config.DataTable),
-	})
+	schema, err := api.Schema(req.Context())
 	if err != nil {
 		log.Errorln("Failed to describe table", err)
 		return nil, err
 	}
 
-	// Get partition key
-	for _, schema := range tableInfo.Table.KeySchema {
-		if schema.KeyType == dynamoTypes.KeyTypeHash {
-			partitionKey = *schema.AttributeName
-			break
+	if _, ok := key[schema.PartitionKey]; !ok {
+		return nil, &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: [%s]", schema.PartitionKey),
+		}
+	}
+
+	notFound := &types.NotFound{
+		Message: "Item does not exist or you do not have permission to view it",
+	}
+
+	var record types.Record
+	if _, hasSortKey := key[schema.SortKey]; schema.SortKey == "" || hasSortKey {
+		// Fetch the item by its full key
+		dynamoKey, err := schema.marshalKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := GetItem[types.Record](req.Context(), api.Client, &dynamodb.GetItemInput{
+			TableName: aws.String(api.Config.DataTable),
+			Key:       dynamoKey,
+		})
+		if err != nil {
+			log.Errorln("Error while attempting to get item", err)
+			return nil, err
+		} else if item == nil {
+			return nil, notFound
+		}
+		record = *item
+	} else {
+		// Query the partition
+		value, ok := schema.keyValue(schema.PartitionKey, fmt.Sprint(key[schema.PartitionKey]))
+		if !ok {
+			return nil, notFound
+		}
+
+		page, err := api.read(req.Context(), &queryPlan{
+			KeySchema:    schema.KeySchema,
+			KeyCondition: expression.Key(schema.PartitionKey).Equal(value),
+		}, nil, nil, 2, "")
+		if err != nil {
+			log.Errorln("Error while attempting to query item", err)
+			return nil, err
+		} else if len(page.Items) > 1 {
+			return nil, &types.BadRequest{
+				Message: "Multiple items returned",
+			}
+		} else if len(page.Items) == 0 {
+			return nil, notFound
 		}
+		record = page.Items[0]
 	}
 
-	// Build filters
-	conditions, err := api.filtering.Filter(user, nil, "")
+	// Items the user is not permitted to view are treated as though they do not exist
+	f := base.NewLocalFilter(record)
+	conditions, err := f.Filter(user, nil, base.FilterActionRead)
 	if err != nil {
 		log.Errorln("Error encountered during filtering", err)
 		return nil, err
+	} else if !f.Matches(conditions) {
+		return nil, notFound
 	}
 
-	// Build key condition
-	keyCondition := expression.Name(partitionKey).Equal(expression.Value(id))
-	conditions = api.filtering.And(conditions, keyCondition)
+	// Filter the response
+	data := []types.Record{record}
+	api.PostProcess(data, user)
 
-	// Build expression
-	expr, err := expression.NewBuilder().WithFilter(conditions.(expression.ConditionBuilder)).Build()
-	if err != nil {
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionGet, req, user, key, nil); err != nil {
 		return nil, err
 	}
 
-	// Build scan input
-	input := &dynamodb.ScanInput{
-		TableName:                 aws.String(api.Config.DataTable),
-		FilterExpression:          expr.Filter(),
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
+	return data[0], nil
+}
+
+// marshalKey : Convert the key of an item to Dynamo format. Values of numeric key attributes may be passed as strings.
+func (schema TableSchema) marshalKey(key types.Key) (map[string]dynamoTypes.AttributeValue, error) {
+	dynamoKey := make(map[string]dynamoTypes.AttributeValue)
+	for _, field := range schema.Keys() {
+		value, ok := key[field]
+		if !ok {
+			return nil, &types.BadRequest{
+				Message: fmt.Sprintf("Missing required key fields: [%s]", field),
+			}
+		}
+
+		if s, ok := value.(string); ok && schema.AttributeTypes[field] == dynamoTypes.ScalarAttributeTypeN {
+			if _, err := strconv.ParseFloat(s, 64); err != nil {
+				return nil, &types.BadRequest{
+					Message: fmt.Sprintf("Key field %s must be a number", field),
+				}
+			}
+			value = json.Number(s)
+		}
+
+		av, err := attributevalue.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		dynamoKey[field] = av
 	}
 
-	// Download the data
-	data, err := Scan[types.Record](api.Client, input)
+	return dynamoKey, nil
+}
+
+// NormalizeKey : Convert the values of numeric key attributes to numbers, so keys given as numbers and as strings
+// refer to the same item
+func (api DynamoAPI) NormalizeKey(ctx context.Context, key types.Key) (types.Key, error) {
+	schema, err := api.Schema(ctx)
 	if err != nil {
-		log.Errorln("Error while attempting to list records", err)
+		log.Errorln("Failed to describe table", err)
 		return nil, err
 	}
 
-	// Filter the response
-	api.PostProcess(data, user)
-
-	// Make sure only a single record was returned
-	if len(data) > 1 {
-		return nil, &types.BadRequest{
-			Message: "Multiple items returned",
+	normalized := make(types.Key, len(key))
+	for field, value := range key {
+		normalized[field] = value
+		if schema.AttributeTypes[field] != dynamoTypes.ScalarAttributeTypeN {
+			continue
 		}
-	} else if len(data) == 0 {
-		return nil, &types.NotFound{
-			Message: "Item does not exist or you do not have permission to view it",
+
+		number, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+		if err != nil {
+			return nil, &types.BadRequest{
+				Message: fmt.Sprintf("Key field %s must be a number", field),
+			}
 		}
+		normalized[field] = number
 	}
 
-	// Create audit log
-	api.auditLog(base.AuditActionGet, req, user, map[string]interface{}{partitionKey: id}, nil)
+	return normalized, nil
+}
 
-	return data[0], nil
+// FetchItem : Fetch an item by its full key, without checking the user's permissions
+func (api DynamoAPI) FetchItem(ctx context.Context, key types.Key) (types.Record, error) {
+	schema, err := api.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamoKey, err := schema.marshalKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := GetItem[types.Record](ctx, api.Client, &dynamodb.GetItemInput{
+		TableName:      aws.String(api.Config.DataTable),
+		Key:            dynamoKey,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil || item == nil {
+		return nil, err
+	}
+
+	return *item, nil
 }
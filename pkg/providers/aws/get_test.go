@@ -0,0 +1,185 @@
+package aws_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/aws"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	awsSDK "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoTables : Serves items from in-memory tables. The data table is keyed by id, and by version when sortKey is set.
+type mockDynamoTables struct {
+	types.DynamoClientAPI
+	tables  map[string][]map[string]dynamoTypes.AttributeValue
+	sortKey bool
+	calls   map[string]int
+}
+
+func (m mockDynamoTables) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	m.calls["DescribeTable"]++
+
+	schema := []dynamoTypes.KeySchemaElement{{AttributeName: awsSDK.String("id"), KeyType: dynamoTypes.KeyTypeHash}}
+	if m.sortKey {
+		schema = append(schema, dynamoTypes.KeySchemaElement{AttributeName: awsSDK.String("version"), KeyType: dynamoTypes.KeyTypeRange})
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: &dynamoTypes.TableDescription{KeySchema: schema}}, nil
+}
+
+func (m mockDynamoTables) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	m.calls["GetItem"]++
+
+	var key map[string]interface{}
+	if err := attributevalue.UnmarshalMap(params.Key, &key); err != nil {
+		return nil, err
+	}
+
+	for _, item := range m.tables[awsSDK.ToString(params.TableName)] {
+		matches := true
+		for field, value := range key {
+			var itemValue interface{}
+			if err := attributevalue.Unmarshal(item[field], &itemValue); err != nil {
+				return nil, err
+			}
+			matches = matches && fmt.Sprint(itemValue) == fmt.Sprint(value)
+		}
+
+		if matches {
+			return &dynamodb.GetItemOutput{Item: item}, nil
+		}
+	}
+
+	return &dynamodb.GetItemOutput{}, nil
+}
+
+func (m mockDynamoTables) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	m.calls["Query"]++
+
+	// The only value in the key condition is the partition key
+	var id string
+	for _, value := range params.ExpressionAttributeValues {
+		if err := attributevalue.Unmarshal(value, &id); err != nil {
+			return nil, err
+		}
+	}
+
+	output := &dynamodb.QueryOutput{}
+	for _, item := range m.tables[awsSDK.ToString(params.TableName)] {
+		if value, ok := item["id"].(*dynamoTypes.AttributeValueMemberS); ok && value.Value == id {
+			output.Items = append(output.Items, item)
+		}
+	}
+
+	return output, nil
+}
+
+func newGetAPI(t *testing.T, sortKey bool) (aws.DynamoAPI, map[string]int) {
+	marshal := func(item interface{}) map[string]dynamoTypes.AttributeValue {
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return av
+	}
+
+	// Users are keyed by id, but their attributes are decoded using the field names of types.User
+	user := marshal(types.User{
+		ID:       "user1",
+		Username: "user1",
+		Name:     "User One",
+		Email:    "user1@example.com",
+		Permissions: types.Permissions{
+			PermittedEndpoints: []types.PermittedEndpoint{{Endpoint: ".*", Method: "GET"}},
+			ReadFilters:        []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}},
+		},
+	})
+	user["id"] = user["ID"]
+
+	calls := make(map[string]int)
+	api := aws.DynamoAPI{
+		Client: mockDynamoTables{
+			tables: map[string][]map[string]dynamoTypes.AttributeValue{
+				"auth": {user},
+				"data": {
+					marshal(map[string]interface{}{"id": "1", "version": "1", "status": "active"}),
+					marshal(map[string]interface{}{"id": "1", "version": "2", "status": "active"}),
+					marshal(map[string]interface{}{"id": "2", "version": "1", "status": "locked"}),
+				},
+			},
+			sortKey: sortKey,
+			calls:   calls,
+		},
+		Scoutr: &base.Scoutr{
+			Config: config.Config{
+				AuthTable: "auth",
+				DataTable: "data",
+			},
+		},
+	}
+	api.ScoutrBase = api
+
+	return api, calls
+}
+
+func TestGet(t *testing.T) {
+	api, calls := newGetAPI(t, false)
+	req := types.Request{User: types.RequestUser{ID: "user1"}, Method: "GET", Path: "/item/1"}
+
+	record, err := api.Get(req, types.Key{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	} else if record["id"] != "1" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	if calls["Query"] != 0 {
+		t.Errorf("Expected a GetItem, but the table was queried %d times", calls["Query"])
+	}
+
+	// Records hidden by read filters and missing records are not found
+	for _, id := range []string{"2", "3"} {
+		if _, err := api.Get(req, types.Key{"id": id}); err == nil {
+			t.Errorf("Expected an error for %s", id)
+		} else if _, ok := err.(*types.NotFound); !ok {
+			t.Errorf("Expected not found, got %v", err)
+		}
+	}
+}
+
+func TestGetCompositeKey(t *testing.T) {
+	api, calls := newGetAPI(t, true)
+	req := types.Request{User: types.RequestUser{ID: "user1"}, Method: "GET", Path: "/item/1"}
+
+	record, err := api.Get(req, types.Key{"id": "1", "version": "2"})
+	if err != nil {
+		t.Fatal(err)
+	} else if record["version"] != "2" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if calls["GetItem"] != 2 || calls["Query"] != 0 {
+		t.Errorf("Expected a single GetItem for the record, got %+v", calls)
+	}
+
+	// The partition key alone must identify a single item
+	if _, err := api.Get(req, types.Key{"id": "1"}); err == nil {
+		t.Error("Expected an error for multiple items")
+	} else if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	if _, err := api.Get(req, types.Key{"id": "2"}); err == nil {
+		t.Error("Expected not found error")
+	}
+
+	if _, err := api.Get(req, types.Key{"version": "2"}); err == nil {
+		t.Error("Expected an error for a missing partition key")
+	}
+}
@@ -5,27 +5,47 @@ import (
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/sirupsen/logrus"
 )
 
 // List : Lists all items in a table
 func (api DynamoAPI) List(req types.Request) ([]types.Record, error) {
+	page, err := api.list(req, false)
+	return page.Items, err
+}
+
+// ListPage : Lists a page of items in a table, in the order they are scanned
+func (api DynamoAPI) ListPage(req types.Request) (types.Page[types.Record], error) {
+	return api.list(req, true)
+}
+
+// list : Lists the items in a table, either all at once or a page at a time
+func (api DynamoAPI) list(req types.Request, paginate bool) (types.Page[types.Record], error) {
 	// Get the user
 	user, err := api.InitializeRequest(req)
 	if err != nil {
 		// Bad user - pass the error through
-		return nil, err
+		return types.Page[types.Record]{}, err
 	}
 
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(api.Config.DataTable),
+	limit := 0
+	if paginate {
+		if limit, err = base.PageLimit(req); err != nil {
+			return types.Page[types.Record]{}, err
+		}
 	}
 
+	// Query instead of scanning when the params cover the key of the table or an index
+	schema, err := api.Schema(req.Context())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to describe table")
+		return types.Page[types.Record]{}, err
+	}
+	plan, params := schema.planQuery(user, api.BuildParams(req))
+
 	// Build filters
-	conditions, err := api.filtering.Filter(user, api.BuildParams(req), "")
+	conditions, err := api.filtering.Filter(user, params, "")
 	if err != nil {
 		logrus.WithError(err).Error("Filtering failed")
 
@@ -33,28 +53,11 @@ func (api DynamoAPI) List(req types.Request) ([]types.Record, error) {
 			api.Config.ErrorFunc(&req, user, err)
 		}
 
-		return nil, err
-	}
-	if conditions != nil {
-		expr, err := expression.NewBuilder().WithFilter(conditions.(expression.ConditionBuilder)).Build()
-		if err != nil {
-			logrus.WithError(err).Error("Failed to build filter expression")
-
-			if api.Config.ErrorFunc != nil {
-				api.Config.ErrorFunc(&req, user, err)
-			}
-
-			return nil, err
-		}
-
-		// Update scan input
-		input.FilterExpression = expr.Filter()
-		input.ExpressionAttributeNames = expr.Names()
-		input.ExpressionAttributeValues = expr.Values()
+		return types.Page[types.Record]{}, err
 	}
 
 	// Download the data
-	data, err := Scan[types.Record](api.Client, input)
+	page, err := api.read(req.Context(), plan, conditions, nil, limit, req.Next)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to list records")
 
@@ -62,16 +65,18 @@ func (api DynamoAPI) List(req types.Request) ([]types.Record, error) {
 			api.Config.ErrorFunc(&req, user, err)
 		}
 
-		return nil, err
+		return types.Page[types.Record]{}, err
 	}
 
 	// Filter the response
-	api.PostProcess(data, user)
+	api.PostProcess(page.Items, user)
 
 	// Create audit log
-	api.auditLog(base.AuditActionList, req, user, nil, nil)
+	if err := api.WriteAuditLog(base.AuditActionList, req, user, nil, nil); err != nil {
+		return types.Page[types.Record]{}, err
+	}
 
-	return data, nil
+	return page, nil
 }
 
 // ListUniqueValues : Lists unique values in a table
@@ -83,16 +88,20 @@ func (api DynamoAPI) ListUniqueValues(req types.Request, uniqueKey string) ([]st
 		return nil, err
 	}
 
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(api.Config.DataTable),
-	}
-
 	// Copy queryParams into params
 	params := make(map[string][]string)
 	for key, values := range req.QueryParams {
 		params[key] = append(params[key], values...)
 	}
 
+	// Query instead of scanning when the params cover the key of the table or an index
+	schema, err := api.Schema(req.Context())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to describe table")
+		return nil, err
+	}
+	plan, params := schema.planQuery(user, params)
+
 	// Build filters
 	conditions, err := api.filtering.Filter(user, params, "")
 	if err != nil {
@@ -105,32 +114,16 @@ func (api DynamoAPI) ListUniqueValues(req types.Request, uniqueKey string) ([]st
 		return nil, err
 	}
 
-	// Build unique key condition
-	conditions = api.filtering.And(conditions, expression.Name(uniqueKey).AttributeExists())
+	// Build unique key condition. Key attributes always exist, and cannot be filtered on when querying
+	if plan == nil || (plan.PartitionKey != uniqueKey && plan.SortKey != uniqueKey) {
+		conditions = api.filtering.And(conditions, expression.Name(uniqueKey).AttributeExists())
+	}
 
 	// Build projection expression
 	projection := expression.NamesList(expression.Name(uniqueKey))
 
-	// Build filter
-	expr, err := expression.NewBuilder().WithFilter(conditions.(expression.ConditionBuilder)).WithProjection(projection).Build()
-	if err != nil {
-		logrus.WithError(err).Error("Failed to build filter expression")
-
-		if api.Config.ErrorFunc != nil {
-			api.Config.ErrorFunc(&req, user, err)
-		}
-
-		return nil, err
-	}
-
-	// Update scan input
-	input.FilterExpression = expr.Filter()
-	input.ExpressionAttributeNames = expr.Names()
-	input.ExpressionAttributeValues = expr.Values()
-	input.ProjectionExpression = expr.Projection()
-
 	// Download the data
-	data, err := Scan[types.Record](api.Client, input)
+	page, err := api.read(req.Context(), plan, conditions, &projection, 0, "")
 	if err != nil {
 		logrus.WithError(err).Error("Failed to list records")
 
@@ -140,6 +133,7 @@ func (api DynamoAPI) ListUniqueValues(req types.Request, uniqueKey string) ([]st
 
 		return nil, err
 	}
+	data := page.Items
 
 	// Filter the response
 	api.PostProcess(data, user)
@@ -159,7 +153,9 @@ func (api DynamoAPI) ListUniqueValues(req types.Request, uniqueKey string) ([]st
 	sort.Strings(values)
 
 	// Create audit log
-	api.auditLog(base.AuditActionList, req, user, nil, nil)
+	if err := api.WriteAuditLog(base.AuditActionList, req, user, nil, nil); err != nil {
+		return nil, err
+	}
 
 	return values, nil
 }
@@ -0,0 +1,148 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// keyAttribute : JSON form of a key attribute in a continuation token. Key attributes can only be
+// strings, numbers or binary, and numbers are kept as strings so they round-trip without losing precision.
+type keyAttribute struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+	B []byte  `json:"B,omitempty"`
+}
+
+// fetchFunc : Fetch a single page of items from Dynamo, starting after startKey
+type fetchFunc func(startKey map[string]dynamoTypes.AttributeValue, limit *int32) ([]map[string]dynamoTypes.AttributeValue, map[string]dynamoTypes.AttributeValue, error)
+
+// ScanPage : Scan a table for up to limit items, continuing from the LastEvaluatedKey stored in the next token
+func ScanPage[T any](ctx context.Context, client types.DynamoClientAPI, input *dynamodb.ScanInput, limit int, next string) (types.Page[T], error) {
+	return fetchPage[T](ctx, limit, next, func(startKey map[string]dynamoTypes.AttributeValue, limit *int32) ([]map[string]dynamoTypes.AttributeValue, map[string]dynamoTypes.AttributeValue, error) {
+		params := *input
+		params.ExclusiveStartKey = startKey
+		params.Limit = limit
+
+		output, err := client.Scan(ctx, &params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return output.Items, output.LastEvaluatedKey, nil
+	})
+}
+
+// QueryPage : Query a table for up to limit items, continuing from the LastEvaluatedKey stored in the next token
+func QueryPage[T any](ctx context.Context, client types.DynamoClientAPI, input *dynamodb.QueryInput, limit int, next string) (types.Page[T], error) {
+	return fetchPage[T](ctx, limit, next, func(startKey map[string]dynamoTypes.AttributeValue, limit *int32) ([]map[string]dynamoTypes.AttributeValue, map[string]dynamoTypes.AttributeValue, error) {
+		params := *input
+		params.ExclusiveStartKey = startKey
+		params.Limit = limit
+
+		output, err := client.Query(ctx, &params)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return output.Items, output.LastEvaluatedKey, nil
+	})
+}
+
+// fetchPage : Fetch a page of up to limit items from Dynamo. Dynamo applies its limit to the items it evaluates,
+// before filter expressions, so each page is read with a single request evaluating limit items. Pages may hold fewer
+// items than the limit, or none, when a filter skips items. The last evaluated key is returned as the next token.
+// Without a limit, requests are made until the table is exhausted.
+func fetchPage[T any](ctx context.Context, limit int, next string, fetch fetchFunc) (types.Page[T], error) {
+	startKey, err := decodeKey(next)
+	if err != nil {
+		return types.Page[T]{}, err
+	}
+
+	var evaluate *int32
+	if limit > 0 {
+		evaluate = aws.Int32(int32(limit))
+	}
+
+	var items []map[string]dynamoTypes.AttributeValue
+	for {
+		data, lastKey, err := fetch(startKey, evaluate)
+		if err != nil {
+			return types.Page[T]{}, base.ContextError(ctx, err)
+		}
+
+		items = append(items, data...)
+		startKey = lastKey
+
+		if len(startKey) == 0 || limit > 0 {
+			break
+		}
+	}
+
+	page := types.Page[T]{Items: []T{}}
+	if err := attributevalue.UnmarshalListOfMaps(items, &page.Items); err != nil {
+		return types.Page[T]{}, err
+	}
+
+	if page.Next, err = encodeKey(startKey); err != nil {
+		return types.Page[T]{}, err
+	}
+
+	return page, nil
+}
+
+// encodeKey : Encode a LastEvaluatedKey as a continuation token
+func encodeKey(key map[string]dynamoTypes.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	token := make(map[string]keyAttribute)
+	for name, value := range key {
+		switch v := value.(type) {
+		case *dynamoTypes.AttributeValueMemberS:
+			token[name] = keyAttribute{S: aws.String(v.Value)}
+		case *dynamoTypes.AttributeValueMemberN:
+			token[name] = keyAttribute{N: aws.String(v.Value)}
+		case *dynamoTypes.AttributeValueMemberB:
+			token[name] = keyAttribute{B: v.Value}
+		}
+	}
+
+	return base.EncodeToken(token)
+}
+
+// decodeKey : Decode a continuation token back into an ExclusiveStartKey
+func decodeKey(next string) (map[string]dynamoTypes.AttributeValue, error) {
+	var token map[string]keyAttribute
+	if ok, err := base.DecodeToken(next, &token); err != nil || !ok {
+		return nil, err
+	} else if len(token) == 0 {
+		return nil, &types.BadRequest{
+			Message: "Invalid continuation token",
+		}
+	}
+
+	key := make(map[string]dynamoTypes.AttributeValue)
+	for name, value := range token {
+		switch {
+		case value.S != nil:
+			key[name] = &dynamoTypes.AttributeValueMemberS{Value: *value.S}
+		case value.N != nil:
+			key[name] = &dynamoTypes.AttributeValueMemberN{Value: *value.N}
+		case value.B != nil:
+			key[name] = &dynamoTypes.AttributeValueMemberB{Value: value.B}
+		default:
+			return nil, &types.BadRequest{
+				Message: "Invalid continuation token",
+			}
+		}
+	}
+
+	return key, nil
+}
@@ -0,0 +1,98 @@
+package aws_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/aws"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoScan : Scans the numbers 1 through 5, keeping only odd numbers like a filter expression would
+type mockDynamoScan struct {
+	types.DynamoClientAPI
+	scans *int
+}
+
+func (m mockDynamoScan) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if m.scans != nil {
+		*m.scans++
+	}
+
+	start := 1
+	if key, ok := params.ExclusiveStartKey["n"].(*dynamoTypes.AttributeValueMemberN); ok {
+		n, _ := strconv.Atoi(key.Value)
+		start = n + 1
+	}
+
+	output := &dynamodb.ScanOutput{}
+	for n := start; n <= 5; n++ {
+		// Dynamo limits the number of items evaluated, not the number of items returned
+		if params.Limit != nil && n-start == int(*params.Limit) {
+			break
+		}
+
+		item := map[string]dynamoTypes.AttributeValue{
+			"n": &dynamoTypes.AttributeValueMemberN{Value: strconv.Itoa(n)},
+		}
+		if n%2 == 1 {
+			output.Items = append(output.Items, item)
+		}
+		if n < 5 {
+			output.LastEvaluatedKey = item
+		} else {
+			output.LastEvaluatedKey = nil
+		}
+	}
+
+	return output, nil
+}
+
+func TestScanPage(t *testing.T) {
+	type record struct {
+		N int `dynamodbav:"n"`
+	}
+
+	var numbers []int
+	var next string
+	for pages := 0; pages == 0 || next != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("Too many pages")
+		}
+
+		// Each page is read with a single scan, even when the filter leaves it short
+		scans := 0
+		page, err := aws.ScanPage[record](context.Background(), mockDynamoScan{scans: &scans}, &dynamodb.ScanInput{}, 2, next)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(page.Items) > 2 {
+			t.Errorf("Expected at most 2 items, got %d", len(page.Items))
+		} else if scans != 1 {
+			t.Errorf("Expected a single scan, got %d", scans)
+		}
+
+		for _, item := range page.Items {
+			numbers = append(numbers, item.N)
+		}
+		next = page.Next
+	}
+
+	if len(numbers) != 3 || numbers[0] != 1 || numbers[1] != 3 || numbers[2] != 5 {
+		t.Errorf("Unexpected items %v", numbers)
+	}
+
+	// Without a limit every item is returned in a single page
+	page, err := aws.ScanPage[record](context.Background(), mockDynamoScan{}, &dynamodb.ScanInput{}, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 3 || page.Next != "" {
+		t.Errorf("Unexpected page %+v", page)
+	}
+
+	if _, err := aws.ScanPage[record](context.Background(), mockDynamoScan{}, &dynamodb.ScanInput{}, 2, "e30"); err == nil {
+		t.Error("Expected an error for an invalid token")
+	}
+}
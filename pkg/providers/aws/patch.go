@@ -1,98 +1,100 @@
 package aws
 
-// Patch : Patch an item
-// func (api DynamoAPI) Patch(request types.Request, partitionKey map[string]interface{}, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error) {
-// 	builder := expression.NewBuilder()
+import (
+	"encoding/json"
+	"fmt"
 
-// 	// Get the user
-// 	user, err := api.InitializeRequest(request)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return nil, err
-// 	}
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	log "github.com/sirupsen/logrus"
+)
 
-// 	// Run data validation
-// 	if validation != nil {
-// 		log.Infoln("Running field validation")
-// 		err := api.ValidateFields(validation, requiredFields, item, nil)
-// 		if err != nil {
-// 			log.Errorln("Field validation error", err)
-// 			return nil, err
-// 		}
-// 	}
+const (
+	// PatchOperationAdd : Add a number to a numeric attribute, e.g. {"count__add": 1}
+	PatchOperationAdd = base.PatchOperationAdd
 
-// 	// Build update expression
-// 	var updateExpr expression.UpdateBuilder
-// 	hasUpdateConds := false
-// 	for key, value := range item {
-// 		if !hasUpdateConds {
-// 			updateExpr = expression.Set(expression.Name(key), expression.Value(value))
-// 			hasUpdateConds = true
-// 		} else {
-// 			updateExpr = updateExpr.Set(expression.Name(key), expression.Value(value))
-// 		}
-// 	}
-// 	builder = builder.WithUpdate(updateExpr)
+	// PatchOperationAppend : Append values to a list attribute, creating the list if it does not exist,
+	// e.g. {"tags__append": ["a", "b"]}
+	PatchOperationAppend = base.PatchOperationAppend
+)
 
-// 	// Build partition key
-// 	dynamoKeyParts, err := attributevalue.MarshalMap(partitionKey)
-// 	if err != nil {
-// 		log.Errorln("Failed to marshal partition key", err)
-// 		return nil, err
-// 	}
+// Patch : Partially update an item. Fields with a null value are removed from the record, and fields can be
+// suffixed with an operator to add to a number or append to a list. Validation only applies to fields that are set.
+func (api DynamoAPI) Patch(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PreparePatch(request, partitionKey, item, validation)
+	if err != nil {
+		return nil, err
+	}
 
-// 	// Build filters
-// 	conditions, err := api.filtering.Filter(user, nil, base.FilterActionUpdate)
-// 	if err != nil {
-// 		log.Errorln("Error encountered during filtering", err)
-// 		return nil, err
-// 	}
+	// Get key schema
+	schema, err := api.Schema(request.Context())
+	if err != nil {
+		log.Errorln("Failed to describe table", err)
+		return nil, err
+	}
 
-// 	// Get key schema
-// 	keySchema, err := api.Client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
-// 		TableName: aws.String(api.Config.DataTable),
-// 	})
-// 	if err != nil {
-// 		log.Errorln("Failed to describe table", err)
-// 		return nil, err
-// 	}
+	// Build update expression
+	updateExpr, err := schema.patchExpression(item)
+	if err != nil {
+		return nil, err
+	}
 
-// 	// Append key schema conditions
-// 	for _, schema := range keySchema.Table.KeySchema {
-// 		condition := expression.Name(*schema.AttributeName).AttributeExists()
-// 		conditions = api.filtering.And(conditions, condition)
-// 	}
+	return api.update(request, user, schema, partitionKey, item, updateExpr, auditAction)
+}
 
-// 	// Add conditions
-// 	if conditions != nil {
-// 		builder = builder.WithCondition(conditions.(expression.ConditionBuilder))
-// 	}
+// patchExpression : Convert the fields of a patch into SET, REMOVE and ADD actions
+func (schema TableSchema) patchExpression(item map[string]interface{}) (expression.UpdateBuilder, error) {
+	var updateExpr expression.UpdateBuilder
+	hasUpdates := false
+	for key, value := range item {
+		// Key attributes cannot be updated
+		field, operator := base.SplitFilterKey(key)
+		if schema.isKey(field) {
+			continue
+		}
 
-// 	// Build expression
-// 	expr, err := builder.WithUpdate(updateExpr).Build()
-// 	if err != nil {
-// 		log.Errorln("Encountered error while building expression", err)
-// 		return nil, err
-// 	}
+		name := expression.Name(field)
+		switch operator {
+		case base.OperationEqual:
+			if value == nil {
+				updateExpr = updateExpr.Remove(name)
+			} else {
+				updateExpr = updateExpr.Set(name, expression.Value(value))
+			}
 
-// 	// Update the item in dynamo
-// 	updatedItem, err := UpdateItem[types.Record](api.Client, api.Config.DataTable, dynamoKeyParts, expr)
-// 	if err != nil {
-// 		log.Errorln("Error while attempting to update item in dynamo", err)
+		case PatchOperationAdd:
+			switch value.(type) {
+			case int, int32, int64, float32, float64, json.Number:
+			default:
+				return expression.UpdateBuilder{}, &types.BadRequest{
+					Message: fmt.Sprintf("Value of %s must be a number", key),
+				}
+			}
+			updateExpr = updateExpr.Add(name, expression.Value(value))
 
-// 		// Check if this was a conditional check failure
-// 		var apiErr smithy.APIError
-// 		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ConditionalCheckFailedException" {
-// 			return nil, &types.BadRequest{
-// 				Message: "Item does not exist or you do not have permission to update it",
-// 			}
-// 		}
+		case PatchOperationAppend:
+			values, ok := value.([]interface{})
+			if !ok {
+				values = []interface{}{value}
+			}
+			list := expression.IfNotExists(name, expression.Value([]interface{}{}))
+			updateExpr = updateExpr.Set(name, expression.ListAppend(list, expression.Value(values)))
 
-// 		return nil, err
-// 	}
+		default:
+			return expression.UpdateBuilder{}, &types.BadRequest{
+				Message: fmt.Sprintf("Unsupported patch operator '%s'", operator),
+			}
+		}
+		hasUpdates = true
+	}
 
-// 	// Create audit log
-// 	api.auditLog(auditAction, request, user, partitionKey, item)
+	if !hasUpdates {
+		return expression.UpdateBuilder{}, &types.BadRequest{
+			Message: "No fields to update",
+		}
+	}
 
-// 	return updatedItem, nil
-// }
+	return updateExpr, nil
+}
@@ -0,0 +1,207 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// queryPlan : Key condition used to query the data table, or one of its indexes, instead of scanning it
+type queryPlan struct {
+	KeySchema
+	KeyCondition expression.KeyConditionBuilder
+}
+
+// planQuery : Find the key of the table or a global secondary index that is covered by a set of filter
+// params. The partition key must be matched by a single equals filter and the sort key by at most one filter
+// using an operator that Dynamo supports in key conditions. Returns nil if the table has to be scanned, along
+// with the params that still need to be applied as a filter expression.
+func (schema TableSchema) planQuery(user *types.User, params map[string][]string) (*queryPlan, map[string][]string) {
+	// Dynamo does not allow key attributes in the filter expression of a query, so fields
+	// used by the read filters of the user cannot be part of the key condition
+	blocked := make(map[string]bool)
+	if user != nil {
+		for _, filter := range user.ReadFilters {
+			blocked[filter.Field] = true
+		}
+	}
+
+	// Group the params by the field they filter on
+	fields := make(map[string][]string)
+	for key := range params {
+		field, _ := base.SplitFilterKey(key)
+		fields[field] = append(fields[field], key)
+	}
+
+	for _, index := range append([]KeySchema{schema.KeySchema}, schema.Indexes...) {
+		if index.PartitionKey == "" || blocked[index.PartitionKey] || blocked[index.SortKey] {
+			continue
+		}
+
+		// Partition key must be an exact match on a single value
+		keys := fields[index.PartitionKey]
+		if len(keys) != 1 || len(params[keys[0]]) != 1 {
+			continue
+		} else if _, operator := base.SplitFilterKey(keys[0]); operator != base.OperationEqual {
+			continue
+		}
+
+		value, ok := schema.keyValue(index.PartitionKey, params[keys[0]][0])
+		if !ok {
+			continue
+		}
+
+		condition := expression.Key(index.PartitionKey).Equal(value)
+		consumed := map[string]bool{keys[0]: true}
+
+		// Any filter on the sort key has to become part of the key condition
+		if index.SortKey != "" {
+			keys := fields[index.SortKey]
+			if len(keys) > 1 || (len(keys) == 1 && len(params[keys[0]]) != 1) {
+				continue
+			} else if len(keys) == 1 {
+				sortCondition, ok := schema.sortKeyCondition(index.SortKey, keys[0], params[keys[0]][0])
+				if !ok {
+					continue
+				}
+
+				condition = expression.KeyAnd(condition, sortCondition)
+				consumed[keys[0]] = true
+			}
+		}
+
+		// Remaining params are applied as a filter
+		remaining := make(map[string][]string)
+		for key, values := range params {
+			if !consumed[key] {
+				remaining[key] = values
+			}
+		}
+
+		return &queryPlan{KeySchema: index, KeyCondition: condition}, remaining
+	}
+
+	return nil, params
+}
+
+// sortKeyCondition : Convert a filter on a sort key into a key condition
+func (schema TableSchema) sortKeyCondition(field string, key string, value string) (expression.KeyConditionBuilder, bool) {
+	_, operator := base.SplitFilterKey(key)
+
+	switch operator {
+	case base.OperationStartsWith:
+		if schema.AttributeTypes[field] != dynamoTypes.ScalarAttributeTypeS {
+			return expression.KeyConditionBuilder{}, false
+		}
+
+		return expression.Key(field).BeginsWith(value), true
+
+	case base.OperationBetween:
+		var values []string
+		if err := json.Unmarshal([]byte(value), &values); err != nil || len(values) != 2 {
+			return expression.KeyConditionBuilder{}, false
+		}
+
+		low, lowOk := schema.keyValue(field, values[0])
+		high, highOk := schema.keyValue(field, values[1])
+
+		return expression.Key(field).Between(low, high), lowOk && highOk
+	}
+
+	keyValue, ok := schema.keyValue(field, value)
+	if !ok {
+		return expression.KeyConditionBuilder{}, false
+	}
+
+	switch operator {
+	case base.OperationEqual:
+		return expression.Key(field).Equal(keyValue), true
+	case base.OperationGreaterThan:
+		return expression.Key(field).GreaterThan(keyValue), true
+	case base.OperationLessThan:
+		return expression.Key(field).LessThan(keyValue), true
+	case base.OperationGreaterThanEqual:
+		return expression.Key(field).GreaterThanEqual(keyValue), true
+	case base.OperationLessThanEqual:
+		return expression.Key(field).LessThanEqual(keyValue), true
+	}
+
+	return expression.KeyConditionBuilder{}, false
+}
+
+// keyValue : Convert a filter value to the type of a key attribute. Attributes of unknown type are treated as strings.
+func (schema TableSchema) keyValue(field string, value string) (expression.ValueBuilder, bool) {
+	switch schema.AttributeTypes[field] {
+	case dynamoTypes.ScalarAttributeTypeS, "":
+		return expression.Value(value), true
+	case dynamoTypes.ScalarAttributeTypeN:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return expression.ValueBuilder{}, false
+		}
+
+		return expression.Value(json.Number(value)), true
+	}
+
+	// Binary keys cannot be expressed as filter values
+	return expression.ValueBuilder{}, false
+}
+
+// read : Read a page of records from the data table. The table is queried when there is a plan and scanned otherwise.
+func (api DynamoAPI) read(ctx context.Context, plan *queryPlan, conditions interface{}, projection *expression.ProjectionBuilder, limit int, next string) (types.Page[types.Record], error) {
+	builder := expression.NewBuilder()
+	hasExpression := false
+
+	if condition, ok := conditions.(expression.ConditionBuilder); ok && condition.IsSet() {
+		builder = builder.WithFilter(condition)
+		hasExpression = true
+	}
+	if projection != nil {
+		builder = builder.WithProjection(*projection)
+		hasExpression = true
+	}
+	if plan != nil {
+		builder = builder.WithKeyCondition(plan.KeyCondition)
+		hasExpression = true
+	}
+
+	var expr expression.Expression
+	if hasExpression {
+		var err error
+		if expr, err = builder.Build(); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+	}
+
+	if plan == nil {
+		input := &dynamodb.ScanInput{
+			TableName:                 aws.String(api.Config.DataTable),
+			FilterExpression:          expr.Filter(),
+			ProjectionExpression:      expr.Projection(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}
+
+		return ScanPage[types.Record](ctx, api.Client, input, limit, next)
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(api.Config.DataTable),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+	if plan.IndexName != "" {
+		input.IndexName = aws.String(plan.IndexName)
+	}
+
+	return QueryPage[types.Record](ctx, api.Client, input, limit, next)
+}
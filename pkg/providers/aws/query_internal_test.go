@@ -0,0 +1,173 @@
+package aws
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoIndexes : Describes a table keyed by id, with a queryable index on owner and created and a keys-only
+// index on status. Queries are recorded.
+type mockDynamoIndexes struct {
+	types.DynamoClientAPI
+	queries *[]*dynamodb.QueryInput
+}
+
+func (m mockDynamoIndexes) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{
+		Table: &dynamoTypes.TableDescription{
+			AttributeDefinitions: []dynamoTypes.AttributeDefinition{
+				{AttributeName: aws.String("id"), AttributeType: dynamoTypes.ScalarAttributeTypeS},
+				{AttributeName: aws.String("owner"), AttributeType: dynamoTypes.ScalarAttributeTypeS},
+				{AttributeName: aws.String("created"), AttributeType: dynamoTypes.ScalarAttributeTypeN},
+				{AttributeName: aws.String("status"), AttributeType: dynamoTypes.ScalarAttributeTypeS},
+			},
+			KeySchema: []dynamoTypes.KeySchemaElement{
+				{AttributeName: aws.String("id"), KeyType: dynamoTypes.KeyTypeHash},
+			},
+			GlobalSecondaryIndexes: []dynamoTypes.GlobalSecondaryIndexDescription{
+				{
+					IndexName: aws.String("owner-index"),
+					KeySchema: []dynamoTypes.KeySchemaElement{
+						{AttributeName: aws.String("owner"), KeyType: dynamoTypes.KeyTypeHash},
+						{AttributeName: aws.String("created"), KeyType: dynamoTypes.KeyTypeRange},
+					},
+					Projection: &dynamoTypes.Projection{ProjectionType: dynamoTypes.ProjectionTypeAll},
+				},
+				{
+					IndexName: aws.String("status-index"),
+					KeySchema: []dynamoTypes.KeySchemaElement{
+						{AttributeName: aws.String("status"), KeyType: dynamoTypes.KeyTypeHash},
+					},
+					Projection: &dynamoTypes.Projection{ProjectionType: dynamoTypes.ProjectionTypeKeysOnly},
+				},
+			},
+		},
+	}, nil
+}
+
+func (m mockDynamoIndexes) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	*m.queries = append(*m.queries, params)
+	return &dynamodb.QueryOutput{}, nil
+}
+
+func newIndexedAPI(t *testing.T) (DynamoAPI, TableSchema, *[]*dynamodb.QueryInput) {
+	queries := &[]*dynamodb.QueryInput{}
+	api := DynamoAPI{
+		Client:    mockDynamoIndexes{queries: queries},
+		filtering: NewFilter(),
+		Scoutr: &base.Scoutr{
+			Config: config.Config{
+				DataTable: "data",
+			},
+		},
+	}
+
+	schema, err := api.Schema(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return api, schema, queries
+}
+
+func TestPlanQuery(t *testing.T) {
+	_, schema, _ := newIndexedAPI(t)
+
+	tests := []struct {
+		name      string
+		user      *types.User
+		params    map[string][]string
+		index     string
+		remaining map[string][]string
+	}{
+		{"Table", nil, map[string][]string{"id": {"1"}, "name": {"a"}}, "", map[string][]string{"name": {"a"}}},
+		{"Index", nil, map[string][]string{"owner": {"a"}, "name": {"a"}}, "owner-index", map[string][]string{"name": {"a"}}},
+		{"SortKey", nil, map[string][]string{"owner": {"a"}, "created__gt": {"5"}}, "owner-index", map[string][]string{}},
+		{"SortKeyBetween", nil, map[string][]string{"owner": {"a"}, "created__between": {`["1", "5"]`}}, "owner-index", map[string][]string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan, remaining := schema.planQuery(test.user, test.params)
+			if plan == nil {
+				t.Fatal("Expected a query plan")
+			} else if plan.IndexName != test.index {
+				t.Errorf("Expected index '%s', got '%s'", test.index, plan.IndexName)
+			}
+
+			if !reflect.DeepEqual(remaining, test.remaining) {
+				t.Errorf("Expected remaining params %v, got %v", test.remaining, remaining)
+			}
+		})
+	}
+
+	scans := []struct {
+		name   string
+		user   *types.User
+		params map[string][]string
+	}{
+		{"NoKey", nil, map[string][]string{"name": {"a"}}},
+		{"MultipleValues", nil, map[string][]string{"owner": {"a", "b"}}},
+		{"NotEqual", nil, map[string][]string{"owner__ne": {"a"}}},
+		{"UnsupportedSortKeyOperator", nil, map[string][]string{"owner": {"a"}, "created__ne": {"5"}}},
+		{"InvalidNumber", nil, map[string][]string{"owner": {"a"}, "created__gt": {"abc"}}},
+		{"KeysOnlyIndex", nil, map[string][]string{"status": {"active"}}},
+		{"ReadFilterOnKey", &types.User{Permissions: types.Permissions{
+			ReadFilters: []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+		}}, map[string][]string{"owner": {"a"}}},
+	}
+
+	for _, test := range scans {
+		t.Run(test.name, func(t *testing.T) {
+			if plan, remaining := schema.planQuery(test.user, test.params); plan != nil {
+				t.Errorf("Expected a scan, got a query on '%s'", plan.IndexName)
+			} else if !reflect.DeepEqual(remaining, test.params) {
+				t.Errorf("Expected params to be unchanged, got %v", remaining)
+			}
+		})
+	}
+}
+
+func TestReadQuery(t *testing.T) {
+	api, schema, queries := newIndexedAPI(t)
+
+	plan, params := schema.planQuery(nil, map[string][]string{"owner": {"a"}, "created__ge": {"10"}, "name": {"x"}})
+	conditions, err := api.filtering.Filter(nil, params, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := api.read(context.Background(), plan, conditions, nil, 0, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*queries) != 1 {
+		t.Fatalf("Expected 1 query, got %d", len(*queries))
+	}
+
+	input := (*queries)[0]
+	if aws.ToString(input.IndexName) != "owner-index" {
+		t.Errorf("Unexpected index %s", aws.ToString(input.IndexName))
+	} else if input.KeyConditionExpression == nil || input.FilterExpression == nil {
+		t.Errorf("Expected key condition and filter expressions, got %+v", input)
+	}
+
+	// Numeric sort keys are compared as numbers
+	found := false
+	for _, value := range input.ExpressionAttributeValues {
+		if n, ok := value.(*dynamoTypes.AttributeValueMemberN); ok && n.Value == "10" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a numeric key value, got %+v", input.ExpressionAttributeValues)
+	}
+}
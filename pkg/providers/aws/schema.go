@@ -0,0 +1,142 @@
+package aws
+
+import (
+	"context"
+	"sync"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// KeySchema : Partition and sort key of a table or index. The index name is empty for the table itself.
+type KeySchema struct {
+	IndexName    string
+	PartitionKey string
+	SortKey      string
+}
+
+// Keys : Names of the key attributes
+func (k KeySchema) Keys() []string {
+	if k.SortKey == "" {
+		return []string{k.PartitionKey}
+	}
+
+	return []string{k.PartitionKey, k.SortKey}
+}
+
+// isKey : Check if an attribute is part of the key
+func (k KeySchema) isKey(name string) bool {
+	return name == k.PartitionKey || (name == k.SortKey && k.SortKey != "")
+}
+
+// TableSchema : Key schema of the data table, the global secondary indexes that can be queried in its place and
+// the types of their key attributes. Indexes that do not project every attribute are left out, since querying
+// them would return partial records.
+type TableSchema struct {
+	KeySchema
+	Indexes        []KeySchema
+	AttributeTypes map[string]dynamoTypes.ScalarAttributeType
+}
+
+// schemaCache : Holds the table schema. It is shared by every copy of the API, so a refresh is seen by all of them.
+type schemaCache struct {
+	sync.RWMutex
+	schema *TableSchema
+}
+
+// Schema : Get the cached schema of the data table, describing the table with ctx if it is not cached yet. APIs that
+// were not created with NewDynamoAPI have no cache, so their table is described on every call.
+func (api DynamoAPI) Schema(ctx context.Context) (TableSchema, error) {
+	if api.schema == nil {
+		return api.describeSchema(ctx)
+	}
+
+	api.schema.RLock()
+	schema := api.schema.schema
+	api.schema.RUnlock()
+
+	if schema != nil {
+		return *schema, nil
+	}
+
+	if err := api.RefreshSchema(ctx); err != nil {
+		return TableSchema{}, err
+	}
+
+	return api.Schema(ctx)
+}
+
+// KeyFields : Key attributes of the data table, as described by DynamoDB. Falls back to the configured keys if the
+// table cannot be described. No request is available here, but the schema is normally cached by NewDynamoAPI.
+func (api DynamoAPI) KeyFields() []string {
+	schema, err := api.Schema(context.Background())
+	if err != nil {
+		log.WithError(err).Errorln("Failed to describe table")
+		return api.Scoutr.KeyFields()
+	}
+
+	return schema.Keys()
+}
+
+// RefreshSchema : Describe the data table again and replace the cached schema, e.g. after an index was added
+func (api DynamoAPI) RefreshSchema(ctx context.Context) error {
+	schema, err := api.describeSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	if api.schema != nil {
+		api.schema.Lock()
+		api.schema.schema = &schema
+		api.schema.Unlock()
+	}
+
+	return nil
+}
+
+// describeSchema : Build the schema of the data table from DescribeTable
+func (api DynamoAPI) describeSchema(ctx context.Context) (TableSchema, error) {
+	output, err := api.Client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(api.Config.DataTable),
+	})
+	if err != nil {
+		return TableSchema{}, base.ContextError(ctx, err)
+	}
+
+	schema := TableSchema{
+		KeySchema:      newKeySchema("", output.Table.KeySchema),
+		AttributeTypes: make(map[string]dynamoTypes.ScalarAttributeType),
+	}
+
+	for _, attribute := range output.Table.AttributeDefinitions {
+		schema.AttributeTypes[aws.ToString(attribute.AttributeName)] = attribute.AttributeType
+	}
+
+	for _, index := range output.Table.GlobalSecondaryIndexes {
+		if index.Projection == nil || index.Projection.ProjectionType != dynamoTypes.ProjectionTypeAll {
+			continue
+		}
+
+		schema.Indexes = append(schema.Indexes, newKeySchema(aws.ToString(index.IndexName), index.KeySchema))
+	}
+
+	return schema, nil
+}
+
+// newKeySchema : Build the key of a table or index from its key schema
+func newKeySchema(indexName string, elements []dynamoTypes.KeySchemaElement) KeySchema {
+	key := KeySchema{IndexName: indexName}
+	for _, element := range elements {
+		switch element.KeyType {
+		case dynamoTypes.KeyTypeHash:
+			key.PartitionKey = aws.ToString(element.AttributeName)
+		case dynamoTypes.KeyTypeRange:
+			key.SortKey = aws.ToString(element.AttributeName)
+		}
+	}
+
+	return key
+}
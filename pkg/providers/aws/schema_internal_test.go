@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoDescribe : Describes a table with a composite key and the configured indexes, counting the calls
+type mockDynamoDescribe struct {
+	types.DynamoClientAPI
+	calls   *int
+	indexes *[]string
+}
+
+func (m mockDynamoDescribe) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	*m.calls++
+
+	table := &dynamoTypes.TableDescription{
+		KeySchema: []dynamoTypes.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: dynamoTypes.KeyTypeHash},
+			{AttributeName: aws.String("version"), KeyType: dynamoTypes.KeyTypeRange},
+		},
+	}
+	for _, name := range *m.indexes {
+		table.GlobalSecondaryIndexes = append(table.GlobalSecondaryIndexes, dynamoTypes.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String(name + "-index"),
+			KeySchema:  []dynamoTypes.KeySchemaElement{{AttributeName: aws.String(name), KeyType: dynamoTypes.KeyTypeHash}},
+			Projection: &dynamoTypes.Projection{ProjectionType: dynamoTypes.ProjectionTypeAll},
+		})
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: table}, nil
+}
+
+func TestSchemaCache(t *testing.T) {
+	calls := 0
+	indexes := []string{"owner"}
+	api := DynamoAPI{
+		Client: mockDynamoDescribe{calls: &calls, indexes: &indexes},
+		Scoutr: &base.Scoutr{Config: config.Config{DataTable: "data"}},
+		schema: &schemaCache{},
+	}
+
+	for i := 0; i < 3; i++ {
+		schema, err := api.Schema(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(schema.Keys(), []string{"id", "version"}) {
+			t.Errorf("Unexpected keys %v", schema.Keys())
+		} else if len(schema.Indexes) != 1 || schema.Indexes[0].PartitionKey != "owner" {
+			t.Errorf("Unexpected indexes %+v", schema.Indexes)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the table to be described once, got %d", calls)
+	}
+
+	// Refreshes are explicit, and are seen by every copy of the API
+	indexes = append(indexes, "status")
+	if schema, _ := api.Schema(context.Background()); len(schema.Indexes) != 1 {
+		t.Errorf("Schema changed without a refresh: %+v", schema.Indexes)
+	}
+
+	copied := api
+	if err := copied.RefreshSchema(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if schema, _ := api.Schema(context.Background()); len(schema.Indexes) != 2 || schema.Indexes[1].IndexName != "status-index" {
+		t.Errorf("Unexpected indexes after refresh %+v", schema.Indexes)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the table to be described twice, got %d", calls)
+	}
+}
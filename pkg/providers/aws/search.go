@@ -1,55 +1,155 @@
 package aws
 
 import (
+	"context"
+
 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	log "github.com/sirupsen/logrus"
 )
 
 // Search : Search items in the table
 func (api DynamoAPI) Search(req types.Request, key string, values []string) ([]types.Record, error) {
+	page, err := api.search(req, key, values, false)
+	return page.Items, err
+}
+
+// SearchPage : Search a page of items in the table, in the order they are scanned
+func (api DynamoAPI) SearchPage(req types.Request, key string, values []string) (types.Page[types.Record], error) {
+	return api.search(req, key, values, true)
+}
+
+// search : Search items in the table, either all at once or a page at a time
+func (api DynamoAPI) search(req types.Request, key string, values []string, paginate bool) (types.Page[types.Record], error) {
 	// Get the user
 	user, err := api.InitializeRequest(req)
 	if err != nil {
 		// Bad user - pass the error through
-		return nil, err
+		return types.Page[types.Record]{}, err
 	}
 
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(api.Config.DataTable),
+	limit := 0
+	if paginate {
+		if limit, err = base.PageLimit(req); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+	}
+
+	schema, err := api.Schema(req.Context())
+	if err != nil {
+		log.Errorln("Failed to describe table", err)
+		return types.Page[types.Record]{}, err
+	}
+
+	// Look up each value with its own query when the search key is the partition key of the table or an index
+	if plans := schema.searchPlans(user, key, values, paginate); plans != nil {
+		conditions, err := api.filtering.Filter(user, nil, "")
+		if err != nil {
+			log.Errorln("Error encountered during filtering", err)
+			return types.Page[types.Record]{}, err
+		}
+
+		page := types.Page[types.Record]{Items: []types.Record{}}
+		for _, plan := range plans {
+			result, err := api.read(req.Context(), plan, conditions, nil, limit, req.Next)
+			if err != nil {
+				log.Errorln("Error while attempting to query records", err)
+				return types.Page[types.Record]{}, err
+			}
+
+			page.Items = append(page.Items, result.Items...)
+			page.Next = result.Next
+		}
+
+		// Filter the response
+		api.PostProcess(page.Items, user)
+
+		// Create audit log
+		if err := api.WriteAuditLog(base.AuditActionSearch, req, user, nil, nil); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+
+		return page, nil
 	}
 
 	// Build filters
 	conditions, err := api.filtering.MultiFilter(user, key, values)
 	if err != nil {
 		log.Errorln("Error encountered during filtering", err)
-		return nil, err
-	}
-	expr, err := expression.NewBuilder().WithFilter(conditions.(expression.ConditionBuilder)).Build()
-	if err != nil {
-		log.Errorln("Failed to build expression", err)
+		return types.Page[types.Record]{}, err
 	}
 
-	// Update scan input
-	input.FilterExpression = expr.Filter()
-	input.ExpressionAttributeNames = expr.Names()
-	input.ExpressionAttributeValues = expr.Values()
-
 	// Download the data
-	data, err := Scan[types.Record](api.Client, input)
+	page, err := api.read(req.Context(), nil, conditions, nil, limit, req.Next)
 	if err != nil {
 		log.Errorln("Error while attempting to list records", err)
-		return nil, err
+		return types.Page[types.Record]{}, err
 	}
 
 	// Filter the response
-	api.PostProcess(data, user)
+	api.PostProcess(page.Items, user)
 
 	// Create audit log
-	api.auditLog(base.AuditActionSearch, req, user, nil, nil)
+	if err := api.WriteAuditLog(base.AuditActionSearch, req, user, nil, nil); err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	return page, nil
+}
+
+// searchPlans : Build a query for each search value. Returns nil if any of the values would need a scan. Pages
+// can only continue a single query, so paginated searches only use queries when there is a single value.
+func (schema TableSchema) searchPlans(user *types.User, key string, values []string, paginate bool) []*queryPlan {
+	if len(values) == 0 || (paginate && len(values) > 1) {
+		return nil
+	} else if field, _ := base.SplitFilterKey(key); field != key {
+		return nil
+	}
+
+	var plans []*queryPlan
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+
+		plan, remaining := schema.planQuery(user, map[string][]string{key: {value}})
+		if plan == nil || len(remaining) > 0 {
+			return nil
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans
+}
+
+// FindItems : Find the items with one of the values in a field, without checking the user's permissions
+func (api DynamoAPI) FindItems(ctx context.Context, key string, values []string) ([]types.Record, error) {
+	schema, err := api.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look up each value with its own query when the key is the partition key of the table or an index
+	if plans := schema.searchPlans(nil, key, values, false); plans != nil {
+		var records []types.Record
+		for _, plan := range plans {
+			page, err := api.read(ctx, plan, nil, nil, 0, "")
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, page.Items...)
+		}
+
+		return records, nil
+	}
+
+	conditions, err := api.filtering.MultiFilter(nil, key, values)
+	if err != nil {
+		return nil, err
+	}
 
-	return data, nil
+	page, err := api.read(ctx, nil, conditions, nil, 0, "")
+	return page.Items, err
 }
@@ -0,0 +1,235 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Transaction : Apply a set of operations with TransactWriteItems, up to 100 at a time. The user's filters, the key
+// and the expected version of each item are conditions of the operation that writes it, and checks are written as a
+// ConditionCheck, so the transaction is cancelled if any item fails them. The items are read beforehand to build the
+// audit logs, and each operation also requires its item to be unchanged since then, so the audit logs match what
+// was written.
+func (api DynamoAPI) Transaction(req types.Request, operations []types.TransactionOperation, validation map[string]types.FieldValidation) ([]types.Record, error) {
+	if len(operations) > transactionLimit {
+		return nil, &types.BadRequest{
+			Message: fmt.Sprintf("Transaction has more than %d operations", transactionLimit),
+		}
+	}
+
+	tx, err := api.PrepareTransaction(req, operations, validation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get key schema
+	schema, err := api.Schema(req.Context())
+	if err != nil {
+		log.Errorln("Failed to describe table", err)
+		return nil, err
+	}
+
+	previous := make([]types.Record, len(operations))
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: make([]dynamoTypes.TransactWriteItem, len(operations)),
+	}
+	for i := range operations {
+		if input.TransactItems[i], previous[i], err = api.transactItem(tx, schema, i); err != nil {
+			return nil, types.NewTransactionFailed(i, err)
+		}
+	}
+
+	if _, err := api.Client.TransactWriteItems(req.Context(), input); err != nil {
+		log.WithError(err).Errorln("Encountered error while attempting to apply transaction")
+		return nil, api.transactionFailure(tx, err)
+	}
+
+	outputs := make([]types.Record, len(operations))
+	for i := range operations {
+		outputs[i] = api.ApplyOperation(tx, i, previous[i])
+	}
+
+	// Create audit logs
+	if err := api.AuditTransaction(tx, previous, outputs); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// transactItem : Build the write of an operation of a transaction, returning the current state of its item
+func (api DynamoAPI) transactItem(tx base.Transaction, schema TableSchema, i int) (dynamoTypes.TransactWriteItem, types.Record, error) {
+	var item dynamoTypes.TransactWriteItem
+	operation := tx.Operations[i]
+
+	// Items are only created if their key is not in use
+	if operation.Action == types.TransactionCreate {
+		var conditions interface{}
+		for _, name := range schema.Keys() {
+			conditions = api.filtering.And(conditions, expression.Name(name).AttributeNotExists())
+		}
+		expr, err := expression.NewBuilder().WithCondition(conditions.(expression.ConditionBuilder)).Build()
+		if err != nil {
+			log.Errorln("Encountered error while building expression", err)
+			return item, nil, err
+		}
+
+		av, err := attributevalue.MarshalMap(api.ApplyOperation(tx, i, nil))
+		if err != nil {
+			return item, nil, err
+		}
+
+		item.Put = &dynamoTypes.Put{
+			TableName:                 aws.String(api.Config.DataTable),
+			Item:                      av,
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}
+		return item, nil, nil
+	}
+
+	dynamoKey, err := schema.marshalKey(tx.Keys[i])
+	if err != nil {
+		return item, nil, err
+	}
+
+	output, err := api.Client.GetItem(tx.Request.Context(), &dynamodb.GetItemInput{
+		TableName:      aws.String(api.Config.DataTable),
+		Key:            dynamoKey,
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return item, nil, base.ContextError(tx.Request.Context(), err)
+	} else if len(output.Item) == 0 {
+		return item, nil, &types.BadRequest{Message: tx.Failure(i)}
+	}
+
+	var current types.Record
+	if err := attributevalue.UnmarshalMap(output.Item, &current); err != nil {
+		return item, nil, err
+	}
+
+	// Build filters, along with conditions that the item exists, is at the expected version and, if it is versioned,
+	// has not changed since it was read. Checking every attribute of other items would soon reach the size limit of
+	// condition expressions, so their audit logs may miss changes made in between.
+	conditions, err := api.filtering.Filter(tx.User, nil, tx.FilterAction(i))
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return item, nil, err
+	}
+	for _, name := range schema.Keys() {
+		conditions = api.filtering.And(conditions, expression.Name(name).AttributeExists())
+	}
+	if conditions, err = api.versionCondition(tx.OperationRequest(i), conditions); err != nil {
+		return item, nil, err
+	}
+	if api.Versioned() {
+		conditions = api.filtering.And(conditions, api.unchangedCondition(output.Item))
+	}
+	builder := expression.NewBuilder().WithCondition(conditions.(expression.ConditionBuilder))
+
+	// Updates and soft deletes change the fields of the item, and move its version on
+	var updateExpr expression.UpdateBuilder
+	hasUpdates := false
+	switch {
+	case operation.Action == types.TransactionUpdate:
+		for key, value := range operation.Item {
+			if schema.isKey(key) {
+				continue
+			}
+			updateExpr = updateExpr.Set(expression.Name(key), expression.Value(value))
+			hasUpdates = true
+		}
+		if !hasUpdates {
+			return item, nil, &types.BadRequest{
+				Message: "No fields to update",
+			}
+		}
+	case operation.Action == types.TransactionDelete && tx.Trash != nil:
+		for key, value := range tx.Trash {
+			updateExpr = updateExpr.Set(expression.Name(key), expression.Value(value))
+		}
+		hasUpdates = true
+	}
+	if hasUpdates {
+		if api.Versioned() {
+			updateExpr = updateExpr.Add(expression.Name(api.Config.VersionField), expression.Value(1))
+		}
+		builder = builder.WithUpdate(updateExpr)
+	}
+
+	expr, err := builder.Build()
+	if err != nil {
+		log.Errorln("Encountered error while building expression", err)
+		return item, nil, err
+	}
+
+	// The item is returned when the condition fails, so the reason can be reported
+	returnValues := dynamoTypes.ReturnValuesOnConditionCheckFailureAllOld
+	switch {
+	case hasUpdates:
+		item.Update = &dynamoTypes.Update{
+			TableName:                           aws.String(api.Config.DataTable),
+			Key:                                 dynamoKey,
+			UpdateExpression:                    expr.Update(),
+			ConditionExpression:                 expr.Condition(),
+			ExpressionAttributeNames:            expr.Names(),
+			ExpressionAttributeValues:           expr.Values(),
+			ReturnValuesOnConditionCheckFailure: returnValues,
+		}
+	case operation.Action == types.TransactionDelete:
+		item.Delete = &dynamoTypes.Delete{
+			TableName:                           aws.String(api.Config.DataTable),
+			Key:                                 dynamoKey,
+			ConditionExpression:                 expr.Condition(),
+			ExpressionAttributeNames:            expr.Names(),
+			ExpressionAttributeValues:           expr.Values(),
+			ReturnValuesOnConditionCheckFailure: returnValues,
+		}
+	default:
+		item.ConditionCheck = &dynamoTypes.ConditionCheck{
+			TableName:                           aws.String(api.Config.DataTable),
+			Key:                                 dynamoKey,
+			ConditionExpression:                 expr.Condition(),
+			ExpressionAttributeNames:            expr.Names(),
+			ExpressionAttributeValues:           expr.Values(),
+			ReturnValuesOnConditionCheckFailure: returnValues,
+		}
+	}
+
+	return item, current, nil
+}
+
+// transactionFailure : Work out which operation cancelled a transaction, and why
+func (api DynamoAPI) transactionFailure(tx base.Transaction, err error) error {
+	var cancelled *dynamoTypes.TransactionCanceledException
+	if !errors.As(err, &cancelled) || len(cancelled.CancellationReasons) != len(tx.Operations) {
+		return base.ContextError(tx.Request.Context(), err)
+	}
+
+	for i, reason := range cancelled.CancellationReasons {
+		switch aws.ToString(reason.Code) {
+		case "", "None":
+			continue
+		case "ConditionalCheckFailed":
+			if tx.Operations[i].Action == types.TransactionCreate {
+				return types.NewTransactionFailed(i, &types.BadRequest{Message: tx.Failure(i)})
+			}
+			return types.NewTransactionFailed(i, api.itemFailure(tx.OperationRequest(i), tx.User, tx.FilterAction(i), reason.Item, tx.Failure(i)))
+		default:
+			return types.NewTransactionFailed(i, fmt.Errorf("%s: %s", aws.ToString(reason.Code), aws.ToString(reason.Message)))
+		}
+	}
+
+	return err
+}
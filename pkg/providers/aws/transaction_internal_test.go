@@ -0,0 +1,208 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// mockDynamoTransactWrite : Serves the auth and data tables, recording TransactWriteItems calls and cancelling
+// transactions whose item at index fail fails its condition
+type mockDynamoTransactWrite struct {
+	types.DynamoClientAPI
+	user   map[string]dynamoTypes.AttributeValue
+	item   map[string]dynamoTypes.AttributeValue
+	fail   int
+	inputs *[]*dynamodb.TransactWriteItemsInput
+}
+
+func (m mockDynamoTransactWrite) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{
+		Table: &dynamoTypes.TableDescription{
+			KeySchema: []dynamoTypes.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: dynamoTypes.KeyTypeHash}},
+		},
+	}, nil
+}
+
+func (m mockDynamoTransactWrite) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if aws.ToString(params.TableName) == "auth" {
+		return &dynamodb.GetItemOutput{Item: m.user}, nil
+	}
+
+	item := make(map[string]dynamoTypes.AttributeValue, len(m.item))
+	for key, value := range m.item {
+		item[key] = value
+	}
+	item["id"] = params.Key["id"]
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (m mockDynamoTransactWrite) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	*m.inputs = append(*m.inputs, params)
+	if m.fail < 0 {
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	}
+
+	reasons := make([]dynamoTypes.CancellationReason, len(params.TransactItems))
+	for i := range reasons {
+		reasons[i].Code = aws.String("None")
+	}
+	reasons[m.fail].Code = aws.String("ConditionalCheckFailed")
+	reasons[m.fail].Item = m.item
+
+	return nil, &dynamoTypes.TransactionCanceledException{CancellationReasons: reasons}
+}
+
+func TestTransaction(t *testing.T) {
+	user, err := attributevalue.MarshalMap(types.User{
+		ID:       "user1",
+		Username: "user1",
+		Name:     "User One",
+		Email:    "user1@example.com",
+		Permissions: types.Permissions{
+			PermittedEndpoints: []types.PermittedEndpoint{{Endpoint: ".*", Method: "POST"}},
+			UpdateFilters:      []types.FilterField{{Field: "status", Operator: base.OperationNotEqual, Value: "locked"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := attributevalue.MarshalMap(map[string]interface{}{"name": "alpha", "status": "active", "count": 1, "version": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newAPI := func(fail int) (DynamoAPI, *[]*dynamodb.TransactWriteItemsInput) {
+		inputs := &[]*dynamodb.TransactWriteItemsInput{}
+		api := DynamoAPI{
+			Client:    mockDynamoTransactWrite{user: user, item: item, fail: fail, inputs: inputs},
+			filtering: NewFilter(),
+			Scoutr: &base.Scoutr{
+				Config: config.Config{
+					AuthTable:    "auth",
+					DataTable:    "data",
+					VersionField: "version",
+				},
+			},
+		}
+		api.ScoutrBase = api
+		return api, inputs
+	}
+
+	req := types.Request{User: types.RequestUser{ID: "user1"}, Method: "POST", Path: "/transaction/"}
+	operations := []types.TransactionOperation{
+		{Action: types.TransactionCreate, Item: map[string]interface{}{"id": "4", "name": "delta"}},
+		{Action: types.TransactionUpdate, Key: types.Key{"id": "1"}, Item: map[string]interface{}{"name": "epsilon", "count": nil}, IfMatch: `"3"`},
+		{Action: types.TransactionDelete, Key: types.Key{"id": "2"}},
+		{Action: types.TransactionCheck, Key: types.Key{"id": "3"}},
+	}
+
+	api, inputs := newAPI(-1)
+	outputs, err := api.Transaction(req, operations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every operation is written in a single transaction, with the conditions of its item
+	if len(*inputs) != 1 {
+		t.Fatalf("Expected a single transaction, got %d", len(*inputs))
+	}
+	items := (*inputs)[0].TransactItems
+	if items[0].Put == nil || items[1].Update == nil || items[2].Delete == nil || items[3].ConditionCheck == nil {
+		t.Fatalf("Unexpected transaction items %+v", items)
+	}
+	if aws.ToString(items[1].Update.ConditionExpression) == "" || aws.ToString(items[3].ConditionCheck.ConditionExpression) == "" {
+		t.Error("Expected existing items to be written only if they satisfy their conditions")
+	}
+
+	// Items are only written at the version that was read, even if the operation does not expect one
+	readVersion := false
+	for _, value := range items[2].Delete.ExpressionAttributeValues {
+		if number, ok := value.(*dynamoTypes.AttributeValueMemberN); ok && number.Value == "3" {
+			readVersion = true
+		}
+	}
+	if !readVersion {
+		t.Errorf("Expected the delete to check the version that was read, got %s", aws.ToString(items[2].Delete.ConditionExpression))
+	}
+
+	if outputs[0]["version"] != int64(1) || outputs[1]["name"] != "epsilon" || outputs[2] != nil || outputs[3]["name"] != "alpha" {
+		t.Errorf("Unexpected outputs %+v", outputs)
+	}
+	if count, ok := outputs[1]["count"]; !ok || count != nil || outputs[1]["version"] != int64(4) {
+		t.Errorf("Unexpected update output %+v", outputs[1])
+	}
+	if expr := aws.ToString(items[1].Update.UpdateExpression); strings.Contains(expr, "REMOVE") {
+		t.Errorf("Expected null fields to be set as Update does, got %s", expr)
+	}
+
+	// Cancelled transactions report the operation that failed its conditions
+	api, _ = newAPI(2)
+	_, err = api.Transaction(req, operations, nil)
+	var failed *types.TransactionFailed
+	var badRequest *types.BadRequest
+	if !errors.As(err, &failed) || failed.Operation != 2 || !errors.As(err, &badRequest) {
+		t.Errorf("Expected operation 2 to fail with a bad request, got %v", err)
+	}
+
+	// Items at a version other than the expected one were modified since
+	operations[1].IfMatch = `"2"`
+	api, _ = newAPI(1)
+	_, err = api.Transaction(req, operations, nil)
+	var conflict *types.PreconditionFailed
+	if !errors.As(err, &failed) || failed.Operation != 1 || !errors.As(err, &conflict) {
+		t.Errorf("Expected operation 1 to fail with a version conflict, got %v", err)
+	}
+
+	// Operators are only supported by patches, so updates with them are rejected before calling Dynamo
+	api, inputs = newAPI(-1)
+	_, err = api.Transaction(req, []types.TransactionOperation{
+		{Action: types.TransactionUpdate, Key: types.Key{"id": "1"}, Item: map[string]interface{}{"count__add": 1}},
+	}, nil)
+	if !errors.As(err, &failed) || failed.Operation != 0 || !errors.As(err, &badRequest) {
+		t.Errorf("Expected operation 0 to fail with a bad request, got %v", err)
+	} else if len(*inputs) != 0 {
+		t.Error("Expected the transaction not to be written")
+	}
+}
+
+func TestItemID(t *testing.T) {
+	api := DynamoAPI{
+		Client:    mockDynamoDelete{},
+		filtering: NewFilter(),
+		Scoutr:    &base.Scoutr{Config: config.Config{DataTable: "data"}},
+	}
+	api.ScoutrBase = api
+
+	// Numeric keys refer to the same item whether they are given as numbers or strings
+	ctx := context.Background()
+	id, err := api.ItemID(ctx, types.Key{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []interface{}{1, 1.0, json.Number("1")} {
+		if other, err := api.ItemID(ctx, types.Key{"id": value}); err != nil {
+			t.Fatal(err)
+		} else if other != id {
+			t.Errorf("Expected %#v to be the same item as \"1\", got %s and %s", value, other, id)
+		}
+	}
+
+	if other, err := api.ItemID(ctx, types.Key{"id": 2}); err != nil || other == id {
+		t.Errorf("Expected 2 to be another item, got %s, %v", other, err)
+	}
+	if _, err := api.ItemID(ctx, types.Key{"id": "one"}); err == nil {
+		t.Error("Expected error for a key that is not a number")
+	}
+}
@@ -1,8 +1,8 @@
 package aws
 
 import (
-	"context"
 	"errors"
+	"reflect"
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
@@ -10,48 +10,58 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/smithy-go"
 	log "github.com/sirupsen/logrus"
 )
 
-// Update : Update an item
-func (api DynamoAPI) Update(request types.Request, partitionKey map[string]interface{}, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error) {
-	builder := expression.NewBuilder()
+// updateAttempts : Number of times an update is tried when the item changes between reading and writing it
+const updateAttempts = 5
 
-	// Get the user
-	user, err := api.InitializeRequest(request)
+// Update : Update an item. Every field of the item is set on the existing record.
+func (api DynamoAPI) Update(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, requiredFields)
 	if err != nil {
-		// Bad user - pass the error through
 		return nil, err
 	}
 
-	// Run data validation
-	if validation != nil {
-		log.Infoln("Running field validation")
-		err := api.ValidateFields(validation, requiredFields, item, nil)
-		if err != nil {
-			log.Errorln("Field validation error", err)
-			return nil, err
-		}
+	// Get key schema
+	schema, err := api.Schema(request.Context())
+	if err != nil {
+		log.Errorln("Failed to describe table", err)
+		return nil, err
 	}
 
-	// Build update expression
+	// Build update expression. Key attributes cannot be updated.
 	var updateExpr expression.UpdateBuilder
-	hasUpdateConds := false
+	hasUpdates := false
 	for key, value := range item {
-		if !hasUpdateConds {
-			updateExpr = expression.Set(expression.Name(key), expression.Value(value))
-			hasUpdateConds = true
-		} else {
+		if !schema.isKey(key) {
 			updateExpr = updateExpr.Set(expression.Name(key), expression.Value(value))
+			hasUpdates = true
+		}
+	}
+	if !hasUpdates {
+		return nil, &types.BadRequest{
+			Message: "No fields to update",
 		}
 	}
-	builder = builder.WithUpdate(updateExpr)
+
+	return api.update(request, user, schema, partitionKey, item, updateExpr, auditAction)
+}
+
+// update : Apply an update expression to the item matching the key, as long as it satisfies the user's update
+// filters. Returns the new version of the item, as stored by Dynamo. Versioned items are read first for their audit
+// log, and the write only applies if the version has not changed since, so the previous version is the one that was
+// updated. If another request changes the item in between, it is read again. Other items are written in a single
+// request that returns the previous version.
+func (api DynamoAPI) update(request types.Request, user *types.User, schema TableSchema, partitionKey types.Key, item map[string]interface{}, updateExpr expression.UpdateBuilder, auditAction string) (interface{}, error) {
+	const message = "Item does not exist or you do not have permission to update it"
 
 	// Build partition key
-	dynamoKeyParts, err := attributevalue.MarshalMap(partitionKey)
+	dynamoKey, err := schema.marshalKey(partitionKey)
 	if err != nil {
-		log.Errorln("Failed to marshal partition key", err)
 		return nil, err
 	}
 
@@ -62,59 +72,202 @@ func (api DynamoAPI) Update(request types.Request, partitionKey map[string]inter
 		return nil, err
 	}
 
-	// Get key schema
-	keySchema, err := api.Client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
-		TableName: aws.String(api.Config.DataTable),
-	})
-	if err != nil {
-		log.Errorln("Failed to describe table", err)
-		return nil, err
+	// Append key schema conditions so missing items are not created
+	for _, name := range schema.Keys() {
+		conditions = api.filtering.And(conditions, expression.Name(name).AttributeExists())
 	}
 
-	// Append key schema conditions
-	for _, schema := range keySchema.Table.KeySchema {
-		condition := expression.Name(*schema.AttributeName).AttributeExists()
-		conditions = api.filtering.And(conditions, condition)
+	// Writes that expect a version only apply to that version, and every write moves the version on
+	if conditions, err = api.versionCondition(request, conditions); err != nil {
+		return nil, err
+	}
+	if !api.Versioned() {
+		return api.updateUnversioned(request, user, dynamoKey, partitionKey, item, updateExpr, conditions.(expression.ConditionBuilder), auditAction)
 	}
+	updateExpr = updateExpr.Add(expression.Name(api.Config.VersionField), expression.Value(1))
 
-	// Add conditions
-	if conditions != nil {
-		builder = builder.WithCondition(conditions.(expression.ConditionBuilder))
+	for attempt := 1; ; attempt++ {
+		// Read the previous version of the item
+		output, err := api.Client.GetItem(request.Context(), &dynamodb.GetItemInput{
+			TableName:      aws.String(api.Config.DataTable),
+			Key:            dynamoKey,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			log.Errorln("Error while attempting to read item from dynamo", err)
+			return nil, base.ContextError(request.Context(), err)
+		} else if len(output.Item) == 0 {
+			return nil, &types.BadRequest{Message: message}
+		}
+
+		var previous types.Record
+		if err := attributevalue.UnmarshalMap(output.Item, &previous); err != nil {
+			return nil, err
+		}
+
+		// Build expression
+		expr, err := expression.NewBuilder().
+			WithUpdate(updateExpr).
+			WithCondition(conditions.(expression.ConditionBuilder).And(api.unchangedCondition(output.Item))).
+			Build()
+		if err != nil {
+			log.Errorln("Encountered error while building expression", err)
+			return nil, err
+		}
+
+		// Update the item in dynamo
+		updatedItem, err := UpdateItem[types.Record](request.Context(), api.Client, api.Config.DataTable, dynamoKey, expr, dynamoTypes.ReturnValueAllNew)
+		if err != nil {
+			log.Errorln("Error while attempting to update item in dynamo", err)
+
+			// Check if this was a conditional check failure
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ConditionalCheckFailedException" {
+				if attempt < updateAttempts && changedSince(err, previous) {
+					continue
+				}
+
+				return nil, api.conditionFailure(request, user, base.FilterActionUpdate, err, message)
+			}
+
+			return nil, err
+		}
+
+		// Create audit log
+		if err := api.WriteChangeAuditLog(auditAction, request, user, partitionKey, item, previous, *updatedItem); err != nil {
+			return nil, err
+		}
+
+		return updatedItem, nil
 	}
+}
+
+// updateUnversioned : Apply an update expression to an item without a version. Dynamo returns the previous version
+// of the item from the write itself, and the new version is read back afterwards, so it can include the changes of
+// requests made in between.
+func (api DynamoAPI) updateUnversioned(request types.Request, user *types.User, dynamoKey map[string]dynamoTypes.AttributeValue, partitionKey types.Key, item map[string]interface{}, updateExpr expression.UpdateBuilder, conditions expression.ConditionBuilder, auditAction string) (interface{}, error) {
+	const message = "Item does not exist or you do not have permission to update it"
 
 	// Build expression
-	expr, err := builder.WithUpdate(updateExpr).Build()
+	expr, err := expression.NewBuilder().WithUpdate(updateExpr).WithCondition(conditions).Build()
 	if err != nil {
 		log.Errorln("Encountered error while building expression", err)
 		return nil, err
 	}
 
 	// Update the item in dynamo
-	if err := api.PutItem(api.Config.DataTable, dynamoKeyParts, &expr); err != nil {
+	previous, err := UpdateItem[types.Record](request.Context(), api.Client, api.Config.DataTable, dynamoKey, expr, dynamoTypes.ReturnValueAllOld)
+	if err != nil {
 		log.Errorln("Error while attempting to update item in dynamo", err)
 
 		// Check if this was a conditional check failure
 		var apiErr smithy.APIError
 		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ConditionalCheckFailedException" {
-			return nil, &types.BadRequest{
-				Message: "Item does not exist or you do not have permission to update it",
-			}
+			return nil, api.conditionFailure(request, user, base.FilterActionUpdate, err, message)
 		}
 
 		return nil, err
 	}
 
-	// Create audit log
-	api.auditLog(auditAction, request, user, partitionKey, item)
-
-	// Get updated item
-	updatedItem, err := GetItem[types.Record](api.Client, &dynamodb.GetItemInput{
-		TableName: aws.String(api.Config.DataTable),
-		Key:       dynamoKeyParts,
+	// Read back the new version of the item
+	updatedItem, err := GetItem[types.Record](request.Context(), api.Client, &dynamodb.GetItemInput{
+		TableName:      aws.String(api.Config.DataTable),
+		Key:            dynamoKey,
+		ConsistentRead: aws.Bool(true),
 	})
 	if err != nil {
+		log.Errorln("Error while attempting to read item from dynamo", err)
+		return nil, err
+	} else if updatedItem == nil {
+		return nil, &types.BadRequest{Message: message}
+	}
+
+	// Create audit log
+	if err := api.WriteChangeAuditLog(auditAction, request, user, partitionKey, item, *previous, *updatedItem); err != nil {
 		return nil, err
 	}
 
 	return updatedItem, nil
 }
+
+// unchangedCondition : Condition that a versioned item is still at the version that was read
+func (api DynamoAPI) unchangedCondition(item map[string]dynamoTypes.AttributeValue) expression.ConditionBuilder {
+	name := expression.Name(api.Config.VersionField)
+	if version, ok := item[api.Config.VersionField]; ok {
+		return name.Equal(expression.Value(rawValue{version}))
+	}
+
+	return name.AttributeNotExists()
+}
+
+// changedSince : Check if a failed condition check returned an item that differs from the one that was read
+func changedSince(err error, previous types.Record) bool {
+	var failed *dynamoTypes.ConditionalCheckFailedException
+	if !errors.As(err, &failed) || len(failed.Item) == 0 {
+		return false
+	}
+
+	var current types.Record
+	if err := attributevalue.UnmarshalMap(failed.Item, &current); err != nil {
+		return false
+	}
+
+	return !reflect.DeepEqual(current, previous)
+}
+
+// rawValue : Attribute value that is used in an expression as it was read, so numbers keep their precision
+type rawValue struct {
+	value dynamoTypes.AttributeValue
+}
+
+// MarshalDynamoDBAttributeValue : Use the attribute value as it is
+func (value rawValue) MarshalDynamoDBAttributeValue() (dynamoTypes.AttributeValue, error) {
+	return value.value, nil
+}
+
+// versionCondition : Add a condition that the item is at the version the request expects, if it expects one
+func (api DynamoAPI) versionCondition(request types.Request, conditions interface{}) (interface{}, error) {
+	expected, ok, err := api.ExpectedVersion(request)
+	if err != nil || !ok {
+		return conditions, err
+	}
+
+	return api.filtering.And(conditions, expression.Name(api.Config.VersionField).Equal(expression.Value(expected))), nil
+}
+
+// conditionFailure : Work out why a conditional write failed. Dynamo returns the item that failed the condition, so
+// if the user may modify it but it is not at the version the request expects, it was modified since.
+func (api DynamoAPI) conditionFailure(request types.Request, user *types.User, action string, err error, message string) error {
+	var failed *dynamoTypes.ConditionalCheckFailedException
+	if !errors.As(err, &failed) {
+		return &types.BadRequest{Message: message}
+	}
+
+	return api.itemFailure(request, user, action, failed.Item, message)
+}
+
+// itemFailure : Work out why a conditional write failed, given the item that failed the condition
+func (api DynamoAPI) itemFailure(request types.Request, user *types.User, action string, item map[string]dynamoTypes.AttributeValue, message string) error {
+	expected, ok, _ := api.ExpectedVersion(request)
+	if !ok || len(item) == 0 {
+		return &types.BadRequest{Message: message}
+	}
+
+	var existing types.Record
+	if err := attributevalue.UnmarshalMap(item, &existing); err != nil {
+		return err
+	}
+
+	// Only reveal the version of items the user may modify
+	f := base.NewLocalFilter(existing)
+	if conditions, err := f.Filter(user, nil, action); err != nil || !f.Matches(conditions) {
+		return &types.BadRequest{Message: message}
+	}
+
+	version, _ := base.RecordVersion(existing, api.Config.VersionField)
+	if version == expected {
+		return &types.BadRequest{Message: message}
+	}
+
+	return api.VersionConflict(expected, version)
+}
@@ -0,0 +1,321 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// mockDynamoUpdate : Serves the auth table and records UpdateItem calls, returning the version of the item that was
+// asked for or failing the condition check. The data table serves the new version of the item once it was written.
+type mockDynamoUpdate struct {
+	types.DynamoClientAPI
+	user    map[string]dynamoTypes.AttributeValue
+	inputs  *[]*dynamodb.UpdateItemInput
+	fail    bool
+	updated map[string]dynamoTypes.AttributeValue
+
+	// conflict : Item returned by failed condition checks
+	conflict map[string]dynamoTypes.AttributeValue
+
+	// stale : Number of writes that fail because the item changed since it was read
+	stale *int
+}
+
+func (m mockDynamoUpdate) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{
+		Table: &dynamoTypes.TableDescription{
+			KeySchema: []dynamoTypes.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: dynamoTypes.KeyTypeHash}},
+		},
+	}, nil
+}
+
+func (m mockDynamoUpdate) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if aws.ToString(params.TableName) == "auth" {
+		return &dynamodb.GetItemOutput{Item: m.user}, nil
+	}
+
+	if len(*m.inputs) > 0 {
+		return &dynamodb.GetItemOutput{Item: m.updated}, nil
+	}
+
+	return &dynamodb.GetItemOutput{Item: mockUpdateItem()}, nil
+}
+
+func (m mockDynamoUpdate) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	*m.inputs = append(*m.inputs, params)
+
+	if m.conflict != nil {
+		return nil, &dynamoTypes.ConditionalCheckFailedException{Message: aws.String("The conditional request failed"), Item: m.conflict}
+	} else if m.fail {
+		return nil, &smithy.GenericAPIError{Code: "ConditionalCheckFailedException"}
+	} else if m.stale != nil && *m.stale > 0 {
+		*m.stale--
+		item := mockUpdateItem()
+		item["name"] = &dynamoTypes.AttributeValueMemberS{Value: "changed"}
+		return nil, &dynamoTypes.ConditionalCheckFailedException{Message: aws.String("The conditional request failed"), Item: item}
+	}
+
+	if params.ReturnValues == dynamoTypes.ReturnValueAllOld {
+		return &dynamodb.UpdateItemOutput{Attributes: mockUpdateItem()}, nil
+	}
+
+	return &dynamodb.UpdateItemOutput{Attributes: m.updated}, nil
+}
+
+// mockUpdateItem : Item of the data table served by mockDynamoUpdate
+func mockUpdateItem() map[string]dynamoTypes.AttributeValue {
+	return map[string]dynamoTypes.AttributeValue{
+		"id":      &dynamoTypes.AttributeValueMemberS{Value: "1"},
+		"name":    &dynamoTypes.AttributeValueMemberS{Value: "gamma"},
+		"owner":   &dynamoTypes.AttributeValueMemberS{Value: "a"},
+		"status":  &dynamoTypes.AttributeValueMemberS{Value: "active"},
+		"count":   &dynamoTypes.AttributeValueMemberN{Value: "1"},
+		"version": &dynamoTypes.AttributeValueMemberN{Value: "1"},
+		"tags":    &dynamoTypes.AttributeValueMemberL{Value: []dynamoTypes.AttributeValue{&dynamoTypes.AttributeValueMemberS{Value: "x"}}},
+	}
+}
+
+func newUpdateAPI(t *testing.T, fail bool) (DynamoAPI, *[]*dynamodb.UpdateItemInput) {
+	// Users are decoded using the field names of types.User
+	user, err := attributevalue.MarshalMap(types.User{
+		ID:       "user1",
+		Username: "user1",
+		Name:     "User One",
+		Email:    "user1@example.com",
+		Permissions: types.Permissions{
+			PermittedEndpoints: []types.PermittedEndpoint{{Endpoint: ".*", Method: "PUT"}, {Endpoint: ".*", Method: "PATCH"}},
+			UpdateFilters:      []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new version of the item is returned by Dynamo
+	updated := mockUpdateItem()
+	updated["name"] = &dynamoTypes.AttributeValueMemberS{Value: "delta"}
+	updated["version"] = &dynamoTypes.AttributeValueMemberN{Value: "2"}
+
+	inputs := &[]*dynamodb.UpdateItemInput{}
+	api := DynamoAPI{
+		Client:    mockDynamoUpdate{user: user, inputs: inputs, fail: fail, updated: updated},
+		filtering: NewFilter(),
+		Scoutr: &base.Scoutr{
+			Config: config.Config{
+				AuthTable: "auth",
+				DataTable: "data",
+			},
+		},
+	}
+	api.ScoutrBase = api
+
+	return api, inputs
+}
+
+func TestUpdate(t *testing.T) {
+	api, inputs := newUpdateAPI(t, false)
+
+	req := types.Request{User: types.RequestUser{ID: "user1"}, Method: "PUT", Path: "/item/1"}
+	output, err := api.Update(req, types.Key{"id": "1"}, map[string]interface{}{"id": "1", "name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if record := output.(*types.Record); (*record)["name"] != "delta" {
+		t.Errorf("Unexpected output %+v", *record)
+	}
+
+	// The item is updated in place rather than replaced, and key attributes are left alone
+	input := (*inputs)[0]
+	if expr := *input.UpdateExpression; !strings.HasPrefix(expr, "SET ") || strings.Contains(expr, ",") {
+		t.Errorf("Unexpected update expression %s", expr)
+	}
+	if condition := *input.ConditionExpression; !strings.Contains(condition, "attribute_exists") || !strings.Contains(condition, "=") {
+		t.Errorf("Expected the update filters and key to be checked, got %s", condition)
+	}
+
+	// Unversioned items are written in one request that returns the previous version, rather than being checked
+	// against every value that was read
+	if input.ReturnValues != dynamoTypes.ReturnValueAllOld {
+		t.Errorf("Expected ALL_OLD, got %s", input.ReturnValues)
+	}
+	if condition := *input.ConditionExpression; strings.Count(condition, "=") != 1 || len(input.ExpressionAttributeValues) != 2 {
+		t.Errorf("Expected only the update filters and key to be checked, got %s", condition)
+	}
+	if key, ok := input.Key["id"].(*dynamoTypes.AttributeValueMemberS); !ok || key.Value != "1" {
+		t.Errorf("Unexpected key %+v", input.Key)
+	}
+
+	// Only key attributes
+	if _, err := api.Update(req, types.Key{"id": "1"}, map[string]interface{}{"id": "1"}, nil, nil, base.AuditActionUpdate); err == nil {
+		t.Error("Expected error updating nothing")
+	}
+
+	// Operators are only supported by patches
+	if _, err := api.Update(req, types.Key{"id": "1"}, map[string]interface{}{"count__add": 1}, nil, nil, base.AuditActionUpdate); err == nil {
+		t.Error("Expected error updating with an operator")
+	}
+
+	// Failed condition checks are reported as bad requests
+	api, _ = newUpdateAPI(t, true)
+	_, err = api.Update(req, types.Key{"id": "1"}, map[string]interface{}{"name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	api, inputs := newUpdateAPI(t, false)
+
+	req := types.Request{User: types.RequestUser{ID: "user1"}, Method: "PATCH", Path: "/item/1"}
+	item := map[string]interface{}{
+		"name":         "delta",
+		"owner":        nil,
+		"count__add":   float64(2),
+		"tags__append": "a",
+	}
+	output, err := api.Patch(req, types.Key{"id": "1"}, item, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new version of the item is the one stored by Dynamo
+	if record := *output.(*types.Record); record["name"] != "delta" {
+		t.Errorf("Unexpected output %+v", record)
+	}
+
+	expr := *(*inputs)[0].UpdateExpression
+	for _, clause := range []string{"SET ", "REMOVE ", "ADD ", "list_append(if_not_exists("} {
+		if !strings.Contains(expr, clause) {
+			t.Errorf("Expected %q in update expression %s", clause, expr)
+		}
+	}
+
+	for _, item := range []map[string]interface{}{
+		{"count__add": "a"},
+		{"name__bogus": "a"},
+		{"id": "2"},
+		{},
+	} {
+		_, err := api.Patch(req, types.Key{"id": "1"}, item, nil, base.AuditActionUpdate)
+		if _, ok := err.(*types.BadRequest); !ok {
+			t.Errorf("Expected bad request patching %v, got %v", item, err)
+		}
+	}
+	if len(*inputs) != 1 {
+		t.Errorf("Expected invalid patches to be rejected before calling Dynamo")
+	}
+
+	// Operators are validated by the value the field ends up with
+	var count interface{}
+	validation := map[string]types.FieldValidation{
+		"count": utils.And(utils.Validator(func(input *types.ValidationInput) (string, error) {
+			count = input.Value
+			return "", nil
+		}), utils.Range(0, 2)),
+		"tags": utils.ListSize(0, 2),
+	}
+	_, err = api.Patch(req, types.Key{"id": "1"}, map[string]interface{}{"count__add": 2, "tags__append": []interface{}{"a", "b"}}, validation, base.AuditActionUpdate)
+	if badRequest, ok := err.(*types.BadRequest); !ok {
+		t.Fatalf("Expected bad request, got %v", err)
+	} else if len(badRequest.Messages) != 2 || count != 3.0 {
+		t.Errorf("Unexpected errors %v validating count %v", badRequest.Messages, count)
+	}
+	if _, err := api.Patch(req, types.Key{"id": "1"}, map[string]interface{}{"count__add": 1}, validation, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	maximum := 2.0
+	api.Config.Schema = &types.JSONSchema{
+		Properties: map[string]*types.JSONSchema{"count": {Maximum: &maximum}},
+	}
+	_, err = api.Patch(req, types.Key{"id": "1"}, map[string]interface{}{"count__add": 2}, nil, base.AuditActionUpdate)
+	if badRequest, ok := err.(*types.BadRequest); !ok || badRequest.Messages["count"] == "" {
+		t.Errorf("Expected the schema to reject count, got %v", err)
+	}
+	if len(*inputs) != 2 {
+		t.Errorf("Expected 2 writes, got %d", len(*inputs))
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	api, inputs := newUpdateAPI(t, false)
+	api.Config.VersionField = "version"
+
+	// Writes only apply to the expected version, and move it on
+	req := types.Request{User: types.RequestUser{ID: "user1"}, Method: "PATCH", Path: "/item/1", IfMatch: `"1"`}
+	output, err := api.Patch(req, types.Key{"id": "1"}, map[string]interface{}{"name": "delta"}, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if version, _ := base.RecordVersion(*output.(*types.Record), "version"); version != 2 {
+		t.Errorf("Expected version 2, got %+v", *output.(*types.Record))
+	}
+
+	input := (*inputs)[0]
+	if expr := *input.UpdateExpression; !strings.Contains(expr, "ADD ") {
+		t.Errorf("Expected the version to be incremented, got %s", expr)
+	}
+	if input.ReturnValues != dynamoTypes.ReturnValueAllNew {
+		t.Errorf("Expected ALL_NEW, got %s", input.ReturnValues)
+	}
+	if input.ReturnValuesOnConditionCheckFailure != dynamoTypes.ReturnValuesOnConditionCheckFailureAllOld {
+		t.Errorf("Expected ALL_OLD on condition check failure, got %s", input.ReturnValuesOnConditionCheckFailure)
+	}
+	expected := false
+	for _, value := range input.ExpressionAttributeValues {
+		if number, ok := value.(*dynamoTypes.AttributeValueMemberN); ok && number.Value == "1" {
+			expected = true
+		}
+	}
+	if condition := *input.ConditionExpression; !expected || strings.Count(condition, "=") < 2 {
+		t.Errorf("Expected the version to be checked, got %s", condition)
+	}
+
+	// Items that change between the read and the write are read again
+	stale := 2
+	mock := api.Client.(mockDynamoUpdate)
+	mock.stale = &stale
+	api.Client = mock
+	api.ScoutrBase = api
+	*inputs = nil
+	if _, err := api.Patch(req, types.Key{"id": "1"}, map[string]interface{}{"name": "delta"}, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	} else if len(*inputs) != 3 {
+		t.Errorf("Expected 3 writes, got %d", len(*inputs))
+	}
+
+	// Failed condition checks are reported as conflicts if the item is at another version
+	for _, test := range []struct {
+		status  string
+		version string
+		err     error
+	}{
+		{"active", "3", &types.PreconditionFailed{}},
+		{"active", "1", &types.BadRequest{}},
+		{"locked", "3", &types.BadRequest{}},
+	} {
+		mock := api.Client.(mockDynamoUpdate)
+		mock.conflict = map[string]dynamoTypes.AttributeValue{
+			"id":      &dynamoTypes.AttributeValueMemberS{Value: "1"},
+			"status":  &dynamoTypes.AttributeValueMemberS{Value: test.status},
+			"version": &dynamoTypes.AttributeValueMemberN{Value: test.version},
+		}
+		api.Client = mock
+		api.ScoutrBase = api
+
+		_, err := api.Patch(req, types.Key{"id": "1"}, map[string]interface{}{"name": "delta"}, nil, base.AuditActionUpdate)
+		if fmt.Sprintf("%T", err) != fmt.Sprintf("%T", test.err) {
+			t.Errorf("Expected %T for a %s item at version %s, got %v", test.err, test.status, test.version, err)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package aws
 
 import (
+	"context"
+
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
@@ -13,7 +15,7 @@ import (
 //   - nil, nil: user does not exist
 //   - nil, error: error while fetching user
 //   - user, nil: found user
-func (api DynamoAPI) GetAuth(id string) (*types.User, error) {
+func (api DynamoAPI) GetAuth(ctx context.Context, id string) (*types.User, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(api.Config.AuthTable),
 		Key: map[string]dynamoTypes.AttributeValue{
@@ -24,7 +26,7 @@ func (api DynamoAPI) GetAuth(id string) (*types.User, error) {
 	}
 
 	// Try to find user in the auth table
-	user, err := GetItem[types.User](api.Client, input)
+	user, err := GetItem[types.User](ctx, api.Client, input)
 	if err != nil {
 		return nil, err
 	} else if user == nil {
@@ -40,7 +42,7 @@ func (api DynamoAPI) GetAuth(id string) (*types.User, error) {
 //   - nil, nil: group does not exist
 //   - nil, error: error while fetching group
 //   - user, nil: found group
-func (api DynamoAPI) GetGroup(id string) (*types.Group, error) {
+func (api DynamoAPI) GetGroup(ctx context.Context, id string) (*types.Group, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(api.Config.GroupTable),
 		Key: map[string]dynamoTypes.AttributeValue{
@@ -50,7 +52,7 @@ func (api DynamoAPI) GetGroup(id string) (*types.Group, error) {
 		},
 	}
 
-	group, err := GetItem[types.Group](api.Client, input)
+	group, err := GetItem[types.Group](ctx, api.Client, input)
 	if err != nil {
 		return nil, err
 	} else if group == nil {
@@ -62,7 +64,7 @@ func (api DynamoAPI) GetGroup(id string) (*types.Group, error) {
 }
 
 // GetEntitlements: Fetch entitlements from the database
-func (api DynamoAPI) GetEntitlements(entitlementIDs []string) ([]types.User, error) {
+func (api DynamoAPI) GetEntitlements(ctx context.Context, entitlementIDs []string) ([]types.User, error) {
 	// Build an IN expression that limits each expression to 100 items
 	conditions := api.filtering.BuildInExpr("id", entitlementIDs, false)
 	if !conditions.IsSet() {
@@ -83,7 +85,7 @@ func (api DynamoAPI) GetEntitlements(entitlementIDs []string) ([]types.User, err
 	}
 
 	// Scan for the entitlement ids
-	if entitlements, err := Scan[types.User](api.Client, input); err != nil {
+	if entitlements, err := Scan[types.User](ctx, api.Client, input); err != nil {
 		return nil, err
 	} else {
 		return entitlements, nil
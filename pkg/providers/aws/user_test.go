@@ -40,7 +40,7 @@ func TestGetAuth(t *testing.T) {
 		},
 	}
 
-	if user, err := api.GetAuth("user-123"); err != nil {
+	if user, err := api.GetAuth(context.Background(), "user-123"); err != nil {
 		t.Error(err)
 	} else if user == nil {
 		t.Fatal("User should not be nil")
@@ -69,7 +69,7 @@ func TestGetAuthNotFound(t *testing.T) {
 		},
 	}
 
-	if user, err := api.GetAuth("user-123"); err != nil {
+	if user, err := api.GetAuth(context.Background(), "user-123"); err != nil {
 		t.Error(err)
 	} else if user != nil {
 		t.Fatal("User should be nil")
@@ -94,7 +94,7 @@ func TestGetAuthError(t *testing.T) {
 		},
 	}
 
-	if _, err := api.GetAuth("user-123"); err == nil {
+	if _, err := api.GetAuth(context.Background(), "user-123"); err == nil {
 		t.Error("Error should not be nil")
 	}
 }
@@ -0,0 +1,788 @@
+package base
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	AuditSinkTable      = "table"
+	AuditSinkCloudTrail = "cloudtrail"
+	AuditSinkFile       = "file"
+	AuditSinkStdout     = "stdout"
+)
+
+// ErrAuditQueryUnsupported : Returned by sinks that audit logs can be written to, but not read back from
+var ErrAuditQueryUnsupported = &types.NotFound{
+	Message: "Audit logs cannot be queried from this sink",
+}
+
+// AuditSink : Destination of audit logs
+type AuditSink interface {
+	// Write : Store an audit log
+	Write(ctx context.Context, auditLog types.AuditLog) error
+
+	// Query : List the audit logs that match a set of filters, newest first. A limit of 0 returns every
+	// matching log, otherwise pages continue from the next token.
+	Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error)
+}
+
+// BatchAuditSink : Audit sink that can store several audit logs at once
+type BatchAuditSink interface {
+	AuditSink
+
+	// WriteBatch : Store a list of audit logs
+	WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error
+}
+
+// AuditFlusher : Audit sink that buffers audit logs, which must be flushed before the application exits
+type AuditFlusher interface {
+	// Flush : Wait for the buffered audit logs to be written
+	Flush(ctx context.Context) error
+
+	// Close : Flush the buffered audit logs and stop accepting new ones
+	Close(ctx context.Context) error
+}
+
+// WriteAuditLogs : Write a list of audit logs to a sink, in a single batch if the sink supports it
+func WriteAuditLogs(ctx context.Context, sink AuditSink, auditLogs []types.AuditLog) error {
+	if batchSink, ok := sink.(BatchAuditSink); ok {
+		return batchSink.WriteBatch(ctx, auditLogs)
+	}
+
+	for _, auditLog := range auditLogs {
+		if err := sink.Write(ctx, auditLog); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewAuditSink : Build the audit sink selected by the AuditSinks of the config, defaulting to the audit table when
+// one is configured. Sinks that belong to the provider, such as its audit table, are passed in by name, along with
+// the store that holds the head of the audit log chain, if the provider has one. Returns nil if audit logging is
+// disabled.
+func NewAuditSink(cfg config.Config, providerSinks map[string]AuditSink, chain AuditChainStore) (AuditSink, error) {
+	names := cfg.AuditSinks
+	if len(names) == 0 && cfg.AuditTable != "" {
+		names = []string{AuditSinkTable}
+	}
+
+	var sinks MultiAuditSink
+	for _, name := range names {
+		switch name {
+		case AuditSinkFile:
+			if cfg.AuditFile == "" {
+				return nil, errors.New("The file audit sink requires an audit file")
+			}
+			sinks = append(sinks, NewFileAuditSink(cfg.AuditFile))
+		case AuditSinkStdout:
+			sinks = append(sinks, NewStreamAuditSink(os.Stdout))
+		default:
+			if name == AuditSinkTable && cfg.AuditTable == "" {
+				return nil, errors.New("The table audit sink requires an audit table")
+			} else if name == AuditSinkCloudTrail && cfg.CloudTrailChannelARN == "" {
+				return nil, errors.New("The cloudtrail audit sink requires a channel ARN")
+			}
+
+			sink, ok := providerSinks[name]
+			if !ok {
+				return nil, fmt.Errorf("Audit sink '%s' is not supported by this provider", name)
+			}
+			sinks = append(sinks, sink)
+		}
+	}
+
+	asyncOptions := func(name string) AsyncAuditOptions {
+		options := AsyncAuditOptions{
+			QueueSize:     cfg.AuditQueueSize,
+			BatchSize:     cfg.AuditBatchSize,
+			FlushInterval: cfg.AuditFlushInterval,
+			Strict:        cfg.AuditStrict,
+		}
+		if cfg.AuditSpillDir != "" {
+			options.SpillFile = filepath.Join(cfg.AuditSpillDir, name+".jsonl")
+		}
+		return options
+	}
+
+	// Logs are chained before they are fanned out, so every sink stores the same chain. The sinks then share one
+	// queue, spilled to chain.jsonl, and each batch claims its sequence numbers from the chain store in the
+	// background.
+	if len(cfg.AuditHMACKey) > 0 {
+		var sink AuditSink
+		switch len(sinks) {
+		case 0:
+			return nil, nil
+		case 1:
+			sink = sinks[0]
+		default:
+			sink = sinks
+		}
+
+		sink = NewChainedAuditSink(sink, cfg.AuditHMACKey, chain)
+		if cfg.AuditAsync {
+			sink = NewAsyncAuditSink(sink, asyncOptions("chain"))
+		}
+		return sink, nil
+	}
+
+	// Each sink gets its own queue, so a slow or unavailable sink does not hold back the others
+	if cfg.AuditAsync {
+		for i, sink := range sinks {
+			sinks[i] = NewAsyncAuditSink(sink, asyncOptions(names[i]))
+		}
+	}
+
+	switch len(sinks) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sinks[0], nil
+	}
+
+	return sinks, nil
+}
+
+// NewAuditLog : Build the audit log of an action taken by a user. Logs of read actions expire after the
+// retention period in the config.
+func (api Scoutr) NewAuditLog(action string, request types.Request, user *types.User, resource map[string]interface{}, changes map[string]interface{}) types.AuditLog {
+	now := time.Now().UTC()
+	auditLog := types.AuditLog{
+		ID:   uuid.NewString(),
+		Time: now.Format(time.RFC3339Nano),
+		User: types.AuditUser{
+			ID:        user.ID,
+			Name:      user.Name,
+			Username:  user.Username,
+			Email:     user.Email,
+			SourceIP:  request.SourceIP,
+			UserAgent: request.UserAgent,
+		},
+		Action: action,
+		Method: request.Method,
+		Path:   request.Path,
+	}
+
+	// Add expiry time for read events. Chained logs are kept, since removing them would break the chain
+	chained := len(api.Config.AuditHMACKey) > 0
+	if !chained && (action == AuditActionGet || action == AuditActionList || action == AuditActionSearch) {
+		auditLog.ExpireTime = now.AddDate(0, 0, api.Config.LogRetentionDays).Unix()
+	}
+
+	// Add query params
+	if len(request.QueryParams) > 0 {
+		auditLog.QueryParams = request.QueryParams
+	}
+
+	// Add body
+	if request.Body != nil {
+		auditLog.Body = request.Body
+	} else if changes != nil {
+		auditLog.Body = changes
+	}
+
+	// Add resource
+	if resource != nil {
+		auditLog.Resource = resource
+	}
+
+	return auditLog
+}
+
+// WriteAuditLog : Create an audit log and write it to the audit sink. Failures are only returned in strict mode.
+// Otherwise they are logged, since the action being audited has already taken place.
+func (api Scoutr) WriteAuditLog(action string, request types.Request, user *types.User, resource map[string]interface{}, changes map[string]interface{}) error {
+	// Only send audit logs if a sink is configured
+	if api.AuditSink == nil {
+		return nil
+	}
+
+	return api.writeAuditLog(request, api.NewAuditLog(action, request, user, resource, changes))
+}
+
+// WriteChangeAuditLog : Create an audit log of an update or delete and write it to the audit sink. The log records
+// the previous state of the item and each field that changed. The current state of deleted items is nil.
+func (api Scoutr) WriteChangeAuditLog(action string, request types.Request, user *types.User, resource map[string]interface{}, changes map[string]interface{}, previous map[string]interface{}, current map[string]interface{}) error {
+	// Only send audit logs if a sink is configured
+	if api.AuditSink == nil {
+		return nil
+	}
+
+	auditLog := api.NewAuditLog(action, request, user, resource, changes)
+
+	var err error
+	if auditLog.Previous, err = normalize(previous); err != nil {
+		return err
+	}
+	if auditLog.Changes, err = DiffRecords(previous, current); err != nil {
+		return err
+	}
+
+	return api.writeAuditLog(request, auditLog)
+}
+
+// writeAuditLog : Write an audit log to the audit sink, only returning failures in strict mode. Actions are audited
+// once they have taken place, so a failure means the log was lost, not that the action failed.
+func (api Scoutr) writeAuditLog(request types.Request, auditLog types.AuditLog) error {
+	if err := api.AuditSink.Write(request.Context(), auditLog); err != nil {
+		logrus.Errorln("Failed to save audit log", err)
+		logrus.Infof("Failed audit log: '%v'", auditLog)
+
+		if api.Config.AuditStrict {
+			return ContextError(request.Context(), err)
+		}
+	}
+
+	return nil
+}
+
+// DiffRecords : Find the fields that differ between two versions of a record. Values are compared by their JSON
+// representation, so the same number stored as an int and a float is not a change.
+func DiffRecords(previous map[string]interface{}, current map[string]interface{}) (map[string]types.AuditChange, error) {
+	previous, err := normalize(previous)
+	if err != nil {
+		return nil, err
+	}
+	current, err = normalize(current)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]types.AuditChange)
+	for key, value := range previous {
+		if newValue, ok := current[key]; !ok || !reflect.DeepEqual(value, newValue) {
+			changes[key] = types.AuditChange{Old: value, New: newValue}
+		}
+	}
+	for key, value := range current {
+		if _, ok := previous[key]; !ok {
+			changes[key] = types.AuditChange{New: value}
+		}
+	}
+
+	return changes, nil
+}
+
+// normalize : Copy a record through JSON, so it holds the same types as records read back from any sink
+func normalize(record map[string]interface{}) (map[string]interface{}, error) {
+	if record == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// FlushAuditLogs : Wait for queued audit logs to be written
+func (api Scoutr) FlushAuditLogs(ctx context.Context) error {
+	if flusher, ok := api.AuditSink.(AuditFlusher); ok {
+		return flusher.Flush(ctx)
+	}
+
+	return nil
+}
+
+// CloseAuditLogs : Write any queued audit logs and stop the background audit writers. This should be called
+// before the application exits.
+func (api Scoutr) CloseAuditLogs(ctx context.Context) error {
+	if flusher, ok := api.AuditSink.(AuditFlusher); ok {
+		return flusher.Close(ctx)
+	}
+
+	return nil
+}
+
+// AuditMetrics : Counts of the audit logs handled by the background audit writers
+func (api Scoutr) AuditMetrics() AuditMetrics {
+	return auditMetrics(api.AuditSink)
+}
+
+// auditMetrics : Sum the metrics of the asynchronous sinks that make up a sink
+func auditMetrics(sink AuditSink) AuditMetrics {
+	var metrics AuditMetrics
+
+	switch sink := sink.(type) {
+	case *AsyncAuditSink:
+		metrics = sink.Metrics()
+	case *ChainedAuditSink:
+		metrics = auditMetrics(sink.sink)
+	case MultiAuditSink:
+		for _, item := range sink {
+			metrics = metrics.add(auditMetrics(item))
+		}
+	}
+
+	return metrics
+}
+
+// ListAuditLogs : List audit logs, newest first. Logs of items the user cannot read are left out, and fields the
+// user cannot see are removed from the rest.
+func (api Scoutr) ListAuditLogs(req types.Request, pathParams map[string]string, queryParams map[string][]string) ([]types.AuditLog, error) {
+	user, page, err := api.listAuditLogs(req, pathParams, queryParams, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Items, err = api.readableAuditLogs(user, page.Items); err != nil {
+		return nil, err
+	}
+	excludeAuditFields(page.Items, user)
+
+	return page.Items, nil
+}
+
+// ListAuditLogsPage : List a page of audit logs, newest first. Logs of items the user cannot read are left out, so
+// a page can hold fewer logs than the limit. Fields the user cannot see are removed from the rest.
+func (api Scoutr) ListAuditLogsPage(req types.Request, pathParams map[string]string, queryParams map[string][]string) (types.Page[types.AuditLog], error) {
+	user, page, err := api.listAuditLogs(req, pathParams, queryParams, true)
+	if err != nil {
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	if page.Items, err = api.readableAuditLogs(user, page.Items); err != nil {
+		return types.Page[types.AuditLog]{}, err
+	}
+	excludeAuditFields(page.Items, user)
+
+	return page, nil
+}
+
+// listAuditLogs : List audit logs from the audit sink, either all at once or a page at a time. The logs are returned
+// as they were written, along with the user that requested them.
+func (api Scoutr) listAuditLogs(req types.Request, pathParams map[string]string, queryParams map[string][]string, paginate bool) (*types.User, types.Page[types.AuditLog], error) {
+	// Only fetch audit logs if a sink is configured
+	if api.AuditSink == nil {
+		return nil, types.Page[types.AuditLog]{}, &types.NotFound{
+			Message: "Audit logs are not enabled",
+		}
+	}
+
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return nil, types.Page[types.AuditLog]{}, err
+	}
+
+	limit := 0
+	if paginate {
+		if limit, err = PageLimit(req); err != nil {
+			return nil, types.Page[types.AuditLog]{}, err
+		}
+	}
+
+	// Generate dynamic search
+	searchKey, hasSearchKey := pathParams["search_key"]
+	searchValue, hasSearchValue := pathParams["search_value"]
+	if hasSearchKey && hasSearchValue {
+		// Map the search key and value into path params
+		pathParams[searchKey] = searchValue
+		delete(pathParams, "search_key")
+		delete(pathParams, "search_value")
+	}
+
+	// Merge pathParams into queryParams
+	filters := make(map[string][]string)
+	for key, value := range queryParams {
+		filters[key] = value
+	}
+	for key, value := range pathParams {
+		filters[key] = append(filters[key], value)
+	}
+
+	page, err := api.AuditSink.Query(req.Context(), filters, limit, req.Next)
+	if err != nil {
+		logrus.Errorln("Error while attempting to list audit logs", err)
+		return nil, types.Page[types.AuditLog]{}, ContextError(req.Context(), err)
+	}
+
+	return user, page, nil
+}
+
+// readableAuditLogs : Leave out the audit logs that record an item the user cannot read, either before or after the
+// change. Items are checked as they would be outside the trash, since logs of deletes and restores hold the
+// deletion fields.
+func (api Scoutr) readableAuditLogs(user *types.User, auditLogs []types.AuditLog) ([]types.AuditLog, error) {
+	output := make([]types.AuditLog, 0, len(auditLogs))
+	for _, auditLog := range auditLogs {
+		records := []types.Record{auditLog.Previous}
+		if auditLog.Action != AuditActionGet && auditLog.Action != AuditActionList && auditLog.Action != AuditActionSearch {
+			next, _, err := applyAuditLog(auditLog.Previous, auditLog)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, next)
+		}
+
+		readable := true
+		for _, record := range records {
+			if record == nil {
+				continue
+			}
+
+			record = copyRecord(record)
+			delete(record, DeletedAtField)
+			delete(record, DeletedByField)
+
+			var notFound *types.NotFound
+			if err := api.readable(user, record); errors.As(err, &notFound) {
+				readable = false
+				break
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+		if readable {
+			output = append(output, auditLog)
+		}
+	}
+
+	return output, nil
+}
+
+// excludeAuditFields : Remove the fields the user cannot see from the previous state, changes and body of audit
+// logs. Each is copied first, since they may be shared with the audit logs of the sink.
+func excludeAuditFields(auditLogs []types.AuditLog, user *types.User) {
+	if len(user.ExcludeFields) == 0 {
+		return
+	}
+
+	for i, auditLog := range auditLogs {
+		if auditLog.Previous != nil {
+			previous := copyRecord(auditLog.Previous)
+			for _, field := range user.ExcludeFields {
+				delete(previous, field)
+			}
+			auditLogs[i].Previous = previous
+		}
+
+		if auditLog.Changes != nil {
+			changes := make(map[string]types.AuditChange, len(auditLog.Changes))
+			for field, change := range auditLog.Changes {
+				changes[field] = change
+			}
+			for _, field := range user.ExcludeFields {
+				delete(changes, field)
+			}
+			auditLogs[i].Changes = changes
+		}
+
+		if body, ok := auditLog.Body.(map[string]interface{}); ok {
+			body = copyRecord(body)
+			for _, field := range user.ExcludeFields {
+				delete(body, field)
+			}
+			auditLogs[i].Body = body
+		}
+	}
+}
+
+// MultiAuditSink : Fan audit logs out to several sinks. Logs are queried from the first sink that supports queries.
+type MultiAuditSink []AuditSink
+
+// Write : Write an audit log to every sink, even if some of them fail
+func (sinks MultiAuditSink) Write(ctx context.Context, auditLog types.AuditLog) error {
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Write(ctx, auditLog); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WriteBatch : Write a list of audit logs to every sink, even if some of them fail
+func (sinks MultiAuditSink) WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error {
+	var errs []error
+	for _, sink := range sinks {
+		if err := WriteAuditLogs(ctx, sink, auditLogs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Flush : Flush every sink that buffers audit logs
+func (sinks MultiAuditSink) Flush(ctx context.Context) error {
+	var errs []error
+	for _, sink := range sinks {
+		if flusher, ok := sink.(AuditFlusher); ok {
+			if err := flusher.Flush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close : Close every sink that buffers audit logs
+func (sinks MultiAuditSink) Close(ctx context.Context) error {
+	var errs []error
+	for _, sink := range sinks {
+		if flusher, ok := sink.(AuditFlusher); ok {
+			if err := flusher.Close(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Query : Query the first sink that supports queries
+func (sinks MultiAuditSink) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	for _, sink := range sinks {
+		page, err := sink.Query(ctx, filters, limit, next)
+		if !errors.Is(err, ErrAuditQueryUnsupported) {
+			return page, err
+		}
+	}
+
+	return types.Page[types.AuditLog]{}, ErrAuditQueryUnsupported
+}
+
+// StreamAuditSink : Write audit logs to a stream, such as stdout, as JSON lines. Streams cannot be queried.
+type StreamAuditSink struct {
+	mutex  *sync.Mutex
+	writer io.Writer
+}
+
+// NewStreamAuditSink : Initialize a sink that writes audit logs to a stream
+func NewStreamAuditSink(writer io.Writer) StreamAuditSink {
+	return StreamAuditSink{
+		mutex:  &sync.Mutex{},
+		writer: writer,
+	}
+}
+
+// Write : Write an audit log as a single line of JSON
+func (sink StreamAuditSink) Write(ctx context.Context, auditLog types.AuditLog) error {
+	return sink.WriteBatch(ctx, []types.AuditLog{auditLog})
+}
+
+// WriteBatch : Write a list of audit logs, one line of JSON each
+func (sink StreamAuditSink) WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error {
+	data, err := encodeAuditLogs(auditLogs)
+	if err != nil {
+		return err
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	_, err = sink.writer.Write(data)
+	return err
+}
+
+// Query : Streams cannot be read back
+func (sink StreamAuditSink) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	return types.Page[types.AuditLog]{}, ErrAuditQueryUnsupported
+}
+
+// FileAuditSink : Append audit logs to a file as JSON lines
+type FileAuditSink struct {
+	mutex *sync.Mutex
+	path  string
+}
+
+// NewFileAuditSink : Initialize a sink that appends audit logs to a file, creating it if it does not exist
+func NewFileAuditSink(path string) FileAuditSink {
+	return FileAuditSink{
+		mutex: &sync.Mutex{},
+		path:  path,
+	}
+}
+
+// Write : Append an audit log to the file as a single line of JSON
+func (sink FileAuditSink) Write(ctx context.Context, auditLog types.AuditLog) error {
+	return sink.WriteBatch(ctx, []types.AuditLog{auditLog})
+}
+
+// WriteBatch : Append a list of audit logs to the file, one line of JSON each
+func (sink FileAuditSink) WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error {
+	data, err := encodeAuditLogs(auditLogs)
+	if err != nil {
+		return err
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	file, err := os.OpenFile(sink.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Query : Read the audit logs in the file, newest first
+func (sink FileAuditSink) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	auditLogs, err := sink.read()
+	if err != nil {
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	return QueryAuditLogs(auditLogs, filters, limit, next)
+}
+
+// take : Read every audit log in the file and remove them from it
+func (sink FileAuditSink) take() ([]types.AuditLog, error) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	auditLogs, err := sink.read()
+	if err != nil || len(auditLogs) == 0 {
+		return nil, err
+	}
+
+	if err := os.Remove(sink.path); err != nil {
+		return nil, err
+	}
+
+	return auditLogs, nil
+}
+
+// read : Read every audit log in the file, oldest first. A missing file has no logs. The mutex must be held.
+func (sink FileAuditSink) read() ([]types.AuditLog, error) {
+	file, err := os.Open(sink.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var auditLogs []types.AuditLog
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var auditLog types.AuditLog
+		if err := json.Unmarshal(scanner.Bytes(), &auditLog); err != nil {
+			return nil, err
+		}
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return auditLogs, nil
+}
+
+// encodeAuditLogs : Encode a list of audit logs as JSON lines
+func encodeAuditLogs(auditLogs []types.AuditLog) ([]byte, error) {
+	var data []byte
+	for _, auditLog := range auditLogs {
+		line, err := json.Marshal(auditLog)
+		if err != nil {
+			return nil, err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	return data, nil
+}
+
+// QueryAuditLogs : Filter a list of audit logs stored oldest first, returning the matches newest first. Pages
+// walk backwards from the position in the next token. Filters use the JSON field names of the audit log.
+func QueryAuditLogs(auditLogs []types.AuditLog, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	start := -1
+	if _, err := DecodeToken(next, &start); err != nil {
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	// Make sure the filters are valid
+	if _, err := NewLocalFilter(nil).Filter(nil, filters, ""); err != nil {
+		logrus.Errorln("Error encountered during filtering", err)
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	if start < 0 || start > len(auditLogs) {
+		start = len(auditLogs)
+	}
+
+	page := types.Page[types.AuditLog]{Items: []types.AuditLog{}}
+	for i := start - 1; i >= 0; i-- {
+		// Filter using the JSON field names of the audit log
+		data, err := json.Marshal(auditLogs[i])
+		if err != nil {
+			return types.Page[types.AuditLog]{}, err
+		}
+
+		var record types.Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return types.Page[types.AuditLog]{}, err
+		}
+
+		f := NewLocalFilter(record)
+		conditions, err := f.Filter(nil, filters, "")
+		if err != nil {
+			return types.Page[types.AuditLog]{}, err
+		}
+
+		if !f.Matches(conditions) {
+			continue
+		}
+
+		// Another match means there is a following page, which starts below the last log returned
+		if limit > 0 && len(page.Items) == limit {
+			page.Next, err = EncodeToken(i + 1)
+			if err != nil {
+				return types.Page[types.AuditLog]{}, err
+			}
+			break
+		}
+
+		// Return a copy, so callers never share maps with the stored log
+		var auditLog types.AuditLog
+		if err := json.Unmarshal(data, &auditLog); err != nil {
+			return types.Page[types.AuditLog]{}, err
+		}
+		page.Items = append(page.Items, auditLog)
+	}
+
+	return page, nil
+}
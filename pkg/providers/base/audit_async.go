@@ -0,0 +1,385 @@
+package base
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrAuditSinkClosed : Returned when an audit log is written after the sink has been closed
+var ErrAuditSinkClosed = errors.New("Audit sink is closed")
+
+// ErrAuditQueueFull : Returned when an audit log is dropped because there is no room for it and no spill file
+var ErrAuditQueueFull = errors.New("Audit queue is full")
+
+// AuditMetrics : Counts of the audit logs handled by an asynchronous audit sink
+type AuditMetrics struct {
+	// Queued : Audit logs waiting in the queue
+	Queued int64 `json:"queued"`
+
+	// Written : Audit logs written to the sink
+	Written int64 `json:"written"`
+
+	// Retried : Failed batch writes that were retried
+	Retried int64 `json:"retried"`
+
+	// Spilled : Audit logs saved to the spill file because the sink was unavailable or the queue was full
+	Spilled int64 `json:"spilled"`
+
+	// Replayed : Audit logs from the spill file written to the sink once it recovered
+	Replayed int64 `json:"replayed"`
+
+	// Dropped : Audit logs that could not be written or spilled, and were lost
+	Dropped int64 `json:"dropped"`
+}
+
+// add : Sum the metrics of two sinks
+func (m AuditMetrics) add(other AuditMetrics) AuditMetrics {
+	return AuditMetrics{
+		Queued:   m.Queued + other.Queued,
+		Written:  m.Written + other.Written,
+		Retried:  m.Retried + other.Retried,
+		Spilled:  m.Spilled + other.Spilled,
+		Replayed: m.Replayed + other.Replayed,
+		Dropped:  m.Dropped + other.Dropped,
+	}
+}
+
+// AsyncAuditOptions : Settings of an asynchronous audit sink
+type AsyncAuditOptions struct {
+	// QueueSize : Number of audit logs the queue holds. Defaults to 1000.
+	QueueSize int
+
+	// BatchSize : Maximum number of audit logs written at once. Defaults to 25.
+	BatchSize int
+
+	// FlushInterval : How long queued audit logs wait for a batch to fill up. Defaults to 1 second.
+	FlushInterval time.Duration
+
+	// SpillFile : JSON lines file that audit logs are saved to when they cannot be written or queued. Logs are
+	// dropped if this is not set.
+	SpillFile string
+
+	// Strict : Wait for each audit log to be written or saved to the spill file, and return the error if it was
+	// dropped instead
+	Strict bool
+
+	// BackOff : Builds the retry policy of each batch. Defaults to exponential backoff for up to 30 seconds.
+	BackOff func() backoff.BackOff
+}
+
+// auditEntry : Queued audit log. In strict mode, the result of writing it is sent to done.
+type auditEntry struct {
+	auditLog types.AuditLog
+	done     chan error
+}
+
+// AsyncAuditSink : Write audit logs to another sink from a background queue, in batches. Failed batches are
+// retried with backoff and then saved to the spill file, which is written to the sink once it recovers.
+type AsyncAuditSink struct {
+	sink    AuditSink
+	options AsyncAuditOptions
+	spill   *FileAuditSink
+
+	queue   chan auditEntry
+	flush   chan chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+
+	// mutex : Guards closed, so nothing is queued once the worker starts draining the queue
+	mutex  sync.RWMutex
+	closed bool
+
+	queued   atomic.Int64
+	written  atomic.Int64
+	retried  atomic.Int64
+	spilled  atomic.Int64
+	replayed atomic.Int64
+	dropped  atomic.Int64
+}
+
+// NewAsyncAuditSink : Start writing audit logs to a sink in the background. Audit logs left in the spill file by a
+// previous run are written first.
+func NewAsyncAuditSink(sink AuditSink, options AsyncAuditOptions) *AsyncAuditSink {
+	if options.QueueSize <= 0 {
+		options.QueueSize = 1000
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 25
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+	if options.BackOff == nil {
+		options.BackOff = func() backoff.BackOff {
+			b := backoff.NewExponentialBackOff()
+			b.MaxElapsedTime = 30 * time.Second
+			return b
+		}
+	}
+
+	async := &AsyncAuditSink{
+		sink:    sink,
+		options: options,
+		queue:   make(chan auditEntry, options.QueueSize),
+		flush:   make(chan chan struct{}),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if options.SpillFile != "" {
+		spill := NewFileAuditSink(options.SpillFile)
+		async.spill = &spill
+	}
+
+	go async.run()
+
+	return async
+}
+
+// Write : Queue an audit log. In strict mode, this waits for the log to be written.
+func (sink *AsyncAuditSink) Write(ctx context.Context, auditLog types.AuditLog) error {
+	entry := auditEntry{auditLog: auditLog}
+	if sink.options.Strict {
+		entry.done = make(chan error, 1)
+	}
+
+	sink.mutex.RLock()
+	if sink.closed {
+		sink.mutex.RUnlock()
+		return ErrAuditSinkClosed
+	}
+
+	select {
+	case sink.queue <- entry:
+		sink.queued.Add(1)
+		sink.mutex.RUnlock()
+	default:
+		sink.mutex.RUnlock()
+
+		// The queue is full, so save the log for later. Spilled logs are written once the sink recovers.
+		return sink.overflow([]types.AuditLog{auditLog})
+	}
+
+	if entry.done == nil {
+		return nil
+	}
+
+	select {
+	case err := <-entry.done:
+		return err
+	case <-ctx.Done():
+		return ContextError(ctx, ctx.Err())
+	}
+}
+
+// WriteBatch : Queue a list of audit logs
+func (sink *AsyncAuditSink) WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error {
+	var errs []error
+	for _, auditLog := range auditLogs {
+		if err := sink.Write(ctx, auditLog); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Query : Query the underlying sink. Queued audit logs are not included until they are written.
+func (sink *AsyncAuditSink) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	return sink.sink.Query(ctx, filters, limit, next)
+}
+
+// Flush : Wait for every queued audit log to be written, spilled or dropped
+func (sink *AsyncAuditSink) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	select {
+	case sink.flush <- flushed:
+	case <-sink.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close : Stop accepting audit logs and wait for the queued ones to be written
+func (sink *AsyncAuditSink) Close(ctx context.Context) error {
+	sink.mutex.Lock()
+	if !sink.closed {
+		sink.closed = true
+		close(sink.closing)
+	}
+	sink.mutex.Unlock()
+
+	select {
+	case <-sink.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Metrics : Counts of the audit logs handled by the sink
+func (sink *AsyncAuditSink) Metrics() AuditMetrics {
+	return AuditMetrics{
+		Queued:   sink.queued.Load(),
+		Written:  sink.written.Load(),
+		Retried:  sink.retried.Load(),
+		Spilled:  sink.spilled.Load(),
+		Replayed: sink.replayed.Load(),
+		Dropped:  sink.dropped.Load(),
+	}
+}
+
+// run : Write queued audit logs in batches until the sink is closed
+func (sink *AsyncAuditSink) run() {
+	defer close(sink.done)
+
+	ticker := time.NewTicker(sink.options.FlushInterval)
+	defer ticker.Stop()
+
+	// Logs spilled by a previous run are written before anything else
+	sink.replay()
+
+	var batch []auditEntry
+	for {
+		select {
+		case entry := <-sink.queue:
+			sink.queued.Add(-1)
+			batch = append(batch, entry)
+			if len(batch) >= sink.options.BatchSize {
+				sink.write(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			sink.write(batch)
+			batch = nil
+			sink.replay()
+		case flushed := <-sink.flush:
+			sink.drain(batch)
+			batch = nil
+			sink.replay()
+			close(flushed)
+		case <-sink.closing:
+			sink.drain(batch)
+			sink.replay()
+			return
+		}
+	}
+}
+
+// drain : Write the current batch and everything left in the queue
+func (sink *AsyncAuditSink) drain(batch []auditEntry) {
+	for {
+		select {
+		case entry := <-sink.queue:
+			sink.queued.Add(-1)
+			batch = append(batch, entry)
+			if len(batch) >= sink.options.BatchSize {
+				sink.write(batch)
+				batch = nil
+			}
+		default:
+			sink.write(batch)
+			return
+		}
+	}
+}
+
+// write : Write a batch of audit logs to the sink, retrying with backoff. Batches that still fail are spilled. The
+// whole batch is sent again on each retry, so sinks rely on the ID of each log to avoid storing it twice.
+func (sink *AsyncAuditSink) write(batch []auditEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	auditLogs := make([]types.AuditLog, len(batch))
+	for i, entry := range batch {
+		auditLogs[i] = entry.auditLog
+	}
+
+	err := backoff.RetryNotify(func() error {
+		return WriteAuditLogs(context.Background(), sink.sink, auditLogs)
+	}, sink.options.BackOff(), func(err error, wait time.Duration) {
+		sink.retried.Add(1)
+		logrus.WithError(err).Warnf("Failed to write audit logs, retrying in %s", wait)
+	})
+
+	if err != nil {
+		logrus.WithError(err).Errorf("Failed to write %d audit logs", len(auditLogs))
+		err = sink.overflow(auditLogs)
+	} else {
+		sink.written.Add(int64(len(auditLogs)))
+	}
+
+	for _, entry := range batch {
+		if entry.done != nil {
+			entry.done <- err
+		}
+	}
+}
+
+// overflow : Save audit logs that could not be written or queued to the spill file, or drop them if there is none
+func (sink *AsyncAuditSink) overflow(auditLogs []types.AuditLog) error {
+	if sink.spill == nil {
+		sink.dropped.Add(int64(len(auditLogs)))
+		logrus.Errorf("Dropped %d audit logs", len(auditLogs))
+		return ErrAuditQueueFull
+	}
+
+	if err := sink.spill.WriteBatch(context.Background(), auditLogs); err != nil {
+		sink.dropped.Add(int64(len(auditLogs)))
+		logrus.WithError(err).Errorf("Dropped %d audit logs", len(auditLogs))
+		return err
+	}
+
+	sink.spilled.Add(int64(len(auditLogs)))
+	return nil
+}
+
+// replay : Write the audit logs in the spill file to the sink. Logs that still cannot be written are put back.
+func (sink *AsyncAuditSink) replay() {
+	if sink.spill == nil {
+		return
+	}
+
+	auditLogs, err := sink.spill.take()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read spilled audit logs")
+		return
+	}
+
+	for len(auditLogs) > 0 {
+		size := sink.options.BatchSize
+		if size > len(auditLogs) {
+			size = len(auditLogs)
+		}
+
+		// The sink is still unavailable, so try again later
+		if err := WriteAuditLogs(context.Background(), sink.sink, auditLogs[:size]); err != nil {
+			if err := sink.spill.WriteBatch(context.Background(), auditLogs); err != nil {
+				sink.dropped.Add(int64(len(auditLogs)))
+				logrus.WithError(err).Errorf("Dropped %d audit logs", len(auditLogs))
+			}
+			return
+		}
+
+		sink.replayed.Add(int64(size))
+		sink.written.Add(int64(size))
+		auditLogs = auditLogs[size:]
+	}
+}
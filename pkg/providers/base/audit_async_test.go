@@ -0,0 +1,197 @@
+package base_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/cenkalti/backoff/v4"
+)
+
+// recordingSink : Audit sink that records the batches written to it, and fails while unavailable
+type recordingSink struct {
+	mutex       sync.Mutex
+	batches     [][]types.AuditLog
+	unavailable bool
+	started     chan struct{}
+	release     chan struct{}
+}
+
+func (sink *recordingSink) Write(ctx context.Context, auditLog types.AuditLog) error {
+	return sink.WriteBatch(ctx, []types.AuditLog{auditLog})
+}
+
+func (sink *recordingSink) WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error {
+	if sink.started != nil {
+		sink.started <- struct{}{}
+		<-sink.release
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	if sink.unavailable {
+		return errors.New("unavailable")
+	}
+	sink.batches = append(sink.batches, auditLogs)
+	return nil
+}
+
+func (sink *recordingSink) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	return types.Page[types.AuditLog]{}, base.ErrAuditQueryUnsupported
+}
+
+func (sink *recordingSink) setUnavailable(unavailable bool) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.unavailable = unavailable
+}
+
+func noRetries() backoff.BackOff {
+	return &backoff.StopBackOff{}
+}
+
+func TestAsyncAuditSinkBatches(t *testing.T) {
+	sink := &recordingSink{}
+	async := base.NewAsyncAuditSink(sink, base.AsyncAuditOptions{BatchSize: 25, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	for i := 0; i < 30; i++ {
+		if err := async.Write(ctx, types.AuditLog{Action: "GET"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := async.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.batches) != 2 || len(sink.batches[0]) != 25 || len(sink.batches[1]) != 5 {
+		t.Errorf("Expected batches of 25 and 5, got %d batches", len(sink.batches))
+	}
+	if metrics := async.Metrics(); metrics.Written != 30 || metrics.Queued != 0 {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+
+	// Closed sinks reject new logs
+	if err := async.Write(ctx, types.AuditLog{}); !errors.Is(err, base.ErrAuditSinkClosed) {
+		t.Errorf("Expected the sink to be closed, got %v", err)
+	}
+}
+
+func TestAsyncAuditSinkSpill(t *testing.T) {
+	sink := &recordingSink{unavailable: true}
+	spillFile := filepath.Join(t.TempDir(), "spill.jsonl")
+	async := base.NewAsyncAuditSink(sink, base.AsyncAuditOptions{
+		FlushInterval: time.Hour,
+		SpillFile:     spillFile,
+		BackOff: func() backoff.BackOff {
+			return backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 2)
+		},
+	})
+	ctx := context.Background()
+
+	for _, action := range []string{"CREATE", "UPDATE"} {
+		if err := async.Write(ctx, types.AuditLog{Action: action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Failed batches are retried and then saved to disk
+	if err := async.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if metrics := async.Metrics(); metrics.Retried != 2 || metrics.Spilled != 2 || metrics.Written != 0 {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+	if page, err := base.NewFileAuditSink(spillFile).Query(ctx, nil, 0, ""); err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 2 {
+		t.Errorf("Expected 2 spilled logs, got %d", len(page.Items))
+	}
+
+	// Spilled logs are written once the sink recovers
+	sink.setUnavailable(false)
+	if err := async.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if metrics := async.Metrics(); metrics.Replayed != 2 || metrics.Written != 2 {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+	if len(sink.batches) != 1 || sink.batches[0][0].Action != "CREATE" || sink.batches[0][1].Action != "UPDATE" {
+		t.Errorf("Unexpected batches %+v", sink.batches)
+	}
+}
+
+func TestAsyncAuditSinkQueueFull(t *testing.T) {
+	sink := &recordingSink{started: make(chan struct{}), release: make(chan struct{})}
+	async := base.NewAsyncAuditSink(sink, base.AsyncAuditOptions{QueueSize: 1, BatchSize: 1, BackOff: noRetries})
+	ctx := context.Background()
+
+	// The first log holds up the worker and the second fills the queue
+	if err := async.Write(ctx, types.AuditLog{Action: "CREATE"}); err != nil {
+		t.Fatal(err)
+	}
+	<-sink.started
+	if err := async.Write(ctx, types.AuditLog{Action: "UPDATE"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a spill file, logs that do not fit are dropped
+	if err := async.Write(ctx, types.AuditLog{Action: "DELETE"}); !errors.Is(err, base.ErrAuditQueueFull) {
+		t.Errorf("Expected the queue to be full, got %v", err)
+	}
+	if metrics := async.Metrics(); metrics.Dropped != 1 || metrics.Queued != 1 {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+
+	sink.release <- struct{}{}
+	<-sink.started
+	sink.release <- struct{}{}
+	if err := async.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if metrics := async.Metrics(); metrics.Written != 2 {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+}
+
+func TestAsyncAuditSinkStrict(t *testing.T) {
+	sink := &recordingSink{unavailable: true}
+	async := base.NewAsyncAuditSink(sink, base.AsyncAuditOptions{FlushInterval: time.Millisecond, Strict: true, BackOff: noRetries})
+	defer async.Close(context.Background())
+
+	// Strict writes wait for the result
+	if err := async.Write(context.Background(), types.AuditLog{Action: "CREATE"}); err == nil {
+		t.Error("Expected the write to fail")
+	}
+
+	sink.setUnavailable(false)
+	if err := async.Write(context.Background(), types.AuditLog{Action: "CREATE"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAsyncAuditSinkStrictSpill(t *testing.T) {
+	sink := &recordingSink{unavailable: true}
+	async := base.NewAsyncAuditSink(sink, base.AsyncAuditOptions{
+		FlushInterval: time.Millisecond,
+		SpillFile:     filepath.Join(t.TempDir(), "spill.jsonl"),
+		Strict:        true,
+		BackOff:       noRetries,
+	})
+	defer async.Close(context.Background())
+
+	// Logs saved to the spill file are written later, so the write succeeds
+	if err := async.Write(context.Background(), types.AuditLog{Action: "CREATE"}); err != nil {
+		t.Error(err)
+	}
+	if metrics := async.Metrics(); metrics.Spilled != 1 || metrics.Dropped != 0 {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+}
@@ -0,0 +1,354 @@
+package base
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	AuditProblemModified   = "modified"
+	AuditProblemMissing    = "missing"
+	AuditProblemBrokenLink = "broken_link"
+	AuditProblemDuplicate  = "duplicate"
+)
+
+// AuditProblem : Problem found while verifying the audit log chain
+type AuditProblem struct {
+	Sequence int64  `json:"sequence"`
+	Time     string `json:"time,omitempty"`
+	Problem  string `json:"problem"`
+	Message  string `json:"message"`
+}
+
+// AuditVerification : Result of verifying the audit log chain
+type AuditVerification struct {
+	// Verified : Number of chained audit logs with a valid HMAC
+	Verified int `json:"verified"`
+
+	// Unchained : Number of audit logs written before the chain was enabled, which cannot be verified
+	Unchained int `json:"unchained"`
+
+	// LastSequence : Sequence number of the newest audit log in the chain
+	LastSequence int64 `json:"last_sequence"`
+
+	Problems []AuditProblem `json:"problems"`
+}
+
+// Valid : Check if the chain is intact
+func (v AuditVerification) Valid() bool {
+	return len(v.Problems) == 0
+}
+
+// chainAttempts : Number of times a log tries to claim the next sequence number before giving up
+const chainAttempts = 10
+
+// AuditChainHead : Sequence number and hash of the newest audit log in the chain
+type AuditChainHead struct {
+	Sequence int64  `json:"sequence"`
+	Hash     string `json:"hash"`
+}
+
+// AuditChainStore : Shared record of the head of the audit log chain. Every writer claims the next sequence number by
+// moving the head on with a conditional write, so the chain does not fork when several processes write to it.
+type AuditChainStore interface {
+	// Head : Read the head of the chain. An empty chain has a zero head.
+	Head(ctx context.Context) (AuditChainHead, error)
+
+	// Advance : Move the head of the chain from one log to the next, only if it is still at the first. Returns
+	// false if another writer moved it first.
+	Advance(ctx context.Context, from AuditChainHead, to AuditChainHead) (bool, error)
+}
+
+// ChainedAuditSink : Chain the audit logs written to another sink. Each log gets the next sequence number and the
+// hash of the log before it, and is signed with an HMAC. Sequence numbers are claimed from the chain store, so any
+// number of processes can write to the same chain. A batch of logs claims its sequence numbers at once, so when the
+// sink is written to from a background queue, the chain store is not on the request path.
+type ChainedAuditSink struct {
+	sink  AuditSink
+	key   []byte
+	store AuditChainStore
+	mutex *sync.Mutex
+
+	// head : Last head of the chain seen by this sink. Another writer may have moved it on since.
+	head   AuditChainHead
+	loaded bool
+}
+
+// NewChainedAuditSink : Initialize a sink that chains the audit logs written to another sink, claiming sequence
+// numbers from the store. Without a store, the chain is continued from the newest log in the sink and kept in
+// memory, which is only safe when a single process writes to the sink.
+func NewChainedAuditSink(sink AuditSink, key []byte, store AuditChainStore) *ChainedAuditSink {
+	if store == nil {
+		store = &localAuditChain{sink: sink}
+	}
+
+	return &ChainedAuditSink{
+		sink:  sink,
+		key:   key,
+		store: store,
+		mutex: &sync.Mutex{},
+	}
+}
+
+// Write : Add an audit log to the end of the chain. The chain moves on even if the write fails, so a lost log
+// shows up as a gap when the chain is verified.
+func (sink *ChainedAuditSink) Write(ctx context.Context, auditLog types.AuditLog) error {
+	return sink.WriteBatch(ctx, []types.AuditLog{auditLog})
+}
+
+// WriteBatch : Add a list of audit logs to the end of the chain. The logs are linked in place, and logs that are
+// already linked keep their place, so a batch that is retried or spilled is not linked again.
+func (sink *ChainedAuditSink) WriteBatch(ctx context.Context, auditLogs []types.AuditLog) error {
+	if err := sink.link(ctx, auditLogs); err != nil {
+		return err
+	}
+
+	return WriteAuditLogs(ctx, sink.sink, auditLogs)
+}
+
+// link : Claim the next sequence numbers for the audit logs that are not linked yet and sign them, moving the head
+// of the chain once for all of them. The head seen last is tried first, and read again from the store if another
+// writer has moved it on.
+func (sink *ChainedAuditSink) link(ctx context.Context, auditLogs []types.AuditLog) error {
+	var unlinked []int
+	for i, auditLog := range auditLogs {
+		if auditLog.Sequence == 0 && auditLog.Hash == "" {
+			unlinked = append(unlinked, i)
+		}
+	}
+	if len(unlinked) == 0 {
+		return nil
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	linked := make([]types.AuditLog, len(unlinked))
+	for attempt := 0; attempt < chainAttempts; attempt++ {
+		if !sink.loaded {
+			head, err := sink.store.Head(ctx)
+			if err != nil {
+				return ContextError(ctx, err)
+			}
+			sink.head = head
+			sink.loaded = true
+		}
+
+		next := sink.head
+		for i, index := range unlinked {
+			auditLog := auditLogs[index]
+			auditLog.Sequence = next.Sequence + 1
+			auditLog.PreviousHash = next.Hash
+
+			hash, err := SignAuditLog(auditLog, sink.key)
+			if err != nil {
+				return err
+			}
+			auditLog.Hash = hash
+
+			linked[i] = auditLog
+			next = AuditChainHead{Sequence: auditLog.Sequence, Hash: auditLog.Hash}
+		}
+
+		ok, err := sink.store.Advance(ctx, sink.head, next)
+		if err != nil {
+			return ContextError(ctx, err)
+		} else if ok {
+			sink.head = next
+			for i, index := range unlinked {
+				auditLogs[index] = linked[i]
+			}
+			return nil
+		}
+
+		// Another writer claimed the sequence numbers, so start again from the current head
+		sink.loaded = false
+	}
+
+	return fmt.Errorf("Failed to claim sequence numbers for %d audit logs after %d attempts", len(unlinked), chainAttempts)
+}
+
+// Query : Query the underlying sink
+func (sink *ChainedAuditSink) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	return sink.sink.Query(ctx, filters, limit, next)
+}
+
+// Flush : Flush the underlying sink if it buffers audit logs
+func (sink *ChainedAuditSink) Flush(ctx context.Context) error {
+	if flusher, ok := sink.sink.(AuditFlusher); ok {
+		return flusher.Flush(ctx)
+	}
+
+	return nil
+}
+
+// Close : Close the underlying sink if it buffers audit logs
+func (sink *ChainedAuditSink) Close(ctx context.Context) error {
+	if flusher, ok := sink.sink.(AuditFlusher); ok {
+		return flusher.Close(ctx)
+	}
+
+	return nil
+}
+
+// localAuditChain : Head of the chain kept in memory, for sinks that have no chain store. The chain is continued
+// from the newest log in the sink.
+type localAuditChain struct {
+	sink   AuditSink
+	head   AuditChainHead
+	loaded bool
+}
+
+// Head : Read the newest log in the sink the first time, and the head kept in memory after that. Sinks that cannot
+// be queried start a new chain.
+func (chain *localAuditChain) Head(ctx context.Context) (AuditChainHead, error) {
+	if chain.loaded {
+		return chain.head, nil
+	}
+
+	page, err := chain.sink.Query(ctx, nil, 1, "")
+	if errors.Is(err, ErrAuditQueryUnsupported) {
+		logrus.Warn("Audit logs cannot be queried, so a new audit chain is started")
+	} else if err != nil {
+		return AuditChainHead{}, err
+	}
+
+	if len(page.Items) > 0 {
+		chain.head = AuditChainHead{Sequence: page.Items[0].Sequence, Hash: page.Items[0].Hash}
+	}
+	chain.loaded = true
+
+	return chain.head, nil
+}
+
+// Advance : Move the head kept in memory. The chained sink holds its lock while it does this.
+func (chain *localAuditChain) Advance(ctx context.Context, from AuditChainHead, to AuditChainHead) (bool, error) {
+	if chain.head != from {
+		return false, nil
+	}
+
+	chain.head = to
+	return true, nil
+}
+
+// SignAuditLog : Calculate the HMAC of an audit log. The HMAC covers the canonical JSON of every field except
+// the hash itself, so it does not change when the log is stored and read back.
+func SignAuditLog(auditLog types.AuditLog, key []byte) (string, error) {
+	auditLog.Hash = ""
+
+	data, err := json.Marshal(auditLog)
+	if err != nil {
+		return "", err
+	}
+
+	// Decoding and encoding again normalizes number formats and sorts the keys of every map
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+	if data, err = json.Marshal(value); err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyAuditChain : Check that a set of audit logs forms an intact chain, reporting logs that were modified,
+// removed from or added to it. Removing the newest logs cannot be detected from the logs alone, so the last
+// sequence number should be compared against a previous verification.
+func VerifyAuditChain(auditLogs []types.AuditLog, key []byte) (AuditVerification, error) {
+	verification := AuditVerification{Problems: []AuditProblem{}}
+
+	var chain []types.AuditLog
+	for _, auditLog := range auditLogs {
+		if auditLog.Sequence == 0 && auditLog.Hash == "" {
+			verification.Unchained++
+			continue
+		}
+		chain = append(chain, auditLog)
+	}
+
+	sort.SliceStable(chain, func(i, j int) bool {
+		return chain[i].Sequence < chain[j].Sequence
+	})
+
+	var previous *types.AuditLog
+	for i := range chain {
+		auditLog := chain[i]
+
+		problem := func(name string, message string) {
+			verification.Problems = append(verification.Problems, AuditProblem{
+				Sequence: auditLog.Sequence,
+				Time:     auditLog.Time,
+				Problem:  name,
+				Message:  message,
+			})
+		}
+
+		hash, err := SignAuditLog(auditLog, key)
+		if err != nil {
+			return AuditVerification{}, err
+		}
+
+		if !hmac.Equal([]byte(hash), []byte(auditLog.Hash)) {
+			problem(AuditProblemModified, "The HMAC does not match the contents of the log")
+		} else {
+			verification.Verified++
+		}
+
+		expected := int64(1)
+		if previous != nil {
+			expected = previous.Sequence + 1
+		}
+
+		switch {
+		case previous != nil && auditLog.Sequence == previous.Sequence:
+			problem(AuditProblemDuplicate, "Another log has the same sequence number")
+			continue
+		case auditLog.Sequence > expected:
+			verification.Problems = append(verification.Problems, AuditProblem{
+				Sequence: expected,
+				Problem:  AuditProblemMissing,
+				Message:  fmt.Sprintf("Logs %d to %d are missing", expected, auditLog.Sequence-1),
+			})
+		case previous == nil && auditLog.PreviousHash != "":
+			problem(AuditProblemBrokenLink, "The first log links to a previous log")
+		case previous != nil && auditLog.PreviousHash != previous.Hash:
+			problem(AuditProblemBrokenLink, "The previous hash does not match the log before it")
+		}
+
+		previous = &chain[i]
+		verification.LastSequence = auditLog.Sequence
+	}
+
+	return verification, nil
+}
+
+// VerifyAuditLogs : Verify the chain of every audit log in the audit sink
+func (api Scoutr) VerifyAuditLogs(req types.Request) (AuditVerification, error) {
+	if len(api.Config.AuditHMACKey) == 0 {
+		return AuditVerification{}, &types.NotFound{
+			Message: "Audit log signing is not enabled",
+		}
+	}
+
+	_, page, err := api.listAuditLogs(req, map[string]string{}, map[string][]string{}, false)
+	if err != nil {
+		return AuditVerification{}, err
+	}
+
+	return VerifyAuditChain(page.Items, api.Config.AuditHMACKey)
+}
@@ -0,0 +1,191 @@
+package base_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+func TestChainedAuditSink(t *testing.T) {
+	key := []byte("secret")
+	cfg := config.Config{
+		AuditSinks:   []string{base.AuditSinkFile},
+		AuditFile:    filepath.Join(t.TempDir(), "audit.jsonl"),
+		AuditHMACKey: key,
+	}
+	ctx := context.Background()
+
+	sink, err := base.NewAuditSink(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{"CREATE", "UPDATE"} {
+		err := sink.Write(ctx, types.AuditLog{
+			Action:   action,
+			Resource: map[string]interface{}{"id": "1", "count": 2},
+			Body:     map[string]interface{}{"b": []interface{}{1.5, "x"}, "a": true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A new sink continues the chain in the file
+	sink, err = base.NewAuditSink(cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, types.AuditLog{Action: "DELETE"}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := sink.Query(ctx, nil, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLogs := page.Items
+
+	if verification, err := base.VerifyAuditChain(auditLogs, key); err != nil {
+		t.Fatal(err)
+	} else if !verification.Valid() || verification.Verified != 3 || verification.LastSequence != 3 {
+		t.Errorf("Expected a valid chain of 3 logs, got %+v", verification)
+	}
+
+	// Logs signed with another key do not verify
+	if verification, _ := base.VerifyAuditChain(auditLogs, []byte("other")); verification.Verified != 0 {
+		t.Errorf("Expected no logs to verify, got %+v", verification)
+	}
+
+	// Logs are newest first
+	tests := []struct {
+		name     string
+		tamper   func([]types.AuditLog) []types.AuditLog
+		problems []string
+	}{
+		{"Modified", func(logs []types.AuditLog) []types.AuditLog {
+			logs[1].Action = "GET"
+			return logs
+		}, []string{base.AuditProblemModified}},
+		{"Missing", func(logs []types.AuditLog) []types.AuditLog {
+			return append(logs[:1], logs[2])
+		}, []string{base.AuditProblemMissing}},
+		{"Duplicate", func(logs []types.AuditLog) []types.AuditLog {
+			return append(logs, logs[1])
+		}, []string{base.AuditProblemDuplicate}},
+		{"Relinked", func(logs []types.AuditLog) []types.AuditLog {
+			logs[2].Hash = "forged"
+			return logs
+		}, []string{base.AuditProblemModified, base.AuditProblemBrokenLink}},
+		{"Unchained", func(logs []types.AuditLog) []types.AuditLog {
+			return append(logs, types.AuditLog{Action: "CREATE"})
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := tt.tamper(append([]types.AuditLog{}, auditLogs...))
+
+			verification, err := base.VerifyAuditChain(logs, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var problems []string
+			for _, problem := range verification.Problems {
+				problems = append(problems, problem.Problem)
+			}
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("Expected problems %v, got %+v", tt.problems, verification.Problems)
+			}
+			for i := range problems {
+				if problems[i] != tt.problems[i] {
+					t.Errorf("Expected problems %v, got %+v", tt.problems, verification.Problems)
+				}
+			}
+		})
+	}
+}
+
+// countingChainStore : Chain store kept in memory that counts how often the head is moved
+type countingChainStore struct {
+	mutex    sync.Mutex
+	head     base.AuditChainHead
+	advances int
+}
+
+func (store *countingChainStore) Head(ctx context.Context) (base.AuditChainHead, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.head, nil
+}
+
+func (store *countingChainStore) Advance(ctx context.Context, from base.AuditChainHead, to base.AuditChainHead) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.head != from {
+		return false, nil
+	}
+	store.head = to
+	store.advances++
+	return true, nil
+}
+
+func (store *countingChainStore) count() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.advances
+}
+
+func TestChainedAuditSinkAsync(t *testing.T) {
+	key := []byte("secret")
+	cfg := config.Config{
+		AuditSinks:         []string{base.AuditSinkFile},
+		AuditFile:          filepath.Join(t.TempDir(), "audit.jsonl"),
+		AuditHMACKey:       key,
+		AuditAsync:         true,
+		AuditBatchSize:     10,
+		AuditFlushInterval: time.Hour,
+	}
+	ctx := context.Background()
+
+	store := &countingChainStore{}
+	sink, err := base.NewAuditSink(cfg, nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.(base.AuditFlusher).Close(ctx)
+
+	// Logs are queued without claiming sequence numbers on the request path
+	for i := 0; i < 5; i++ {
+		if err := sink.Write(ctx, types.AuditLog{Action: "GET"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := store.count(); count != 0 {
+		t.Errorf("Expected no sequence numbers to be claimed before the queue is flushed, got %d", count)
+	}
+
+	// The batch claims its sequence numbers at once
+	if err := sink.(base.AuditFlusher).Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if count := store.count(); count != 1 {
+		t.Errorf("Expected the head to be moved once, got %d", count)
+	}
+
+	page, err := sink.Query(ctx, nil, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verification, err := base.VerifyAuditChain(page.Items, key); err != nil {
+		t.Fatal(err)
+	} else if !verification.Valid() || verification.Verified != 5 || verification.LastSequence != 5 {
+		t.Errorf("Expected a valid chain of 5 logs, got %+v", verification)
+	}
+}
@@ -0,0 +1,154 @@
+package base_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+func TestFileAuditSink(t *testing.T) {
+	sink := base.NewFileAuditSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	ctx := context.Background()
+
+	// Missing files have no logs
+	if page, err := sink.Query(ctx, nil, 0, ""); err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 0 {
+		t.Errorf("Expected no logs, got %d", len(page.Items))
+	}
+
+	for _, action := range []string{"CREATE", "GET", "UPDATE", "GET"} {
+		if err := sink.Write(ctx, types.AuditLog{Action: action, Resource: map[string]interface{}{"id": "1"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Logs are returned newest first
+	page, err := sink.Query(ctx, map[string][]string{"action__ne": {"GET"}}, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 2 || page.Items[0].Action != "UPDATE" || page.Items[1].Action != "CREATE" {
+		t.Errorf("Unexpected logs %+v", page.Items)
+	}
+
+	// Pages continue from the token
+	page, err = sink.Query(ctx, map[string][]string{"resource.id": {"1"}}, 3, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 3 || page.Next == "" {
+		t.Fatalf("Expected a full page with a token, got %+v", page)
+	}
+
+	page, err = sink.Query(ctx, nil, 3, page.Next)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 1 || page.Items[0].Action != "CREATE" || page.Next != "" {
+		t.Errorf("Unexpected last page %+v", page)
+	}
+
+	// Invalid filters are rejected
+	if _, err := sink.Query(ctx, map[string][]string{"action__bad": {"GET"}}, 0, ""); err == nil {
+		t.Error("Expected an error for an invalid filter")
+	}
+}
+
+func TestMultiAuditSink(t *testing.T) {
+	var buffer bytes.Buffer
+	file := base.NewFileAuditSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	sinks := base.MultiAuditSink{base.NewStreamAuditSink(&buffer), file}
+	ctx := context.Background()
+
+	if err := sinks.Write(ctx, types.AuditLog{Action: "CREATE"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every sink receives the log
+	var auditLog types.AuditLog
+	if err := json.Unmarshal(buffer.Bytes(), &auditLog); err != nil {
+		t.Fatal(err)
+	} else if auditLog.Action != "CREATE" {
+		t.Errorf("Unexpected log %+v", auditLog)
+	}
+
+	// Streams cannot be queried, so the file is read instead
+	if _, err := sinks[0].Query(ctx, nil, 0, ""); !errors.Is(err, base.ErrAuditQueryUnsupported) {
+		t.Errorf("Expected queries to be unsupported, got %v", err)
+	}
+	if page, err := sinks.Query(ctx, nil, 0, ""); err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 1 {
+		t.Errorf("Expected 1 log, got %d", len(page.Items))
+	}
+}
+
+func TestNewAuditSink(t *testing.T) {
+	table := base.NewFileAuditSink(filepath.Join(t.TempDir(), "table.jsonl"))
+	providerSinks := map[string]base.AuditSink{base.AuditSinkTable: table}
+
+	// Audit logging is disabled without an audit table
+	if sink, err := base.NewAuditSink(config.Config{}, providerSinks, nil); err != nil || sink != nil {
+		t.Errorf("Expected no sink, got %v, %v", sink, err)
+	}
+
+	// The audit table is used by default
+	if sink, err := base.NewAuditSink(config.Config{AuditTable: "audit"}, providerSinks, nil); err != nil {
+		t.Fatal(err)
+	} else if sink != table {
+		t.Errorf("Expected the table sink, got %T", sink)
+	}
+
+	// Several sinks fan out
+	sink, err := base.NewAuditSink(config.Config{
+		AuditTable: "audit",
+		AuditSinks: []string{base.AuditSinkStdout, base.AuditSinkTable},
+	}, providerSinks, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if sinks, ok := sink.(base.MultiAuditSink); !ok || len(sinks) != 2 {
+		t.Errorf("Expected 2 sinks, got %+v", sink)
+	}
+
+	for _, cfg := range []config.Config{
+		{AuditSinks: []string{base.AuditSinkTable}},
+		{AuditSinks: []string{base.AuditSinkFile}},
+		{AuditSinks: []string{base.AuditSinkCloudTrail}, CloudTrailChannelARN: "arn"},
+		{AuditSinks: []string{"unknown"}},
+	} {
+		if _, err := base.NewAuditSink(cfg, providerSinks, nil); err == nil {
+			t.Errorf("Expected an error for sinks %v", cfg.AuditSinks)
+		}
+	}
+}
+
+func TestDiffRecords(t *testing.T) {
+	previous := map[string]interface{}{"id": "1", "count": 1, "name": "alpha", "owner": "a"}
+	current := map[string]interface{}{"id": "1", "count": float64(1), "name": "beta", "tags": []string{"x"}}
+
+	changes, err := base.DiffRecords(previous, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Numbers are compared by value, and removed and added fields are changes
+	expected := map[string]types.AuditChange{
+		"name":  {Old: "alpha", New: "beta"},
+		"owner": {Old: "a"},
+		"tags":  {New: []interface{}{"x"}},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected changes %v, got %v", expected, changes)
+	}
+	for key, change := range expected {
+		if fmt.Sprint(changes[key]) != fmt.Sprint(change) {
+			t.Errorf("Expected %s to change %v, got %v", key, change, changes[key])
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package base
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
@@ -13,12 +16,22 @@ import (
 )
 
 const (
-	AuditActionCreate = "CREATE"
-	AuditActionUpdate = "UPDATE"
-	AuditActionList   = "LIST"
-	AuditActionGet    = "GET"
-	AuditActionSearch = "SEARCH"
-	AuditActionDelete = "DELETE"
+	AuditActionCreate  = "CREATE"
+	AuditActionUpdate  = "UPDATE"
+	AuditActionList    = "LIST"
+	AuditActionGet     = "GET"
+	AuditActionSearch  = "SEARCH"
+	AuditActionDelete  = "DELETE"
+	AuditActionRestore = "RESTORE"
+	AuditActionPurge   = "PURGE"
+)
+
+const (
+	// PatchOperationAdd : Add a number to a numeric field, e.g. {"count__add": 1}
+	PatchOperationAdd = "add"
+
+	// PatchOperationAppend : Append a value, or a list of values, to a list field, e.g. {"tags__append": ["a", "b"]}
+	PatchOperationAppend = "append"
 )
 
 // ScoutrBase : Low level interface that defines all the functions used by a Scoutr provider. Some of these would be
@@ -26,23 +39,59 @@ const (
 type ScoutrBase interface {
 	ScoutrProvider
 	GetConfig() config.Config
+	KeyFields() []string
+	ItemKey(values ...string) (types.Key, error)
 	CanAccessEndpoint(string, string, *types.User, *types.Request) bool
+
+	// FetchItem : Fetch an item of the data table by its full key, without checking the user's permissions.
+	// Returns nil if the item does not exist.
+	FetchItem(ctx context.Context, key types.Key) (types.Record, error)
+
+	// FindItems : Find the items of the data table with one of the values in a field, without checking the user's
+	// permissions or writing an audit log
+	FindItems(ctx context.Context, key string, values []string) ([]types.Record, error)
 }
 
 type ScoutrProvider interface {
-	GetEntitlements([]string) ([]types.User, error)
-	GetAuth(string) (*types.User, error)
-	GetGroup(string) (*types.Group, error)
-	Create(request types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string) error
-	Update(request types.Request, partitionKey map[string]interface{}, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error)
-	Patch(request types.Request, partitionKey map[string]interface{}, item map[string]interface{}, validation map[string]types.FieldValidation, auditAction string) (interface{}, error)
-	Get(request types.Request, id string) (types.Record, error)
+	GetEntitlements(context.Context, []string) ([]types.User, error)
+	GetAuth(context.Context, string) (*types.User, error)
+	GetGroup(context.Context, string) (*types.Group, error)
+	Create(request types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) error
+	Update(request types.Request, key types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error)
+	Patch(request types.Request, key types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, auditAction string) (interface{}, error)
+	Get(request types.Request, key types.Key) (types.Record, error)
 	List(request types.Request) ([]types.Record, error)
 	ListUniqueValues(request types.Request, uniqueKey string) ([]string, error)
 	ListAuditLogs(request types.Request, pathParams map[string]string, queryParams map[string][]string) ([]types.AuditLog, error)
-	History(request types.Request, key string, value string, queryParams map[string][]string, actions []string) ([]types.History, error)
+	History(request types.Request, key types.Key, queryParams map[string][]string, actions []string) ([]types.History, error)
+	HistoryAt(request types.Request, key types.Key, at time.Time) (types.Record, error)
+	Restore(request types.Request, key types.Key, point types.HistoryPoint, validation map[string]types.FieldValidation) (interface{}, error)
 	Search(request types.Request, key string, values []string) ([]types.Record, error)
-	Delete(request types.Request, partitionKey map[string]interface{}) error
+	Delete(request types.Request, key types.Key) error
+
+	// Paginated variants of List, Search and ListAuditLogs. The page size and continuation token are
+	// taken from the Limit and Next fields of the request.
+	ListPage(request types.Request) (types.Page[types.Record], error)
+	SearchPage(request types.Request, key string, values []string) (types.Page[types.Record], error)
+	ListAuditLogsPage(request types.Request, pathParams map[string]string, queryParams map[string][]string) (types.Page[types.AuditLog], error)
+
+	// VerifyAuditLogs : Check that the chain of signed audit logs has not been tampered with
+	VerifyAuditLogs(request types.Request) (AuditVerification, error)
+
+	// Trash operations, available when soft delete is enabled
+	ListTrash(request types.Request) ([]types.Record, error)
+	ListTrashPage(request types.Request) (types.Page[types.Record], error)
+	RestoreFromTrash(request types.Request, key types.Key) (interface{}, error)
+	PurgeTrash(request types.Request) (int, error)
+
+	// Batch operations, which authenticate the user once and report the result of each item
+	BatchCreate(request types.Request, items []map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) ([]types.BatchResult, error)
+	BatchUpdate(request types.Request, updates []types.BatchUpdate, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) ([]types.BatchResult, error)
+	BatchDelete(request types.Request, keys []types.Key) ([]types.BatchResult, error)
+
+	// Transaction : Apply a set of operations atomically, returning the new state of each item. Nothing is written
+	// if any operation fails.
+	Transaction(request types.Request, operations []types.TransactionOperation, validation map[string]types.FieldValidation) ([]types.Record, error)
 }
 
 // Scoutr : Base struct that implements ScoutrBase and sets up some commonly used functions across
@@ -51,6 +100,10 @@ type Scoutr struct {
 	ScoutrBase
 	Filtering FilterBase
 	Config    config.Config
+
+	// AuditSink : Destination of audit logs. Providers select it from the config, and audit logging is
+	// disabled when it is nil.
+	AuditSink AuditSink
 }
 
 // GetConfig : Return config
@@ -58,6 +111,39 @@ func (api *Scoutr) GetConfig() config.Config {
 	return api.Config
 }
 
+// KeyFields : Attributes that make up the key of the data table, starting with the partition key
+func (api *Scoutr) KeyFields() []string {
+	if api.Config.SortKey == "" {
+		return []string{api.Config.PrimaryKey}
+	}
+
+	return []string{api.Config.PrimaryKey, api.Config.SortKey}
+}
+
+// ItemKey : Build the key of an item from the values of its key attributes, in the order returned by KeyFields.
+// Trailing key attributes may be omitted.
+func (api *Scoutr) ItemKey(values ...string) (types.Key, error) {
+	// Providers may learn the key of the table from the database
+	fields := api.KeyFields()
+	if api.ScoutrBase != nil {
+		fields = api.ScoutrBase.KeyFields()
+	}
+
+	key := make(types.Key)
+	for i, value := range values {
+		if value == "" {
+			continue
+		} else if i >= len(fields) {
+			return nil, &types.BadRequest{
+				Message: fmt.Sprintf("Expected at most %d key fields: %v", len(fields), fields),
+			}
+		}
+		key[fields[i]] = value
+	}
+
+	return key, nil
+}
+
 // UserIdentifier : Generate a user identifier for logs
 func (api *Scoutr) UserIdentifier(user *types.User) string {
 	return fmt.Sprintf("%s: %s (%s - %s)", user.ID, user.Name, user.Username, user.Email)
@@ -68,7 +154,7 @@ func (api *Scoutr) CanAccessEndpoint(method string, path string, user *types.Use
 	var err error
 	if request != nil {
 		// Fetch the user
-		user, err = api.GetUser(request.User.ID, request.User.Data)
+		user, err = api.GetUser(request.Context(), request.User.ID, request.User.Data)
 		if err != nil {
 			logrus.WithError(err).Error("Failed to fetch user")
 			return false
@@ -166,7 +252,14 @@ func (api *Scoutr) ValidateRequest(req types.Request, user *types.User) error {
 	}
 }
 
+// ValidateFields : Check a new item for required fields, and run the field validators and the schema of the data
+// table against it. Every failed validation is returned in the messages of a bad request.
 func (api *Scoutr) ValidateFields(validation map[string]types.FieldValidation, requiredFields []string, item map[string]interface{}, existingItem map[string]interface{}) error {
+	return api.validateFields(context.Background(), validation, requiredFields, item, existingItem, false)
+}
+
+// validateFields : Validate an item, which only holds the fields being changed when partial is set
+func (api *Scoutr) validateFields(ctx context.Context, validation map[string]types.FieldValidation, requiredFields []string, item map[string]interface{}, existingItem map[string]interface{}, partial bool) error {
 	// Check for required fields
 	if len(requiredFields) > 0 {
 		var missingKeys []string
@@ -182,11 +275,23 @@ func (api *Scoutr) ValidateFields(validation map[string]types.FieldValidation, r
 		}
 	}
 
+	// Check the item against the schema. Field validators run alongside it, and their messages take precedence.
+	errors, err := api.ValidateSchema(item, partial)
+	if err != nil {
+		return err
+	}
+
 	// Create channels and wait group
 	wg := &sync.WaitGroup{}
 	ch := make(chan types.ValidationOutput, len(validation))
 	done := make(chan bool, 1)
 
+	// Validators such as utils.Unique look up other items through the provider
+	var findItems func(context.Context, string, []string) ([]types.Record, error)
+	if api.ScoutrBase != nil {
+		findItems = api.ScoutrBase.FindItems
+	}
+
 	// Trigger validation goroutines
 	for key, fn := range validation {
 		if _, ok := item[key]; ok {
@@ -195,6 +300,8 @@ func (api *Scoutr) ValidateFields(validation map[string]types.FieldValidation, r
 				Value:        item[key],
 				Item:         item,
 				ExistingItem: existingItem,
+				Context:      ctx,
+				FindItems:    findItems,
 			}
 
 			// Increment wait group and start goroutine
@@ -209,9 +316,6 @@ func (api *Scoutr) ValidateFields(validation map[string]types.FieldValidation, r
 		ch <- true
 	}(done)
 
-	// Create result object
-	errors := make(map[string]string)
-
 	// Receive results
 	for {
 		select {
@@ -299,8 +403,13 @@ func (api *Scoutr) BuildParams(req types.Request) map[string][]string {
 // InitializeRequest : Given a request, get the corresponding user and perform
 // user and request validation.
 func (api Scoutr) InitializeRequest(req types.Request) (*types.User, error) {
+	// Users of a batch were already validated
+	if user, ok := preparedUser(req); ok {
+		return user, nil
+	}
+
 	// Get user
-	user, err := api.GetUser(req.User.ID, req.User.Data)
+	user, err := api.GetUser(req.Context(), req.User.ID, req.User.Data)
 	if err != nil {
 		if api.Config.ErrorFunc != nil {
 			api.Config.ErrorFunc(&req, user, err)
@@ -321,6 +430,11 @@ func (api Scoutr) InitializeRequest(req types.Request) (*types.User, error) {
 		return nil, err
 	}
 
+	// Hide the items in the trash, unless the request operates on the trash
+	if api.Config.SoftDelete {
+		filterTrash(user, InTrash(req))
+	}
+
 	return user, nil
 }
 
@@ -347,20 +461,18 @@ func (api Scoutr) PrepareCreate(request types.Request, data map[string]interface
 	}
 
 	// Run validation
-	err = api.ValidateFields(validation, requiredFields, data, nil)
+	err = api.validateFields(request.Context(), validation, requiredFields, data, nil, false)
 	if err != nil {
 		return nil, err
 	}
 
 	// Creation filters
-	localFilter := LocalFiltering{
-		data: data,
-	}
+	localFilter := NewLocalFilter(data)
 	results, err := localFilter.Filter(user, nil, FilterActionCreate)
 	if err != nil {
 		return nil, err
 	}
-	if results == false {
+	if !localFilter.Matches(results) {
 		return nil, &types.Unauthorized{
 			Message: fmt.Sprintf("Unauthorized value(s) for field(s): %+v", localFilter.failedFilters),
 		}
@@ -369,8 +481,235 @@ func (api Scoutr) PrepareCreate(request types.Request, data map[string]interface
 	return user, nil
 }
 
+// PrepareUpdate : Initialize an update request. Makes sure the user is permitted to update every field of the item,
+// other than the key attributes, and runs validation. Fields with an operator suffix, such as "count__add", are
+// rejected since they would be written under their literal name.
+func (api Scoutr) PrepareUpdate(request types.Request, key types.Key, data map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string) (*types.User, error) {
+	return api.prepareUpdate(request, key, data, validation, requiredFields, false)
+}
+
+// PreparePatch : Initialize a patch for a provider that supports operator suffixes, such as "count__add". Fields are
+// checked against the user's permissions, the validators and the schema by the name of the field they change, with
+// the value the field will end up with.
+func (api Scoutr) PreparePatch(request types.Request, key types.Key, data map[string]interface{}, validation map[string]types.FieldValidation) (*types.User, error) {
+	return api.prepareUpdate(request, key, data, validation, nil, true)
+}
+
+// prepareUpdate : Initialize an update or patch request, allowing operator suffixes if operators is true
+func (api Scoutr) prepareUpdate(request types.Request, key types.Key, data map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, operators bool) (*types.User, error) {
+	// Get user
+	user, err := api.InitializeRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields with an operator would be checked as the field they change, but written under their literal name
+	if !operators {
+		invalid := make(map[string]string)
+		for name := range data {
+			if _, operator := SplitFilterKey(name); operator != OperationEqual {
+				invalid[name] = "Operators are not supported by this update"
+			}
+		}
+		if len(invalid) > 0 {
+			var fields []string
+			for field := range invalid {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+
+			return nil, &types.BadRequest{
+				Message:  fmt.Sprintf("Fields with operators cannot be updated: %v", fields),
+				Messages: invalid,
+			}
+		}
+	}
+
+	// Versions are moved on by every write, so they cannot be changed directly
+	if _, ok := data[api.Config.VersionField]; ok && api.Versioned() {
+		return nil, &types.BadRequest{
+			Message: fmt.Sprintf("Field %s cannot be updated", api.Config.VersionField),
+		}
+	}
+
+	// Make sure the user has permission to update all the fields specified
+	if invalid := updateFieldErrors(user, key, data); len(invalid) > 0 {
+		var fields []string
+		for field := range invalid {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		logrus.Warnf("[%s] Not authorized to update fields %v", api.UserIdentifier(user), fields)
+		return nil, &types.BadRequest{
+			Message:  fmt.Sprintf("Not authorized to update item with fields %v", fields),
+			Messages: invalid,
+		}
+	}
+
+	// Fields with an operator are validated by the value they will end up with, which depends on the current item
+	hasOperators := false
+	for name := range data {
+		if _, operator := SplitFilterKey(name); operator != OperationEqual {
+			hasOperators = true
+		}
+	}
+
+	// Fetch the item, so validators can compare the update with its current state
+	var existing types.Record
+	if len(validation) > 0 || (hasOperators && api.Config.Schema != nil) {
+		existing, err = api.existingItem(request, user, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fields := data
+	if hasOperators && existing != nil {
+		if fields, err = resolvePatch(data, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	// Run validation
+	if validation != nil || len(requiredFields) > 0 || api.Config.Schema != nil {
+		logrus.Infoln("Running field validation")
+		if err := api.validateFields(request.Context(), validation, requiredFields, fields, existing, true); err != nil {
+			logrus.Errorln("Field validation error", err)
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// existingItem : Fetch the item an update applies to. Items the user is not permitted to read or update are treated
+// as though they do not exist, so the update fails with NotFound before the item reaches any validator. Providers
+// still check the update filters as part of the write, in case the item changes in the meantime.
+func (api Scoutr) existingItem(request types.Request, user *types.User, key types.Key) (types.Record, error) {
+	notFound := &types.NotFound{
+		Message: "Item does not exist or you do not have permission to update it",
+	}
+
+	record, err := api.ScoutrBase.FetchItem(request.Context(), key)
+	if err != nil {
+		logrus.Errorln("Error while attempting to fetch item", err)
+		return nil, err
+	} else if record == nil {
+		return nil, notFound
+	}
+
+	f := NewLocalFilter(record)
+	for _, action := range []string{FilterActionRead, FilterActionUpdate} {
+		conditions, err := f.Filter(user, nil, action)
+		if err != nil {
+			logrus.Errorln("Error encountered during filtering", err)
+			return nil, err
+		} else if !f.Matches(conditions) {
+			return nil, notFound
+		}
+	}
+
+	return record, nil
+}
+
+// resolvePatch : Fields of a patch by the value they will end up with, applying any operators to the current item.
+// Fields that do not exist yet are added to as zero or an empty list, as the providers do.
+func resolvePatch(data map[string]interface{}, existing types.Record) (map[string]interface{}, error) {
+	fields := make(map[string]interface{}, len(data))
+	for name, value := range data {
+		field, operator := SplitFilterKey(name)
+		switch operator {
+		case OperationEqual:
+			fields[field] = value
+
+		case PatchOperationAdd:
+			delta, ok := patchNumber(value)
+			if !ok {
+				return nil, &types.BadRequest{
+					Message: fmt.Sprintf("Value of %s must be a number", name),
+				}
+			}
+
+			var current float64
+			if existing[field] != nil {
+				current, ok = patchNumber(existing[field])
+				if !ok {
+					return nil, &types.BadRequest{
+						Message: fmt.Sprintf("Field %s is not a number", field),
+					}
+				}
+			}
+			fields[field] = current + delta
+
+		case PatchOperationAppend:
+			values, ok := value.([]interface{})
+			if !ok {
+				values = []interface{}{value}
+			}
+
+			var current []interface{}
+			if existing[field] != nil {
+				current, ok = toList(existing[field])
+				if !ok {
+					return nil, &types.BadRequest{
+						Message: fmt.Sprintf("Field %s is not a list", field),
+					}
+				}
+			}
+			fields[field] = append(append([]interface{}{}, current...), values...)
+
+		default:
+			// Unsupported operators are rejected by the provider
+			fields[name] = value
+		}
+	}
+
+	return fields, nil
+}
+
+// patchNumber : Convert a field or operand of a patch to a number. Strings are not numbers, even if they hold one.
+func patchNumber(value interface{}) (float64, bool) {
+	if _, ok := value.(string); ok {
+		return 0, false
+	}
+
+	return toFloat(value)
+}
+
+// updateFieldErrors : Find the fields of an update that the user is not permitted to change, along with the reason.
+// Key attributes are skipped since they identify the item rather than update it.
+func updateFieldErrors(user *types.User, key types.Key, data map[string]interface{}) map[string]string {
+	contains := func(values []string, value string) bool {
+		for _, item := range values {
+			if item == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	invalid := make(map[string]string)
+	for name := range data {
+		field, _ := SplitFilterKey(name)
+		if _, isKey := key[field]; isKey {
+			continue
+		}
+
+		if contains(user.ExcludeFields, field) {
+			invalid[field] = "Field is excluded"
+		} else if contains(user.UpdateFieldsRestricted, field) {
+			invalid[field] = "Field is restricted"
+		} else if len(user.UpdateFieldsPermitted) > 0 && !contains(user.UpdateFieldsPermitted, field) {
+			invalid[field] = "Field is not permitted"
+		}
+	}
+
+	return invalid
+}
+
 // GetUser : Fetch a user from the backend, merging any permissions from group memberships
-func (api Scoutr) GetUser(id string, userData *types.UserData) (*types.User, error) {
+func (api Scoutr) GetUser(ctx context.Context, id string, userData *types.UserData) (*types.User, error) {
 	isUser := true
 	user := types.User{ID: id}
 
@@ -382,7 +721,7 @@ func (api Scoutr) GetUser(id string, userData *types.UserData) (*types.User, err
 	}
 
 	// Try to find user in the auth table
-	if auth, err := api.ScoutrBase.GetAuth(id); err != nil {
+	if auth, err := api.ScoutrBase.GetAuth(ctx, id); err != nil {
 		// Error while fetching user
 		logrus.WithError(err).Errorf("Failed to get user %s", id)
 
@@ -402,7 +741,7 @@ func (api Scoutr) GetUser(id string, userData *types.UserData) (*types.User, err
 	// Try to find supplied entitlements in the auth table
 	var entitlementIDs []string
 	if userData != nil && len(userData.Entitlements) > 0 {
-		entitlements, err := api.ScoutrBase.GetEntitlements(userData.Entitlements)
+		entitlements, err := api.ScoutrBase.GetEntitlements(ctx, userData.Entitlements)
 		if err != nil {
 			return nil, err
 		}
@@ -434,7 +773,7 @@ func (api Scoutr) GetUser(id string, userData *types.UserData) (*types.User, err
 
 	// If the user is a member of a group, merge in the group's permissions
 	for _, groupID := range user.Groups {
-		group, err := api.ScoutrBase.GetGroup(groupID)
+		group, err := api.ScoutrBase.GetGroup(ctx, groupID)
 		if err != nil {
 			logrus.WithError(err).Error("Error while fetching group")
 			return nil, err
@@ -0,0 +1,133 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// batchKey : Context key holding the user of a batch
+type batchKey struct{}
+
+// batchUser : User of a batch, along with whether it was prepared for the trash
+type batchUser struct {
+	user  *types.User
+	trash bool
+}
+
+// PrepareBatch : Authenticate the user of a batch. Operations made with the returned request reuse the user rather
+// than fetching and validating it again for every item.
+func (api Scoutr) PrepareBatch(req types.Request) (types.Request, error) {
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		return req, err
+	}
+
+	return req.WithContext(context.WithValue(req.Context(), batchKey{}, batchUser{user: user, trash: InTrash(req)})), nil
+}
+
+// preparedUser : User of the batch a request belongs to, if any. The user is copied so its filters can be changed
+// without affecting the rest of the batch.
+func preparedUser(req types.Request) (*types.User, bool) {
+	prepared, ok := req.Context().Value(batchKey{}).(batchUser)
+	if !ok || prepared.trash != InTrash(req) {
+		return nil, false
+	}
+
+	user := *prepared.user
+	return &user, true
+}
+
+// BatchCreate : Create several items, authenticating the user once. Each item is validated, checked against the
+// creation filters and audited on its own, with only that item as the body of its audit log. The result of each is
+// returned in the same order. Only errors that stop the whole batch, such as a bad user, are returned as an error.
+func (api Scoutr) BatchCreate(req types.Request, items []map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) ([]types.BatchResult, error) {
+	req, err := api.PrepareBatch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]types.BatchResult, len(items))
+	for i, item := range items {
+		err := api.ScoutrBase.Create(req.WithBody(item), item, validation, requiredFields, auditAction)
+		results[i] = types.NewBatchResult(api.recordKey(item), types.BatchStatusCreated, nil, err)
+	}
+
+	return results, nil
+}
+
+// BatchUpdate : Update several items, authenticating the user once. Each update is checked against the update
+// filters and audited on its own, and the result of each is returned in the same order, along with the updated item.
+func (api Scoutr) BatchUpdate(req types.Request, updates []types.BatchUpdate, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) ([]types.BatchResult, error) {
+	req, err := api.PrepareBatch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]types.BatchResult, len(updates))
+	for i, update := range updates {
+		output, err := api.ScoutrBase.Update(req.WithBody(update.Item), update.Key, update.Item, validation, requiredFields, auditAction)
+		results[i] = types.NewBatchResult(update.Key, types.BatchStatusUpdated, output, err)
+	}
+
+	return results, nil
+}
+
+// BatchDelete : Delete several items, authenticating the user once. Each item is checked against the delete filters
+// and audited on its own, and the result of each is returned in the same order.
+func (api Scoutr) BatchDelete(req types.Request, keys []types.Key) ([]types.BatchResult, error) {
+	req, err := api.PrepareBatch(req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]types.BatchResult, len(keys))
+	for i, key := range keys {
+		err := api.ScoutrBase.Delete(req.WithBody(nil), key)
+		results[i] = types.NewBatchResult(key, types.BatchStatusDeleted, nil, err)
+	}
+
+	return results, nil
+}
+
+// KeyNormalizer : Implemented by providers whose key attributes have types, to convert the values of a key to those
+// types. Values that refer to the same item, such as 1 and "1" for a numeric key, are converted to the same value.
+type KeyNormalizer interface {
+	NormalizeKey(ctx context.Context, key types.Key) (types.Key, error)
+}
+
+// ItemID : Identify the item a key refers to, so keys of the same item can be compared. Values are compared by type
+// as well, once converted by providers that implement KeyNormalizer. Other providers store items by the string form
+// of their key, so values are compared as strings.
+func (api Scoutr) ItemID(ctx context.Context, key types.Key) (string, error) {
+	normalizer, ok := api.ScoutrBase.(KeyNormalizer)
+	if !ok {
+		return fmt.Sprint(key), nil
+	}
+
+	normalized, err := normalizer.NormalizeKey(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	var values []string
+	for _, field := range api.ScoutrBase.KeyFields() {
+		values = append(values, fmt.Sprintf("%T:%#v", normalized[field], normalized[field]))
+	}
+
+	return strings.Join(values, "#"), nil
+}
+
+// recordKey : Key of a record, made up of whichever key attributes it has
+func (api Scoutr) recordKey(record map[string]interface{}) types.Key {
+	key := make(types.Key)
+	for _, field := range api.ScoutrBase.KeyFields() {
+		if value, ok := record[field]; ok {
+			key[field] = value
+		}
+	}
+
+	return key
+}
@@ -0,0 +1,27 @@
+package base
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// ContextError : Convert an error returned by a backend call into a timeout error when the call ran past the
+// deadline of its context. Other errors are returned unchanged.
+func ContextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var timeout *types.Timeout
+	if errors.As(err, &timeout) {
+		return err
+	} else if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &types.Timeout{
+			Message: "Request timed out",
+		}
+	}
+
+	return err
+}
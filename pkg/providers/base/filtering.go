@@ -168,6 +168,12 @@ func (f *Filtering) userFilters(filterFields []types.FilterField) (interface{},
 }
 
 func (f *Filtering) getOperator(key string) (string, string) {
+	return SplitFilterKey(key)
+}
+
+// SplitFilterKey : Split a filter key such as "name__startswith" into the field name and the operator. Keys
+// without an operator use the equals operator.
+func SplitFilterKey(key string) (string, string) {
 	// Check if this is a operator
 	operation := OperationEqual
 	matches := regexp.MustCompile("^(.+)__(.+)$").FindAllStringSubmatch(key, -1)
@@ -0,0 +1,430 @@
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// historyNotFound : Error returned for items the user is not permitted to view
+const historyNotFound = "Item does not exist or you do not have permission to view it"
+
+// History : List the changes made to an item, newest first. Each entry has the user and action that made the
+// change, the fields that changed and the state of the item afterwards. Only the given actions are listed, or every
+// change if there are none. Read actions are never listed.
+func (api Scoutr) History(req types.Request, key types.Key, queryParams map[string][]string, actions []string) ([]types.History, error) {
+	user, history, _, err := api.replayHistory(req, key, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create audit log
+	if err := api.WriteAuditLog(AuditActionGet, req, user, key, nil); err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		listed[action] = true
+	}
+
+	output := []types.History{}
+	for i := len(history) - 1; i >= 0; i-- {
+		if len(listed) > 0 && !listed[history[i].Action] {
+			continue
+		}
+
+		output = append(output, history[i])
+	}
+
+	api.excludeHistoryFields(output, user)
+
+	return output, nil
+}
+
+// HistoryAt : Get the state of an item at a point in time
+func (api Scoutr) HistoryAt(req types.Request, key types.Key, at time.Time) (types.Record, error) {
+	user, history, initial, err := api.replayHistory(req, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := historyState(history, initial, types.HistoryPoint{Time: at})
+	if err != nil {
+		return nil, err
+	}
+
+	// The item as it was must also be visible to the user
+	if err := api.readable(user, record); err != nil {
+		return nil, err
+	}
+
+	api.PostProcess([]types.Record{record}, user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(AuditActionGet, req, user, key, nil); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// Restore : Roll an item back to an earlier point in its history. The item is patched back to its earlier state,
+// so update filters, validation and field restrictions apply. Deleted items are taken out of the trash, or created
+// again if they were removed. Either way, the change is audited as a restore.
+func (api Scoutr) Restore(req types.Request, key types.Key, point types.HistoryPoint, validation map[string]types.FieldValidation) (interface{}, error) {
+	_, history, initial, err := api.replayHistory(req, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := historyState(history, initial, point)
+	if err != nil {
+		return nil, err
+	}
+
+	current := initial
+	if len(history) > 0 {
+		current = history[len(history)-1].Data
+	}
+
+	// Deleted items are taken out of the trash, or created again if they were removed. The item in the trash is
+	// compared as it is stored, since the fields the user cannot see are restored as they are.
+	if current == nil && api.Config.SoftDelete {
+		trashed, err := api.ScoutrBase.FetchItem(req.Context(), key)
+		if err != nil {
+			return nil, err
+		}
+		if trashed != nil && trashed[DeletedAtField] != nil {
+			req = WithTrash(req)
+			user, err := api.InitializeRequest(req)
+			if err != nil {
+				return nil, err
+			} else if err := api.readable(user, trashed); err != nil {
+				return nil, err
+			}
+			current = trashed
+		}
+	}
+	if current == nil {
+		record = api.InitialVersion(record)
+		if err := api.ScoutrBase.Create(req, record, validation, nil, AuditActionRestore); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+
+	// Only the fields that changed since then are patched, so field restrictions only apply to those. Fields added
+	// since then are removed. Key fields cannot be changed, and the version moves on rather than back.
+	changes, err := DiffRecords(current, record)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range api.ScoutrBase.KeyFields() {
+		delete(changes, field)
+	}
+	if api.Versioned() {
+		delete(changes, api.Config.VersionField)
+	}
+	if len(changes) == 0 {
+		return nil, &types.BadRequest{
+			Message: "Item is already in the requested state",
+		}
+	}
+
+	item := make(map[string]interface{}, len(changes))
+	for field, change := range changes {
+		item[field] = change.New
+	}
+
+	return api.ScoutrBase.Patch(req, key, item, validation, AuditActionRestore)
+}
+
+// historyState : Find the state of an item at a point in its history. At a point in time, this is the state after
+// the last change made at or before it. Items that existed before auditing was enabled are in their previous state
+// until the first change.
+func historyState(history []types.History, initial types.Record, point types.HistoryPoint) (types.Record, error) {
+	if point.Version > 0 {
+		if point.Version > len(history) {
+			return nil, &types.NotFound{
+				Message: fmt.Sprintf("Version %d does not exist", point.Version),
+			}
+		}
+
+		record := history[point.Version-1].Data
+		if record == nil {
+			return nil, &types.NotFound{
+				Message: fmt.Sprintf("Item was deleted in version %d", point.Version),
+			}
+		}
+
+		return copyRecord(record), nil
+	}
+
+	record := initial
+	for _, item := range history {
+		if changed, ok := parseAuditTime(item.Time); ok && changed.After(point.Time) {
+			break
+		}
+		record = item.Data
+	}
+
+	if record == nil {
+		return nil, &types.NotFound{
+			Message: fmt.Sprintf("Item did not exist at %s", point.Time.UTC().Format(time.RFC3339Nano)),
+		}
+	}
+
+	return copyRecord(record), nil
+}
+
+// replayHistory : Rebuild each change made to an item from its audit logs, oldest first. Also returns the state of
+// the item before the first change, which is only known for items that existed before auditing was enabled. The
+// newest state of the item must satisfy the user's read filters, or the item is treated as though it does not exist.
+func (api Scoutr) replayHistory(req types.Request, key types.Key, queryParams map[string][]string) (*types.User, []types.History, types.Record, error) {
+	// Only fetch audit logs if a sink is configured
+	if api.AuditSink == nil {
+		return nil, nil, nil, &types.NotFound{
+			Message: "Audit logs are not enabled",
+		}
+	}
+
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return nil, nil, nil, err
+	}
+
+	// Audit logs of the item are found by every attribute of its key
+	if partitionKey := api.ScoutrBase.KeyFields()[0]; key[partitionKey] == nil {
+		return nil, nil, nil, &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: [%s]", partitionKey),
+		}
+	}
+
+	// Get the audit logs. Every field is needed to rebuild the item, so fields are only excluded from the output
+	_, page, err := api.listAuditLogs(req, key.AuditParams(), queryParams, false)
+	if err != nil {
+		log.Errorln("Error listing audit logs", err)
+		return nil, nil, nil, err
+	}
+	data := page.Items
+
+	// Audit logs are listed newest first. Reverse them before sorting by time, since entries written in quick
+	// succession can share a timestamp
+	for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+		data[i], data[j] = data[j], data[i]
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		first, ok := parseAuditTime(data[i].Time)
+		second, ok2 := parseAuditTime(data[j].Time)
+
+		// Logs with a time that cannot be parsed go first, in the order they were listed
+		if !ok || !ok2 {
+			return !ok && ok2
+		}
+		return first.Before(second)
+	})
+
+	history := []types.History{}
+	var initial, current types.Record
+	for _, auditLog := range data {
+		if auditLog.Action == AuditActionGet || auditLog.Action == AuditActionList || auditLog.Action == AuditActionSearch {
+			continue
+		}
+
+		// Logs of updates and deletes hold the state of the item before the change
+		if auditLog.Previous != nil {
+			if len(history) == 0 {
+				initial = copyRecord(auditLog.Previous)
+			}
+			current = copyRecord(auditLog.Previous)
+		}
+
+		next, changes, err := applyAuditLog(current, auditLog)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		history = append(history, types.History{
+			Version: len(history) + 1,
+			Time:    auditLog.Time,
+			Action:  auditLog.Action,
+			User:    auditLog.User,
+			Changes: changes,
+			Data:    copyRecord(next),
+		})
+		current = next
+	}
+
+	// Deleted items are checked as they were before they were deleted
+	latest := initial
+	for _, item := range history {
+		if item.Data != nil {
+			latest = item.Data
+		}
+	}
+	if latest != nil {
+		if err := api.readable(user, latest); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return user, history, initial, nil
+}
+
+// readable : Check that a record satisfies the user's read filters. Records the user is not permitted to view are
+// treated as though they do not exist.
+func (api Scoutr) readable(user *types.User, record types.Record) error {
+	f := NewLocalFilter(record)
+	conditions, err := f.Filter(user, nil, FilterActionRead)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return err
+	} else if !f.Matches(conditions) {
+		return &types.NotFound{Message: historyNotFound}
+	}
+
+	return nil
+}
+
+// applyAuditLog : Apply the change recorded in an audit log to an item, returning the new state and the fields
+// that changed. Logs written before changes were recorded fall back to the request body.
+func applyAuditLog(current types.Record, auditLog types.AuditLog) (types.Record, map[string]types.AuditChange, error) {
+	var next types.Record
+
+	switch {
+	case auditLog.Action == AuditActionDelete || auditLog.Action == AuditActionPurge:
+		next = nil
+	case auditLog.Previous != nil || auditLog.Changes != nil:
+		next = copyRecord(current)
+		if next == nil {
+			next = types.Record{}
+		}
+		for field, change := range auditLog.Changes {
+			if change.New == nil {
+				delete(next, field)
+			} else {
+				next[field] = change.New
+			}
+		}
+	default:
+		body, ok := auditBody(auditLog.Body)
+		if !ok {
+			// The body is not a record, such as a list of patch operations, so the change is unknown
+			return copyRecord(current), nil, nil
+		}
+
+		// Values are compared with the JSON values of recorded changes, so bodies are converted to match
+		body, err := normalize(body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if auditLog.Action == AuditActionCreate || current == nil {
+			next = types.Record{}
+		} else {
+			next = copyRecord(current)
+		}
+		for field, value := range body {
+			if value == nil {
+				delete(next, field)
+			} else {
+				next[field] = value
+			}
+		}
+	}
+
+	if auditLog.Changes != nil {
+		return next, auditLog.Changes, nil
+	}
+
+	changes, err := DiffRecords(current, next)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return next, changes, nil
+}
+
+// auditBody : Convert the body of an audit log into a record. Bodies of API Gateway requests are raw JSON strings.
+func auditBody(body interface{}) (map[string]interface{}, bool) {
+	var data []byte
+	switch value := body.(type) {
+	case nil:
+		return nil, false
+	case types.Record:
+		return value, true
+	case map[string]interface{}:
+		return value, true
+	case string:
+		data = []byte(value)
+	case []byte:
+		data = value
+	default:
+		// Documents decoded by database drivers may use their own map types
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return nil, false
+		}
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil || record == nil {
+		return nil, false
+	}
+
+	return record, true
+}
+
+// excludeHistoryFields : Remove the fields the user cannot see from history entries. Changes are copied first,
+// since they may be shared with the audit logs of the sink.
+func (api Scoutr) excludeHistoryFields(history []types.History, user *types.User) {
+	if len(user.ExcludeFields) == 0 {
+		return
+	}
+
+	for i, item := range history {
+		if item.Data != nil {
+			api.PostProcess([]types.Record{item.Data}, user)
+		}
+
+		changes := make(map[string]types.AuditChange, len(item.Changes))
+		for field, change := range item.Changes {
+			changes[field] = change
+		}
+		for _, field := range user.ExcludeFields {
+			delete(changes, field)
+		}
+		history[i].Changes = changes
+	}
+}
+
+// copyRecord : Make a shallow copy of a record
+func copyRecord(record map[string]interface{}) types.Record {
+	if record == nil {
+		return nil
+	}
+
+	output := make(types.Record, len(record))
+	for key, value := range record {
+		output[key] = value
+	}
+
+	return output
+}
+
+// parseAuditTime : Parse the time of an audit log
+func parseAuditTime(value string) (time.Time, bool) {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return parsed, true
+}
@@ -3,18 +3,58 @@ package base
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
 )
 
+// LocalFiltering : Evaluates filters in memory against a single record. Conditions are plain booleans.
 type LocalFiltering struct {
 	Filtering
 	data          map[string]interface{}
 	failedFilters []string
 }
 
+// NewLocalFilter : Create a filter that evaluates conditions against the supplied record
+func NewLocalFilter(data map[string]interface{}) *LocalFiltering {
+	f := &LocalFiltering{data: data}
+	f.FilterBase = f
+	f.ScoutrFilters = f
+	return f
+}
+
+// FailedFilters : Fields whose user filters did not match the record
+func (f *LocalFiltering) FailedFilters() []string {
+	return f.failedFilters
+}
+
+// Operations : Map of supported operations for this filter provider
+func (f *LocalFiltering) Operations() OperationMap {
+	return OperationMap{
+		OperationStartsWith:       f.StartsWith,
+		OperationEqual:            f.Equals,
+		OperationNotEqual:         f.NotEqual,
+		OperationContains:         f.Contains,
+		OperationNotContains:      f.NotContains,
+		OperationExists:           f.Exists,
+		OperationGreaterThan:      f.GreaterThan,
+		OperationLessThan:         f.LessThan,
+		OperationGreaterThanEqual: f.GreaterThanEqual,
+		OperationLessThanEqual:    f.LessThanEqual,
+		OperationBetween:          f.Between,
+		OperationIn:               f.In,
+		OperationNotIn:            f.NotIn,
+	}
+}
+
+// Matches : Determine if the record matches the conditions returned by Filter
+func (f *LocalFiltering) Matches(conditions interface{}) bool {
+	result, ok := conditions.(bool)
+	return conditions == nil || (ok && result)
+}
+
 func (f *LocalFiltering) userFilters(filterFields []types.FilterField) (interface{}, error) {
 	// Merge all possible values for this filter key together
 	filters := make(map[string][]types.FilterField)
@@ -29,14 +69,15 @@ func (f *LocalFiltering) userFilters(filterFields []types.FilterField) (interfac
 		if len(filterItems) == 1 {
 			// Perform a single query
 			item := filterItems[0]
-			existingConditions := conditions
-			conditions, err = f.performFilter(conditions, fmt.Sprintf("%s__%s", key, item.Operator), item.Value)
+			var result interface{}
+			result, err = f.performFilter(nil, fmt.Sprintf("%s__%s", key, item.Operator), item.Value)
 			if err != nil {
 				return nil, err
 			}
-			if (existingConditions == nil || existingConditions == true) && conditions == false {
+			if result == false {
 				f.failedFilters = append(f.failedFilters, key)
 			}
+			conditions = f.And(conditions, result)
 		} else if len(filterItems) > 1 {
 			// Perform an OR query against all possible values for this key
 			var filterConds interface{}
@@ -45,210 +86,326 @@ func (f *LocalFiltering) userFilters(filterFields []types.FilterField) (interfac
 				if err != nil {
 					return nil, err
 				}
-				filterConds = f.Or(conditions, result)
+				filterConds = f.Or(filterConds, result)
 			}
-			existingConditions := conditions
-			conditions = f.And(conditions, filterConds)
-			if (existingConditions == nil || existingConditions == true) && conditions == false {
+			if filterConds == false {
 				f.failedFilters = append(f.failedFilters, key)
 			}
+			conditions = f.And(conditions, filterConds)
 		}
 	}
 
 	return conditions, nil
 }
 
-func (f *LocalFiltering) And(condition1, condition2 interface{}) bool {
+// And : Takes two conditions and performs an AND operation on them. A nil condition is ignored.
+func (f *LocalFiltering) And(condition1, condition2 interface{}) interface{} {
 	if condition1 == nil {
-		condition1 = true
-	}
-	if condition2 == nil {
-		condition2 = true
+		return condition2
+	} else if condition2 == nil {
+		return condition1
 	}
 
-	c1 := condition1.(bool)
-	c2 := condition2.(bool)
+	c1, _ := condition1.(bool)
+	c2, _ := condition2.(bool)
 
 	return c1 && c2
 }
 
-func (f *LocalFiltering) Or(condition1, condition2 interface{}) bool {
+// Or : Takes two conditions and performs an OR operation on them. A nil condition is ignored.
+func (f *LocalFiltering) Or(condition1, condition2 interface{}) interface{} {
 	if condition1 == nil {
-		condition1 = true
-	}
-	if condition2 == nil {
-		condition2 = true
+		return condition2
+	} else if condition2 == nil {
+		return condition1
 	}
 
-	c1 := condition1.(bool)
-	c2 := condition2.(bool)
+	c1, _ := condition1.(bool)
+	c2, _ := condition2.(bool)
 
 	return c1 || c2
 }
 
-func (f *LocalFiltering) Equals(attr string, value interface{}) bool {
+// lookup : Find the value of an attribute, following dot-separated paths into nested maps
+func (f *LocalFiltering) lookup(attr string) (interface{}, bool) {
 	if val, ok := f.data[attr]; ok {
-		return val == value
-	} else {
-		return false
+		return val, true
+	}
+
+	var current interface{} = f.data
+	for _, part := range strings.Split(attr, ".") {
+		m, ok := toMap(current)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
 	}
+
+	return current, true
 }
 
-func (f *LocalFiltering) NotEqual(attr string, value interface{}) bool {
-	if val, ok := f.data[attr]; ok {
-		return val != value
-	} else {
-		return false
+// toMap : Convert a nested document into a map, regardless of the named map type a provider decoded it as
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, true
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	m := make(map[string]interface{}, v.Len())
+	for _, key := range v.MapKeys() {
+		m[key.String()] = v.MapIndex(key).Interface()
 	}
+
+	return m, true
 }
 
-func (f *LocalFiltering) Contains(attr string, value interface{}) bool {
-	if val, ok := f.data[attr]; ok {
-		return strings.Contains(val.(string), value.(string))
-	} else {
-		return false
+// toFloat : Convert a numeric value (or a string containing a number) to a float
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
 	}
+
+	return 0, false
 }
 
-func (f *LocalFiltering) NotContains(attr string, value interface{}) bool {
-	if val, ok := f.data[attr]; ok {
-		return !strings.Contains(val.(string), value.(string))
-	} else {
-		return false
+// compare : Compare a record value with a filter value. Numbers are compared numerically, and everything
+// else is compared by its string representation. Returns false if the values cannot be compared.
+func compare(val interface{}, value interface{}) (int, bool) {
+	switch val.(type) {
+	case string, nil:
+	default:
+		if v1, ok := toFloat(val); ok {
+			v2, ok := toFloat(value)
+			if !ok {
+				return 0, false
+			}
+
+			if v1 < v2 {
+				return -1, true
+			} else if v1 > v2 {
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	switch val.(type) {
+	case string, bool:
+		return strings.Compare(fmt.Sprint(val), fmt.Sprint(value)), true
 	}
+
+	return 0, false
 }
 
-func (f *LocalFiltering) StartsWith(attr string, value interface{}) bool {
-	if val, ok := f.data[attr]; ok {
-		return strings.HasPrefix(val.(string), value.(string))
-	} else {
-		return false
+// equal : Determine if a record value is equal to a filter value
+func equal(val interface{}, value interface{}) bool {
+	if result, ok := compare(val, value); ok {
+		return result == 0
 	}
+
+	return reflect.DeepEqual(val, value)
 }
 
-func (f *LocalFiltering) Exists(attr string, value interface{}) bool {
-	_, exists := f.data[attr]
-	if value == "true" {
-		return exists
-	} else if value == "false" {
-		return !exists
+// unmarshalValues : Convert a JSON-encoded list of values into a slice
+func unmarshalValues(values interface{}) ([]interface{}, error) {
+	var valueList []interface{}
+
+	s, ok := values.(string)
+	if !ok {
+		return nil, fmt.Errorf("%+v could not be cast as a string", values)
+	}
+
+	if err := json.Unmarshal([]byte(s), &valueList); err != nil {
+		return nil, err
 	}
 
-	return false
+	return valueList, nil
 }
 
-func (f *LocalFiltering) GreaterThan(attr string, value interface{}) bool {
-	if val, ok := f.data[attr]; ok {
-		val2 := value.(string)
+// Equals : Standard equals operation
+func (f *LocalFiltering) Equals(attr string, value interface{}) (interface{}, error) {
+	if val, ok := f.lookup(attr); ok {
+		return equal(val, value), nil
+	}
 
-		switch val1 := val.(type) {
-		case int64:
-			val2, err := strconv.ParseInt(val2, 10, 64)
-			if err != nil {
-				return false
-			}
+	return false, nil
+}
 
-			return val1 > val2
-		case string:
-			return val1 > val2
-		}
+// NotEqual : Standard not equals operation
+func (f *LocalFiltering) NotEqual(attr string, value interface{}) (interface{}, error) {
+	if val, ok := f.lookup(attr); ok {
+		return !equal(val, value), nil
 	}
 
-	return false
+	return false, nil
 }
 
-func (f *LocalFiltering) LessThan(attr string, value interface{}) bool {
-	if val, ok := f.data[attr]; ok {
-		val2 := value.(string)
+// Contains : Check if a string contains a value, or a list contains an element
+func (f *LocalFiltering) Contains(attr string, value interface{}) (interface{}, error) {
+	val, ok := f.lookup(attr)
+	if !ok {
+		return false, nil
+	}
 
-		switch val1 := val.(type) {
-		case int64:
-			val2, err := strconv.ParseInt(val2, 10, 64)
-			if err != nil {
-				return false
-			}
+	if s, ok := val.(string); ok {
+		return strings.Contains(s, fmt.Sprint(value)), nil
+	}
 
-			return val1 < val2
-		case string:
-			return val1 < val2
+	if items, ok := val.([]interface{}); ok {
+		for _, item := range items {
+			if equal(item, value) {
+				return true, nil
+			}
 		}
 	}
 
-	return false
+	return false, nil
 }
 
-func (f *LocalFiltering) GreaterThanEqual(attr string, value interface{}) bool {
-	if val, ok := f.data[attr]; ok {
-		val2 := value.(string)
+// NotContains : Check for values that do not contain a string
+func (f *LocalFiltering) NotContains(attr string, value interface{}) (interface{}, error) {
+	result, err := f.Contains(attr, value)
+	if err != nil {
+		return nil, err
+	}
 
-		switch val1 := val.(type) {
-		case int64:
-			val2, err := strconv.ParseInt(val2, 10, 64)
-			if err != nil {
-				return false
-			}
+	return !result.(bool), nil
+}
 
-			return val1 >= val2
-		case string:
-			return val1 >= val2
+// StartsWith : Check if a string starts with a value
+func (f *LocalFiltering) StartsWith(attr string, value interface{}) (interface{}, error) {
+	if val, ok := f.lookup(attr); ok {
+		if s, ok := val.(string); ok {
+			return strings.HasPrefix(s, fmt.Sprint(value)), nil
 		}
 	}
 
-	return false
+	return false, nil
 }
 
-func (f *LocalFiltering) LessThanEqual(attr string, value interface{}) bool {
-	if val, ok := f.data[attr]; ok {
-		val2 := value.(string)
+// Exists : Checks if an attribute exists. Only accepts true/false values.
+func (f *LocalFiltering) Exists(attr string, value interface{}) (interface{}, error) {
+	_, exists := f.lookup(attr)
+	if value == "true" || value == true {
+		return exists, nil
+	} else if value == "false" || value == false {
+		return !exists, nil
+	}
 
-		switch val1 := val.(type) {
-		case int64:
-			val2, err := strconv.ParseInt(val2, 10, 64)
-			if err != nil {
-				return false
-			}
+	return nil, fmt.Errorf("invalid value for Exists operation. Supported values are ['true'/'false']")
+}
 
-			return val1 <= val2
-		case string:
-			return val1 <= val2
-		}
+// GreaterThan : Check if a value is greater than another value
+func (f *LocalFiltering) GreaterThan(attr string, value interface{}) (interface{}, error) {
+	if val, ok := f.lookup(attr); ok {
+		result, ok := compare(val, value)
+		return ok && result > 0, nil
 	}
 
-	return false
+	return false, nil
 }
 
-func (f *LocalFiltering) In(attr string, values interface{}) bool {
-	var valueList []string
-	err := json.Unmarshal([]byte(values.(string)), &valueList)
+// LessThan : Check if a value is less than another value
+func (f *LocalFiltering) LessThan(attr string, value interface{}) (interface{}, error) {
+	if val, ok := f.lookup(attr); ok {
+		result, ok := compare(val, value)
+		return ok && result < 0, nil
+	}
+
+	return false, nil
+}
+
+// GreaterThanEqual : Check if a value is greater than or equal to another value
+func (f *LocalFiltering) GreaterThanEqual(attr string, value interface{}) (interface{}, error) {
+	if val, ok := f.lookup(attr); ok {
+		result, ok := compare(val, value)
+		return ok && result >= 0, nil
+	}
+
+	return false, nil
+}
+
+// LessThanEqual : Check if a value is less than or equal to another value
+func (f *LocalFiltering) LessThanEqual(attr string, value interface{}) (interface{}, error) {
+	if val, ok := f.lookup(attr); ok {
+		result, ok := compare(val, value)
+		return ok && result <= 0, nil
+	}
+
+	return false, nil
+}
+
+// Between : Check for records that are between a low and high value
+//
+// Operator: key__between=["1", "2"]
+func (f *LocalFiltering) Between(attr string, values interface{}) (interface{}, error) {
+	valueList, err := unmarshalValues(values)
 	if err != nil {
-		return false
+		return nil, err
 	}
 
-	if val, ok := f.data[attr]; ok {
-		for _, item := range valueList {
-			if item == val {
-				return true
-			}
-		}
+	if len(valueList) != 2 {
+		return nil, fmt.Errorf("between operation requires exactly 2 values, but got %d", len(valueList))
+	}
+
+	low, err := f.GreaterThanEqual(attr, valueList[0])
+	if err != nil {
+		return nil, err
+	}
+
+	high, err := f.LessThanEqual(attr, valueList[1])
+	if err != nil {
+		return nil, err
 	}
 
-	return false
+	return low.(bool) && high.(bool), nil
 }
 
-func (f *LocalFiltering) NotIn(attr string, values interface{}) bool {
-	var valueList []string
-	err := json.Unmarshal([]byte(values.(string)), &valueList)
+// In : Find all records with a list of values
+func (f *LocalFiltering) In(attr string, values interface{}) (interface{}, error) {
+	valueList, err := unmarshalValues(values)
 	if err != nil {
-		return false
+		return nil, err
 	}
 
-	if val, ok := f.data[attr]; ok {
+	if val, ok := f.lookup(attr); ok {
 		for _, item := range valueList {
-			if item == val {
-				return false
+			if equal(val, item) {
+				return true, nil
 			}
 		}
 	}
 
-	return true
+	return false, nil
+}
+
+// NotIn : Find all records without a list of values
+func (f *LocalFiltering) NotIn(attr string, values interface{}) (interface{}, error) {
+	result, err := f.In(attr, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return !result.(bool), nil
 }
@@ -0,0 +1,60 @@
+package base
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+const (
+	// DefaultPageLimit : Page size used when a paginated request does not set a limit
+	DefaultPageLimit = 100
+
+	// MaxPageLimit : Largest page size a request may ask for
+	MaxPageLimit = 1000
+)
+
+// PageLimit : Get the page size of a paginated request, defaulting to DefaultPageLimit
+func PageLimit(req types.Request) (int, error) {
+	if req.Limit < 0 || req.Limit > MaxPageLimit {
+		return 0, &types.BadRequest{
+			Message: fmt.Sprintf("Limit must be a number between 1 and %d", MaxPageLimit),
+		}
+	} else if req.Limit == 0 {
+		return DefaultPageLimit, nil
+	}
+
+	return req.Limit, nil
+}
+
+// EncodeToken : Encode the position of a provider in a result set as an opaque continuation token
+func EncodeToken(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeToken : Decode a continuation token created by EncodeToken. An empty token leaves value unchanged
+// and reports false.
+func DecodeToken(token string, value interface{}) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(data, value)
+	}
+	if err != nil {
+		return false, &types.BadRequest{
+			Message: "Invalid continuation token",
+		}
+	}
+
+	return true, nil
+}
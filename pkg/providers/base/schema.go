@@ -0,0 +1,266 @@
+package base
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// schemaPatterns : Compiled patterns of the schema, which are shared by every request
+var schemaPatterns sync.Map
+
+// ValidateSchema : Check an item against the schema of the data table, returning a message for each violation keyed
+// by the JSON path of the value, such as address.zip or tags[0]. Partial items, as sent to Update and Patch, only
+// have the fields they contain checked. Fields set to null are removed, so they must not be required. Fields with an
+// operator suffix, such as count__add, are skipped since their new value is not known. PreparePatch resolves them to
+// their new value first.
+func (api Scoutr) ValidateSchema(item map[string]interface{}, partial bool) (map[string]string, error) {
+	messages := make(map[string]string)
+	schema := api.Config.Schema
+	if schema == nil {
+		return messages, nil
+	}
+
+	if !partial {
+		return messages, validateSchema(schema, "", item, messages)
+	}
+
+	for name, value := range item {
+		field, operator := SplitFilterKey(name)
+		if operator != OperationEqual {
+			continue
+		}
+
+		if value == nil {
+			for _, required := range schema.Required {
+				if required == field {
+					messages[field] = "Field is required"
+				}
+			}
+			continue
+		}
+
+		if err := validateSchema(propertySchema(schema, field), field, value, messages); err != nil {
+			return nil, err
+		}
+	}
+
+	return messages, nil
+}
+
+// propertySchema : Schema of a property of an object. Properties that are not listed use additionalProperties.
+func propertySchema(schema *types.JSONSchema, name string) *types.JSONSchema {
+	if property, ok := schema.Properties[name]; ok {
+		return property
+	}
+
+	return schema.AdditionalProperties
+}
+
+// validateSchema : Check a value against a schema, adding a message for each violation to messages
+func validateSchema(schema *types.JSONSchema, path string, value interface{}, messages map[string]string) error {
+	if schema == nil {
+		return nil
+	}
+
+	report := func(format string, args ...interface{}) {
+		key := path
+		if key == "" {
+			key = "$"
+		}
+		if _, ok := messages[key]; !ok {
+			messages[key] = fmt.Sprintf(format, args...)
+		}
+	}
+
+	if schema.Reject {
+		report("Field is not allowed")
+		return nil
+	}
+
+	if len(schema.Type) > 0 && !hasSchemaType(schema.Type, value) {
+		report("Value must be of type %s", strings.Join(schema.Type, " or "))
+		return nil
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, option := range schema.Enum {
+			if schemaEqual(value, option) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("Value must be one of %v", schema.Enum)
+			return nil
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			report("Value must be at least %d characters long", *schema.MinLength)
+		} else if schema.MaxLength != nil && length > *schema.MaxLength {
+			report("Value must be at most %d characters long", *schema.MaxLength)
+		} else if schema.Pattern != "" {
+			pattern, err := compilePattern(schema.Pattern)
+			if err != nil {
+				return err
+			} else if !pattern.MatchString(v) {
+				report("Value must match pattern %s", schema.Pattern)
+			}
+		}
+		return nil
+	case bool, nil:
+		return nil
+	}
+
+	if n, ok := toFloat(value); ok {
+		switch {
+		case schema.Minimum != nil && n < *schema.Minimum:
+			report("Value must be at least %v", *schema.Minimum)
+		case schema.Maximum != nil && n > *schema.Maximum:
+			report("Value must be at most %v", *schema.Maximum)
+		case schema.ExclusiveMinimum != nil && n <= *schema.ExclusiveMinimum:
+			report("Value must be greater than %v", *schema.ExclusiveMinimum)
+		case schema.ExclusiveMaximum != nil && n >= *schema.ExclusiveMaximum:
+			report("Value must be less than %v", *schema.ExclusiveMaximum)
+		}
+		return nil
+	}
+
+	if object, ok := toMap(value); ok {
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				messages[schemaPath(path, name)] = "Field is required"
+			}
+		}
+		for name, property := range object {
+			if err := validateSchema(propertySchema(schema, name), schemaPath(path, name), property, messages); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if list, ok := toList(value); ok {
+		if schema.MinItems != nil && len(list) < *schema.MinItems {
+			report("List must have at least %d items", *schema.MinItems)
+		} else if schema.MaxItems != nil && len(list) > *schema.MaxItems {
+			report("List must have at most %d items", *schema.MaxItems)
+		}
+		for i, element := range list {
+			if schema.UniqueItems {
+				for _, previous := range list[:i] {
+					if schemaEqual(element, previous) {
+						report("List items must be unique")
+					}
+				}
+			}
+			if err := validateSchema(schema.Items, fmt.Sprintf("%s[%d]", path, i), element, messages); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasSchemaType : Check if a value has one of the types of a schema
+func hasSchemaType(names []string, value interface{}) bool {
+	for _, name := range names {
+		switch name {
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number", "integer":
+			if _, ok := value.(string); ok {
+				continue
+			}
+			if n, ok := toFloat(value); ok && (name == "number" || n == float64(int64(n))) {
+				return true
+			}
+		case "object":
+			if _, ok := toMap(value); ok {
+				return true
+			}
+		case "array":
+			if _, ok := toList(value); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// schemaEqual : Compare two values of a schema. Numbers are compared by value, whatever their type.
+func schemaEqual(v1 interface{}, v2 interface{}) bool {
+	_, isString1 := v1.(string)
+	_, isString2 := v2.(string)
+	if n1, ok := toFloat(v1); ok && !isString1 {
+		n2, ok := toFloat(v2)
+		return ok && !isString2 && n1 == n2
+	}
+
+	return reflect.DeepEqual(v1, v2)
+}
+
+// schemaPath : JSON path of a property of an object
+func schemaPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// compilePattern : Compile a pattern of the schema, reusing it if it was already compiled
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := schemaPatterns.Load(pattern); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema pattern %s: %w", pattern, err)
+	}
+	schemaPatterns.Store(pattern, compiled)
+
+	return compiled, nil
+}
+
+// toList : Convert a list into a slice, regardless of the named slice type a provider decoded it as
+func toList(value interface{}) ([]interface{}, bool) {
+	if list, ok := value.([]interface{}); ok {
+		return list, true
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	list := make([]interface{}, v.Len())
+	for i := range list {
+		list[i] = v.Index(i).Interface()
+	}
+
+	return list, true
+}
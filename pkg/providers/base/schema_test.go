@@ -0,0 +1,118 @@
+package base_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["id", "name"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^[0-9]+$"},
+		"name": {"type": "string", "minLength": 2, "maxLength": 10},
+		"status": {"enum": ["active", "locked"]},
+		"count": {"type": "integer", "minimum": 0, "exclusiveMaximum": 10},
+		"tags": {"type": "array", "maxItems": 3, "uniqueItems": true, "items": {"type": "string"}},
+		"address": {
+			"type": "object",
+			"required": ["zip"],
+			"properties": {"zip": {"type": ["string", "null"], "pattern": "^[0-9]{5}$"}}
+		}
+	}
+}`
+
+func TestValidateSchema(t *testing.T) {
+	var schema types.JSONSchema
+	if err := json.Unmarshal([]byte(testSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	api := base.Scoutr{Config: config.Config{Schema: &schema}}
+
+	tests := []struct {
+		name     string
+		item     string
+		partial  bool
+		expected map[string]string
+	}{
+		{"Valid", `{"id": "1", "name": "alpha", "count": 3, "tags": ["a", "b"], "address": {"zip": null}}`, false, map[string]string{}},
+		{"Required", `{"id": "1"}`, false, map[string]string{"name": "Field is required"}},
+		{"Types", `{"id": 1, "name": "alpha", "count": 1.5}`, false, map[string]string{
+			"id":    "Value must be of type string",
+			"count": "Value must be of type integer",
+		}},
+		{"Limits", `{"id": "x", "name": "a", "count": 10, "status": "gone"}`, false, map[string]string{
+			"id":     "Value must match pattern ^[0-9]+$",
+			"name":   "Value must be at least 2 characters long",
+			"count":  "Value must be less than 10",
+			"status": "Value must be one of [active locked]",
+		}},
+		{"Nested", `{"id": "1", "name": "alpha", "tags": ["a", 2, "a"], "address": {"zip": "123"}, "extra": true}`, false, map[string]string{
+			"tags":        "List items must be unique",
+			"tags[1]":     "Value must be of type string",
+			"address.zip": "Value must match pattern ^[0-9]{5}$",
+			"extra":       "Field is not allowed",
+		}},
+		{"NestedRequired", `{"id": "1", "name": "alpha", "address": {}}`, false, map[string]string{"address.zip": "Field is required"}},
+		{"Partial", `{"count": -1, "status": "active"}`, true, map[string]string{"count": "Value must be at least 0"}},
+		{"PartialRemove", `{"name": null, "tags": null}`, true, map[string]string{"name": "Field is required"}},
+		{"PartialOperator", `{"count__add": "x"}`, true, map[string]string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var item map[string]interface{}
+			if err := json.Unmarshal([]byte(test.item), &item); err != nil {
+				t.Fatal(err)
+			}
+
+			messages, err := api.ValidateSchema(item, test.partial)
+			if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(messages, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, messages)
+			}
+		})
+	}
+}
+
+func TestValidateFieldsSchema(t *testing.T) {
+	var schema types.JSONSchema
+	if err := json.Unmarshal([]byte(testSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	api := base.Scoutr{Config: config.Config{Schema: &schema}}
+
+	// Field validators run alongside the schema, and every violation is reported
+	validation := map[string]types.FieldValidation{
+		"status": func(input *types.ValidationInput, ch chan types.ValidationOutput) {
+			ch <- types.ValidationOutput{Input: input, Result: input.Value != "locked", Message: "Status cannot be locked"}
+		},
+	}
+	err := api.ValidateFields(validation, nil, map[string]interface{}{"id": "1", "name": "a", "status": "locked"}, nil)
+
+	var badRequest *types.BadRequest
+	if !errors.As(err, &badRequest) {
+		t.Fatalf("Expected a bad request, got %v", err)
+	}
+	expected := map[string]string{
+		"name":   "Value must be at least 2 characters long",
+		"status": "Status cannot be locked",
+	}
+	if !reflect.DeepEqual(badRequest.Messages, expected) {
+		t.Errorf("Expected %v, got %v", expected, badRequest.Messages)
+	}
+
+	// Required fields are still checked first
+	err = api.ValidateFields(nil, []string{"status"}, map[string]interface{}{"id": "1", "name": "alpha"}, nil)
+	if !errors.As(err, &badRequest) || badRequest.Message != "Missing required fields: status" {
+		t.Errorf("Expected missing required fields, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package base
+
+import (
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// Transaction : Operations of a transaction that passed their checks, ready to be committed by a provider
+type Transaction struct {
+	// Request : Request to commit the operations with. The user is authenticated once for the whole transaction.
+	Request types.Request
+	User    *types.User
+
+	Operations []types.TransactionOperation
+
+	// Keys : Key of the item of each operation
+	Keys []types.Key
+
+	// Trash : Fields set on the items deleted by the transaction when soft delete is enabled
+	Trash map[string]interface{}
+}
+
+// OperationRequest : Request for one operation of the transaction, expecting the version given for it. The body is
+// only the item of the operation, so its audit log does not hold the whole transaction.
+func (tx Transaction) OperationRequest(i int) types.Request {
+	var body interface{}
+	if item := tx.Operations[i].Item; item != nil {
+		body = item
+	}
+
+	req := tx.Request.WithBody(body)
+	req.IfMatch = tx.Operations[i].IfMatch
+	return req
+}
+
+// FilterAction : Filters that apply to the item of an operation. Checks only need the user to be able to read it.
+func (tx Transaction) FilterAction(i int) string {
+	switch tx.Operations[i].Action {
+	case types.TransactionCreate:
+		return FilterActionCreate
+	case types.TransactionUpdate:
+		return FilterActionUpdate
+	case types.TransactionDelete:
+		return FilterActionDelete
+	}
+
+	return FilterActionRead
+}
+
+// Failure : Message reported when the item of an operation does not exist or fails the user's filters
+func (tx Transaction) Failure(i int) string {
+	switch tx.Operations[i].Action {
+	case types.TransactionCreate:
+		return "Item already exists or you do not have permission to create it"
+	case types.TransactionUpdate:
+		return "Item does not exist or you do not have permission to update it"
+	case types.TransactionDelete:
+		return "Item does not exist or you do not have permission to delete it"
+	}
+
+	return "Item does not exist or you do not have permission to read it"
+}
+
+// PrepareTransaction : Authenticate the user of a transaction and check every operation before anything is written.
+// Creates and updates are validated and checked against the field permissions and creation filters, as they are by
+// Create and Patch. The filters on existing items are left to the provider, which checks them as part of the commit.
+// The first operation to fail is returned in a TransactionFailed error.
+func (api Scoutr) PrepareTransaction(req types.Request, operations []types.TransactionOperation, validation map[string]types.FieldValidation) (Transaction, error) {
+	if len(operations) == 0 {
+		return Transaction{}, &types.BadRequest{
+			Message: "Transaction has no operations",
+		}
+	}
+
+	req, err := api.PrepareBatch(req)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	tx := Transaction{
+		Request:    req,
+		User:       user,
+		Operations: operations,
+		Keys:       make([]types.Key, len(operations)),
+	}
+	if api.SoftDeletes(req) {
+		tx.Trash = TrashFields(user)
+	}
+
+	// An item can only be used by one operation, since each is checked against the item as it was before the
+	// transaction
+	seen := make(map[string]bool)
+	for i, operation := range operations {
+		key, err := api.prepareOperation(tx.OperationRequest(i), operation, validation)
+		if err != nil {
+			return Transaction{}, types.NewTransactionFailed(i, err)
+		}
+
+		id, err := api.ItemID(req.Context(), key)
+		if err != nil {
+			return Transaction{}, types.NewTransactionFailed(i, err)
+		} else if seen[id] {
+			return Transaction{}, types.NewTransactionFailed(i, &types.BadRequest{
+				Message: "Item is used by more than one operation of the transaction",
+			})
+		}
+		seen[id] = true
+		tx.Keys[i] = key
+	}
+
+	return tx, nil
+}
+
+// prepareOperation : Check an operation of a transaction, returning the key of its item
+func (api Scoutr) prepareOperation(req types.Request, operation types.TransactionOperation, validation map[string]types.FieldValidation) (types.Key, error) {
+	key := operation.Key
+	if operation.Action == types.TransactionCreate {
+		key = api.recordKey(operation.Item)
+	}
+
+	var missing []string
+	for _, field := range api.ScoutrBase.KeyFields() {
+		if _, ok := key[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: %v", missing),
+		}
+	}
+
+	if _, _, err := api.ExpectedVersion(req); err != nil {
+		return nil, err
+	}
+
+	switch operation.Action {
+	case types.TransactionCreate:
+		_, err := api.PrepareCreate(req, operation.Item, validation, nil)
+		return key, err
+	case types.TransactionUpdate:
+		if len(operation.Item) == 0 {
+			return nil, &types.BadRequest{
+				Message: "No fields to update",
+			}
+		}
+		_, err := api.PrepareUpdate(req, key, operation.Item, validation, nil)
+		return key, err
+	case types.TransactionDelete, types.TransactionCheck:
+		return key, nil
+	}
+
+	return nil, &types.BadRequest{
+		Message: fmt.Sprintf("Unknown action '%s'", operation.Action),
+	}
+}
+
+// ApplyOperation : State of an item after an operation of the transaction, given its state before. Updates set
+// their fields on the item, including the ones that are null as Update does, leaving the key unchanged. Every write moves the
+// version of the item on. Returns nil for items that are removed.
+func (api Scoutr) ApplyOperation(tx Transaction, i int, current map[string]interface{}) types.Record {
+	operation := tx.Operations[i]
+
+	var output types.Record
+	switch operation.Action {
+	case types.TransactionCreate:
+		return copyRecord(api.InitialVersion(operation.Item))
+	case types.TransactionCheck:
+		return copyRecord(current)
+	case types.TransactionUpdate:
+		output = copyRecord(current)
+		for field, value := range operation.Item {
+			if _, isKey := tx.Keys[i][field]; isKey {
+				continue
+			}
+			output[field] = value
+		}
+	case types.TransactionDelete:
+		if tx.Trash == nil {
+			return nil
+		}
+		output = copyRecord(current)
+		for field, value := range tx.Trash {
+			output[field] = value
+		}
+	}
+
+	api.IncrementVersion(output)
+
+	return output
+}
+
+// AuditTransaction : Audit each write of a committed transaction, given the state of every item before and after it
+func (api Scoutr) AuditTransaction(tx Transaction, previous []types.Record, outputs []types.Record) error {
+	for i, operation := range tx.Operations {
+		var action string
+		switch operation.Action {
+		case types.TransactionCreate:
+			action = AuditActionCreate
+		case types.TransactionUpdate:
+			action = AuditActionUpdate
+		case types.TransactionDelete:
+			action = DeleteAuditAction(tx.Request)
+		default:
+			continue
+		}
+
+		if err := api.WriteChangeAuditLog(action, tx.OperationRequest(i), tx.User, tx.Keys[i], operation.Item, previous[i], outputs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package base
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+const (
+	// DeletedAtField : Field holding the time an item was moved to the trash, in RFC 3339 format
+	DeletedAtField = "deleted_at"
+
+	// DeletedByField : Field holding the ID of the user that moved an item to the trash
+	DeletedByField = "deleted_by"
+)
+
+// trashKey : Context key marking requests that operate on the trash
+type trashKey struct{}
+
+// WithTrash : Copy of a request that operates on the items in the trash instead of the items that have not been
+// deleted. Deleting an item from the trash purges it.
+func WithTrash(req types.Request) types.Request {
+	return req.WithContext(context.WithValue(req.Context(), trashKey{}, true))
+}
+
+// InTrash : Check if a request operates on the items in the trash
+func InTrash(req types.Request) bool {
+	trash, _ := req.Context().Value(trashKey{}).(bool)
+	return trash
+}
+
+// SoftDeletes : Check if deleting an item should move it to the trash rather than removing it
+func (api Scoutr) SoftDeletes(req types.Request) bool {
+	return api.Config.SoftDelete && !InTrash(req)
+}
+
+// TrashFields : Fields set on an item when a user moves it to the trash
+func TrashFields(user *types.User) map[string]interface{} {
+	return map[string]interface{}{
+		DeletedAtField: time.Now().UTC().Format(time.RFC3339),
+		DeletedByField: user.ID,
+	}
+}
+
+// DeleteAuditAction : Action recorded in the audit log when an item is removed. Removing an item from the trash
+// purges it.
+func DeleteAuditAction(req types.Request) string {
+	if InTrash(req) {
+		return AuditActionPurge
+	}
+
+	return AuditActionDelete
+}
+
+// filterTrash : Limit the items a user can read, update and delete to the ones in the trash, or to the ones that are
+// not. The filters are copied, so the user's own permissions are left unchanged.
+func filterTrash(user *types.User, trash bool) {
+	filter := types.FilterField{
+		Field:    DeletedAtField,
+		Operator: OperationExists,
+		Value:    strconv.FormatBool(trash),
+	}
+
+	user.ReadFilters = append(append([]types.FilterField{}, user.ReadFilters...), filter)
+	user.UpdateFilters = append(append([]types.FilterField{}, user.UpdateFilters...), filter)
+	user.DeleteFilters = append(append([]types.FilterField{}, user.DeleteFilters...), filter)
+}
+
+// trashEnabled : Return an error if soft delete is not enabled
+func (api Scoutr) trashEnabled() error {
+	if !api.Config.SoftDelete {
+		return &types.NotFound{
+			Message: "Soft delete is not enabled",
+		}
+	}
+
+	return nil
+}
+
+// ListTrash : List the items in the trash
+func (api Scoutr) ListTrash(req types.Request) ([]types.Record, error) {
+	if err := api.trashEnabled(); err != nil {
+		return nil, err
+	}
+
+	return api.ScoutrBase.List(WithTrash(req))
+}
+
+// ListTrashPage : List a page of the items in the trash
+func (api Scoutr) ListTrashPage(req types.Request) (types.Page[types.Record], error) {
+	if err := api.trashEnabled(); err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	return api.ScoutrBase.ListPage(WithTrash(req))
+}
+
+// RestoreFromTrash : Move an item out of the trash. The deletion fields are removed with Patch, so update filters
+// and field restrictions apply, and the change is audited as a restore.
+func (api Scoutr) RestoreFromTrash(req types.Request, key types.Key) (interface{}, error) {
+	if err := api.trashEnabled(); err != nil {
+		return nil, err
+	}
+
+	return api.ScoutrBase.Patch(WithTrash(req), key, map[string]interface{}{
+		DeletedAtField: nil,
+		DeletedByField: nil,
+	}, nil, AuditActionRestore)
+}
+
+// PurgeTrash : Remove the items that have been in the trash for longer than the retention period, returning the
+// number of items removed. Each item is audited as a purge.
+func (api Scoutr) PurgeTrash(req types.Request) (int, error) {
+	if err := api.trashEnabled(); err != nil {
+		return 0, err
+	}
+
+	retention := api.Config.TrashRetentionDays
+	if retention <= 0 {
+		retention = 30
+	}
+	cutoff := time.Now().UTC().AddDate(0, 0, -retention).Format(time.RFC3339)
+
+	// Deletion times have a fixed format, so they sort as strings
+	req = WithTrash(req)
+	req.PathParams = nil
+	req.QueryParams = map[string][]string{DeletedAtField + "__lt": {cutoff}}
+	records, err := api.ScoutrBase.List(req)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, record := range records {
+		if err := api.ScoutrBase.Delete(req, api.recordKey(record)); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,136 @@
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// ETag : Entity tag of a version of an item
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// RecordETag : Entity tag of the version of an item, or an empty string if the item has no version
+func RecordETag(record map[string]interface{}, field string) string {
+	if field == "" {
+		return ""
+	}
+
+	version, ok := RecordVersion(record, field)
+	if !ok {
+		return ""
+	}
+
+	return ETag(version)
+}
+
+// ParseETag : Read the version from an entity tag, as sent in an If-Match header. Weak tags are accepted since
+// versions are compared by value. Returns false if the tag is empty or "*", which match any version.
+func ParseETag(tag string) (int64, bool, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || tag == "*" {
+		return 0, false, nil
+	}
+
+	value, err := strconv.Unquote(strings.TrimPrefix(tag, "W/"))
+	if err != nil {
+		value = tag
+	}
+
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, &types.PreconditionFailed{
+			Message: fmt.Sprintf("Entity tag %s does not match a version of the item", tag),
+		}
+	}
+
+	return version, true, nil
+}
+
+// RecordVersion : Read the version of an item. Numbers are decoded differently by each database, so any numeric
+// type is accepted. Returns false if the item has no version.
+func RecordVersion(record map[string]interface{}, field string) (int64, bool) {
+	switch value := record[field].(type) {
+	case int:
+		return int64(value), true
+	case int32:
+		return int64(value), true
+	case int64:
+		return value, true
+	case float64:
+		return int64(value), true
+	case json.Number:
+		version, err := value.Int64()
+		return version, err == nil
+	case string:
+		version, err := strconv.ParseInt(value, 10, 64)
+		return version, err == nil
+	}
+
+	return 0, false
+}
+
+// Versioned : Check if items are versioned
+func (api Scoutr) Versioned() bool {
+	return api.Config.VersionField != ""
+}
+
+// ExpectedVersion : Version an item must be at for the request to modify it, taken from the If-Match header of the
+// request. Returns false if the request accepts any version, or items are not versioned.
+func (api Scoutr) ExpectedVersion(req types.Request) (int64, bool, error) {
+	if !api.Versioned() {
+		return 0, false, nil
+	}
+
+	return ParseETag(req.IfMatch)
+}
+
+// CheckVersion : Make sure an item is at the version the request expects
+func (api Scoutr) CheckVersion(req types.Request, record map[string]interface{}) error {
+	expected, ok, err := api.ExpectedVersion(req)
+	if err != nil || !ok {
+		return err
+	}
+
+	if version, _ := RecordVersion(record, api.Config.VersionField); version != expected {
+		return api.VersionConflict(expected, version)
+	}
+
+	return nil
+}
+
+// VersionConflict : Error returned when an item is not at the version a request expects
+func (api Scoutr) VersionConflict(expected int64, version int64) error {
+	return &types.PreconditionFailed{
+		Message: fmt.Sprintf("Item is at version %d, not version %d", version, expected),
+	}
+}
+
+// IncrementVersion : Move an item on to its next version
+func (api Scoutr) IncrementVersion(record map[string]interface{}) {
+	if !api.Versioned() {
+		return
+	}
+
+	version, _ := RecordVersion(record, api.Config.VersionField)
+	record[api.Config.VersionField] = version + 1
+}
+
+// InitialVersion : Copy of a new item at its first version. Any version given by the user is replaced.
+func (api Scoutr) InitialVersion(item map[string]interface{}) map[string]interface{} {
+	if !api.Versioned() {
+		return item
+	}
+
+	output := make(map[string]interface{}, len(item)+1)
+	for key, value := range item {
+		output[key] = value
+	}
+	output[api.Config.VersionField] = int64(1)
+
+	return output
+}
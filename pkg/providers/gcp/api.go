@@ -0,0 +1,279 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreAPI : API, based off of Scoutr, used to talk to Google Cloud Firestore. Set the
+// FIRESTORE_EMULATOR_HOST environment variable to run against the Firestore emulator.
+type FirestoreAPI struct {
+	*base.Scoutr
+	Client    *firestore.Client
+	filtering FirestoreFiltering
+}
+
+// NewFirestoreAPI : Connect to Firestore with ctx and initialize the API
+func NewFirestoreAPI(ctx context.Context, firestoreConfig config.FirestoreConfig, opts ...option.ClientOption) (FirestoreAPI, error) {
+	client, err := firestore.NewClient(ctx, firestoreConfig.ProjectID, opts...)
+	if err != nil {
+		return FirestoreAPI{}, err
+	}
+
+	api := FirestoreAPI{
+		Client:    client,
+		filtering: NewFilter(),
+		Scoutr: &base.Scoutr{
+			Config: firestoreConfig.Config,
+		},
+	}
+
+	// Select the audit sink
+	sink, err := base.NewAuditSink(api.Config, map[string]base.AuditSink{
+		base.AuditSinkTable: api.AuditTableSink(),
+	}, api.AuditChainStore())
+	if err != nil {
+		client.Close()
+		return FirestoreAPI{}, err
+	}
+	api.AuditSink = sink
+
+	api.ScoutrBase = api
+
+	return api, nil
+}
+
+// Close : Close connection with Firestore, after writing any queued audit logs
+func (api FirestoreAPI) Close() error {
+	// Queued audit logs may be written to the database, so they are flushed first
+	auditErr := api.CloseAuditLogs(context.Background())
+
+	return errors.Join(auditErr, api.Client.Close())
+}
+
+// docID : Records are stored using their key as the document id. The values of composite keys are escaped and
+// joined with "#".
+func (api FirestoreAPI) docID(key map[string]interface{}) (string, error) {
+	var values []string
+	var missing []string
+	for _, field := range api.KeyFields() {
+		value, ok := key[field]
+		if !ok || value == nil {
+			missing = append(missing, field)
+			continue
+		}
+		values = append(values, fmt.Sprint(value))
+	}
+
+	if len(missing) > 0 {
+		return "", &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: %v", missing),
+		}
+	} else if len(values) == 1 {
+		return values[0], nil
+	}
+
+	for i, value := range values {
+		values[i] = url.PathEscape(value)
+	}
+
+	return strings.Join(values, "#"), nil
+}
+
+// keyOf : Extract the key attributes from a record
+func (api FirestoreAPI) keyOf(record map[string]interface{}) types.Key {
+	key := make(types.Key)
+	for _, field := range api.KeyFields() {
+		key[field] = record[field]
+	}
+
+	return key
+}
+
+// query : Run a filtered query against a collection. Conditions that cannot be pushed down to
+// Firestore are evaluated against each document as it is read.
+func (api FirestoreAPI) query(ctx context.Context, collection string, conditions interface{}) ([]types.Record, error) {
+	condition := toCondition(conditions)
+	if condition == nil {
+		return api.documents(ctx, api.Client.Collection(collection).Query, nil)
+	}
+
+	q := api.Client.Collection(collection).Query
+	if condition.Filter != nil {
+		q = q.WhereEntity(condition.Filter)
+	}
+
+	records, err := api.documents(ctx, q, condition)
+	if condition.Filter != nil && isCode(err, codes.FailedPrecondition) {
+		// The query needs a composite index that has not been created. Rather than failing the request,
+		// read the whole collection and evaluate the conditions in memory.
+		log.WithError(err).Warn("Query requires a missing index, falling back to in-memory filtering")
+		condition = &FirestoreCondition{Match: condition.Match, Local: true}
+		records, err = api.documents(ctx, api.Client.Collection(collection).Query, condition)
+	}
+
+	return records, err
+}
+
+// documents : Read all documents returned by a query, dropping any that do not match a local condition
+func (api FirestoreAPI) documents(ctx context.Context, q firestore.Query, condition *FirestoreCondition) ([]types.Record, error) {
+	records := []types.Record{}
+
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, base.ContextError(ctx, err)
+		}
+
+		record := doc.Data()
+		if condition != nil && condition.Local && !condition.Matches(record) {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// page : Run a filtered query against a collection, returning up to limit records ordered by the given fields and
+// then by document id. Pages continue after the position in the continuation token. Like query, conditions that
+// need a missing index are evaluated in memory.
+func (api FirestoreAPI) page(ctx context.Context, collection string, conditions interface{}, fields []string, direction firestore.Direction, limit int, next string) (types.Page[types.Record], error) {
+	// The token holds the values of the ordered fields and the id of the last document
+	var after []interface{}
+	if _, err := base.DecodeToken(next, &after); err != nil {
+		return types.Page[types.Record]{}, err
+	} else if len(after) > 0 && len(after) != len(fields)+1 {
+		return types.Page[types.Record]{}, &types.BadRequest{Message: "Invalid continuation token"}
+	}
+
+	build := func(condition *FirestoreCondition) firestore.Query {
+		q := api.Client.Collection(collection).Query
+		if condition != nil && condition.Filter != nil {
+			q = q.WhereEntity(condition.Filter)
+		}
+		for _, field := range fields {
+			q = q.OrderBy(field, direction)
+		}
+		q = q.OrderBy(firestore.DocumentID, direction)
+		if len(after) > 0 {
+			q = q.StartAfter(after...)
+		}
+
+		// Fetch an extra document to find out if there is a following page. When conditions are evaluated
+		// locally, documents are read until the page is full instead.
+		if condition == nil || !condition.Local {
+			q = q.Limit(limit + 1)
+		}
+
+		return q
+	}
+
+	condition := toCondition(conditions)
+	page, err := api.pageDocuments(ctx, build(condition), condition, fields, limit)
+	if condition != nil && condition.Filter != nil && isCode(err, codes.FailedPrecondition) {
+		log.WithError(err).Warn("Query requires a missing index, falling back to in-memory filtering")
+		condition = &FirestoreCondition{Match: condition.Match, Local: true}
+		page, err = api.pageDocuments(ctx, build(condition), condition, fields, limit)
+	}
+
+	return page, err
+}
+
+// pageDocuments : Read up to limit documents returned by a query, dropping any that do not match a local condition
+func (api FirestoreAPI) pageDocuments(ctx context.Context, q firestore.Query, condition *FirestoreCondition, fields []string, limit int) (types.Page[types.Record], error) {
+	page := types.Page[types.Record]{Items: []types.Record{}}
+
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+
+	var last []interface{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return types.Page[types.Record]{}, base.ContextError(ctx, err)
+		}
+
+		record := doc.Data()
+		if condition != nil && condition.Local && !condition.Matches(record) {
+			continue
+		}
+
+		if len(page.Items) == limit {
+			page.Next, err = base.EncodeToken(last)
+			if err != nil {
+				return types.Page[types.Record]{}, err
+			}
+			break
+		}
+
+		page.Items = append(page.Items, record)
+
+		last = nil
+		for _, field := range fields {
+			last = append(last, record[field])
+		}
+		last = append(last, doc.Ref.ID)
+	}
+
+	return page, nil
+}
+
+// decode : Convert a Firestore document into a struct using its JSON tags. This handles embedded
+// structs (such as types.Permissions) the same way the other providers do.
+func decode[T any](document map[string]interface{}) (*T, error) {
+	var output *T
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// encode : Convert a struct into a Firestore document using its JSON tags
+func encode(value interface{}) (map[string]interface{}, error) {
+	var output map[string]interface{}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// isCode : Check if an error is a gRPC error with the given status code
+func isCode(err error, code codes.Code) bool {
+	return status.Code(err) == code
+}
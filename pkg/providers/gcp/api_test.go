@@ -0,0 +1,166 @@
+package gcp_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/gcp"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// newEmulatorAPI : Connect to the Firestore emulator, using collections unique to this test run.
+// The test is skipped unless FIRESTORE_EMULATOR_HOST is set.
+func newEmulatorAPI(t *testing.T) gcp.FirestoreAPI {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	api, err := gcp.NewFirestoreAPI(context.Background(), config.FirestoreConfig{
+		ProjectID: "scoutr-test",
+		Config: config.Config{
+			DataTable:  "data-" + suffix,
+			AuthTable:  "auth-" + suffix,
+			GroupTable: "groups-" + suffix,
+			AuditTable: "audit-" + suffix,
+			PrimaryKey: "id",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { api.Close() })
+
+	user := map[string]interface{}{
+		"id":       "user1",
+		"username": "user1",
+		"name":     "User One",
+		"email":    "user1@example.com",
+		"permitted_endpoints": []map[string]interface{}{
+			{"endpoint": ".*", "method": "GET"},
+			{"endpoint": ".*", "method": "POST"},
+			{"endpoint": ".*", "method": "PUT"},
+			{"endpoint": ".*", "method": "PATCH"},
+			{"endpoint": ".*", "method": "DELETE"},
+		},
+		"update_filters": []map[string]interface{}{
+			{"field": "status", "operator": base.OperationNotEqual, "value": "locked"},
+		},
+	}
+	if _, err := api.Client.Collection(api.Config.AuthTable).Doc("user1").Set(context.TODO(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	return api
+}
+
+func request(method string, path string) types.Request {
+	return types.Request{
+		User:   types.RequestUser{ID: "user1"},
+		Method: method,
+		Path:   path,
+	}
+}
+
+func TestEmulatorCrud(t *testing.T) {
+	api := newEmulatorAPI(t)
+
+	// Create items
+	items := []map[string]interface{}{
+		{"id": "1", "name": "alpha", "status": "active", "count": 1},
+		{"id": "2", "name": "beta", "status": "locked", "count": 2},
+		{"id": "3", "name": "gamma", "status": "active", "count": 3},
+	}
+	for _, item := range items {
+		if err := api.Create(request("POST", "/items/"), item, nil, nil, base.AuditActionCreate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Duplicates are rejected
+	err := api.Create(request("POST", "/items/"), map[string]interface{}{"id": "1"}, nil, nil, base.AuditActionCreate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	// Get
+	record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	} else if record["name"] != "alpha" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	if _, err := api.Get(request("GET", "/item/4"), types.Key{"id": "4"}); err == nil {
+		t.Error("Expected not found error")
+	}
+
+	// List with a pushed down filter and a local filter
+	req := request("GET", "/items/")
+	req.QueryParams = map[string][]string{"status": {"active"}, "name__contains": {"amm"}}
+	records, err := api.List(req)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 1 || records[0]["id"] != "3" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+
+	// Search
+	records, err = api.Search(request("POST", "/search/name"), "name", []string{"alpha", "beta"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 2 {
+		t.Errorf("Expected 2 records, got %d", len(records))
+	}
+
+	// Unique values
+	values, err := api.ListUniqueValues(request("GET", "/items/status"), "status")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(values) != 2 || values[0] != "active" || values[1] != "locked" {
+		t.Errorf("Unexpected values %+v", values)
+	}
+
+	// Update
+	output, err := api.Update(request("PUT", "/item/1"), map[string]interface{}{"id": "1"}, map[string]interface{}{"name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if output.(types.Record)["name"] != "delta" {
+		t.Errorf("Unexpected output %+v", output)
+	}
+
+	// Update filters are enforced
+	_, err = api.Update(request("PUT", "/item/2"), map[string]interface{}{"id": "2"}, map[string]interface{}{"name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	// Patch removes null fields
+	output, err = api.Patch(request("PATCH", "/item/1"), map[string]interface{}{"id": "1"}, map[string]interface{}{"count": nil}, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := output.(types.Record)["count"]; ok {
+		t.Errorf("Field should have been removed: %+v", output)
+	}
+
+	// Delete
+	if err := api.Delete(request("DELETE", "/item/3"), map[string]interface{}{"id": "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Delete(request("DELETE", "/item/3"), map[string]interface{}{"id": "3"}); err == nil {
+		t.Error("Expected error deleting missing item")
+	}
+
+	// History
+	history, err := api.History(request("GET", "/history/1"), types.Key{"id": "1"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 3 {
+		t.Errorf("Expected 3 history entries, got %d", len(history))
+	}
+}
@@ -0,0 +1,152 @@
+package gcp
+
+import (
+	"context"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+// chainSuffix : Suffix of the collection that holds the head of the audit log chain
+const chainSuffix = "_chain"
+
+// chainHeadID : Identifier of the document that holds the head of the audit log chain
+const chainHeadID = "head"
+
+// auditTable : Audit sink that stores audit logs in a collection
+type auditTable struct {
+	api  FirestoreAPI
+	name string
+}
+
+// AuditTableSink : Sink that stores audit logs in the audit collection
+func (api FirestoreAPI) AuditTableSink() base.AuditSink {
+	return auditTable{api: api, name: api.Config.AuditTable}
+}
+
+// Write : Add an audit log to the collection, using its JSON field names. Logs are stored under their ID, so writing
+// a log again replaces it.
+func (sink auditTable) Write(ctx context.Context, auditLog types.AuditLog) error {
+	document, err := encode(auditLog)
+	if err != nil {
+		return err
+	}
+
+	collection := sink.api.Client.Collection(sink.name)
+	if auditLog.ID == "" {
+		_, err = collection.NewDoc().Create(ctx, document)
+	} else {
+		_, err = collection.Doc(auditLog.ID).Set(ctx, document)
+	}
+
+	return base.ContextError(ctx, err)
+}
+
+// Query : List the audit logs in the collection, newest first
+func (sink auditTable) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	// Build filters
+	conditions, err := sink.api.filtering.Filter(nil, filters, "")
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	// Query the data. Pages are read newest first, while full listings are sorted in memory
+	var records types.Page[types.Record]
+	if limit > 0 {
+		records, err = sink.api.page(ctx, sink.name, conditions, []string{"time"}, firestore.Desc, limit, next)
+	} else {
+		records.Items, err = sink.api.query(ctx, sink.name, conditions)
+	}
+	if err != nil {
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	data := []types.AuditLog{}
+	for _, record := range records.Items {
+		auditLog, err := decode[types.AuditLog](record)
+		if err != nil {
+			log.Errorln("Error while attempting to decode audit logs", err)
+			return types.Page[types.AuditLog]{}, err
+		}
+		data = append(data, *auditLog)
+	}
+
+	// Sort the data, newest first
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].Time > data[j].Time
+	})
+
+	return types.Page[types.AuditLog]{Items: data, Next: records.Next}, nil
+}
+
+// auditChain : Head of the audit log chain, kept in a single document of the chain collection
+type auditChain struct {
+	api  FirestoreAPI
+	name string
+}
+
+// AuditChainStore : Store that keeps the head of the audit log chain in a collection next to the audit collection.
+// Returns nil if there is no audit collection.
+func (api FirestoreAPI) AuditChainStore() base.AuditChainStore {
+	if api.Config.AuditTable == "" {
+		return nil
+	}
+
+	return auditChain{api: api, name: api.Config.AuditTable + chainSuffix}
+}
+
+// Head : Read the head of the chain
+func (chain auditChain) Head(ctx context.Context) (base.AuditChainHead, error) {
+	snapshot, err := chain.api.Client.Collection(chain.name).Doc(chainHeadID).Get(ctx)
+	if isCode(err, codes.NotFound) {
+		return base.AuditChainHead{}, nil
+	} else if err != nil {
+		return base.AuditChainHead{}, base.ContextError(ctx, err)
+	}
+
+	return chainHead(snapshot), nil
+}
+
+// Advance : Move the head of the chain on in a transaction, if it has not moved since it was read
+func (chain auditChain) Advance(ctx context.Context, from base.AuditChainHead, to base.AuditChainHead) (bool, error) {
+	doc := chain.api.Client.Collection(chain.name).Doc(chainHeadID)
+
+	advanced := false
+	err := chain.api.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		advanced = false
+
+		var head base.AuditChainHead
+		snapshot, err := tx.Get(doc)
+		if err == nil {
+			head = chainHead(snapshot)
+		} else if !isCode(err, codes.NotFound) {
+			return err
+		}
+
+		if head != from {
+			return nil
+		}
+
+		advanced = true
+		return tx.Set(doc, map[string]interface{}{"sequence": to.Sequence, "hash": to.Hash})
+	})
+	if err != nil {
+		return false, base.ContextError(ctx, err)
+	}
+
+	return advanced, nil
+}
+
+// chainHead : Read the head of the chain from its document
+func chainHead(snapshot *firestore.DocumentSnapshot) base.AuditChainHead {
+	data := snapshot.Data()
+	sequence, _ := data["sequence"].(int64)
+	hash, _ := data["hash"].(string)
+
+	return base.AuditChainHead{Sequence: sequence, Hash: hash}
+}
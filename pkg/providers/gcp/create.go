@@ -0,0 +1,43 @@
+package gcp
+
+import (
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+// Create : Create an item
+func (api FirestoreAPI) Create(req types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) error {
+	// Get the user
+	user, err := api.PrepareCreate(req, item, validation, requiredFields)
+	if err != nil {
+		// Bad user - pass the error through
+		return err
+	}
+
+	// Make sure the key was provided
+	item = api.InitialVersion(item)
+	id, err := api.docID(item)
+	if err != nil {
+		return err
+	}
+
+	// Create the document, failing if it already exists
+	doc := api.Client.Collection(api.Config.DataTable).Doc(id)
+	if _, err := doc.Create(req.Context(), item); err != nil {
+		log.WithError(err).Errorln("Encountered error while attempting to create record")
+
+		// Check if the primary key is already in use
+		if isCode(err, codes.AlreadyExists) {
+			return &types.BadRequest{
+				Message: "Item already exists or you do not have permission to create it",
+			}
+		}
+
+		return base.ContextError(req.Context(), err)
+	}
+
+	// Create audit log, recording every field as a change
+	return api.WriteChangeAuditLog(auditAction, req, user, api.keyOf(item), nil, nil, item)
+}
@@ -0,0 +1,75 @@
+package gcp
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Delete : Delete an item
+func (api FirestoreAPI) Delete(req types.Request, partitionKey types.Key) error {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return err
+	}
+
+	// Build pre-condition filters. These are checked against the item inside a transaction and
+	// throw an error if the user is not permitted to delete the item
+	conditions, err := api.filtering.Filter(user, nil, base.FilterActionDelete)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return err
+	}
+
+	// Delete the item, or move it to the trash when soft delete is enabled
+	id, err := api.docID(partitionKey)
+	if err != nil {
+		return err
+	}
+	softDelete, fields := api.SoftDeletes(req), base.TrashFields(user)
+	var previous, output types.Record
+	doc := api.Client.Collection(api.Config.DataTable).Doc(id)
+	err = api.Client.RunTransaction(req.Context(), func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := api.existingItem(tx, doc, conditions, "Item does not exist or you do not have permission to delete it")
+		if err != nil {
+			return err
+		}
+		if err := api.CheckVersion(req, existing); err != nil {
+			return err
+		}
+		previous = existing
+
+		if !softDelete {
+			return tx.Delete(doc)
+		}
+
+		output = make(types.Record, len(existing)+len(fields))
+		for key, value := range existing {
+			output[key] = value
+		}
+		var updates []firestore.Update
+		for key, value := range fields {
+			output[key] = value
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{key}, Value: value})
+		}
+		if api.Versioned() {
+			api.IncrementVersion(output)
+			field := api.Config.VersionField
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{field}, Value: output[field]})
+		}
+
+		return tx.Update(doc, updates)
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to delete item", err)
+		return base.ContextError(req.Context(), err)
+	}
+
+	// Create audit log
+	return api.WriteChangeAuditLog(base.DeleteAuditAction(req), req, user, partitionKey, nil, previous, output)
+}
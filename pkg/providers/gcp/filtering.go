@@ -0,0 +1,310 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+)
+
+const (
+	// Maximum number of values Firestore accepts in an IN / NOT IN filter
+	maxInValues    = 30
+	maxNotInValues = 10
+)
+
+// FirestoreCondition : A filter condition. Filter is the part of the condition that can be pushed down
+// to Firestore, while Match evaluates the entire condition against a record in memory. When Local is
+// true, Filter is only a superset of the condition and the results must be checked with Match.
+type FirestoreCondition struct {
+	Filter firestore.EntityFilter
+	Match  func(map[string]interface{}) bool
+	Local  bool
+}
+
+// Matches : Determine if a record satisfies the condition
+func (c *FirestoreCondition) Matches(record map[string]interface{}) bool {
+	return c == nil || c.Match(record)
+}
+
+// FirestoreFiltering : Used by the FirestoreAPI to perform filters against a Firestore backend
+type FirestoreFiltering struct {
+	base.Filtering
+}
+
+func NewFilter() FirestoreFiltering {
+	f := FirestoreFiltering{}
+	f.FilterBase = &f
+	f.ScoutrFilters = &f
+	return f
+}
+
+// Operations : Map of supported operations for this filter provider
+func (f *FirestoreFiltering) Operations() base.OperationMap {
+	return base.OperationMap{
+		base.OperationStartsWith:       f.StartsWith,
+		base.OperationEqual:            f.Equals,
+		base.OperationNotEqual:         f.NotEqual,
+		base.OperationContains:         f.Contains,
+		base.OperationNotContains:      f.NotContains,
+		base.OperationExists:           f.Exists,
+		base.OperationGreaterThan:      f.GreaterThan,
+		base.OperationLessThan:         f.LessThan,
+		base.OperationGreaterThanEqual: f.GreaterThanEqual,
+		base.OperationLessThanEqual:    f.LessThanEqual,
+		base.OperationBetween:          f.Between,
+		base.OperationIn:               f.In,
+		base.OperationNotIn:            f.NotIn,
+	}
+}
+
+// And : Takes two conditions and performs an AND operation on them
+func (f *FirestoreFiltering) And(condition1, condition2 interface{}) interface{} {
+	cond1, ok1 := condition1.(*FirestoreCondition)
+	cond2, ok2 := condition2.(*FirestoreCondition)
+
+	if ok1 && cond1 != nil && ok2 && cond2 != nil {
+		// Anything that can be pushed down narrows the query, even if the other side is evaluated locally
+		var filter firestore.EntityFilter
+		if cond1.Filter != nil && cond2.Filter != nil {
+			filter = firestore.AndFilter{Filters: []firestore.EntityFilter{cond1.Filter, cond2.Filter}}
+		} else if cond1.Filter != nil {
+			filter = cond1.Filter
+		} else {
+			filter = cond2.Filter
+		}
+
+		return &FirestoreCondition{
+			Filter: filter,
+			Match: func(record map[string]interface{}) bool {
+				return cond1.Match(record) && cond2.Match(record)
+			},
+			Local: cond1.Local || cond2.Local,
+		}
+	} else if ok1 && cond1 != nil {
+		return cond1
+	} else if ok2 && cond2 != nil {
+		return cond2
+	}
+
+	return nil
+}
+
+// Or : Takes two conditions and performs an OR operation on them
+func (f *FirestoreFiltering) Or(condition1, condition2 interface{}) interface{} {
+	cond1, ok1 := condition1.(*FirestoreCondition)
+	cond2, ok2 := condition2.(*FirestoreCondition)
+
+	if ok1 && cond1 != nil && ok2 && cond2 != nil {
+		condition := &FirestoreCondition{
+			Match: func(record map[string]interface{}) bool {
+				return cond1.Match(record) || cond2.Match(record)
+			},
+			Local: true,
+		}
+
+		// An OR can only be pushed down when both sides are fully evaluated by Firestore
+		if !cond1.Local && !cond2.Local {
+			condition.Filter = firestore.OrFilter{Filters: []firestore.EntityFilter{cond1.Filter, cond2.Filter}}
+			condition.Local = false
+		}
+
+		return condition
+	} else if ok1 && cond1 != nil {
+		return cond1
+	} else if ok2 && cond2 != nil {
+		return cond2
+	}
+
+	return nil
+}
+
+// toCondition : Extract the Firestore condition from a set of conditions
+func toCondition(conditions interface{}) *FirestoreCondition {
+	if condition, ok := conditions.(*FirestoreCondition); ok {
+		return condition
+	}
+
+	return nil
+}
+
+// propertyFilter : Build a Firestore filter on a single field. Dots in the key refer to nested fields.
+func propertyFilter(key string, op string, value interface{}) firestore.EntityFilter {
+	return firestore.PropertyPathFilter{
+		Path:     strings.Split(key, "."),
+		Operator: op,
+		Value:    value,
+	}
+}
+
+// condition : Build a condition from an operation. If op is empty, the operation cannot be pushed
+// down to Firestore and is evaluated in memory using base.LocalFiltering.
+func condition(key string, operation string, op string, value interface{}, filterValue interface{}) (*FirestoreCondition, error) {
+	// Validate the value up front, so that evaluating the condition cannot fail
+	if _, err := base.NewLocalFilter(nil).Operations()[operation](key, value); err != nil {
+		return nil, err
+	}
+
+	output := &FirestoreCondition{
+		Match: func(record map[string]interface{}) bool {
+			result, err := base.NewLocalFilter(record).Operations()[operation](key, value)
+			return err == nil && result == true
+		},
+		Local: op == "",
+	}
+
+	if op != "" {
+		output.Filter = propertyFilter(key, op, filterValue)
+	}
+
+	return output, nil
+}
+
+// unmarshalValues : Convert a JSON-encoded list of values into a slice of strings
+func unmarshalValues(values interface{}) ([]string, error) {
+	var valueList []string
+
+	s, ok := values.(string)
+	if !ok {
+		return nil, fmt.Errorf("%+v could not be cast as a string", values)
+	}
+
+	if err := json.Unmarshal([]byte(s), &valueList); err != nil {
+		return nil, err
+	}
+
+	return valueList, nil
+}
+
+// Equals : Standard equals operation
+func (f *FirestoreFiltering) Equals(key string, value interface{}) (interface{}, error) {
+	return condition(key, base.OperationEqual, "==", value, value)
+}
+
+// NotEqual : Standard not equals operation
+func (f *FirestoreFiltering) NotEqual(key string, value interface{}) (interface{}, error) {
+	return condition(key, base.OperationNotEqual, "!=", value, value)
+}
+
+// Contains : Check if a string contains a value. Firestore has no substring query, so this is
+// evaluated in memory.
+func (f *FirestoreFiltering) Contains(key string, value interface{}) (interface{}, error) {
+	if _, ok := value.(string); !ok {
+		return nil, fmt.Errorf("%+v could not be cast as a string", value)
+	}
+
+	return condition(key, base.OperationContains, "", value, nil)
+}
+
+// NotContains : Check for values that do not contain a string. This is evaluated in memory.
+func (f *FirestoreFiltering) NotContains(key string, value interface{}) (interface{}, error) {
+	if _, ok := value.(string); !ok {
+		return nil, fmt.Errorf("%+v could not be cast as a string", value)
+	}
+
+	return condition(key, base.OperationNotContains, "", value, nil)
+}
+
+// StartsWith : Check if a string starts with a value. This is pushed down as a range query.
+func (f *FirestoreFiltering) StartsWith(key string, value interface{}) (interface{}, error) {
+	prefix, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%+v could not be cast as a string", value)
+	}
+
+	low, err := condition(key, base.OperationStartsWith, ">=", value, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every string with the prefix sorts before the prefix followed by a very high code point
+	low.Filter = firestore.AndFilter{Filters: []firestore.EntityFilter{
+		low.Filter,
+		propertyFilter(key, "<", prefix+"\uf8ff"),
+	}}
+
+	return low, nil
+}
+
+// Exists : Checks if an attribute exists. Only accepts true/false values. This is evaluated in memory.
+func (f *FirestoreFiltering) Exists(key string, value interface{}) (interface{}, error) {
+	if value != "true" && value != "false" {
+		return nil, fmt.Errorf("invalid value for Exists operation. Supported values are ['true'/'false']")
+	}
+
+	return condition(key, base.OperationExists, "", value, nil)
+}
+
+// GreaterThan : Check if a value is greater than another value
+func (f *FirestoreFiltering) GreaterThan(key string, value interface{}) (interface{}, error) {
+	return condition(key, base.OperationGreaterThan, ">", value, value)
+}
+
+// LessThan : Check if a value is less than another value
+func (f *FirestoreFiltering) LessThan(key string, value interface{}) (interface{}, error) {
+	return condition(key, base.OperationLessThan, "<", value, value)
+}
+
+// GreaterThanEqual : Check if a value is greater than or equal to another value
+func (f *FirestoreFiltering) GreaterThanEqual(key string, value interface{}) (interface{}, error) {
+	return condition(key, base.OperationGreaterThanEqual, ">=", value, value)
+}
+
+// LessThanEqual : Check if a value is less than or equal to another value
+func (f *FirestoreFiltering) LessThanEqual(key string, value interface{}) (interface{}, error) {
+	return condition(key, base.OperationLessThanEqual, "<=", value, value)
+}
+
+// Between : Check for records that are between a low and high value
+//
+// Operator: key__between=["1", "2"]
+func (f *FirestoreFiltering) Between(key string, values interface{}) (interface{}, error) {
+	valueList, err := unmarshalValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := condition(key, base.OperationBetween, ">=", values, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	output.Filter = firestore.AndFilter{Filters: []firestore.EntityFilter{
+		propertyFilter(key, ">=", valueList[0]),
+		propertyFilter(key, "<=", valueList[1]),
+	}}
+
+	return output, nil
+}
+
+// In : Find all records with a list of values. Lists larger than Firestore supports are evaluated in memory.
+func (f *FirestoreFiltering) In(key string, values interface{}) (interface{}, error) {
+	valueList, err := unmarshalValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	op := "in"
+	if len(valueList) == 0 || len(valueList) > maxInValues {
+		op = ""
+	}
+
+	return condition(key, base.OperationIn, op, values, valueList)
+}
+
+// NotIn : Find all records without a list of values. Lists larger than Firestore supports are evaluated in memory.
+func (f *FirestoreFiltering) NotIn(key string, values interface{}) (interface{}, error) {
+	valueList, err := unmarshalValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	op := "not-in"
+	if len(valueList) == 0 || len(valueList) > maxNotInValues {
+		op = ""
+	}
+
+	return condition(key, base.OperationNotIn, op, values, valueList)
+}
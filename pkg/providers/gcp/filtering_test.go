@@ -0,0 +1,316 @@
+package gcp_test
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/gcp"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+func property(key string, op string, value interface{}) firestore.EntityFilter {
+	return firestore.PropertyPathFilter{Path: firestore.FieldPath{key}, Operator: op, Value: value}
+}
+
+func TestOperations(t *testing.T) {
+	f := gcp.NewFilter()
+
+	operationMap := f.Operations()
+
+	if len(operationMap) != 13 {
+		t.Errorf("Expected 13 operations, but got %d", len(operationMap))
+	}
+}
+
+func TestAnd(t *testing.T) {
+	f := gcp.NewFilter()
+
+	cond1, _ := f.Equals("key1", "val1")
+	cond2, _ := f.Equals("key2", "val2")
+
+	conditions := f.And(cond1, cond2).(*gcp.FirestoreCondition)
+
+	expected := firestore.AndFilter{Filters: []firestore.EntityFilter{property("key1", "==", "val1"), property("key2", "==", "val2")}}
+	if !reflect.DeepEqual(conditions.Filter, expected) {
+		t.Errorf("Incorrect filter. Expected %+v, got %+v", expected, conditions.Filter)
+	}
+
+	if conditions.Local {
+		t.Error("Condition should not require local evaluation")
+	}
+
+	if !conditions.Matches(map[string]interface{}{"key1": "val1", "key2": "val2"}) {
+		t.Error("Record should match")
+	}
+
+	if conditions.Matches(map[string]interface{}{"key1": "val1", "key2": "val3"}) {
+		t.Error("Record should not match")
+	}
+}
+
+func TestAndLocal(t *testing.T) {
+	f := gcp.NewFilter()
+
+	cond1, _ := f.Equals("key1", "val1")
+	cond2, _ := f.Contains("key2", "val")
+
+	conditions := f.And(cond1, cond2).(*gcp.FirestoreCondition)
+
+	// The pushable side still narrows the query
+	if !reflect.DeepEqual(conditions.Filter, property("key1", "==", "val1")) {
+		t.Errorf("Incorrect filter. Got %+v", conditions.Filter)
+	}
+
+	if !conditions.Local {
+		t.Error("Condition should require local evaluation")
+	}
+
+	if !conditions.Matches(map[string]interface{}{"key1": "val1", "key2": "value"}) {
+		t.Error("Record should match")
+	}
+
+	if conditions.Matches(map[string]interface{}{"key1": "val1", "key2": "other"}) {
+		t.Error("Record should not match")
+	}
+}
+
+func TestAndCondsNil(t *testing.T) {
+	f := gcp.NewFilter()
+
+	cond1, _ := f.Equals("key1", "val1")
+
+	if f.And(nil, nil) != nil {
+		t.Error("Conditions should be not be set")
+	}
+
+	if f.And(cond1, nil) != cond1 || f.And(nil, cond1) != cond1 {
+		t.Error("Single condition should be returned as is")
+	}
+}
+
+func TestOr(t *testing.T) {
+	f := gcp.NewFilter()
+
+	cond1, _ := f.Equals("key1", "val1")
+	cond2, _ := f.Equals("key2", "val2")
+
+	conditions := f.Or(cond1, cond2).(*gcp.FirestoreCondition)
+
+	expected := firestore.OrFilter{Filters: []firestore.EntityFilter{property("key1", "==", "val1"), property("key2", "==", "val2")}}
+	if !reflect.DeepEqual(conditions.Filter, expected) {
+		t.Errorf("Incorrect filter. Expected %+v, got %+v", expected, conditions.Filter)
+	}
+
+	if conditions.Local {
+		t.Error("Condition should not require local evaluation")
+	}
+}
+
+func TestOrLocal(t *testing.T) {
+	f := gcp.NewFilter()
+
+	cond1, _ := f.Equals("key1", "val1")
+	cond2, _ := f.Contains("key2", "val")
+
+	conditions := f.Or(cond1, cond2).(*gcp.FirestoreCondition)
+
+	// An OR with a local side cannot be pushed down at all
+	if conditions.Filter != nil {
+		t.Errorf("Filter should not be set, got %+v", conditions.Filter)
+	}
+
+	if !conditions.Local {
+		t.Error("Condition should require local evaluation")
+	}
+
+	if !conditions.Matches(map[string]interface{}{"key1": "other", "key2": "value"}) {
+		t.Error("Record should match")
+	}
+
+	if conditions.Matches(map[string]interface{}{"key1": "other", "key2": "other"}) {
+		t.Error("Record should not match")
+	}
+}
+
+func TestOrCondsNil(t *testing.T) {
+	f := gcp.NewFilter()
+
+	if f.Or(nil, nil) != nil {
+		t.Error("Conditions should be not be set")
+	}
+}
+
+func TestOperators(t *testing.T) {
+	f := gcp.NewFilter()
+
+	tests := []struct {
+		name     string
+		fn       func(string, interface{}) (interface{}, error)
+		value    interface{}
+		expected firestore.EntityFilter
+		match    map[string]interface{}
+		noMatch  map[string]interface{}
+	}{
+		{"Equals", f.Equals, "value123", property("key", "==", "value123"), map[string]interface{}{"key": "value123"}, map[string]interface{}{"key": "value"}},
+		{"NotEqual", f.NotEqual, "value123", property("key", "!=", "value123"), map[string]interface{}{"key": "value"}, map[string]interface{}{"key": "value123"}},
+		{"GreaterThan", f.GreaterThan, "5", property("key", ">", "5"), map[string]interface{}{"key": int64(6)}, map[string]interface{}{"key": int64(5)}},
+		{"GreaterThanEqual", f.GreaterThanEqual, "5", property("key", ">=", "5"), map[string]interface{}{"key": int64(5)}, map[string]interface{}{"key": int64(4)}},
+		{"LessThan", f.LessThan, "5", property("key", "<", "5"), map[string]interface{}{"key": 4.5}, map[string]interface{}{"key": 5.0}},
+		{"LessThanEqual", f.LessThanEqual, "5", property("key", "<=", "5"), map[string]interface{}{"key": int64(5)}, map[string]interface{}{"key": int64(6)}},
+		{"StartsWith", f.StartsWith, "val", firestore.AndFilter{Filters: []firestore.EntityFilter{property("key", ">=", "val"), property("key", "<", "val")}}, map[string]interface{}{"key": "value"}, map[string]interface{}{"key": "other"}},
+		{"Contains", f.Contains, "lu", nil, map[string]interface{}{"key": "value"}, map[string]interface{}{"key": "other"}},
+		{"NotContains", f.NotContains, "lu", nil, map[string]interface{}{"key": "other"}, map[string]interface{}{"key": "value"}},
+		{"ExistsTrue", f.Exists, "true", nil, map[string]interface{}{"key": "value"}, map[string]interface{}{"other": "value"}},
+		{"ExistsFalse", f.Exists, "false", nil, map[string]interface{}{"other": "value"}, map[string]interface{}{"key": "value"}},
+		{"Between", f.Between, `["1", "2"]`, firestore.AndFilter{Filters: []firestore.EntityFilter{property("key", ">=", "1"), property("key", "<=", "2")}}, map[string]interface{}{"key": "15"}, map[string]interface{}{"key": "3"}},
+		{"In", f.In, `["1", "2"]`, property("key", "in", []string{"1", "2"}), map[string]interface{}{"key": "2"}, map[string]interface{}{"key": "3"}},
+		{"NotIn", f.NotIn, `["1", "2"]`, property("key", "not-in", []string{"1", "2"}), map[string]interface{}{"key": "3"}, map[string]interface{}{"key": "2"}},
+		{"Nested", f.Equals, "value", firestore.PropertyPathFilter{Path: firestore.FieldPath{"key", "sub"}, Operator: "==", Value: "value"}, map[string]interface{}{"key": map[string]interface{}{"sub": "value"}}, map[string]interface{}{"key": "value"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := "key"
+			if test.name == "Nested" {
+				key = "key.sub"
+			}
+
+			result, err := test.fn(key, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			conds := result.(*gcp.FirestoreCondition)
+			if !reflect.DeepEqual(conds.Filter, test.expected) {
+				t.Errorf("Incorrect filter. Expected %+v, got %+v", test.expected, conds.Filter)
+			}
+
+			if conds.Local != (test.expected == nil) {
+				t.Errorf("Expected local evaluation to be %t", test.expected == nil)
+			}
+
+			if !conds.Matches(test.match) {
+				t.Errorf("Record %+v should match", test.match)
+			}
+
+			if conds.Matches(test.noMatch) {
+				t.Errorf("Record %+v should not match", test.noMatch)
+			}
+		})
+	}
+}
+
+func TestInTooManyValues(t *testing.T) {
+	f := gcp.NewFilter()
+
+	values := `["1","2","3","4","5","6","7","8","9","10","11"]`
+
+	result, err := f.NotIn("key", values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conds := result.(*gcp.FirestoreCondition)
+	if conds.Filter != nil || !conds.Local {
+		t.Error("Large NOT IN lists should be evaluated locally")
+	}
+
+	if conds.Matches(map[string]interface{}{"key": "11"}) {
+		t.Error("Record should not match")
+	}
+}
+
+func TestOperatorErrors(t *testing.T) {
+	f := gcp.NewFilter()
+
+	tests := []struct {
+		name  string
+		fn    func(string, interface{}) (interface{}, error)
+		value interface{}
+	}{
+		{"ExistsOther", f.Exists, "blah"},
+		{"StartsWithInvalidValue", f.StartsWith, 1},
+		{"ContainsInvalidValue", f.Contains, 1},
+		{"NotContainsInvalidValue", f.NotContains, 1},
+		{"BetweenInvalidValue", f.Between, 1},
+		{"BetweenUnmarshalError", f.Between, "1"},
+		{"BetweenWrongLength", f.Between, `["1"]`},
+		{"InInvalidValue", f.In, 1},
+		{"InJsonUnmarshalError", f.In, "1"},
+		{"NotInInvalidValue", f.NotIn, 1},
+		{"NotInJsonUnmarshalError", f.NotIn, "1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conds, err := test.fn("key", test.value)
+
+			if conds != nil {
+				t.Error("Conditions should be nil")
+			}
+
+			if err == nil {
+				t.Error("Error should not be nil")
+			}
+		})
+	}
+}
+
+func TestFilterWithUser(t *testing.T) {
+	f := gcp.NewFilter()
+
+	filters := map[string][]string{
+		"key2":     {"value1", "value2"},
+		"key3__gt": {"value3"},
+	}
+
+	user := &types.User{
+		Permissions: types.Permissions{
+			ReadFilters: []types.FilterField{
+				{
+					Field:    "key",
+					Operator: base.OperationContains,
+					Value:    "value4",
+				},
+			},
+		},
+	}
+
+	conditions, err := f.Filter(user, filters, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conds := conditions.(*gcp.FirestoreCondition)
+	if !conds.Local {
+		t.Error("Contains filter should require local evaluation")
+	}
+
+	if !conds.Matches(map[string]interface{}{"key": "value45", "key2": "value2", "key3": "value4"}) {
+		t.Error("Record should match")
+	}
+
+	if conds.Matches(map[string]interface{}{"key": "value5", "key2": "value2", "key3": "value4"}) {
+		t.Error("Record should not match user filter")
+	}
+
+	if conds.Matches(map[string]interface{}{"key": "value45", "key2": "value3", "key3": "value4"}) {
+		t.Error("Record should not match query filter")
+	}
+}
+
+func TestMultiFilter(t *testing.T) {
+	f := gcp.NewFilter()
+
+	conditions, err := f.MultiFilter(nil, "key", []string{"value1", "value2", "value3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := property("key", "in", []string{"value1", "value2", "value3"})
+	if filter := conditions.(*gcp.FirestoreCondition).Filter; !reflect.DeepEqual(filter, expected) {
+		t.Errorf("Invalid filter. Expected %+v but got %+v", expected, filter)
+	}
+}
@@ -0,0 +1,112 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+// Get : Get an item from the table. Tables with a sort key can also be looked up by partition key alone, in
+// which case the partition must contain a single item.
+func (api FirestoreAPI) Get(req types.Request, key types.Key) (types.Record, error) {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return nil, err
+	}
+
+	// Build filters. Firestore cannot apply a query to a single document lookup, so the filters
+	// are evaluated against the document once it has been fetched
+	conditions, err := api.filtering.Filter(user, nil, base.FilterActionRead)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return nil, err
+	}
+
+	notFound := &types.NotFound{
+		Message: "Item does not exist or you do not have permission to view it",
+	}
+
+	if partitionKey := api.KeyFields()[0]; key[partitionKey] == nil {
+		return nil, &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: [%s]", partitionKey),
+		}
+	}
+
+	var record types.Record
+	if id, err := api.docID(key); err == nil {
+		// Fetch the item
+		doc, err := api.Client.Collection(api.Config.DataTable).Doc(id).Get(req.Context())
+		if err != nil {
+			if isCode(err, codes.NotFound) {
+				return nil, notFound
+			}
+
+			log.Errorln("Error while attempting to get record", err)
+			return nil, base.ContextError(req.Context(), err)
+		}
+		record = doc.Data()
+	} else {
+		// Query the partition
+		var keyConditions interface{}
+		for field, value := range key {
+			condition, err := api.filtering.Equals(field, value)
+			if err != nil {
+				return nil, err
+			}
+			keyConditions = api.filtering.And(keyConditions, condition)
+		}
+
+		records, err := api.query(req.Context(), api.Config.DataTable, keyConditions)
+		if err != nil {
+			log.Errorln("Error while attempting to query record", err)
+			return nil, err
+		} else if len(records) > 1 {
+			return nil, &types.BadRequest{
+				Message: "Multiple items returned",
+			}
+		} else if len(records) == 0 {
+			return nil, notFound
+		}
+		record = records[0]
+	}
+
+	// Make sure the user is permitted to view the item
+	if !toCondition(conditions).Matches(record) {
+		return nil, notFound
+	}
+
+	// Filter the response
+	api.PostProcess([]types.Record{record}, user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionGet, req, user, key, nil); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// FetchItem : Fetch an item by its full key, without checking the user's permissions
+func (api FirestoreAPI) FetchItem(ctx context.Context, key types.Key) (types.Record, error) {
+	id, err := api.docID(key)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := api.Client.Collection(api.Config.DataTable).Doc(id).Get(ctx)
+	if err != nil {
+		if isCode(err, codes.NotFound) {
+			return nil, nil
+		}
+
+		return nil, base.ContextError(ctx, err)
+	}
+
+	return doc.Data(), nil
+}
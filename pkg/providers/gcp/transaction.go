@@ -0,0 +1,96 @@
+package gcp
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+// Transaction : Apply a set of operations in a single Firestore transaction. Every item is read and checked against
+// the user's filters and the expected versions before any of them is written.
+func (api FirestoreAPI) Transaction(req types.Request, operations []types.TransactionOperation, validation map[string]types.FieldValidation) ([]types.Record, error) {
+	transaction, err := api.PrepareTransaction(req, operations, validation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build pre-condition filters for each operation. These are checked against the items inside the transaction.
+	docs := make([]*firestore.DocumentRef, len(operations))
+	conditions := make([]interface{}, len(operations))
+	for i := range operations {
+		id, err := api.docID(transaction.Keys[i])
+		if err != nil {
+			return nil, types.NewTransactionFailed(i, err)
+		}
+		docs[i] = api.Client.Collection(api.Config.DataTable).Doc(id)
+
+		conditions[i], err = api.filtering.Filter(transaction.User, nil, transaction.FilterAction(i))
+		if err != nil {
+			log.Errorln("Error encountered during filtering", err)
+			return nil, types.NewTransactionFailed(i, err)
+		}
+	}
+
+	var previous, outputs []types.Record
+	err = api.Client.RunTransaction(req.Context(), func(ctx context.Context, tx *firestore.Transaction) error {
+		previous = make([]types.Record, len(operations))
+		outputs = make([]types.Record, len(operations))
+
+		// All reads must happen before any writes
+		for i, operation := range operations {
+			if operation.Action == types.TransactionCreate {
+				if _, err := tx.Get(docs[i]); err == nil {
+					return types.NewTransactionFailed(i, &types.BadRequest{Message: transaction.Failure(i)})
+				} else if !isCode(err, codes.NotFound) {
+					return types.NewTransactionFailed(i, err)
+				}
+				continue
+			}
+
+			existing, err := api.existingItem(tx, docs[i], conditions[i], transaction.Failure(i))
+			if err != nil {
+				return types.NewTransactionFailed(i, err)
+			}
+			if err := api.CheckVersion(transaction.OperationRequest(i), existing); err != nil {
+				return types.NewTransactionFailed(i, err)
+			}
+			previous[i] = existing
+		}
+
+		for i, operation := range operations {
+			outputs[i] = api.ApplyOperation(transaction, i, previous[i])
+
+			var err error
+			switch {
+			case operation.Action == types.TransactionCheck:
+				continue
+			case operation.Action == types.TransactionCreate:
+				err = tx.Create(docs[i], outputs[i])
+			case outputs[i] == nil:
+				err = tx.Delete(docs[i])
+			default:
+				err = tx.Set(docs[i], outputs[i])
+			}
+			if err != nil {
+				return types.NewTransactionFailed(i, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to apply transaction", err)
+		return nil, base.ContextError(req.Context(), err)
+	}
+
+	// Create audit logs
+	if err := api.AuditTransaction(transaction, previous, outputs); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
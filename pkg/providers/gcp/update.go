@@ -0,0 +1,136 @@
+package gcp
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+)
+
+// Update : Update an item
+func (api FirestoreAPI) Update(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, requiredFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the list of updates
+	var updates []firestore.Update
+	for key, value := range item {
+		updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{key}, Value: value})
+	}
+
+	return api.update(request, user, partitionKey, item, updates, auditAction)
+}
+
+// Patch : Partially update an item. Fields with a null value are removed from the record.
+func (api FirestoreAPI) Patch(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the list of updates
+	var updates []firestore.Update
+	for key, value := range item {
+		if value == nil {
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{key}, Value: firestore.Delete})
+		} else {
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{key}, Value: value})
+		}
+	}
+
+	return api.update(request, user, partitionKey, item, updates, auditAction)
+}
+
+// update : Apply updates to the item matching the partition key, as long as it satisfies the user's update filters
+func (api FirestoreAPI) update(request types.Request, user *types.User, partitionKey types.Key, item map[string]interface{}, updates []firestore.Update, auditAction string) (interface{}, error) {
+	var previous, output types.Record
+
+	// Build pre-condition filters. These are checked against the item inside a transaction and
+	// throw an error if the user is not permitted to update the item
+	conditions, err := api.filtering.Filter(user, nil, base.FilterActionUpdate)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return nil, err
+	}
+
+	// Update the item
+	id, err := api.docID(partitionKey)
+	if err != nil {
+		return nil, err
+	}
+	doc := api.Client.Collection(api.Config.DataTable).Doc(id)
+	err = api.Client.RunTransaction(request.Context(), func(ctx context.Context, tx *firestore.Transaction) error {
+		existing, err := api.existingItem(tx, doc, conditions, "Item does not exist or you do not have permission to update it")
+		if err != nil {
+			return err
+		}
+		if err := api.CheckVersion(request, existing); err != nil {
+			return err
+		}
+
+		// Build the new version of the item. Writes cannot be read back within the transaction.
+		previous = make(types.Record, len(existing))
+		for key, value := range existing {
+			previous[key] = value
+		}
+		output = existing
+		for _, update := range updates {
+			if update.Value == firestore.Delete {
+				delete(output, update.FieldPath[0])
+			} else {
+				output[update.FieldPath[0]] = update.Value
+			}
+		}
+
+		if len(updates) == 0 {
+			return nil
+		}
+
+		// The version is only moved on when the item is written
+		if api.Versioned() {
+			api.IncrementVersion(output)
+			field := api.Config.VersionField
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{field}, Value: output[field]})
+		}
+
+		return tx.Update(doc, updates)
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to update item", err)
+		return nil, base.ContextError(request.Context(), err)
+	}
+
+	// Create audit log
+	if err := api.WriteChangeAuditLog(auditAction, request, user, partitionKey, item, previous, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// existingItem : Fetch an item within a transaction, returning a bad request error if it does not
+// exist or does not satisfy the conditions
+func (api FirestoreAPI) existingItem(tx *firestore.Transaction, doc *firestore.DocumentRef, conditions interface{}, message string) (types.Record, error) {
+	snapshot, err := tx.Get(doc)
+	if err != nil {
+		if isCode(err, codes.NotFound) {
+			return nil, &types.BadRequest{Message: message}
+		}
+
+		return nil, err
+	}
+
+	record := types.Record(snapshot.Data())
+	if !toCondition(conditions).Matches(record) {
+		return nil, &types.BadRequest{Message: message}
+	}
+
+	return record, nil
+}
@@ -0,0 +1,83 @@
+package gcp
+
+import (
+	"context"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetAuth : Fetch an auth identity from the collection
+// Responses:
+//   - nil, nil: user does not exist
+//   - nil, error: error while fetching user
+//   - user, nil: found user
+func (api FirestoreAPI) GetAuth(ctx context.Context, id string) (*types.User, error) {
+	conditions, _ := api.filtering.Equals("id", id)
+
+	// Try to find user in the auth table
+	results, err := api.query(ctx, api.Config.AuthTable, conditions)
+	if err != nil {
+		log.WithError(err).Error("Failed to get user")
+		return nil, err
+	} else if len(results) == 0 {
+		// Failed to find user in the table
+		return nil, nil
+	}
+
+	return decode[types.User](results[0])
+}
+
+// GetGroup : Fetch a group from the collection
+// Responses:
+//   - nil, nil: group does not exist
+//   - nil, error: error while fetching group
+//   - user, nil: found group
+func (api FirestoreAPI) GetGroup(ctx context.Context, id string) (*types.Group, error) {
+	conditions, _ := api.filtering.Equals("group_id", id)
+
+	// Try to find group in the group table
+	results, err := api.query(ctx, api.Config.GroupTable, conditions)
+	if err != nil {
+		log.WithError(err).Error("Failed to get group")
+		return nil, err
+	} else if len(results) == 0 {
+		// Group is not in the table
+		return nil, nil
+	}
+
+	return decode[types.Group](results[0])
+}
+
+// GetEntitlements: Fetch entitlements from the database
+func (api FirestoreAPI) GetEntitlements(ctx context.Context, entitlementIDs []string) ([]types.User, error) {
+	var entitlements []types.User
+
+	// Firestore limits the number of values in an IN query, so search in batches
+	for start := 0; start < len(entitlementIDs); start += maxInValues {
+		end := start + maxInValues
+		if end > len(entitlementIDs) {
+			end = len(entitlementIDs)
+		}
+
+		// Search for the entitlement ids
+		conditions, err := api.filtering.MultiFilter(nil, "id", entitlementIDs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results, err := api.query(ctx, api.Config.AuthTable, conditions)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			entitlement, err := decode[types.User](result)
+			if err != nil {
+				return nil, err
+			}
+			entitlements = append(entitlements, *entitlement)
+		}
+	}
+
+	return entitlements, nil
+}
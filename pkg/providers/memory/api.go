@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// MemoryAPI : API, based off of Scoutr, that keeps all tables in memory. Useful for tests and local development.
+type MemoryAPI struct {
+	*base.Scoutr
+	store *store
+}
+
+// table : A table of records, indexed by their encoded key
+type table struct {
+	keySchema []string
+	items     map[string]types.Record
+}
+
+// store : All tables used by the API. Records are copied in and out of the store so callers never share
+// maps with it.
+type store struct {
+	sync.RWMutex
+	tables    map[string]*table
+	auditLogs []types.AuditLog
+	chainHead base.AuditChainHead
+}
+
+// NewMemoryAPI : Initialize an API backed by in-memory tables
+func NewMemoryAPI(memoryConfig config.MemoryConfig) MemoryAPI {
+	api := MemoryAPI{
+		store: &store{
+			tables: make(map[string]*table),
+		},
+		Scoutr: &base.Scoutr{
+			Config: memoryConfig.Config,
+		},
+	}
+
+	// Select the audit sink
+	sink, err := base.NewAuditSink(api.Config, map[string]base.AuditSink{
+		base.AuditSinkTable: api.AuditTableSink(),
+	}, api.AuditChainStore())
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize the audit sink")
+	}
+	api.AuditSink = sink
+
+	api.ScoutrBase = api
+
+	// Default key schemas
+	keySchemas := map[string][]string{
+		api.Config.DataTable:  api.KeyFields(),
+		api.Config.AuthTable:  {"id"},
+		api.Config.GroupTable: {"group_id"},
+	}
+	for name, keySchema := range memoryConfig.KeySchemas {
+		keySchemas[name] = keySchema
+	}
+
+	for name, keySchema := range keySchemas {
+		if name != "" {
+			api.store.tables[name] = &table{
+				keySchema: keySchema,
+				items:     make(map[string]types.Record),
+			}
+		}
+	}
+
+	return api
+}
+
+// PutItem : Store an item in a table, replacing any existing item with the same key. The item may be
+// a map or any struct that can be converted to JSON, such as a types.User or types.Group.
+func (api MemoryAPI) PutItem(tableName string, item interface{}) error {
+	record, err := clone(item)
+	if err != nil {
+		return err
+	}
+
+	api.store.Lock()
+	defer api.store.Unlock()
+
+	t, err := api.store.table(tableName)
+	if err != nil {
+		return err
+	}
+
+	id, err := t.key(record)
+	if err != nil {
+		return err
+	}
+
+	t.items[id] = record
+
+	return nil
+}
+
+// table : Look up a table by name
+func (s *store) table(name string) (*table, error) {
+	t, ok := s.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("table '%s' does not exist", name)
+	}
+
+	return t, nil
+}
+
+// key : Encode the key attributes of a record. All attributes in the key schema are required.
+func (t *table) key(record map[string]interface{}) (string, error) {
+	var values []interface{}
+	var missing []string
+	for _, name := range t.keySchema {
+		value, ok := record[name]
+		if !ok || value == nil {
+			missing = append(missing, name)
+			continue
+		}
+		values = append(values, fmt.Sprint(value))
+	}
+
+	if len(missing) > 0 {
+		return "", &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: %v", missing),
+		}
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// keyOf : Extract the key attributes from a record
+func (t *table) keyOf(record map[string]interface{}) map[string]interface{} {
+	key := make(map[string]interface{})
+	for _, name := range t.keySchema {
+		key[name] = record[name]
+	}
+
+	return key
+}
+
+// ids : Encoded keys of all records in the table, in sorted order
+func (t *table) ids() []string {
+	ids := make([]string, 0, len(t.items))
+	for id := range t.items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// filter : Copy the records in a table that match a filter. The filter is first run against an empty record so that
+// invalid filters are reported even when the table is empty.
+func (api MemoryAPI) filter(tableName string, fn func(*base.LocalFiltering) (interface{}, error)) ([]types.Record, error) {
+	page, err := api.filterPage(tableName, 0, "", fn)
+	return page.Items, err
+}
+
+// filterPage : Copy up to limit records that match a filter, in key order, starting after the record in the
+// continuation token. A limit of 0 returns all matching records.
+func (api MemoryAPI) filterPage(tableName string, limit int, next string, fn func(*base.LocalFiltering) (interface{}, error)) (types.Page[types.Record], error) {
+	page := types.Page[types.Record]{Items: []types.Record{}}
+
+	if _, err := fn(base.NewLocalFilter(nil)); err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	var after string
+	if _, err := base.DecodeToken(next, &after); err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	api.store.RLock()
+	defer api.store.RUnlock()
+
+	t, err := api.store.table(tableName)
+	if err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	var last string
+	for _, id := range t.ids() {
+		if after != "" && id <= after {
+			continue
+		}
+
+		record := t.items[id]
+		f := base.NewLocalFilter(record)
+		conditions, err := fn(f)
+		if err != nil {
+			return types.Page[types.Record]{}, err
+		}
+
+		if !f.Matches(conditions) {
+			continue
+		}
+
+		// Another match means there is a following page
+		if limit > 0 && len(page.Items) == limit {
+			page.Next, err = base.EncodeToken(last)
+			if err != nil {
+				return types.Page[types.Record]{}, err
+			}
+			break
+		}
+
+		item, err := clone(record)
+		if err != nil {
+			return types.Page[types.Record]{}, err
+		}
+		page.Items = append(page.Items, item)
+		last = id
+	}
+
+	return page, nil
+}
+
+// clone : Deep copy a value into a record, normalizing it the same way a JSON document store would
+func clone(value interface{}) (types.Record, error) {
+	var output types.Record
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// decode : Convert a record into a struct using its JSON tags
+func decode[T any](record types.Record) (*T, error) {
+	var output *T
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
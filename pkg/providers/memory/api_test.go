@@ -0,0 +1,1523 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/memory"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/utils"
+)
+
+var allEndpoints = []types.PermittedEndpoint{
+	{Endpoint: ".*", Method: "GET"},
+	{Endpoint: ".*", Method: "POST"},
+	{Endpoint: ".*", Method: "PUT"},
+	{Endpoint: ".*", Method: "PATCH"},
+	{Endpoint: ".*", Method: "DELETE"},
+}
+
+func newAPI(t *testing.T, permissions types.Permissions) memory.MemoryAPI {
+	api := memory.NewMemoryAPI(config.MemoryConfig{
+		Config: config.Config{
+			DataTable:  "data",
+			AuthTable:  "auth",
+			GroupTable: "groups",
+			AuditTable: "audit",
+			PrimaryKey: "id",
+		},
+	})
+
+	if permissions.PermittedEndpoints == nil {
+		permissions.PermittedEndpoints = allEndpoints
+	}
+
+	user := types.User{
+		ID:          "user1",
+		Username:    "user1",
+		Name:        "User One",
+		Email:       "user1@example.com",
+		Permissions: permissions,
+	}
+	if err := api.PutItem("auth", user); err != nil {
+		t.Fatal(err)
+	}
+
+	items := []map[string]interface{}{
+		{"id": "1", "name": "alpha", "status": "active", "owner": "a", "count": 1},
+		{"id": "2", "name": "beta", "status": "locked", "owner": "b", "count": 2},
+		{"id": "3", "name": "gamma", "status": "active", "owner": "a", "count": 3},
+	}
+	for _, item := range items {
+		if err := api.PutItem("data", item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return api
+}
+
+func request(method string, path string) types.Request {
+	return types.Request{
+		User:   types.RequestUser{ID: "user1"},
+		Method: method,
+		Path:   path,
+	}
+}
+
+func TestCreate(t *testing.T) {
+	api := newAPI(t, types.Permissions{})
+
+	if err := api.Create(request("POST", "/items/"), map[string]interface{}{"id": "4", "name": "delta"}, nil, nil, base.AuditActionCreate); err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := api.Get(request("GET", "/item/4"), types.Key{"id": "4"})
+	if err != nil {
+		t.Fatal(err)
+	} else if record["name"] != "delta" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	// Duplicate keys are rejected
+	err = api.Create(request("POST", "/items/"), map[string]interface{}{"id": "4"}, nil, nil, base.AuditActionCreate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	// Key fields are required
+	err = api.Create(request("POST", "/items/"), map[string]interface{}{"name": "epsilon"}, nil, nil, base.AuditActionCreate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+}
+
+func TestCreateFilters(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		CreateFilters: []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+	})
+
+	if err := api.Create(request("POST", "/items/"), map[string]interface{}{"id": "4", "owner": "a"}, nil, nil, base.AuditActionCreate); err != nil {
+		t.Fatal(err)
+	}
+
+	err := api.Create(request("POST", "/items/"), map[string]interface{}{"id": "5", "owner": "b"}, nil, nil, base.AuditActionCreate)
+	if _, ok := err.(*types.Unauthorized); !ok {
+		t.Errorf("Expected unauthorized, got %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	api := newAPI(t, types.Permissions{})
+
+	tests := []struct {
+		name     string
+		params   map[string][]string
+		expected []string
+	}{
+		{"All", nil, []string{"1", "2", "3"}},
+		{"Equals", map[string][]string{"status": {"active"}}, []string{"1", "3"}},
+		{"Or", map[string][]string{"name": {"alpha", "beta"}}, []string{"1", "2"}},
+		{"Contains", map[string][]string{"name__contains": {"amm"}}, []string{"3"}},
+		{"GreaterThan", map[string][]string{"count__gt": {"1"}}, []string{"2", "3"}},
+		{"Between", map[string][]string{"count__between": {`["2", "3"]`}}, []string{"2", "3"}},
+		{"NotIn", map[string][]string{"owner__notin": {`["a"]`}}, []string{"2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := request("GET", "/items/")
+			req.QueryParams = test.params
+
+			records, err := api.List(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(records) != len(test.expected) {
+				t.Fatalf("Expected %d records, got %d", len(test.expected), len(records))
+			}
+			for i, id := range test.expected {
+				if records[i]["id"] != id {
+					t.Errorf("Expected record %s, got %v", id, records[i]["id"])
+				}
+			}
+		})
+	}
+
+	// Unsupported operators are reported
+	req := request("GET", "/items/")
+	req.QueryParams = map[string][]string{"count__bad": {"1"}}
+	if _, err := api.List(req); err == nil {
+		t.Error("Expected an error for an invalid operator")
+	}
+}
+
+func TestReadPermissions(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		ReadFilters:   []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+		ExcludeFields: []string{"count"},
+	})
+
+	records, err := api.List(request("GET", "/items/"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 2 {
+		t.Errorf("Expected 2 records, got %d", len(records))
+	}
+	for _, record := range records {
+		if _, ok := record["count"]; ok {
+			t.Errorf("Excluded field was returned: %+v", record)
+		}
+	}
+
+	if _, err := api.Get(request("GET", "/item/2"), types.Key{"id": "2"}); err == nil {
+		t.Error("Expected not found error")
+	}
+
+	records, err = api.Search(request("POST", "/search/name"), "name", []string{"alpha", "beta"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 1 {
+		t.Errorf("Expected 1 record, got %d", len(records))
+	}
+
+	values, err := api.ListUniqueValues(request("GET", "/items/status"), "status")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(values) != 1 || values[0] != "active" {
+		t.Errorf("Unexpected values %+v", values)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		ReadFilters: []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}},
+	})
+	for _, item := range []map[string]interface{}{
+		{"id": "4", "name": "delta", "status": "active"},
+		{"id": "5", "name": "epsilon", "status": "locked"},
+	} {
+		if err := api.PutItem("data", item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Read filters are applied to each page
+	var ids []string
+	req := request("GET", "/items/")
+	req.Limit = 2
+	for pages := 0; ; pages++ {
+		page, err := api.ListPage(req)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(page.Items) > 2 || pages > 2 {
+			t.Fatalf("Unexpected page %+v", page)
+		}
+
+		for _, record := range page.Items {
+			ids = append(ids, record["id"].(string))
+		}
+
+		if page.Next == "" {
+			break
+		}
+		req.Next = page.Next
+	}
+	if len(ids) != 3 || ids[0] != "1" || ids[1] != "3" || ids[2] != "4" {
+		t.Errorf("Unexpected records %v", ids)
+	}
+
+	page, err := api.SearchPage(request("POST", "/search/name"), "name", []string{"alpha", "beta", "delta"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 2 || page.Next != "" {
+		t.Errorf("Unexpected page %+v", page)
+	}
+
+	// Audit log pages are listed newest first
+	logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{}, map[string][]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paged []types.AuditLog
+	req = request("GET", "/audit/")
+	req.Limit = 2
+	for {
+		page, err := api.ListAuditLogsPage(req, map[string]string{}, map[string][]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paged = append(paged, page.Items...)
+		if page.Next == "" {
+			break
+		}
+		req.Next = page.Next
+	}
+	if len(paged) != len(logs) || len(logs) < 3 || paged[0].Time != logs[0].Time {
+		t.Errorf("Expected %d audit logs, got %d", len(logs), len(paged))
+	}
+
+	// Invalid limits and tokens are rejected
+	for _, req := range []types.Request{
+		{User: types.RequestUser{ID: "user1"}, Method: "GET", Path: "/items/", Limit: -1},
+		{User: types.RequestUser{ID: "user1"}, Method: "GET", Path: "/items/", Limit: base.MaxPageLimit + 1},
+		{User: types.RequestUser{ID: "user1"}, Method: "GET", Path: "/items/", Next: "!"},
+	} {
+		if _, err := api.ListPage(req); err == nil {
+			t.Errorf("Expected an error for %+v", req)
+		} else if _, ok := err.(*types.BadRequest); !ok {
+			t.Errorf("Expected bad request, got %v", err)
+		}
+	}
+}
+
+func TestEndpointPermissions(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		PermittedEndpoints: []types.PermittedEndpoint{{Endpoint: "^/items/$", Method: "GET"}},
+	})
+
+	if _, err := api.List(request("GET", "/items/")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"})
+	if _, ok := err.(*types.Forbidden); !ok {
+		t.Errorf("Expected forbidden, got %v", err)
+	}
+
+	_, err = api.List(types.Request{User: types.RequestUser{ID: "unknown"}, Method: "GET", Path: "/items/"})
+	if _, ok := err.(*types.Unauthorized); !ok {
+		t.Errorf("Expected unauthorized, got %v", err)
+	}
+}
+
+func TestGroupsAndEntitlements(t *testing.T) {
+	api := newAPI(t, types.Permissions{})
+
+	group := types.Group{
+		ID: "readers",
+		Permissions: types.Permissions{
+			PermittedEndpoints: []types.PermittedEndpoint{{Endpoint: "^/items/$", Method: "GET"}},
+			ReadFilters:        []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}},
+		},
+	}
+	if err := api.PutItem("groups", group); err != nil {
+		t.Fatal(err)
+	}
+
+	entitlement := types.User{ID: "entitlement1", Groups: []string{"readers"}}
+	if err := api.PutItem("auth", entitlement); err != nil {
+		t.Fatal(err)
+	}
+
+	req := types.Request{
+		User: types.RequestUser{
+			ID: "oidc-user",
+			Data: &types.UserData{
+				Username:     "oidc-user",
+				Name:         "OIDC User",
+				Email:        "oidc@example.com",
+				Entitlements: []string{"entitlement1"},
+			},
+		},
+		Method: "GET",
+		Path:   "/items/",
+	}
+
+	records, err := api.List(req)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 2 {
+		t.Errorf("Expected 2 records, got %d", len(records))
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		UpdateFilters: []types.FilterField{{Field: "status", Operator: base.OperationNotEqual, Value: "locked"}},
+	})
+
+	output, err := api.Update(request("PUT", "/item/1"), map[string]interface{}{"id": "1"}, map[string]interface{}{"name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if record := output.(types.Record); record["name"] != "delta" || record["owner"] != "a" {
+		t.Errorf("Unexpected output %+v", output)
+	}
+
+	_, err = api.Update(request("PUT", "/item/2"), map[string]interface{}{"id": "2"}, map[string]interface{}{"name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	_, err = api.Update(request("PUT", "/item/9"), map[string]interface{}{"id": "9"}, map[string]interface{}{"name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	output, err = api.Patch(request("PATCH", "/item/1"), map[string]interface{}{"id": "1"}, map[string]interface{}{"owner": nil}, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := output.(types.Record)["owner"]; ok {
+		t.Errorf("Field should have been removed: %+v", output)
+	}
+
+	// Operators are not supported, so they cannot be used to get around the field permissions
+	_, err = api.Update(request("PUT", "/item/1"), map[string]interface{}{"id": "1"}, map[string]interface{}{"name__x": "delta"}, nil, nil, base.AuditActionUpdate)
+	if badRequest, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	} else if _, ok := badRequest.Messages["name__x"]; !ok {
+		t.Errorf("Unexpected errors %v", badRequest.Messages)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		DeleteFilters: []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+	})
+
+	if err := api.Delete(request("DELETE", "/item/1"), map[string]interface{}{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := api.Delete(request("DELETE", "/item/1"), map[string]interface{}{"id": "1"}); err == nil {
+		t.Error("Expected error deleting a missing item")
+	}
+
+	if err := api.Delete(request("DELETE", "/item/2"), map[string]interface{}{"id": "2"}); err == nil {
+		t.Error("Expected error deleting an item the user cannot delete")
+	}
+}
+
+func TestUpdateFieldPermissions(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		ExcludeFields:          []string{"owner"},
+		UpdateFieldsPermitted:  []string{"name", "status", "owner"},
+		UpdateFieldsRestricted: []string{"status"},
+	})
+
+	// Key attributes may be included in the item
+	if _, err := api.Update(request("PUT", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"id": "1", "name": "delta"}, nil, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every offending field is reported
+	item := map[string]interface{}{"name": "delta", "status": "locked", "owner": "b", "count": 4}
+	_, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, item, nil, base.AuditActionUpdate)
+	if badRequest, ok := err.(*types.BadRequest); !ok {
+		t.Fatalf("Expected bad request, got %v", err)
+	} else if len(badRequest.Messages) != 3 || badRequest.Messages["owner"] != "Field is excluded" ||
+		badRequest.Messages["status"] != "Field is restricted" || badRequest.Messages["count"] != "Field is not permitted" {
+		t.Errorf("Unexpected errors %v", badRequest.Messages)
+	}
+
+	// Nothing is written when the update is rejected
+	if record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	} else if record["status"] != "active" {
+		t.Errorf("Unexpected record %v", record)
+	}
+}
+
+func TestUpdateExistingItem(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		ReadFilters: []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+	})
+
+	var existing map[string]interface{}
+	validation := map[string]types.FieldValidation{
+		"name": utils.Validator(func(input *types.ValidationInput) (string, error) {
+			existing = input.ExistingItem
+			return "", nil
+		}),
+		"status": utils.StateMachine(map[string][]string{"active": {"locked"}}),
+	}
+
+	// Validators are given the item as it is before the update
+	if _, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"name": "delta"}, validation, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	} else if existing["name"] != "alpha" || existing["status"] != "active" {
+		t.Errorf("Unexpected existing item %v", existing)
+	}
+
+	if _, err := api.Update(request("PUT", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"status": "locked"}, validation, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Transitions are checked against the current state
+	_, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"status": "active"}, validation, base.AuditActionUpdate)
+	if badRequest, ok := err.(*types.BadRequest); !ok {
+		t.Fatalf("Expected bad request, got %v", err)
+	} else if badRequest.Messages["status"] != "Value cannot be changed from locked" {
+		t.Errorf("Unexpected errors %v", badRequest.Messages)
+	}
+
+	// Items the user cannot read are not validated
+	existing = nil
+	_, err = api.Patch(request("PATCH", "/item/2"), types.Key{"id": "2"}, map[string]interface{}{"name": "delta"}, validation, base.AuditActionUpdate)
+	if _, ok := err.(*types.NotFound); !ok {
+		t.Errorf("Expected not found, got %v", err)
+	} else if existing != nil {
+		t.Errorf("Validators should not have run, got existing item %v", existing)
+	}
+
+	// Nor are items the user cannot update
+	user, err := api.GetUser(context.Background(), "user1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.UpdateFilters = []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}}
+	if err := api.PutItem("auth", *user); err != nil {
+		t.Fatal(err)
+	}
+	_, err = api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"name": "delta"}, validation, base.AuditActionUpdate)
+	if _, ok := err.(*types.NotFound); !ok {
+		t.Errorf("Expected not found, got %v", err)
+	} else if existing != nil {
+		t.Errorf("Validators should not have run, got existing item %v", existing)
+	}
+	user.UpdateFilters = nil
+	if err := api.PutItem("auth", *user); err != nil {
+		t.Fatal(err)
+	}
+
+	// The item is only fetched when there are validators to run
+	fetches := 0
+	api.ScoutrBase = fetchCounter{ScoutrBase: api.ScoutrBase, fetches: &fetches}
+	if _, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"count": 5}, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	} else if fetches != 0 {
+		t.Errorf("Expected no fetches, got %d", fetches)
+	}
+	if _, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"count": 6}, validation, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	} else if fetches != 1 {
+		t.Errorf("Expected 1 fetch, got %d", fetches)
+	}
+}
+
+// fetchCounter : Records the items fetched through a provider
+type fetchCounter struct {
+	base.ScoutrBase
+	fetches *int
+}
+
+func (f fetchCounter) FetchItem(ctx context.Context, key types.Key) (types.Record, error) {
+	*f.fetches++
+	return f.ScoutrBase.FetchItem(ctx, key)
+}
+
+func TestUniqueValidation(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		ReadFilters: []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}},
+	})
+	validation := map[string]types.FieldValidation{"name": utils.Unique()}
+
+	// Items the user cannot read are still checked
+	err := api.Create(request("POST", "/items/"), map[string]interface{}{"id": "4", "name": "beta", "status": "active"}, validation, nil, base.AuditActionCreate)
+	if badRequest, ok := err.(*types.BadRequest); !ok {
+		t.Fatalf("Expected bad request, got %v", err)
+	} else if badRequest.Messages["name"] != "Value is already in use" {
+		t.Errorf("Unexpected errors %v", badRequest.Messages)
+	}
+
+	if err := api.Create(request("POST", "/items/"), map[string]interface{}{"id": "4", "name": "delta", "status": "active"}, validation, nil, base.AuditActionCreate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates that leave the value unchanged pass
+	if _, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"name": "alpha", "count": 5}, validation, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// The lookups are not audited
+	logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{"action": base.AuditActionSearch}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 0 {
+		t.Errorf("Unexpected audit logs %+v", logs)
+	}
+}
+
+func TestCompositeKeySchema(t *testing.T) {
+	api := memory.NewMemoryAPI(config.MemoryConfig{
+		Config: config.Config{
+			DataTable:  "data",
+			AuthTable:  "auth",
+			PrimaryKey: "id",
+			SortKey:    "version",
+		},
+	})
+
+	user := types.User{ID: "user1", Username: "user1", Name: "User One", Email: "user1@example.com"}
+	user.PermittedEndpoints = allEndpoints
+	if err := api.PutItem("auth", user); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []string{"1", "2"} {
+		item := map[string]interface{}{"id": "a", "version": version}
+		if err := api.Create(request("POST", "/items/"), item, nil, nil, base.AuditActionCreate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := api.List(request("GET", "/items/"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 2 {
+		t.Errorf("Expected 2 records, got %d", len(records))
+	}
+
+	// Items are addressed by their full key, or by partition key when it holds a single item
+	record, err := api.Get(request("GET", "/item/a/2"), types.Key{"id": "a", "version": "2"})
+	if err != nil {
+		t.Fatal(err)
+	} else if record["version"] != "2" {
+		t.Errorf("Unexpected record %v", record)
+	}
+
+	if _, err := api.Get(request("GET", "/item/a"), types.Key{"id": "a"}); err == nil {
+		t.Error("Expected error getting a partition with multiple items")
+	}
+	if _, err := api.Get(request("GET", "/item/a/3"), types.Key{"id": "a", "version": "3"}); err == nil {
+		t.Error("Expected error getting an item that does not exist")
+	}
+
+	// Writes require the full key
+	_, err = api.Update(request("PUT", "/item/a"), map[string]interface{}{"id": "a"}, map[string]interface{}{"name": "x"}, nil, nil, base.AuditActionUpdate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	if err := api.Delete(request("DELETE", "/item/a"), map[string]interface{}{"id": "a", "version": "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if record, err := api.Get(request("GET", "/item/a"), types.Key{"id": "a"}); err != nil {
+		t.Fatal(err)
+	} else if record["version"] != "1" {
+		t.Errorf("Unexpected record %v", record)
+	}
+}
+
+func TestAuditAndHistory(t *testing.T) {
+	api := newAPI(t, types.Permissions{})
+
+	// The audit log records the request body, as it would for an HTTP request
+	item := map[string]interface{}{"id": "4", "name": "delta"}
+	req := request("POST", "/items/")
+	req.Body = item
+	if err := api.Create(req, item, nil, nil, base.AuditActionCreate); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Update(request("PUT", "/item/4"), map[string]interface{}{"id": "4"}, map[string]interface{}{"name": "epsilon"}, nil, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Get(request("GET", "/item/4"), types.Key{"id": "4"}); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{"resource.id": "4"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{base.AuditActionGet, base.AuditActionUpdate, base.AuditActionCreate}
+	if len(logs) != len(expected) {
+		t.Fatalf("Expected %d audit logs, got %d", len(expected), len(logs))
+	}
+	for i, action := range expected {
+		if logs[i].Action != action {
+			t.Errorf("Expected action %s, got %s", action, logs[i].Action)
+		}
+	}
+
+	history, err := api.History(request("GET", "/history/4"), types.Key{"id": "4"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", len(history))
+	} else if history[0].Data["name"] != "epsilon" || history[1].Data["name"] != "delta" {
+		t.Errorf("Unexpected history %+v", history)
+	}
+
+	// Reading the history is audited
+	expected = append([]string{base.AuditActionGet}, expected...)
+	logs, err = api.ListAuditLogs(request("GET", "/audit/"), map[string]string{"resource.id": "4"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != len(expected) || logs[0].Action != base.AuditActionGet {
+		t.Fatalf("Expected a read log, got %+v", logs)
+	}
+
+	// Updates record the previous state of the item and what changed
+	if change := logs[2].Changes["name"]; logs[2].Previous["name"] != "delta" || change.Old != "delta" || change.New != "epsilon" {
+		t.Errorf("Unexpected update log %+v", logs[2])
+	}
+
+	// Logs written again by a retry are only stored once
+	if logs[0].ID == "" {
+		t.Fatal("Expected the audit log to have an ID")
+	} else if err := api.AuditSink.Write(context.Background(), logs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{"resource.id": "4"}, nil); err != nil {
+		t.Fatal(err)
+	} else if len(logs) != len(expected) {
+		t.Errorf("Expected %d audit logs, got %d", len(expected), len(logs))
+	}
+
+	// Fields the user cannot see are removed from the logs, without changing the stored logs
+	hidden := types.User{
+		ID:          "user2",
+		Username:    "user2",
+		Name:        "User Two",
+		Email:       "user2@example.com",
+		Permissions: types.Permissions{PermittedEndpoints: allEndpoints, ExcludeFields: []string{"name"}},
+	}
+	if err := api.PutItem("auth", hidden); err != nil {
+		t.Fatal(err)
+	}
+	req = request("GET", "/audit/")
+	req.User.ID = "user2"
+	page, err := api.ListAuditLogsPage(req, map[string]string{"resource.id": "4"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != len(expected) {
+		t.Fatalf("Expected %d audit logs, got %d", len(expected), len(page.Items))
+	}
+	if _, ok := page.Items[2].Previous["name"]; ok {
+		t.Errorf("Expected the previous state to exclude name, got %+v", page.Items[2].Previous)
+	}
+	if _, ok := page.Items[2].Changes["name"]; ok {
+		t.Errorf("Expected the changes to exclude name, got %+v", page.Items[2].Changes)
+	}
+	if body, _ := page.Items[3].Body.(map[string]interface{}); body == nil || body["name"] != nil {
+		t.Errorf("Expected the body to exclude name, got %+v", page.Items[3].Body)
+	}
+	if logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{"resource.id": "4"}, nil); err != nil {
+		t.Fatal(err)
+	} else if logs[2].Previous["name"] != "delta" || logs[2].Changes["name"].New != "epsilon" {
+		t.Errorf("Expected the stored logs to keep name, got %+v", logs[2])
+	}
+}
+
+func TestHistory(t *testing.T) {
+	api := newAPI(t, types.Permissions{ExcludeFields: []string{"owner"}})
+
+	// Item 1 existed before auditing was enabled, so its first audit log is an update
+	before := time.Now()
+	if _, err := api.Update(request("PUT", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"name": "alpha2"}, nil, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+	updated := time.Now()
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := api.History(request("GET", "/history/1"), types.Key{"id": "1"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", len(history))
+	}
+
+	deleted, update := history[0], history[1]
+	if deleted.Action != base.AuditActionDelete || deleted.Data != nil || deleted.Changes["name"].Old != "alpha2" {
+		t.Errorf("Unexpected delete entry %+v", deleted)
+	}
+	if change := update.Changes["name"]; update.Action != base.AuditActionUpdate || update.User.ID != "user1" || change.Old != "alpha" || change.New != "alpha2" || len(update.Changes) != 1 {
+		t.Errorf("Unexpected update entry %+v", update)
+	}
+	if update.Data["name"] != "alpha2" || update.Data["status"] != "active" {
+		t.Errorf("Unexpected update data %+v", update.Data)
+	}
+
+	// Excluded fields are hidden
+	if _, ok := update.Data["owner"]; ok {
+		t.Errorf("Expected owner to be excluded from %+v", update.Data)
+	}
+	if _, ok := deleted.Changes["owner"]; ok {
+		t.Errorf("Expected owner to be excluded from %+v", deleted.Changes)
+	}
+
+	// Only the requested actions are listed
+	if history, err := api.History(request("GET", "/history/1"), types.Key{"id": "1"}, nil, []string{base.AuditActionDelete}); err != nil {
+		t.Fatal(err)
+	} else if len(history) != 1 || history[0].Action != base.AuditActionDelete {
+		t.Errorf("Unexpected history %+v", history)
+	}
+
+	// The state before the first change comes from the previous state in its audit log
+	for at, name := range map[time.Time]string{before: "alpha", updated: "alpha2"} {
+		record, err := api.HistoryAt(request("GET", "/history/1"), types.Key{"id": "1"}, at)
+		if err != nil {
+			t.Fatal(err)
+		} else if record["name"] != name {
+			t.Errorf("Expected name %s at %s, got %+v", name, at, record)
+		}
+	}
+
+	if _, err := api.HistoryAt(request("GET", "/history/1"), types.Key{"id": "1"}, time.Now()); err == nil {
+		t.Error("Expected an error for a deleted item")
+	} else if _, ok := err.(*types.NotFound); !ok {
+		t.Errorf("Expected NotFound, got %T", err)
+	}
+}
+
+func TestHistoryReadFilters(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		ReadFilters: []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}},
+	})
+
+	if _, err := api.Update(request("PUT", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"count": 5}, nil, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another user changed an item that is locked
+	err := api.AuditSink.Write(context.Background(), types.AuditLog{
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Action:   base.AuditActionUpdate,
+		Resource: map[string]interface{}{"id": "2"},
+		Previous: map[string]interface{}{"id": "2", "name": "beta", "status": "locked", "owner": "b", "count": 2},
+		Changes:  map[string]types.AuditChange{"count": {Old: 2, New: 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := time.Now()
+
+	// Items the user cannot view have no history
+	var notFound *types.NotFound
+	if _, err := api.History(request("GET", "/history/2"), types.Key{"id": "2"}, nil, nil); !errors.As(err, &notFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+	if _, err := api.HistoryAt(request("GET", "/history/2"), types.Key{"id": "2"}, changed); !errors.As(err, &notFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+
+	// Reads of the history of items the user can view are audited
+	if _, err := api.History(request("GET", "/history/1"), types.Key{"id": "1"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := api.HistoryAt(request("GET", "/history/1"), types.Key{"id": "1"}, changed); err != nil {
+		t.Fatal(err)
+	} else if record["count"] != 5.0 {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{"resource.id": "1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 3 || logs[0].Action != base.AuditActionGet || logs[1].Action != base.AuditActionGet {
+		t.Errorf("Unexpected audit logs %+v", logs)
+	}
+}
+
+func TestAuditLogReadFilters(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		ReadFilters: []types.FilterField{{Field: "status", Operator: base.OperationEqual, Value: "active"}},
+	})
+
+	if _, err := api.Update(request("PUT", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"count": 5}, nil, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another user changed a locked item, locked an active item and created a locked item
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	auditLogs := []types.AuditLog{
+		{
+			Action:   base.AuditActionUpdate,
+			Resource: map[string]interface{}{"id": "2"},
+			Previous: map[string]interface{}{"id": "2", "name": "beta", "status": "locked", "owner": "b", "count": 2},
+			Changes:  map[string]types.AuditChange{"count": {Old: 2, New: 5}},
+		},
+		{
+			Action:   base.AuditActionUpdate,
+			Resource: map[string]interface{}{"id": "3"},
+			Previous: map[string]interface{}{"id": "3", "name": "gamma", "status": "active", "owner": "a", "count": 3},
+			Changes:  map[string]types.AuditChange{"status": {Old: "active", New: "locked"}},
+		},
+		{
+			Action:   base.AuditActionCreate,
+			Resource: map[string]interface{}{"id": "4"},
+			Changes:  map[string]types.AuditChange{"id": {New: "4"}, "status": {New: "locked"}},
+		},
+	}
+	for _, auditLog := range auditLogs {
+		auditLog.Time = now
+		if err := api.AuditSink.Write(context.Background(), auditLog); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the logs of items the user can read, both before and after the change, are listed
+	logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 1 || logs[0].Resource["id"] != "1" {
+		t.Errorf("Unexpected audit logs %+v", logs)
+	}
+
+	page, err := api.ListAuditLogsPage(request("GET", "/audit/"), map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Items) != 1 || page.Items[0].Resource["id"] != "1" {
+		t.Errorf("Unexpected audit logs %+v", page.Items)
+	}
+}
+
+func TestHistoryUnparseableTimes(t *testing.T) {
+	api := newAPI(t, types.Permissions{})
+	ctx := context.Background()
+
+	// Logs with a time that cannot be parsed are ordered before the rest, rather than in between them
+	start := time.Now().UTC()
+	auditLogs := []types.AuditLog{
+		{Time: start.Add(2 * time.Second).Format(time.RFC3339Nano), Action: base.AuditActionUpdate, Changes: map[string]types.AuditChange{"count": {Old: 2, New: 3}}},
+		{Time: "unknown", Action: base.AuditActionCreate, Changes: map[string]types.AuditChange{"id": {New: "9"}, "count": {New: 1}}},
+		{Time: start.Add(time.Second).Format(time.RFC3339Nano), Action: base.AuditActionUpdate, Changes: map[string]types.AuditChange{"count": {Old: 1, New: 2}}},
+	}
+	for _, auditLog := range auditLogs {
+		auditLog.Resource = map[string]interface{}{"id": "9"}
+		if err := api.AuditSink.Write(ctx, auditLog); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := api.History(request("GET", "/history/9"), types.Key{"id": "9"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 3 {
+		t.Fatalf("Expected 3 history entries, got %+v", history)
+	}
+	for i, count := range []float64{3, 2, 1} {
+		if history[i].Data["count"] != count {
+			t.Errorf("Expected count %v in history entry %d, got %+v", count, i, history[i])
+		}
+	}
+	if history[2].Action != base.AuditActionCreate {
+		t.Errorf("Expected the create to be the first version, got %+v", history[2])
+	}
+}
+
+func TestHistoryFromBodies(t *testing.T) {
+	api := newAPI(t, types.Permissions{})
+	ctx := context.Background()
+
+	// Logs written before changes were recorded only have the request body, which may be a raw JSON string
+	start := time.Now().UTC()
+	auditLogs := []types.AuditLog{
+		{Action: base.AuditActionCreate, Body: `{"id": "9", "name": "iota", "count": 1}`},
+		{Action: base.AuditActionGet},
+		{Action: base.AuditActionUpdate, Body: map[string]interface{}{"count": 2}},
+		{Action: "PATCH", Body: []interface{}{map[string]interface{}{"op": "replace"}}},
+	}
+	for i, auditLog := range auditLogs {
+		auditLog.Time = start.Add(time.Duration(i) * time.Second).Format(time.RFC3339Nano)
+		auditLog.Resource = map[string]interface{}{"id": "9"}
+		if err := api.AuditSink.Write(ctx, auditLog); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := api.History(request("GET", "/history/9"), types.Key{"id": "9"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 3 {
+		t.Fatalf("Expected 3 history entries, got %+v", history)
+	}
+
+	patch, update, create := history[0], history[1], history[2]
+	if create.Data["name"] != "iota" || create.Changes["name"].New != "iota" {
+		t.Errorf("Unexpected create entry %+v", create)
+	}
+	if update.Data["name"] != "iota" || update.Data["count"] != float64(2) || len(update.Changes) != 1 {
+		t.Errorf("Unexpected update entry %+v", update)
+	}
+	if patch.Data["count"] != float64(2) || len(patch.Changes) != 0 {
+		t.Errorf("Unexpected patch entry %+v", patch)
+	}
+
+	if _, err := api.HistoryAt(request("GET", "/history/9"), types.Key{"id": "9"}, start.Add(-time.Second)); err == nil {
+		t.Error("Expected an error before the item was created")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	api := newAPI(t, types.Permissions{})
+
+	before := time.Now()
+	if _, err := api.Update(request("PUT", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"name": "beta", "extra": "x"}, nil, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Item 1 existed before auditing was enabled, so it is restored to the previous state in its first audit log.
+	// Fields added since then are removed.
+	if _, err := api.Restore(request("POST", "/restore/1"), types.Key{"id": "1"}, types.HistoryPoint{Time: before}, nil); err != nil {
+		t.Fatal(err)
+	}
+	record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := record["extra"]; ok || record["name"] != "alpha" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	// Deleted items are created again
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Restore(request("POST", "/restore/1"), types.Key{"id": "1"}, types.HistoryPoint{Version: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	} else if record["name"] != "beta" || record["extra"] != "x" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	history, err := api.History(request("GET", "/history/1"), types.Key{"id": "1"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{base.AuditActionRestore, base.AuditActionDelete, base.AuditActionRestore, base.AuditActionUpdate}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d history entries, got %+v", len(expected), history)
+	}
+	for i, action := range expected {
+		if history[i].Action != action || history[i].Version != len(expected)-i {
+			t.Errorf("Unexpected history entry %+v", history[i])
+		}
+	}
+
+	// Versions that do not exist or were deletions cannot be restored
+	for _, version := range []int{2 + len(expected), 3} {
+		if _, err := api.Restore(request("POST", "/restore/1"), types.Key{"id": "1"}, types.HistoryPoint{Version: version}, nil); err == nil {
+			t.Errorf("Expected an error restoring version %d", version)
+		}
+	}
+}
+
+func TestRestoreExcludedFields(t *testing.T) {
+	api := newAPI(t, types.Permissions{ExcludeFields: []string{"owner"}})
+
+	before := time.Now()
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Items are rebuilt from the full audit logs, so the item is not created again without the fields the user
+	// cannot see
+	if _, err := api.Restore(request("POST", "/restore/1"), types.Key{"id": "1"}, types.HistoryPoint{Time: before}, nil); err == nil {
+		t.Error("Expected an error restoring an excluded field")
+	}
+	if record, err := api.FetchItem(context.Background(), types.Key{"id": "1"}); err != nil || record != nil {
+		t.Errorf("Expected the item to stay deleted, got %+v, %v", record, err)
+	}
+}
+
+func TestRestoreRestrictedFields(t *testing.T) {
+	api := newAPI(t, types.Permissions{UpdateFieldsRestricted: []string{"status"}})
+
+	// Another user changed the status before this user changed the name
+	err := api.AuditSink.Write(context.Background(), types.AuditLog{
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Action:   base.AuditActionUpdate,
+		Resource: map[string]interface{}{"id": "1"},
+		Previous: map[string]interface{}{"id": "1", "name": "alpha", "status": "new", "owner": "a", "count": 1},
+		Changes:  map[string]types.AuditChange{"status": {Old: "new", New: "active"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"name": "beta"}, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restores go through the update path, so only fields the user may update can be restored
+	if _, err := api.Restore(request("POST", "/restore/1"), types.Key{"id": "1"}, types.HistoryPoint{Version: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Restore(request("POST", "/restore/1"), types.Key{"id": "1"}, types.HistoryPoint{Time: time.Now().Add(-time.Hour)}, nil); err == nil {
+		t.Error("Expected an error restoring a restricted field")
+	}
+
+	if record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	} else if record["name"] != "alpha" || record["status"] != "active" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+}
+
+func TestAuditVerification(t *testing.T) {
+	api := memory.NewMemoryAPI(config.MemoryConfig{
+		Config: config.Config{
+			DataTable:    "data",
+			AuthTable:    "auth",
+			AuditTable:   "audit",
+			PrimaryKey:   "id",
+			AuditHMACKey: []byte("secret"),
+		},
+	})
+	user := types.User{ID: "user1", Username: "user1", Name: "User One", Email: "user1@example.com", Permissions: types.Permissions{PermittedEndpoints: allEndpoints}}
+	if err := api.PutItem("auth", user); err != nil {
+		t.Fatal(err)
+	}
+
+	item := map[string]interface{}{"id": "1", "name": "alpha", "count": 1}
+	req := request("POST", "/items/")
+	req.Body = item
+	if err := api.Create(req, item, nil, nil, base.AuditActionCreate); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Logs read back from the audit table still verify, and read logs do not expire
+	logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 2 || logs[0].Sequence != 2 || logs[0].ExpireTime != 0 {
+		t.Fatalf("Unexpected audit logs %+v", logs)
+	}
+
+	verification, err := api.VerifyAuditLogs(request("GET", "/audit-verify/"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !verification.Valid() || verification.Verified != 2 {
+		t.Errorf("Expected a valid chain, got %+v", verification)
+	}
+
+	// Another writer sharing the chain store continues the same chain instead of forking it
+	other, err := base.NewAuditSink(api.Config, map[string]base.AuditSink{
+		base.AuditSinkTable: api.AuditTableSink(),
+	}, api.AuditChainStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Write(context.Background(), api.NewAuditLog(base.AuditActionGet, request("GET", "/item/1"), &user, map[string]interface{}{"id": "1"}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err = api.VerifyAuditLogs(request("GET", "/audit-verify/"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !verification.Valid() || verification.Verified != 4 || verification.LastSequence != 4 {
+		t.Errorf("Expected a valid chain of 4 logs, got %+v", verification)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	api := memory.NewMemoryAPI(config.MemoryConfig{
+		Config: config.Config{
+			DataTable:          "data",
+			AuthTable:          "auth",
+			AuditTable:         "audit",
+			PrimaryKey:         "id",
+			SoftDelete:         true,
+			TrashRetentionDays: 7,
+		},
+	})
+	user := types.User{ID: "user1", Username: "user1", Name: "User One", Email: "user1@example.com", Permissions: types.Permissions{PermittedEndpoints: allEndpoints}}
+	if err := api.PutItem("auth", user); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []map[string]interface{}{{"id": "1", "name": "alpha"}, {"id": "2", "name": "beta"}} {
+		if err := api.PutItem("data", item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deleted items move to the trash and are hidden from everything else
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err == nil {
+		t.Error("Expected an error getting a deleted item")
+	}
+	if records, err := api.List(request("GET", "/items/")); err != nil {
+		t.Fatal(err)
+	} else if len(records) != 1 || records[0]["id"] != "2" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+	if records, err := api.Search(request("POST", "/search/id"), "id", []string{"1", "2"}); err != nil {
+		t.Fatal(err)
+	} else if len(records) != 1 {
+		t.Errorf("Unexpected records %+v", records)
+	}
+	if _, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"name": "gamma"}, nil, base.AuditActionUpdate); err == nil {
+		t.Error("Expected an error updating a deleted item")
+	}
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err == nil {
+		t.Error("Expected an error deleting a deleted item")
+	}
+
+	trash, err := api.ListTrash(request("GET", "/trash/"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(trash) != 1 || trash[0]["id"] != "1" || trash[0][base.DeletedByField] != "user1" || trash[0][base.DeletedAtField] == nil {
+		t.Fatalf("Unexpected trash %+v", trash)
+	}
+
+	// Restoring an item removes the deletion fields
+	if _, err := api.RestoreFromTrash(request("POST", "/trash/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	} else if _, ok := record[base.DeletedAtField]; ok {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	// Items are only purged once they have been in the trash for the retention period
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := api.PurgeTrash(request("DELETE", "/trash/")); err != nil {
+		t.Fatal(err)
+	} else if purged != 0 {
+		t.Errorf("Expected nothing to be purged, got %d", purged)
+	}
+
+	expired := time.Now().UTC().AddDate(0, 0, -8).Format(time.RFC3339)
+	if err := api.PutItem("data", map[string]interface{}{"id": "1", "name": "alpha", base.DeletedAtField: expired, base.DeletedByField: "user1"}); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := api.PurgeTrash(request("DELETE", "/trash/")); err != nil {
+		t.Fatal(err)
+	} else if purged != 1 {
+		t.Errorf("Expected 1 item to be purged, got %d", purged)
+	}
+	if trash, err := api.ListTrash(request("GET", "/trash/")); err != nil {
+		t.Fatal(err)
+	} else if len(trash) != 0 {
+		t.Errorf("Expected an empty trash, got %+v", trash)
+	}
+
+	// Every transition is audited
+	history, err := api.History(request("GET", "/history/1"), types.Key{"id": "1"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{base.AuditActionPurge, base.AuditActionDelete, base.AuditActionRestore, base.AuditActionDelete}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d history entries, got %+v", len(expected), history)
+	}
+	for i, action := range expected {
+		if history[i].Action != action {
+			t.Errorf("Expected action %s, got %s", action, history[i].Action)
+		}
+	}
+
+	// Items in the trash can also be restored to an earlier version
+	before := time.Now()
+	if err := api.Delete(request("DELETE", "/item/2"), types.Key{"id": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Restore(request("POST", "/restore/2"), types.Key{"id": "2"}, types.HistoryPoint{Time: before}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := api.Get(request("GET", "/item/2"), types.Key{"id": "2"}); err != nil {
+		t.Fatal(err)
+	} else if _, ok := record[base.DeletedAtField]; ok || record["name"] != "beta" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+}
+
+func TestRestoreFromTrashExcludedFields(t *testing.T) {
+	api := memory.NewMemoryAPI(config.MemoryConfig{
+		Config: config.Config{
+			DataTable:  "data",
+			AuthTable:  "auth",
+			AuditTable: "audit",
+			PrimaryKey: "id",
+			SoftDelete: true,
+		},
+	})
+	user := types.User{ID: "user1", Username: "user1", Name: "User One", Email: "user1@example.com", Permissions: types.Permissions{PermittedEndpoints: allEndpoints, ExcludeFields: []string{"owner"}}}
+	if err := api.PutItem("auth", user); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.PutItem("data", map[string]interface{}{"id": "1", "name": "alpha", "owner": "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The item in the trash is compared as it is stored, so the fields the user cannot see are left as they are
+	if _, err := api.Restore(request("POST", "/restore/1"), types.Key{"id": "1"}, types.HistoryPoint{Time: before}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := api.FetchItem(context.Background(), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	} else if _, ok := record[base.DeletedAtField]; ok || record["name"] != "alpha" || record["owner"] != "a" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	// Reading the item out of the trash is not audited as a get
+	logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, log := range logs {
+		if log.Action == base.AuditActionGet {
+			t.Errorf("Unexpected audit log %+v", log)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	api := memory.NewMemoryAPI(config.MemoryConfig{
+		Config: config.Config{
+			DataTable:    "data",
+			AuthTable:    "auth",
+			AuditTable:   "audit",
+			PrimaryKey:   "id",
+			VersionField: "version",
+		},
+	})
+	user := types.User{ID: "user1", Username: "user1", Name: "User One", Email: "user1@example.com", Permissions: types.Permissions{PermittedEndpoints: allEndpoints}}
+	if err := api.PutItem("auth", user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Items start at version 1, whatever version they are created with
+	if err := api.Create(request("POST", "/item/"), map[string]interface{}{"id": "1", "name": "alpha", "version": 7}, nil, nil, base.AuditActionCreate); err != nil {
+		t.Fatal(err)
+	}
+	record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	} else if tag := base.RecordETag(record, "version"); tag != `"1"` {
+		t.Errorf("Expected ETag \"1\", got %s", tag)
+	}
+
+	// Writes that expect the current version move it on
+	req := request("PATCH", "/item/1")
+	req.IfMatch = `"1"`
+	output, err := api.Patch(req, types.Key{"id": "1"}, map[string]interface{}{"name": "beta"}, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if version, _ := base.RecordVersion(output.(types.Record), "version"); version != 2 {
+		t.Errorf("Expected version 2, got %+v", output)
+	}
+
+	// Writes that expect an earlier version are rejected
+	var conflict *types.PreconditionFailed
+	if _, err := api.Update(req, types.Key{"id": "1"}, map[string]interface{}{"name": "gamma"}, nil, nil, base.AuditActionUpdate); err == nil {
+		t.Error("Expected an error updating an earlier version")
+	} else if !errors.As(err, &conflict) {
+		t.Errorf("Expected a precondition failure, got %v", err)
+	}
+	req.Method = "DELETE"
+	if err := api.Delete(req, types.Key{"id": "1"}); !errors.As(err, &conflict) {
+		t.Errorf("Expected a precondition failure, got %v", err)
+	}
+
+	// Writes that accept any version still move it on
+	req.IfMatch = "*"
+	if output, err := api.Update(req, types.Key{"id": "1"}, map[string]interface{}{"name": "gamma"}, nil, nil, base.AuditActionUpdate); err != nil {
+		t.Fatal(err)
+	} else if version, _ := base.RecordVersion(output.(types.Record), "version"); version != 3 {
+		t.Errorf("Expected version 3, got %+v", output)
+	}
+
+	// The version cannot be changed directly
+	var badRequest *types.BadRequest
+	if _, err := api.Patch(request("PATCH", "/item/1"), types.Key{"id": "1"}, map[string]interface{}{"version": 1}, nil, base.AuditActionUpdate); !errors.As(err, &badRequest) {
+		t.Errorf("Expected a bad request, got %v", err)
+	}
+
+	req.IfMatch = `W/"3"`
+	if err := api.Delete(req, types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		CreateFilters:          []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+		UpdateFilters:          []types.FilterField{{Field: "status", Operator: base.OperationNotEqual, Value: "locked"}},
+		UpdateFieldsRestricted: []string{"status"},
+	})
+
+	// Each item is created on its own, so bad items do not stop the rest
+	items := []map[string]interface{}{
+		{"id": "4", "name": "delta", "owner": "a"},
+		{"id": "1", "name": "duplicate", "owner": "a"},
+		{"id": "5", "name": "epsilon", "owner": "b"},
+		{"id": "6", "name": "zeta", "owner": "a"},
+	}
+	req := request("POST", "/batch/create/")
+	req.Body = items
+	results, err := api.BatchCreate(req, items, nil, nil, base.AuditActionCreate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, status := range []string{types.BatchStatusCreated, types.BatchStatusFailed, types.BatchStatusFailed, types.BatchStatusCreated} {
+		if results[i].Status != status {
+			t.Errorf("Expected item %d to be %s, got %+v", i, status, results[i])
+		}
+	}
+	if results[0].Key["id"] != "4" || results[1].Message == "" {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	// Failed updates carry the structured errors of the update
+	updates := []types.BatchUpdate{
+		{Key: types.Key{"id": "1"}, Item: map[string]interface{}{"name": "eta"}},
+		{Key: types.Key{"id": "2"}, Item: map[string]interface{}{"name": "theta"}},
+		{Key: types.Key{"id": "3"}, Item: map[string]interface{}{"status": "locked"}},
+	}
+	req = request("POST", "/batch/update/")
+	req.Body = updates
+	results, err = api.BatchUpdate(req, updates, nil, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != types.BatchStatusUpdated || results[0].Item.(types.Record)["name"] != "eta" {
+		t.Errorf("Unexpected result %+v", results[0])
+	}
+	if !results[1].Failed() || !results[2].Failed() || results[2].Messages["status"] != "Field is restricted" {
+		t.Errorf("Unexpected results %+v", results[1:])
+	}
+
+	results, err = api.BatchDelete(request("POST", "/batch/delete/"), []types.Key{{"id": "4"}, {"id": "9"}})
+	if err != nil {
+		t.Fatal(err)
+	} else if results[0].Status != types.BatchStatusDeleted || !results[1].Failed() {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	// Every item is audited on its own, with only that item as the body
+	for id, name := range map[string]string{"4": "delta", "6": "zeta", "1": "eta"} {
+		logs, err := api.ListAuditLogs(request("GET", "/audit/"+id), types.Key{"id": id}.AuditParams(), nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(logs) == 0 {
+			t.Fatalf("Expected item %s to be audited", id)
+		}
+
+		body, ok := logs[len(logs)-1].Body.(map[string]interface{})
+		if !ok || body["name"] != name {
+			t.Errorf("Unexpected body of the audit log of item %s: %+v", id, logs[len(logs)-1].Body)
+		}
+	}
+
+	// Users that cannot be authenticated fail the whole batch
+	req = request("POST", "/batch/delete/")
+	req.User.ID = "unknown"
+	if _, err := api.BatchDelete(req, []types.Key{{"id": "1"}}); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	api := newAPI(t, types.Permissions{
+		CreateFilters:          []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+		ReadFilters:            []types.FilterField{{Field: "owner", Operator: base.OperationEqual, Value: "a"}},
+		UpdateFilters:          []types.FilterField{{Field: "status", Operator: base.OperationNotEqual, Value: "locked"}},
+		UpdateFieldsRestricted: []string{"owner"},
+	})
+
+	operations := []types.TransactionOperation{
+		{Action: types.TransactionCreate, Item: map[string]interface{}{"id": "4", "name": "delta", "owner": "a"}},
+		{Action: types.TransactionUpdate, Key: types.Key{"id": "1"}, Item: map[string]interface{}{"name": "epsilon", "count": nil}},
+		{Action: types.TransactionCheck, Key: types.Key{"id": "3"}},
+	}
+	req := request("POST", "/transaction/")
+	req.Body = operations
+	outputs, err := api.Transaction(req, operations, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outputs[0]["name"] != "delta" || outputs[1]["name"] != "epsilon" || outputs[2]["name"] != "gamma" {
+		t.Errorf("Unexpected outputs %+v", outputs)
+	}
+	if count, ok := outputs[1]["count"]; !ok || count != nil {
+		t.Errorf("Expected null fields to be set as Update does, got %+v", outputs[1])
+	}
+
+	// Nothing is written unless every operation passes, and the failure identifies the operation
+	tests := []struct {
+		name       string
+		operations []types.TransactionOperation
+		index      int
+	}{
+		{"Filtered", []types.TransactionOperation{
+			{Action: types.TransactionDelete, Key: types.Key{"id": "3"}},
+			{Action: types.TransactionUpdate, Key: types.Key{"id": "2"}, Item: map[string]interface{}{"name": "zeta"}},
+		}, 1},
+		{"Exists", []types.TransactionOperation{
+			{Action: types.TransactionDelete, Key: types.Key{"id": "3"}},
+			{Action: types.TransactionCreate, Item: map[string]interface{}{"id": "1", "owner": "a"}},
+		}, 1},
+		{"Unreadable", []types.TransactionOperation{
+			{Action: types.TransactionDelete, Key: types.Key{"id": "3"}},
+			{Action: types.TransactionCheck, Key: types.Key{"id": "2"}},
+		}, 1},
+		{"Restricted", []types.TransactionOperation{
+			{Action: types.TransactionUpdate, Key: types.Key{"id": "3"}, Item: map[string]interface{}{"owner": "b"}},
+		}, 0},
+		{"Duplicate", []types.TransactionOperation{
+			{Action: types.TransactionDelete, Key: types.Key{"id": "3"}},
+			{Action: types.TransactionCheck, Key: types.Key{"id": "3"}},
+		}, 1},
+		{"Missing", []types.TransactionOperation{
+			{Action: types.TransactionDelete, Key: types.Key{"id": "9"}},
+		}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := api.Transaction(request("POST", "/transaction/"), test.operations, nil)
+
+			var failed *types.TransactionFailed
+			var badRequest *types.BadRequest
+			if !errors.As(err, &failed) || failed.Operation != test.index || !errors.As(err, &badRequest) {
+				t.Fatalf("Expected operation %d to fail with a bad request, got %v", test.index, err)
+			}
+
+			if _, err := api.Get(request("GET", "/item/3"), types.Key{"id": "3"}); err != nil {
+				t.Errorf("Expected item 3 to be kept, got %v", err)
+			}
+		})
+	}
+
+	// Writes are audited, with only the item of their operation as the body, but checks are not
+	names := map[string]string{"4": "delta", "1": "epsilon"}
+	for id, expected := range map[string]string{"4": base.AuditActionCreate, "1": base.AuditActionUpdate, "3": ""} {
+		logs, err := api.ListAuditLogs(request("GET", "/audit/"+id), types.Key{"id": id}.AuditParams(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var actions []string
+		for _, log := range logs {
+			if log.Action != base.AuditActionGet {
+				actions = append(actions, log.Action)
+				if body, ok := log.Body.(map[string]interface{}); !ok || body["name"] != names[id] {
+					t.Errorf("Unexpected body of the audit log of item %s: %+v", id, log.Body)
+				}
+			}
+		}
+		if (expected == "" && len(actions) > 0) || (expected != "" && (len(actions) != 1 || actions[0] != expected)) {
+			t.Errorf("Expected item %s to be audited with %q, got %v", id, expected, actions)
+		}
+	}
+}
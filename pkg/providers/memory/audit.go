@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// auditTable : Audit sink that keeps audit logs in memory, oldest first
+type auditTable struct {
+	store *store
+}
+
+// AuditTableSink : Sink that keeps audit logs in the in-memory audit table
+func (api MemoryAPI) AuditTableSink() base.AuditSink {
+	return auditTable{store: api.store}
+}
+
+// Write : Add an audit log to the table. Logs that are already in the table are skipped.
+func (sink auditTable) Write(ctx context.Context, auditLog types.AuditLog) error {
+	sink.store.Lock()
+	defer sink.store.Unlock()
+
+	if auditLog.ID != "" {
+		for _, existing := range sink.store.auditLogs {
+			if existing.ID == auditLog.ID {
+				return nil
+			}
+		}
+	}
+
+	sink.store.auditLogs = append(sink.store.auditLogs, auditLog)
+
+	return nil
+}
+
+// Query : List the audit logs in the table, newest first
+func (sink auditTable) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	sink.store.RLock()
+	defer sink.store.RUnlock()
+
+	return base.QueryAuditLogs(sink.store.auditLogs, filters, limit, next)
+}
+
+// auditChain : Head of the audit log chain, kept alongside the audit table
+type auditChain struct {
+	store *store
+}
+
+// AuditChainStore : Store that keeps the head of the audit log chain in memory
+func (api MemoryAPI) AuditChainStore() base.AuditChainStore {
+	return auditChain{store: api.store}
+}
+
+// Head : Read the head of the chain
+func (chain auditChain) Head(ctx context.Context) (base.AuditChainHead, error) {
+	chain.store.RLock()
+	defer chain.store.RUnlock()
+
+	return chain.store.chainHead, nil
+}
+
+// Advance : Move the head of the chain on, if it has not moved since it was read
+func (chain auditChain) Advance(ctx context.Context, from base.AuditChainHead, to base.AuditChainHead) (bool, error) {
+	chain.store.Lock()
+	defer chain.store.Unlock()
+
+	if chain.store.chainHead != from {
+		return false, nil
+	}
+	chain.store.chainHead = to
+
+	return true, nil
+}
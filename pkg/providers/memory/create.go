@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Create : Create an item
+func (api MemoryAPI) Create(req types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) error {
+	// Get the user
+	user, err := api.PrepareCreate(req, item, validation, requiredFields)
+	if err != nil {
+		// Bad user - pass the error through
+		return err
+	}
+
+	record, err := clone(api.InitialVersion(item))
+	if err != nil {
+		return err
+	}
+
+	api.store.Lock()
+	t, err := api.store.table(api.Config.DataTable)
+	if err != nil {
+		api.store.Unlock()
+		return err
+	}
+
+	// Make sure all the key fields were provided
+	id, err := t.key(record)
+	if err != nil {
+		api.store.Unlock()
+		return err
+	}
+
+	// Check if the key is already in use
+	if _, ok := t.items[id]; ok {
+		api.store.Unlock()
+		log.Errorln("Encountered error while attempting to create record: item already exists")
+		return &types.BadRequest{
+			Message: "Item already exists or you do not have permission to create it",
+		}
+	}
+
+	t.items[id] = record
+	api.store.Unlock()
+
+	// Create audit log, recording every field as a change
+	return api.WriteChangeAuditLog(auditAction, req, user, t.keyOf(record), nil, nil, record)
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Delete : Delete an item
+func (api MemoryAPI) Delete(req types.Request, partitionKey types.Key) error {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return err
+	}
+
+	// Delete the item, or move it to the trash when soft delete is enabled
+	softDelete, fields := api.SoftDeletes(req), base.TrashFields(user)
+	previous, output, err := api.write(req, user, partitionKey, base.FilterActionDelete, "Item does not exist or you do not have permission to delete it", func(record types.Record) types.Record {
+		if !softDelete {
+			return nil
+		}
+		for key, value := range fields {
+			record[key] = value
+		}
+		return record
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to delete item", err)
+		return err
+	}
+
+	// Create audit log
+	return api.WriteChangeAuditLog(base.DeleteAuditAction(req), req, user, partitionKey, nil, previous, output)
+}
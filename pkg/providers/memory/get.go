@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Get : Get an item from the table. Tables with a sort key can also be looked up by partition key alone, in
+// which case the partition must contain a single item.
+func (api MemoryAPI) Get(req types.Request, key types.Key) (types.Record, error) {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return nil, err
+	}
+
+	if partitionKey := api.KeyFields()[0]; key[partitionKey] == nil {
+		return nil, &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: [%s]", partitionKey),
+		}
+	}
+
+	// Find the item, applying all the filter criteria for the user. Items the user is not
+	// permitted to view are treated as though they do not exist
+	records, err := api.filter(api.Config.DataTable, func(f *base.LocalFiltering) (interface{}, error) {
+		conditions, err := f.Filter(user, nil, base.FilterActionRead)
+		if err != nil {
+			return nil, err
+		}
+
+		for field, value := range key {
+			condition, _ := f.Equals(field, value)
+			conditions = f.And(conditions, condition)
+		}
+		return conditions, nil
+	})
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, &types.NotFound{
+			Message: "Item does not exist or you do not have permission to view it",
+		}
+	} else if len(records) > 1 {
+		return nil, &types.BadRequest{
+			Message: "Multiple items returned",
+		}
+	}
+
+	// Filter the response
+	api.PostProcess(records[:1], user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionGet, req, user, key, nil); err != nil {
+		return nil, err
+	}
+
+	return records[0], nil
+}
+
+// FetchItem : Fetch an item by its full key, without checking the user's permissions
+func (api MemoryAPI) FetchItem(ctx context.Context, key types.Key) (types.Record, error) {
+	api.store.RLock()
+	defer api.store.RUnlock()
+
+	t, err := api.store.table(api.Config.DataTable)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := t.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	record, ok := t.items[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return clone(record)
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Transaction : Apply a set of operations atomically. Every operation is checked against the items while the store
+// is locked, and the items are only written once all of them pass.
+func (api MemoryAPI) Transaction(req types.Request, operations []types.TransactionOperation, validation map[string]types.FieldValidation) ([]types.Record, error) {
+	tx, err := api.PrepareTransaction(req, operations, validation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the filters are valid before taking the lock
+	for i := range operations {
+		if _, err := base.NewLocalFilter(nil).Filter(tx.User, nil, tx.FilterAction(i)); err != nil {
+			log.Errorln("Error encountered during filtering", err)
+			return nil, err
+		}
+	}
+
+	api.store.Lock()
+	t, err := api.store.table(api.Config.DataTable)
+	if err != nil {
+		api.store.Unlock()
+		return nil, err
+	}
+
+	ids := make([]string, len(operations))
+	previous := make([]types.Record, len(operations))
+	outputs := make([]types.Record, len(operations))
+	for i, operation := range operations {
+		if ids[i], previous[i], outputs[i], err = api.stage(t, tx, i); err != nil {
+			api.store.Unlock()
+			return nil, types.NewTransactionFailed(i, err)
+		}
+
+		if operation.Action != types.TransactionCheck {
+			outputs[i] = api.ApplyOperation(tx, i, previous[i])
+		}
+	}
+
+	for i, operation := range operations {
+		switch {
+		case operation.Action == types.TransactionCheck:
+			continue
+		case outputs[i] == nil:
+			delete(t.items, ids[i])
+		default:
+			record, err := clone(outputs[i])
+			if err != nil {
+				api.store.Unlock()
+				return nil, err
+			}
+			t.items[ids[i]] = record
+		}
+	}
+	api.store.Unlock()
+
+	// Create audit logs
+	if err := api.AuditTransaction(tx, previous, outputs); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// stage : Check the item of an operation of a transaction, returning its ID and its current state
+func (api MemoryAPI) stage(t *table, tx base.Transaction, i int) (string, types.Record, types.Record, error) {
+	id, err := t.key(tx.Keys[i])
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	existing, ok := t.items[id]
+	if tx.Operations[i].Action == types.TransactionCreate {
+		if ok {
+			return "", nil, nil, &types.BadRequest{Message: tx.Failure(i)}
+		}
+		return id, nil, nil, nil
+	}
+
+	// Make sure the item exists and the user is permitted to use it
+	if !ok {
+		return "", nil, nil, &types.BadRequest{Message: tx.Failure(i)}
+	}
+	if ok, err := permitted(tx.User, existing, tx.FilterAction(i)); err != nil {
+		return "", nil, nil, err
+	} else if !ok {
+		return "", nil, nil, &types.BadRequest{Message: tx.Failure(i)}
+	}
+
+	if err := api.CheckVersion(tx.OperationRequest(i), existing); err != nil {
+		return "", nil, nil, err
+	}
+
+	current, err := clone(existing)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return id, current, current, nil
+}
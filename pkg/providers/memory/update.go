@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Update : Update an item
+func (api MemoryAPI) Update(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, requiredFields)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := clone(item)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the item
+	previous, output, err := api.write(request, user, partitionKey, base.FilterActionUpdate, "Item does not exist or you do not have permission to update it", func(record types.Record) types.Record {
+		for key, value := range changes {
+			record[key] = value
+		}
+		return record
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to update item", err)
+		return nil, err
+	}
+
+	// Create audit log
+	if err := api.WriteChangeAuditLog(auditAction, request, user, partitionKey, item, previous, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// Patch : Partially update an item. Fields with a null value are removed from the record.
+func (api MemoryAPI) Patch(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := clone(item)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the item
+	previous, output, err := api.write(request, user, partitionKey, base.FilterActionUpdate, "Item does not exist or you do not have permission to update it", func(record types.Record) types.Record {
+		for key, value := range changes {
+			if value == nil {
+				delete(record, key)
+			} else {
+				record[key] = value
+			}
+		}
+		return record
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to update item", err)
+		return nil, err
+	}
+
+	// Create audit log
+	if err := api.WriteChangeAuditLog(auditAction, request, user, partitionKey, item, previous, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// write : Replace the item matching the key with the output of fn, as long as the item satisfies the
+// user's filters for the action and is at the version the request expects. If fn returns nil, the item is
+// deleted. Otherwise, its version is incremented. Returns the previous and new versions of the item.
+func (api MemoryAPI) write(req types.Request, user *types.User, partitionKey types.Key, action string, message string, fn func(types.Record) types.Record) (types.Record, types.Record, error) {
+	// Make sure the filters are valid before taking the lock
+	if _, err := base.NewLocalFilter(nil).Filter(user, nil, action); err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return nil, nil, err
+	}
+
+	api.store.Lock()
+	defer api.store.Unlock()
+
+	t, err := api.store.table(api.Config.DataTable)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id, err := t.key(partitionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Make sure the item exists and the user is permitted to modify it
+	existing, ok := t.items[id]
+	if !ok {
+		return nil, nil, &types.BadRequest{Message: message}
+	}
+
+	if ok, err := permitted(user, existing, action); err != nil {
+		return nil, nil, err
+	} else if !ok {
+		return nil, nil, &types.BadRequest{Message: message}
+	}
+
+	if err := api.CheckVersion(req, existing); err != nil {
+		return nil, nil, err
+	}
+
+	previous, err := clone(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record, err := clone(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record = fn(record)
+	if record == nil {
+		delete(t.items, id)
+		return previous, nil, nil
+	}
+
+	// The key of an item cannot be changed
+	for key, value := range t.keyOf(existing) {
+		record[key] = value
+	}
+	api.IncrementVersion(record)
+	t.items[id] = record
+
+	output, err := clone(record)
+	return previous, output, err
+}
+
+// permitted : Check if an item satisfies the user's filters for an action
+func permitted(user *types.User, record types.Record, action string) (bool, error) {
+	f := base.NewLocalFilter(record)
+	conditions, err := f.Filter(user, nil, action)
+	if err != nil {
+		return false, err
+	}
+
+	return f.Matches(conditions), nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// GetAuth : Fetch an auth identity from the table
+// Responses:
+//   - nil, nil: user does not exist
+//   - nil, error: error while fetching user
+//   - user, nil: found user
+func (api MemoryAPI) GetAuth(ctx context.Context, id string) (*types.User, error) {
+	record, err := api.getItem(ctx, api.Config.AuthTable, map[string]interface{}{"id": id})
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	return decode[types.User](record)
+}
+
+// GetGroup : Fetch a group from the table
+// Responses:
+//   - nil, nil: group does not exist
+//   - nil, error: error while fetching group
+//   - user, nil: found group
+func (api MemoryAPI) GetGroup(ctx context.Context, id string) (*types.Group, error) {
+	record, err := api.getItem(ctx, api.Config.GroupTable, map[string]interface{}{"group_id": id})
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	return decode[types.Group](record)
+}
+
+// GetEntitlements: Fetch entitlements from the database
+func (api MemoryAPI) GetEntitlements(ctx context.Context, entitlementIDs []string) ([]types.User, error) {
+	var entitlements []types.User
+
+	for _, id := range entitlementIDs {
+		entitlement, err := api.GetAuth(ctx, id)
+		if err != nil {
+			return nil, err
+		} else if entitlement != nil {
+			entitlements = append(entitlements, *entitlement)
+		}
+	}
+
+	return entitlements, nil
+}
+
+// getItem : Fetch a copy of a single item by its key. Returns nil if the item does not exist.
+func (api MemoryAPI) getItem(ctx context.Context, tableName string, key map[string]interface{}) (types.Record, error) {
+	// The store does no I/O, so a request can only be abandoned before it starts
+	if err := ctx.Err(); err != nil {
+		return nil, base.ContextError(ctx, err)
+	}
+
+	api.store.RLock()
+	defer api.store.RUnlock()
+
+	t, err := api.store.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := t.key(key)
+	if err != nil {
+		return nil, err
+	}
+
+	record, ok := t.items[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return clone(record)
+}
@@ -1,46 +1,279 @@
 package mongo
 
-// import (
-// 	"crypto/tls"
-// 	"net"
-// 	"time"
-
-// 	"github.com/globalsign/mgo"
-
-// 	log "github.com/sirupsen/logrus"
-
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// )
-
-// // MongoAPI : Implementation of Scoutr that interacts with MongoDB backends
-// type MongoAPI struct {
-// 	*base.Scoutr
-// 	Filtering MongoDBFiltering
-// 	Client    *mgo.Database
-// }
-
-// // Init : Initialize connection to MongoDB
-// func (api *MongoAPI) Init(address, database, username, password string) {
-// 	dialInfo := &mgo.DialInfo{
-// 		Addrs:    []string{address},
-// 		Timeout:  60 * time.Second,
-// 		Database: database,
-// 		Username: username,
-// 		Password: password,
-// 		DialServer: func(addr *mgo.ServerAddr) (net.Conn, error) {
-// 			return tls.Dial("tcp", addr.String(), &tls.Config{})
-// 		},
-// 	}
-
-// 	session, err := mgo.DialWithInfo(dialInfo)
-// 	if err != nil {
-// 		log.Fatalf("Failed to connect to MongoDB: %v", err)
-// 	}
-// 	session.SetSafe(&mgo.Safe{})
-// 	api.Client = session.DB(database)
-// }
-
-// // Close : Close connection with MongoDB
-// func (api *MongoAPI) Close() {
-// 	api.Client.Session.Close()
-// }
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoAPI : API, based off of Scoutr, used to talk to MongoDB (or CosmosDB) backends
+type MongoAPI struct {
+	*base.Scoutr
+	Client    *mongo.Database
+	filtering MongoFiltering
+}
+
+// NewMongoAPI : Connect to MongoDB with ctx and initialize the API
+func NewMongoAPI(ctx context.Context, mongoConfig config.MongoConfig) (MongoAPI, error) {
+	clientOptions := options.Client().
+		ApplyURI(mongoConfig.ConnectionString).
+		SetBSONOptions(&options.BSONOptions{
+			UseJSONStructTags: true,
+			DefaultDocumentM:  true,
+		})
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return MongoAPI{}, err
+	}
+
+	// Make sure the server is reachable
+	if err := client.Ping(ctx, nil); err != nil {
+		return MongoAPI{}, err
+	}
+
+	api := MongoAPI{
+		Client:    client.Database(mongoConfig.Database),
+		filtering: NewFilter(),
+		Scoutr: &base.Scoutr{
+			Config: mongoConfig.Config,
+		},
+	}
+
+	// Select the audit sink
+	sink, err := base.NewAuditSink(api.Config, map[string]base.AuditSink{
+		base.AuditSinkTable: api.AuditTableSink(),
+	}, api.AuditChainStore())
+	if err != nil {
+		return MongoAPI{}, err
+	}
+	api.AuditSink = sink
+
+	api.ScoutrBase = api
+
+	// Enforce uniqueness of the key so creates cannot overwrite records
+	if api.Config.PrimaryKey != "" {
+		var keys bson.D
+		for _, field := range api.KeyFields() {
+			keys = append(keys, bson.E{Key: field, Value: 1})
+		}
+
+		_, err := api.Client.Collection(api.Config.DataTable).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    keys,
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			return MongoAPI{}, err
+		}
+	}
+
+	return api, nil
+}
+
+// Close : Close connection with MongoDB, after writing any queued audit logs
+func (api MongoAPI) Close() error {
+	// Queued audit logs may be written to the database, so they are flushed first
+	ctx := context.Background()
+	auditErr := api.CloseAuditLogs(ctx)
+
+	return errors.Join(auditErr, api.Client.Client().Disconnect(ctx))
+}
+
+// keySelector : Build a selector that matches every attribute of a key
+func (api MongoAPI) keySelector(key types.Key) bson.D {
+	var selector interface{}
+	for name, value := range key {
+		condition, _ := api.filtering.Equals(name, value)
+		selector = api.filtering.And(selector, condition)
+	}
+
+	return toSelector(selector)
+}
+
+// NormalizeKey : Convert the numbers of a key to a single type, since MongoDB matches numbers by value whatever
+// their type. Strings never match numbers, so they are left as they are.
+func (api MongoAPI) NormalizeKey(ctx context.Context, key types.Key) (types.Key, error) {
+	normalized := make(types.Key, len(key))
+	for field, value := range key {
+		switch v := value.(type) {
+		case int:
+			normalized[field] = float64(v)
+		case int32:
+			normalized[field] = float64(v)
+		case int64:
+			normalized[field] = float64(v)
+		case float32:
+			normalized[field] = float64(v)
+		case json.Number:
+			number, err := v.Float64()
+			if err != nil {
+				return nil, &types.BadRequest{
+					Message: fmt.Sprintf("Key field %s is not a valid number", field),
+				}
+			}
+			normalized[field] = number
+		default:
+			normalized[field] = value
+		}
+	}
+
+	return normalized, nil
+}
+
+// idTypeOrder : BSON types of _id values, in the order MongoDB sorts them. The types in each group are compared by
+// value with each other.
+var idTypeOrder = [][]bsontype.Type{
+	{bsontype.MinKey},
+	{bsontype.Null, bsontype.Undefined},
+	{bsontype.Int32, bsontype.Int64, bsontype.Double, bsontype.Decimal128},
+	{bsontype.Symbol, bsontype.String},
+	{bsontype.EmbeddedDocument},
+	{bsontype.Binary},
+	{bsontype.ObjectID},
+	{bsontype.Boolean},
+	{bsontype.DateTime},
+	{bsontype.Timestamp},
+	{bsontype.Regex},
+	{bsontype.MaxKey},
+}
+
+// afterSelector : Build a selector that matches the documents sorted after an _id. Range operators only match
+// values of the same type, so values of the types sorted after it are matched by type.
+func afterSelector(id bson.RawValue, descending bool) bson.D {
+	op := "$gt"
+	if descending {
+		op = "$lt"
+	}
+	selector := bson.D{{Key: "_id", Value: bson.D{{Key: op, Value: id}}}}
+
+	rank := -1
+	for i, group := range idTypeOrder {
+		for _, idType := range group {
+			if idType == id.Type {
+				rank = i
+			}
+		}
+	}
+	if rank < 0 {
+		return selector
+	}
+
+	var later bson.A
+	for i, group := range idTypeOrder {
+		if (!descending && i > rank) || (descending && i < rank) {
+			// $type numbers are signed, so MinKey (0xFF) is -1
+			for _, idType := range group {
+				later = append(later, int32(int8(idType)))
+			}
+		}
+	}
+	if len(later) == 0 {
+		return selector
+	}
+
+	return bson.D{{Key: "$or", Value: bson.A{selector, bson.D{{Key: "_id", Value: bson.D{{Key: "$type", Value: later}}}}}}}
+}
+
+// find : Find up to limit documents that match a selector, ordered by _id and continuing after the _id in the
+// continuation token. The token holds the _id as BSON, so it keeps its type. A limit of 0 returns all matching
+// documents.
+func find[T any](ctx context.Context, collection *mongo.Collection, selector bson.D, descending bool, limit int, next string) (types.Page[T], error) {
+	page := types.Page[T]{Items: []T{}}
+
+	order := 1
+	if descending {
+		order = -1
+	}
+
+	// Continue after the last document of the previous page
+	var after []byte
+	if ok, err := base.DecodeToken(next, &after); err != nil {
+		return types.Page[T]{}, err
+	} else if ok {
+		if bson.Raw(after).Validate() != nil {
+			return types.Page[T]{}, &types.BadRequest{Message: "Invalid continuation token"}
+		}
+		id, err := bson.Raw(after).LookupErr("_id")
+		if err != nil {
+			return types.Page[T]{}, &types.BadRequest{Message: "Invalid continuation token"}
+		}
+		selector = bson.D{{Key: "$and", Value: bson.A{selector, afterSelector(id, descending)}}}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: order}})
+
+	// Fetch an extra document to find out if there is a following page
+	if limit > 0 {
+		opts.SetLimit(int64(limit + 1))
+	}
+
+	cursor, err := collection.Find(ctx, selector, opts)
+	if err != nil {
+		return types.Page[T]{}, base.ContextError(ctx, err)
+	}
+	defer cursor.Close(ctx)
+
+	var last bson.RawValue
+	for cursor.Next(ctx) {
+		if limit > 0 && len(page.Items) == limit {
+			token, err := bson.Marshal(bson.D{{Key: "_id", Value: last}})
+			if err != nil {
+				return types.Page[T]{}, err
+			}
+			if page.Next, err = base.EncodeToken(token); err != nil {
+				return types.Page[T]{}, err
+			}
+			break
+		}
+
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return types.Page[T]{}, err
+		}
+
+		// Drop the internal identifier
+		if record, ok := any(item).(types.Record); ok {
+			delete(record, "_id")
+		}
+
+		// The current document is reused by the cursor, so the _id is copied
+		id := cursor.Current.Lookup("_id")
+		last = bson.RawValue{Type: id.Type, Value: append([]byte(nil), id.Value...)}
+		page.Items = append(page.Items, item)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return types.Page[T]{}, base.ContextError(ctx, err)
+	}
+
+	return page, nil
+}
+
+// decode : Convert a BSON document into a struct using its JSON tags. This handles embedded structs
+// (such as types.Permissions) the same way the other providers do.
+func decode[T any](document bson.M) (*T, error) {
+	var output *T
+
+	// Drop the internal identifier
+	delete(document, "_id")
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
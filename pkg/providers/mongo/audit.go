@@ -1,132 +1,120 @@
 package mongo
 
-// import (
-// 	"time"
-
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	"github.com/globalsign/mgo/bson"
-// 	log "github.com/sirupsen/logrus"
-// )
-
-// // ListAuditLogs : List audit logs
-// func (api MongoAPI) ListAuditLogs(req types.Request, pathParams map[string]string, queryParams map[string][]string) ([]types.AuditLog, error) {
-// 	// Only fetch audit logs if the table is configured
-// 	if api.Config.AuditTable == "" {
-// 		return nil, &types.NotFound{
-// 			Message: "Audit logs are not enabled",
-// 		}
-// 	}
-
-// 	// Get the user
-// 	_, err := api.InitializeRequest(req)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return nil, err
-// 	}
-
-// 	// Generate dynamic search
-// 	searchKey, hasSearchKey := pathParams["search_key"]
-// 	searchValue, hasSearchValue := pathParams["search_value"]
-// 	if hasSearchKey && hasSearchValue {
-// 		// Map the search key and value into path params
-// 		pathParams[searchKey] = searchValue
-// 		delete(pathParams, "search_key")
-// 		delete(pathParams, "search_value")
-// 	}
-
-// 	// Merge pathParams into queryParams
-// 	for key, value := range pathParams {
-// 		queryParams[key] = []string{value}
-// 	}
-
-// 	// Create collection and filter
-// 	collection := api.Client.C(api.Config.AuditTable)
-// 	rawFilters, err := api.Filtering.Filter(nil, queryParams, base.FilterActionRead)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	// Make sure filters are cast as bson.D
-// 	var filters bson.D
-// 	if rawFilters == nil {
-// 		// No filters
-// 		filters = nil
-// 	} else if _, ok := rawFilters.(bson.DocElem); ok {
-// 		// Single filter
-// 		filters = bson.D{rawFilters.(bson.DocElem)}
-// 	} else {
-// 		// Multiple filters
-// 		filters = rawFilters.(bson.D)
-// 	}
-
-// 	// Query the data
-// 	var records []types.AuditLog
-// 	err = collection.Find(filters).Sort("time").All(&records)
-// 	if err != nil {
-// 		log.Errorf("Error querying collection: %v", err)
-// 		return nil, err
-// 	}
-
-// 	return records, nil
-// }
-
-// // auditLog : Creates an audit log
-// func (api MongoAPI) auditLog(action string, request types.Request, user types.User, resource *map[string]interface{}, changes *map[string]string) error {
-// 	// Only send audit logs if the table is configured
-// 	if api.Config.AuditTable == "" {
-// 		return nil
-// 	}
-
-// 	// Create audit log
-// 	now := time.Now().UTC()
-// 	auditLog := types.AuditLog{
-// 		Time: now.Format(time.RFC3339Nano),
-// 		User: types.AuditUser{
-// 			ID:        user.ID,
-// 			Name:      user.Name,
-// 			Username:  user.Username,
-// 			Email:     user.Email,
-// 			SourceIP:  request.SourceIP,
-// 			UserAgent: request.UserAgent,
-// 		},
-// 		Action: action,
-// 		Method: request.Method,
-// 		Path:   request.Path,
-// 	}
-
-// 	// Add expiry time for read events
-// 	if action == base.AuditActionGet || action == base.AuditActionList || action == base.AuditActionSearch {
-// 		auditLog.ExpireTime = now.AddDate(0, 0, api.Config.LogRetentionDays).Unix()
-// 	}
-
-// 	// Add query params
-// 	if len(request.QueryParams) > 0 {
-// 		auditLog.QueryParams = request.QueryParams
-// 	}
-
-// 	// Add body
-// 	if request.Body != nil {
-// 		auditLog.Body = request.Body
-// 	} else if changes != nil {
-// 		auditLog.Body = *changes
-// 	}
-
-// 	// Add resource
-// 	if resource != nil {
-// 		auditLog.Resource = *resource
-// 	}
-
-// 	// Create collection
-// 	collection := api.Client.C(api.Config.AuditTable)
-
-// 	// Add the record
-// 	err := collection.Insert(auditLog)
-// 	if err != nil {
-// 		log.Errorln("Failed to save audit log", err)
-// 		log.Infof("Failed audit log: '%v'", auditLog)
-// 		return err
-// 	}
-
-// 	return nil
-// }
+import (
+	"context"
+	"errors"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// chainSuffix : Suffix of the collection that holds the head of the audit log chain
+const chainSuffix = "_chain"
+
+// chainHeadID : Identifier of the document that holds the head of the audit log chain
+const chainHeadID = "head"
+
+// auditTable : Audit sink that stores audit logs in a collection
+type auditTable struct {
+	api  MongoAPI
+	name string
+}
+
+// AuditTableSink : Sink that stores audit logs in the audit collection of the database
+func (api MongoAPI) AuditTableSink() base.AuditSink {
+	return auditTable{api: api, name: api.Config.AuditTable}
+}
+
+// Write : Add an audit log to the collection. Logs are matched by their ID, so writing a log again replaces it.
+func (sink auditTable) Write(ctx context.Context, auditLog types.AuditLog) error {
+	collection := sink.api.Client.Collection(sink.name)
+
+	var err error
+	if auditLog.ID == "" {
+		_, err = collection.InsertOne(ctx, auditLog)
+	} else {
+		_, err = collection.ReplaceOne(ctx, bson.D{{Key: "id", Value: auditLog.ID}}, auditLog, options.Replace().SetUpsert(true))
+	}
+
+	return base.ContextError(ctx, err)
+}
+
+// Query : List the audit logs in the collection, newest first
+func (sink auditTable) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	// Build filters
+	conditions, err := sink.api.filtering.Filter(nil, filters, "")
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	// Query the data, newest first. Identifiers are assigned in insertion order
+	collection := sink.api.Client.Collection(sink.name)
+	return find[types.AuditLog](ctx, collection, toSelector(conditions), true, limit, next)
+}
+
+// auditChain : Head of the audit log chain, kept in a single document of the chain collection
+type auditChain struct {
+	api  MongoAPI
+	name string
+}
+
+// AuditChainStore : Store that keeps the head of the audit log chain in a collection next to the audit collection.
+// Returns nil if there is no audit collection.
+func (api MongoAPI) AuditChainStore() base.AuditChainStore {
+	if api.Config.AuditTable == "" {
+		return nil
+	}
+
+	return auditChain{api: api, name: api.Config.AuditTable + chainSuffix}
+}
+
+// Head : Read the head of the chain
+func (chain auditChain) Head(ctx context.Context) (base.AuditChainHead, error) {
+	var head base.AuditChainHead
+	err := chain.api.Client.Collection(chain.name).FindOne(ctx, bson.D{{Key: "_id", Value: chainHeadID}}).Decode(&head)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return base.AuditChainHead{}, nil
+	}
+
+	return head, base.ContextError(ctx, err)
+}
+
+// Advance : Move the head of the chain on, if it has not moved since it was read. The first log of the chain inserts
+// the document, and every log after that updates it.
+func (chain auditChain) Advance(ctx context.Context, from base.AuditChainHead, to base.AuditChainHead) (bool, error) {
+	collection := chain.api.Client.Collection(chain.name)
+
+	if from == (base.AuditChainHead{}) {
+		_, err := collection.InsertOne(ctx, bson.D{
+			{Key: "_id", Value: chainHeadID},
+			{Key: "sequence", Value: to.Sequence},
+			{Key: "hash", Value: to.Hash},
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		} else if err != nil {
+			return false, base.ContextError(ctx, err)
+		}
+
+		return true, nil
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.D{
+		{Key: "_id", Value: chainHeadID},
+		{Key: "sequence", Value: from.Sequence},
+		{Key: "hash", Value: from.Hash},
+	}, bson.D{{Key: "$set", Value: bson.D{
+		{Key: "sequence", Value: to.Sequence},
+		{Key: "hash", Value: to.Hash},
+	}}})
+	if err != nil {
+		return false, base.ContextError(ctx, err)
+	}
+
+	return result.MatchedCount == 1, nil
+}
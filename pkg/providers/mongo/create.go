@@ -1,44 +1,48 @@
 package mongo
 
-// import (
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	log "github.com/sirupsen/logrus"
-// )
+import (
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
-// // Create : Create an item
-// func (api MongoAPI) Create(request types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string) error {
-// 	// Get the user
-// 	user, err := api.InitializeRequest(request)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return err
-// 	}
+// Create : Create an item
+func (api MongoAPI) Create(req types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) error {
+	// Get the user
+	user, err := api.PrepareCreate(req, item, validation, requiredFields)
+	if err != nil {
+		// Bad user - pass the error through
+		return err
+	}
 
-// 	// Run data validation
-// 	if validation != nil {
-// 		log.Infoln("Running field validation")
-// 		err := api.ValidateFields(validation, requiredFields, item, nil)
-// 		if err != nil {
-// 			log.Errorln("Field validation error", err)
-// 			return err
-// 		}
-// 	}
+	// Make sure the key was provided
+	item = api.InitialVersion(item)
+	key := make(types.Key)
+	for _, field := range api.KeyFields() {
+		if _, ok := item[field]; !ok {
+			return &types.BadRequest{
+				Message: "Missing required fields: " + field,
+			}
+		}
+		key[field] = item[field]
+	}
 
-// 	// TODO: Build pre-condition filters
+	// Insert the item into the collection
+	collection := api.Client.Collection(api.Config.DataTable)
+	if _, err := collection.InsertOne(req.Context(), item); err != nil {
+		log.WithError(err).Errorln("Encountered error while attempting to create record")
 
-// 	// Insert the item
-// 	collection := api.Client.C(api.Config.DataTable)
-// 	err = collection.Insert(item)
-// 	if err != nil {
-// 		return err
-// 	}
+		// Check if the primary key is already in use
+		if mongo.IsDuplicateKeyError(err) {
+			return &types.BadRequest{
+				Message: "Item already exists or you do not have permission to create it",
+			}
+		}
 
-// 	// Create audit log
-// 	partitionKey := map[string]interface{}{api.Config.PrimaryKey: item[api.Config.PrimaryKey]}
-// 	if err := api.auditLog(base.AuditActionCreate, request, *user, &partitionKey, nil); err != nil {
-// 		log.Warnf("Failed to create audit log: %v", err)
-// 	}
+		return base.ContextError(req.Context(), err)
+	}
 
-// 	return nil
-// }
+	// Create audit log, recording every field as a change
+	return api.WriteChangeAuditLog(auditAction, req, user, key, nil, nil, item)
+}
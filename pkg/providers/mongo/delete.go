@@ -1,61 +1,82 @@
 package mongo
 
-// import (
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	"github.com/globalsign/mgo/bson"
-// 	log "github.com/sirupsen/logrus"
-// )
-
-// // Delete : Delete an item
-// func (api MongoAPI) Delete(req types.Request, partitionKey map[string]interface{}) error {
-// 	// Get the user
-// 	user, err := api.InitializeRequest(req)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return err
-// 	}
-
-// 	// Build pre-condition filters. This will apply all the filter criteria for the user to this selector query and
-// 	// throw a not found error if the user is not permitted to view the item
-// 	rawFilters, err := api.Filtering.Filter(
-// 		user,
-// 		map[string][]string{api.Config.PrimaryKey: []string{partitionKey[api.Config.PrimaryKey].(string)}},
-// 		base.FilterActionDelete,
-// 	)
-// 	if err != nil {
-// 		log.Errorf("Error generating rawFilters: %v", err)
-// 		return err
-// 	}
-
-// 	// Make sure filters are cast as bson.D
-// 	var selector bson.D
-// 	if _, ok := rawFilters.(bson.DocElem); ok {
-// 		// Single filter
-// 		selector = bson.D{rawFilters.(bson.DocElem)}
-// 	} else {
-// 		// Multiple filters
-// 		selector = rawFilters.(bson.D)
-// 	}
-
-// 	// Delete the item
-// 	collection := api.Client.C(api.Config.DataTable)
-// 	err = collection.Remove(selector)
-// 	if err != nil {
-// 		if err.Error() == "not found" {
-// 			return &types.NotFound{
-// 				Message: "Item does not exist or you do not have permission to view it",
-// 			}
-// 		} else {
-// 			log.Errorln("Error while attempting to delete item", err)
-// 			return err
-// 		}
-// 	}
-
-// 	// Create audit log
-// 	if err := api.auditLog(base.AuditActionDelete, req, *user, &partitionKey, nil); err != nil {
-// 		log.Warnf("Failed to create audit log: %v", err)
-// 	}
-
-// 	return nil
-// }
+import (
+	"errors"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Delete : Delete an item
+func (api MongoAPI) Delete(req types.Request, partitionKey types.Key) error {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return err
+	}
+
+	// Build pre-condition filters. This will apply all the filter criteria for the user to this selector query and
+	// throw an error if the user is not permitted to delete the item
+	conditions, err := api.filtering.Filter(user, nil, base.FilterActionDelete)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return err
+	}
+	conditions = api.filtering.And(conditions, api.keySelector(partitionKey))
+	selector, err := api.versionSelector(req, conditions)
+	if err != nil {
+		return err
+	}
+
+	// Delete the item, or move it to the trash when soft delete is enabled, keeping the previous version of it for
+	// the audit log
+	var previous, output types.Record
+	var result *mongo.SingleResult
+	softDelete, fields := api.SoftDeletes(req), base.TrashFields(user)
+	projection := bson.D{{Key: "_id", Value: 0}}
+	collection := api.Client.Collection(api.Config.DataTable)
+	if softDelete {
+		var set bson.D
+		for key, value := range fields {
+			set = append(set, bson.E{Key: key, Value: value})
+		}
+		updates := bson.D{{Key: "$set", Value: set}}
+		if api.Versioned() {
+			updates = append(updates, bson.E{Key: "$inc", Value: bson.D{{Key: api.Config.VersionField, Value: int64(1)}}})
+		}
+
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.Before).SetProjection(projection)
+		result = collection.FindOneAndUpdate(req.Context(), selector, updates, opts)
+	} else {
+		opts := options.FindOneAndDelete().SetProjection(projection)
+		result = collection.FindOneAndDelete(req.Context(), selector, opts)
+	}
+
+	if err := result.Decode(&previous); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return api.writeFailure(req, conditions, "Item does not exist or you do not have permission to delete it")
+		}
+
+		log.Errorln("Error while attempting to delete item", err)
+		return base.ContextError(req.Context(), err)
+	}
+
+	if softDelete {
+		output = make(types.Record, len(previous)+len(fields))
+		for key, value := range previous {
+			output[key] = value
+		}
+		for key, value := range fields {
+			output[key] = value
+		}
+		api.IncrementVersion(output)
+	}
+
+	// Create audit log
+	return api.WriteChangeAuditLog(base.DeleteAuditAction(req), req, user, partitionKey, nil, previous, output)
+}
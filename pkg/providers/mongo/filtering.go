@@ -1,221 +1,224 @@
 package mongo
 
-// import (
-// 	"encoding/json"
-// 	"fmt"
-
-// 	log "github.com/sirupsen/logrus"
-
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// 	"github.com/globalsign/mgo/bson"
-// )
-
-// // MongoDBFiltering : Used by the MongoAPI to perform filters against a MongoDB backend
-// type MongoDBFiltering struct {
-// 	base.FilterBase
-// }
-
-// // Operations : Map of supported operations for this filter provider
-// func (f *MongoDBFiltering) Operations() base.OperationMap {
-// 	return base.OperationMap{
-// 		"ne":         f.NotEqual,
-// 		"startswith": f.StartsWith,
-// 		"contains":   f.Contains,
-// 		// TODO: NotContains does not work right
-// 		//"notcontains": f.NotContains,
-// 		"exists":  f.Exists,
-// 		"gt":      f.GreaterThan,
-// 		"lt":      f.LessThan,
-// 		"ge":      f.GreaterThanEqual,
-// 		"le":      f.LessThanEqual,
-// 		"between": f.Between,
-// 		"in":      f.In,
-// 		"notin":   f.NotIn,
-// 	}
-// }
-
-// // And : Takes two conditions and performs an AND operation on them
-// func (f *MongoDBFiltering) And(conditions, condition interface{}) interface{} {
-// 	var output bson.D
-
-// 	if _, ok := conditions.(bson.DocElem); ok {
-// 		// If element, add the element
-// 		output = append(output, conditions.(bson.DocElem))
-// 	} else {
-// 		// If element array, add all items of the array
-// 		output = append(output, conditions.(bson.D)...)
-// 	}
-
-// 	if _, ok := condition.(bson.DocElem); ok {
-// 		// If element, add the element
-// 		output = append(output, condition.(bson.DocElem))
-// 	} else {
-// 		// If element array, add all items of the array
-// 		output = append(output, condition.(bson.D)...)
-// 	}
-
-// 	return output
-// }
-
-// // Equals : Standard equals operation
-// func (f *MongoDBFiltering) Equals(key string, value interface{}) interface{} {
-// 	return bson.DocElem{Name: key, Value: value}
-// }
-
-// // NotEqual : Standard not equals operation
-// func (f *MongoDBFiltering) NotEqual(key string, value interface{}) interface{} {
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{{
-// 			Name:  "$ne",
-// 			Value: value,
-// 		}},
-// 	}
-// }
-
-// // StartsWith : Check if string starts with a value
-// func (f *MongoDBFiltering) StartsWith(key string, value interface{}) interface{} {
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{{
-// 			Name:  "$regex",
-// 			Value: fmt.Sprintf("^%s.*", value),
-// 		}},
-// 	}
-// }
-
-// // Contains : Check if string is contained in record
-// func (f *MongoDBFiltering) Contains(key string, value interface{}) interface{} {
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{{
-// 			Name:  "$regex",
-// 			Value: value,
-// 		}},
-// 	}
-// }
-
-// // TODO: Feel like this *should* work, but it never returns results
-// // NotContains : Check if string is not contained in record
-// func (f *MongoDBFiltering) NotContains(key string, value interface{}) interface{} {
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.DocElem{
-// 			Name: "$not",
-// 			Value: bson.D{{
-// 				Name:  "$regex",
-// 				Value: value,
-// 			}},
-// 		},
-// 	}
-// }
-
-// // Exists : Check if attribute exists
-// func (f *MongoDBFiltering) Exists(key string, value interface{}) interface{} {
-// 	var exists bool
-// 	if value == "true" {
-// 		exists = true
-// 	} else if value == "false" {
-// 		exists = false
-// 	} else {
-// 		log.Warnf("Invalid value for EXISTS operation: %s", value)
-// 		return nil
-// 	}
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{{
-// 			Name:  "$exists",
-// 			Value: exists,
-// 		}},
-// 	}
-// }
-
-// // GreaterThan : Check if a value is greater than a string
-// func (f *MongoDBFiltering) GreaterThan(key string, value interface{}) interface{} {
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{{
-// 			Name:  "$gt",
-// 			Value: value,
-// 		}},
-// 	}
-// }
-
-// // LessThan : Check if a value is greater than a string
-// func (f *MongoDBFiltering) LessThan(key string, value interface{}) interface{} {
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{{
-// 			Name:  "$lt",
-// 			Value: value,
-// 		}},
-// 	}
-// }
-
-// // GreaterThanEqual : Check if a value is greater than a string
-// func (f *MongoDBFiltering) GreaterThanEqual(key string, value interface{}) interface{} {
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{{
-// 			Name:  "$gte",
-// 			Value: value,
-// 		}},
-// 	}
-// }
-
-// // LessThanEqual : Check if a value is greater than a string
-// func (f *MongoDBFiltering) LessThanEqual(key string, value interface{}) interface{} {
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{{
-// 			Name:  "$lte",
-// 			Value: value,
-// 		}},
-// 	}
-// }
-
-// // Between : Check for records that are between a low and high value
-// func (f *MongoDBFiltering) Between(key string, value interface{}) interface{} {
-// 	var valueList []string
-// 	err := json.Unmarshal([]byte(value.(string)), &valueList)
-// 	if err != nil {
-// 		log.Errorf("Failed to unmarshal value list for BETWEEN operation: %v", err)
-// 		return nil
-// 	}
-// 	return f.And(
-// 		f.GreaterThanEqual(key, valueList[0]),
-// 		f.LessThanEqual(key, valueList[1]),
-// 	)
-// }
-
-// // In : Find all records with a list of values
-// func (f *MongoDBFiltering) In(key string, values interface{}) interface{} {
-// 	var valueList []string
-// 	err := json.Unmarshal([]byte(values.(string)), &valueList)
-// 	if err != nil {
-// 		log.Errorf("Failed to unmarshal value list for IN operation: %v", err)
-// 		return nil
-// 	}
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{
-// 			{Name: "$in", Value: valueList},
-// 		},
-// 	}
-// }
-
-// // NotIn : Find all records not in a list of values
-// func (f *MongoDBFiltering) NotIn(key string, values interface{}) interface{} {
-// 	var valueList []string
-// 	err := json.Unmarshal([]byte(values.(string)), &valueList)
-// 	if err != nil {
-// 		log.Errorf("Failed to unmarshal value list for NOT IN operation: %v", err)
-// 		return nil
-// 	}
-// 	return bson.DocElem{
-// 		Name: key,
-// 		Value: bson.D{
-// 			{Name: "$nin", Value: valueList},
-// 		},
-// 	}
-// }
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MongoFiltering : Used by the MongoAPI to perform filters against a MongoDB backend
+type MongoFiltering struct {
+	base.Filtering
+}
+
+func NewFilter() MongoFiltering {
+	f := MongoFiltering{}
+	f.FilterBase = &f
+	f.ScoutrFilters = &f
+	return f
+}
+
+// Operations : Map of supported operations for this filter provider
+func (f *MongoFiltering) Operations() base.OperationMap {
+	return base.OperationMap{
+		base.OperationStartsWith:       f.StartsWith,
+		base.OperationEqual:            f.Equals,
+		base.OperationNotEqual:         f.NotEqual,
+		base.OperationContains:         f.Contains,
+		base.OperationNotContains:      f.NotContains,
+		base.OperationExists:           f.Exists,
+		base.OperationGreaterThan:      f.GreaterThan,
+		base.OperationLessThan:         f.LessThan,
+		base.OperationGreaterThanEqual: f.GreaterThanEqual,
+		base.OperationLessThanEqual:    f.LessThanEqual,
+		base.OperationBetween:          f.Between,
+		base.OperationIn:               f.In,
+		base.OperationNotIn:            f.NotIn,
+	}
+}
+
+// And : Takes two conditions and performs an AND operation on them
+func (f *MongoFiltering) And(condition1, condition2 interface{}) interface{} {
+	cond1, ok1 := condition1.(bson.D)
+	cond2, ok2 := condition2.(bson.D)
+
+	if ok1 && len(cond1) > 0 && ok2 && len(cond2) > 0 {
+		return bson.D{{Key: "$and", Value: bson.A{cond1, cond2}}}
+	} else if ok1 && len(cond1) > 0 {
+		return cond1
+	} else if ok2 && len(cond2) > 0 {
+		return cond2
+	} else {
+		return bson.D{}
+	}
+}
+
+// Or : Takes two conditions and performs an OR operation on them
+func (f *MongoFiltering) Or(condition1, condition2 interface{}) interface{} {
+	cond1, ok1 := condition1.(bson.D)
+	cond2, ok2 := condition2.(bson.D)
+
+	if ok1 && len(cond1) > 0 && ok2 && len(cond2) > 0 {
+		return bson.D{{Key: "$or", Value: bson.A{cond1, cond2}}}
+	} else if ok1 && len(cond1) > 0 {
+		return cond1
+	} else if ok2 && len(cond2) > 0 {
+		return cond2
+	} else {
+		return bson.D{}
+	}
+}
+
+// operator : Build a condition that applies a single query operator to a key
+func operator(key string, op string, value interface{}) bson.D {
+	return bson.D{{Key: key, Value: bson.D{{Key: op, Value: value}}}}
+}
+
+// Equals : Standard equals operation
+func (f *MongoFiltering) Equals(key string, value interface{}) (interface{}, error) {
+	return operator(key, "$eq", value), nil
+}
+
+// NotEqual : Standard not equals operation
+func (f *MongoFiltering) NotEqual(key string, value interface{}) (interface{}, error) {
+	return operator(key, "$ne", value), nil
+}
+
+// StartsWith : Find all records that contain items that start with a specific value
+func (f *MongoFiltering) StartsWith(key string, value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		log.Errorf("Failed to cast %+v to string", value)
+		return nil, fmt.Errorf("%+v could not be cast as a string", value)
+	}
+
+	return operator(key, "$regex", primitive.Regex{Pattern: "^" + regexp.QuoteMeta(s)}), nil
+}
+
+// Contains : Check if a value contains a string
+func (f *MongoFiltering) Contains(key string, value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		log.Errorf("Failed to cast %+v to string", value)
+		return nil, fmt.Errorf("%+v could not be cast as a string", value)
+	}
+
+	return operator(key, "$regex", primitive.Regex{Pattern: regexp.QuoteMeta(s)}), nil
+}
+
+// NotContains : Check for values that do not contain a string
+//
+// MongoDB only accepts a regex object (not the $regex operator) inside of $not
+func (f *MongoFiltering) NotContains(key string, value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		log.Errorf("Failed to cast %+v to string", value)
+		return nil, fmt.Errorf("%+v could not be cast as a string", value)
+	}
+
+	return operator(key, "$not", primitive.Regex{Pattern: regexp.QuoteMeta(s)}), nil
+}
+
+// Exists : Checks if an attribute exists. Only accepts true/false values. Returns nil for all other values.
+func (f *MongoFiltering) Exists(key string, value interface{}) (interface{}, error) {
+	if value == "true" {
+		return operator(key, "$exists", true), nil
+	} else if value == "false" {
+		return operator(key, "$exists", false), nil
+	} else {
+		return nil, fmt.Errorf("invalid value for Exists operation. Supported values are ['true'/'false']")
+	}
+}
+
+// GreaterThan : Check if a value is greater than a string
+func (f *MongoFiltering) GreaterThan(key string, value interface{}) (interface{}, error) {
+	return operator(key, "$gt", value), nil
+}
+
+// LessThan : Check if a value is less than a string
+func (f *MongoFiltering) LessThan(key string, value interface{}) (interface{}, error) {
+	return operator(key, "$lt", value), nil
+}
+
+// GreaterThanEqual : Check if a value is greater than or equal to a string
+func (f *MongoFiltering) GreaterThanEqual(key string, value interface{}) (interface{}, error) {
+	return operator(key, "$gte", value), nil
+}
+
+// LessThanEqual : Check if a value is less than or equal to a string
+func (f *MongoFiltering) LessThanEqual(key string, value interface{}) (interface{}, error) {
+	return operator(key, "$lte", value), nil
+}
+
+// Between : Check for records that are between a low and high value
+//
+// Operator: key__between=["1", "2"]
+func (f *MongoFiltering) Between(key string, values interface{}) (interface{}, error) {
+	valueList, err := unmarshalValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(valueList) != 2 {
+		return nil, fmt.Errorf("between operation requires exactly 2 values, but got %d", len(valueList))
+	}
+
+	return bson.D{{Key: key, Value: bson.D{
+		{Key: "$gte", Value: valueList[0]},
+		{Key: "$lte", Value: valueList[1]},
+	}}}, nil
+}
+
+// In : Find all records with a list of values
+func (f *MongoFiltering) In(key string, values interface{}) (interface{}, error) {
+	valueList, err := unmarshalValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return operator(key, "$in", valueList), nil
+}
+
+// NotIn : Find all records without a list of values
+func (f *MongoFiltering) NotIn(key string, values interface{}) (interface{}, error) {
+	valueList, err := unmarshalValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return operator(key, "$nin", valueList), nil
+}
+
+// unmarshalValues : Convert a JSON-encoded list of strings into a slice
+func unmarshalValues(values interface{}) ([]string, error) {
+	var valueList []string
+
+	// Convert to string
+	s, ok := values.(string)
+	if !ok {
+		log.Errorf("Failed to cast %+v to string", values)
+		return nil, fmt.Errorf("%+v could not be cast as a string", values)
+	}
+
+	// Unmarshal JSON
+	if err := json.Unmarshal([]byte(s), &valueList); err != nil {
+		log.WithError(err).Error("Failed to unmarshal data")
+		return nil, err
+	}
+
+	return valueList, nil
+}
+
+// toSelector : Convert the output of a filter call into a query document
+func toSelector(conditions interface{}) bson.D {
+	if selector, ok := conditions.(bson.D); ok {
+		return selector
+	}
+
+	return bson.D{}
+}
@@ -0,0 +1,316 @@
+package mongo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/mongo"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// flatten : Collect the leaf conditions of a tree of $and operations
+func flatten(conditions bson.D) []bson.D {
+	if len(conditions) == 1 && conditions[0].Key == "$and" {
+		var output []bson.D
+		for _, item := range conditions[0].Value.(bson.A) {
+			output = append(output, flatten(item.(bson.D))...)
+		}
+		return output
+	}
+
+	return []bson.D{conditions}
+}
+
+// hasCondition : Check whether a condition is in a list of conditions
+func hasCondition(conditions []bson.D, condition bson.D) bool {
+	for _, item := range conditions {
+		if reflect.DeepEqual(item, condition) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestOperations(t *testing.T) {
+	f := mongo.NewFilter()
+
+	operationMap := f.Operations()
+
+	if len(operationMap) != 13 {
+		t.Errorf("Expected 13 operations, but got %d", len(operationMap))
+	}
+}
+
+func TestAnd(t *testing.T) {
+	f := mongo.NewFilter()
+
+	cond1 := bson.D{{Key: "key1", Value: "val1"}}
+	cond2 := bson.D{{Key: "key2", Value: "val2"}}
+
+	conditions := f.And(cond1, cond2).(bson.D)
+
+	expected := bson.D{{Key: "$and", Value: bson.A{cond1, cond2}}}
+	if !reflect.DeepEqual(conditions, expected) {
+		t.Errorf("Incorrect filter. Expected %+v, got %+v", expected, conditions)
+	}
+}
+
+func TestAndCond1(t *testing.T) {
+	f := mongo.NewFilter()
+
+	cond1 := bson.D{{Key: "key1", Value: "val1"}}
+
+	conditions := f.And(cond1, nil).(bson.D)
+
+	if !reflect.DeepEqual(conditions, cond1) {
+		t.Errorf("Incorrect filter. Expected %+v, got %+v", cond1, conditions)
+	}
+}
+
+func TestAndCond2(t *testing.T) {
+	f := mongo.NewFilter()
+
+	cond2 := bson.D{{Key: "key2", Value: "val2"}}
+
+	conditions := f.And(nil, cond2).(bson.D)
+
+	if !reflect.DeepEqual(conditions, cond2) {
+		t.Errorf("Incorrect filter. Expected %+v, got %+v", cond2, conditions)
+	}
+}
+
+func TestAndCondsNil(t *testing.T) {
+	f := mongo.NewFilter()
+
+	conditions := f.And(nil, nil).(bson.D)
+
+	if len(conditions) != 0 {
+		t.Error("Conditions should be not be set")
+	}
+}
+
+func TestOr(t *testing.T) {
+	f := mongo.NewFilter()
+
+	cond1 := bson.D{{Key: "key1", Value: "val1"}}
+	cond2 := bson.D{{Key: "key2", Value: "val2"}}
+
+	conditions := f.Or(cond1, cond2).(bson.D)
+
+	expected := bson.D{{Key: "$or", Value: bson.A{cond1, cond2}}}
+	if !reflect.DeepEqual(conditions, expected) {
+		t.Errorf("Incorrect filter. Expected %+v, got %+v", expected, conditions)
+	}
+}
+
+func TestOrCond1(t *testing.T) {
+	f := mongo.NewFilter()
+
+	cond1 := bson.D{{Key: "key1", Value: "val1"}}
+
+	conditions := f.Or(cond1, nil).(bson.D)
+
+	if !reflect.DeepEqual(conditions, cond1) {
+		t.Errorf("Incorrect filter. Expected %+v, got %+v", cond1, conditions)
+	}
+}
+
+func TestOrCond2(t *testing.T) {
+	f := mongo.NewFilter()
+
+	cond2 := bson.D{{Key: "key2", Value: "val2"}}
+
+	conditions := f.Or(nil, cond2).(bson.D)
+
+	if !reflect.DeepEqual(conditions, cond2) {
+		t.Errorf("Incorrect filter. Expected %+v, got %+v", cond2, conditions)
+	}
+}
+
+func TestOrCondsNil(t *testing.T) {
+	f := mongo.NewFilter()
+
+	conditions := f.Or(nil, nil).(bson.D)
+
+	if len(conditions) != 0 {
+		t.Error("Conditions should be not be set")
+	}
+}
+
+func TestOperators(t *testing.T) {
+	f := mongo.NewFilter()
+
+	tests := []struct {
+		name     string
+		fn       func(string, interface{}) (interface{}, error)
+		value    interface{}
+		expected bson.D
+	}{
+		{"Equals", f.Equals, "value123", bson.D{{Key: "key", Value: bson.D{{Key: "$eq", Value: "value123"}}}}},
+		{"NotEqual", f.NotEqual, "value123", bson.D{{Key: "key", Value: bson.D{{Key: "$ne", Value: "value123"}}}}},
+		{"GreaterThan", f.GreaterThan, "value123", bson.D{{Key: "key", Value: bson.D{{Key: "$gt", Value: "value123"}}}}},
+		{"GreaterThanEqual", f.GreaterThanEqual, "value123", bson.D{{Key: "key", Value: bson.D{{Key: "$gte", Value: "value123"}}}}},
+		{"LessThan", f.LessThan, "value123", bson.D{{Key: "key", Value: bson.D{{Key: "$lt", Value: "value123"}}}}},
+		{"LessThanEqual", f.LessThanEqual, "value123", bson.D{{Key: "key", Value: bson.D{{Key: "$lte", Value: "value123"}}}}},
+		{"StartsWith", f.StartsWith, "value.123", bson.D{{Key: "key", Value: bson.D{{Key: "$regex", Value: primitive.Regex{Pattern: `^value\.123`}}}}}},
+		{"Contains", f.Contains, "value.123", bson.D{{Key: "key", Value: bson.D{{Key: "$regex", Value: primitive.Regex{Pattern: `value\.123`}}}}}},
+		{"NotContains", f.NotContains, "value123", bson.D{{Key: "key", Value: bson.D{{Key: "$not", Value: primitive.Regex{Pattern: "value123"}}}}}},
+		{"ExistsTrue", f.Exists, "true", bson.D{{Key: "key", Value: bson.D{{Key: "$exists", Value: true}}}}},
+		{"ExistsFalse", f.Exists, "false", bson.D{{Key: "key", Value: bson.D{{Key: "$exists", Value: false}}}}},
+		{"Between", f.Between, `["1", "2"]`, bson.D{{Key: "key", Value: bson.D{{Key: "$gte", Value: "1"}, {Key: "$lte", Value: "2"}}}}},
+		{"In", f.In, `["1", "2"]`, bson.D{{Key: "key", Value: bson.D{{Key: "$in", Value: []string{"1", "2"}}}}}},
+		{"NotIn", f.NotIn, `["1", "2"]`, bson.D{{Key: "key", Value: bson.D{{Key: "$nin", Value: []string{"1", "2"}}}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conds, err := test.fn("key", test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(conds, test.expected) {
+				t.Errorf("Incorrect filter. Expected %+v, got %+v", test.expected, conds)
+			}
+		})
+	}
+}
+
+func TestOperatorErrors(t *testing.T) {
+	f := mongo.NewFilter()
+
+	tests := []struct {
+		name  string
+		fn    func(string, interface{}) (interface{}, error)
+		value interface{}
+	}{
+		{"ExistsOther", f.Exists, "blah"},
+		{"StartsWithInvalidValue", f.StartsWith, 1},
+		{"ContainsInvalidValue", f.Contains, 1},
+		{"NotContainsInvalidValue", f.NotContains, 1},
+		{"BetweenInvalidValue", f.Between, 1},
+		{"BetweenUnmarshalError", f.Between, "1"},
+		{"BetweenWrongLength", f.Between, `["1"]`},
+		{"InInvalidValue", f.In, 1},
+		{"InJsonUnmarshalError", f.In, "1"},
+		{"NotInInvalidValue", f.NotIn, 1},
+		{"NotInJsonUnmarshalError", f.NotIn, "1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conds, err := test.fn("key", test.value)
+
+			if conds != nil {
+				t.Error("Conditions should be nil")
+			}
+
+			if err == nil {
+				t.Error("Error should not be nil")
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := mongo.NewFilter()
+
+	filters := map[string][]string{
+		"key":      {"value"},
+		"key2":     {"value1", "value2"},
+		"key3__gt": {"value3"},
+		"key4__lt": nil,
+	}
+
+	conditions, err := f.Filter(nil, filters, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conds := flatten(conditions.(bson.D))
+	if len(conds) != 3 {
+		t.Errorf("Expected 3 conditions, but got %d", len(conds))
+	}
+
+	exprs := []bson.D{
+		{{Key: "key", Value: bson.D{{Key: "$eq", Value: "value"}}}},
+		{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "key2", Value: bson.D{{Key: "$eq", Value: "value1"}}}},
+			bson.D{{Key: "key2", Value: bson.D{{Key: "$eq", Value: "value2"}}}},
+		}}},
+		{{Key: "key3", Value: bson.D{{Key: "$gt", Value: "value3"}}}},
+	}
+
+	for _, e := range exprs {
+		if !hasCondition(conds, e) {
+			t.Errorf("Missing condition %+v", e)
+		}
+	}
+}
+
+func TestFilterWithUser(t *testing.T) {
+	f := mongo.NewFilter()
+
+	filters := map[string][]string{
+		"key":      {"value"},
+		"key2":     {"value1", "value2"},
+		"key3__gt": {"value3"},
+		"key4__lt": nil,
+	}
+
+	user := &types.User{
+		Permissions: types.Permissions{
+			ReadFilters: []types.FilterField{
+				{
+					Field:    "key",
+					Operator: base.OperationContains,
+					Value:    "value4",
+				},
+			},
+		},
+	}
+
+	conditions, err := f.Filter(user, filters, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conds := flatten(conditions.(bson.D))
+	if len(conds) != 4 {
+		t.Errorf("Expected 4 conditions, but got %d", len(conds))
+	}
+
+	exprs := []bson.D{
+		{{Key: "key", Value: bson.D{{Key: "$regex", Value: primitive.Regex{Pattern: "value4"}}}}},
+		{{Key: "key", Value: bson.D{{Key: "$eq", Value: "value"}}}},
+		{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "key2", Value: bson.D{{Key: "$eq", Value: "value1"}}}},
+			bson.D{{Key: "key2", Value: bson.D{{Key: "$eq", Value: "value2"}}}},
+		}}},
+		{{Key: "key3", Value: bson.D{{Key: "$gt", Value: "value3"}}}},
+	}
+
+	for _, e := range exprs {
+		if !hasCondition(conds, e) {
+			t.Errorf("Missing condition %+v", e)
+		}
+	}
+}
+
+func TestMultiFilter(t *testing.T) {
+	f := mongo.NewFilter()
+
+	conditions, err := f.MultiFilter(nil, "key", []string{"value1", "value2", "value3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := bson.D{{Key: "key", Value: bson.D{{Key: "$in", Value: []string{"value1", "value2", "value3"}}}}}
+	if !reflect.DeepEqual(conditions, expected) {
+		t.Errorf("Invalid filter. Expected %+v but got %+v", expected, conditions)
+	}
+}
@@ -1,64 +1,93 @@
 package mongo
 
-// import (
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	"github.com/globalsign/mgo/bson"
-// 	log "github.com/sirupsen/logrus"
-// )
+import (
+	"context"
+	"errors"
+	"fmt"
 
-// // Get : Get an item from the table
-// func (api MongoAPI) Get(req types.Request, id string) (types.Record, error) {
-// 	var record types.Record
-// 	collection := api.Client.C(api.Config.DataTable)
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
 
-// 	// Get the user
-// 	user, err := api.InitializeRequest(req)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return nil, err
-// 	}
+// Get : Get an item from the table. Tables with a sort key can also be looked up by partition key alone, in
+// which case the partition must contain a single item.
+func (api MongoAPI) Get(req types.Request, key types.Key) (types.Record, error) {
+	collection := api.Client.Collection(api.Config.DataTable)
 
-// 	// Build pre-condition filters. This will apply all the filter criteria for the user to this selector query and
-// 	// throw a not found error if the user is not permitted to view the item
-// 	rawFilters, err := api.Filtering.Filter(
-// 		user,
-// 		map[string][]string{api.Config.PrimaryKey: []string{id}},
-// 		base.FilterActionRead,
-// 	)
-// 	if err != nil {
-// 		log.Errorf("Error generating rawFilters: %v", err)
-// 		return nil, err
-// 	}
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return nil, err
+	}
 
-// 	// Make sure filters are cast as bson.D
-// 	var selector bson.D
-// 	if _, ok := rawFilters.(bson.DocElem); ok {
-// 		// Single filter
-// 		selector = bson.D{rawFilters.(bson.DocElem)}
-// 	} else {
-// 		// Multiple filters
-// 		selector = rawFilters.(bson.D)
-// 	}
+	// Build filters. This will apply all the filter criteria for the user to this selector query and
+	// throw a not found error if the user is not permitted to view the item
+	conditions, err := api.filtering.Filter(user, nil, base.FilterActionRead)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return nil, err
+	}
 
-// 	// Fetch the item
-// 	err = collection.Find(selector).One(&record)
-// 	if err != nil {
-// 		if err.Error() == "not found" {
-// 			return nil, &types.NotFound{
-// 				Message: "Item does not exist or you do not have permission to view it",
-// 			}
-// 		} else {
-// 			// Pass through any errors
-// 			return nil, err
-// 		}
-// 	}
+	// Build key condition
+	if partitionKey := api.KeyFields()[0]; key[partitionKey] == nil {
+		return nil, &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: [%s]", partitionKey),
+		}
+	}
+	conditions = api.filtering.And(conditions, api.keySelector(key))
 
-// 	// Create audit log
-// 	partitionKey := map[string]interface{}{api.Config.PrimaryKey: id}
-// 	if err := api.auditLog(base.AuditActionGet, req, *user, &partitionKey, nil); err != nil {
-// 		log.Warnf("Failed to create audit log: %v", err)
-// 	}
+	// Fetch the item. A second document means the key only matched a partition
+	opts := options.Find().SetProjection(bson.D{{Key: "_id", Value: 0}}).SetLimit(2)
+	cursor, err := collection.Find(req.Context(), toSelector(conditions), opts)
+	if err != nil {
+		log.Errorln("Error while attempting to get record", err)
+		return nil, base.ContextError(req.Context(), err)
+	}
 
-// 	return record, nil
-// }
+	var records []types.Record
+	if err := cursor.All(req.Context(), &records); err != nil {
+		log.Errorln("Error while attempting to get record", err)
+		return nil, base.ContextError(req.Context(), err)
+	}
+
+	if len(records) == 0 {
+		return nil, &types.NotFound{
+			Message: "Item does not exist or you do not have permission to view it",
+		}
+	} else if len(records) > 1 {
+		return nil, &types.BadRequest{
+			Message: "Multiple items returned",
+		}
+	}
+	record := records[0]
+
+	// Filter the response
+	api.PostProcess([]types.Record{record}, user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionGet, req, user, key, nil); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// FetchItem : Fetch an item by its full key, without checking the user's permissions
+func (api MongoAPI) FetchItem(ctx context.Context, key types.Key) (types.Record, error) {
+	var record types.Record
+	opts := options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 0}})
+	if err := api.Client.Collection(api.Config.DataTable).FindOne(ctx, api.keySelector(key), opts).Decode(&record); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+
+		return nil, base.ContextError(ctx, err)
+	}
+
+	return record, nil
+}
@@ -1,108 +1,143 @@
 package mongo
 
-// import (
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	"github.com/globalsign/mgo/bson"
-// 	log "github.com/sirupsen/logrus"
-// )
-
-// // List : Lists all items in a table
-// func (api MongoAPI) List(req types.Request) ([]types.Record, error) {
-// 	records := []types.Record{}
-// 	collection := api.Client.C(api.Config.DataTable)
-
-// 	// Get the user
-// 	user, err := api.InitializeRequest(req)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return nil, err
-// 	}
-
-// 	// Build rawFilters
-// 	rawFilters, err := api.Filtering.Filter(user, api.BuildParams(req), base.FilterActionRead)
-// 	if err != nil {
-// 		log.Errorf("Error generating rawFilters: %v", err)
-// 		return nil, err
-// 	}
-
-// 	// Make sure filters are cast as bson.D
-// 	var filters bson.D
-// 	if rawFilters == nil {
-// 		// No filters
-// 		filters = nil
-// 	} else if _, ok := rawFilters.(bson.DocElem); ok {
-// 		// Single filter
-// 		filters = bson.D{rawFilters.(bson.DocElem)}
-// 	} else {
-// 		// Multiple filters
-// 		filters = rawFilters.(bson.D)
-// 	}
-
-// 	// Query the data
-// 	err = collection.Find(filters).All(&records)
-// 	if err != nil {
-// 		log.Errorf("Error querying collection: %v", err)
-// 		return nil, err
-// 	}
-
-// 	// Filter the response
-// 	api.PostProcess(records, user)
-
-// 	// Create audit log
-// 	if err := api.auditLog(base.AuditActionList, req, *user, nil, nil); err != nil {
-// 		log.Warnf("Failed to create audit log: %v", err)
-// 	}
-
-// 	return records, nil
-// }
-
-// // ListUniqueValues : Lists unique values for a column
-// func (api MongoAPI) ListUniqueValues(req types.Request, uniqueKey string) ([]string, error) {
-// 	records := []string{}
-// 	collection := api.Client.C(api.Config.DataTable)
-
-// 	// Get the user
-// 	user, err := api.InitializeRequest(req)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return nil, err
-// 	}
-
-// 	// Build rawFilters
-// 	rawFilters, err := api.Filtering.Filter(user, api.BuildParams(req), base.FilterActionRead)
-// 	if err != nil {
-// 		log.Errorf("Error generating rawFilters: %v", err)
-// 		return nil, err
-// 	}
-
-// 	// Make sure filters are cast as bson.D
-// 	var filters bson.D
-// 	if rawFilters == nil {
-// 		// No filters
-// 		filters = nil
-// 	} else if _, ok := rawFilters.(bson.DocElem); ok {
-// 		// Single filter
-// 		filters = bson.D{rawFilters.(bson.DocElem)}
-// 	} else {
-// 		// Multiple filters
-// 		filters = rawFilters.(bson.D)
-// 	}
-
-// 	// Query the data
-// 	err = collection.Find(filters).Distinct(uniqueKey, &records)
-// 	if err != nil {
-// 		log.Errorf("Error querying collection: %v", err)
-// 		return nil, err
-// 	}
-
-// 	// TODO: Filter the response
-// 	//api.PostProcess(records, user)
-
-// 	// Create audit log
-// 	if err := api.auditLog(base.AuditActionList, req, *user, nil, nil); err != nil {
-// 		log.Warnf("Failed to create audit log: %v", err)
-// 	}
-
-// 	return records, nil
-// }
+import (
+	"sort"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// List : Lists all items in a table
+func (api MongoAPI) List(req types.Request) ([]types.Record, error) {
+	page, err := api.list(req, false)
+	return page.Items, err
+}
+
+// ListPage : Lists a page of items in a table
+func (api MongoAPI) ListPage(req types.Request) (types.Page[types.Record], error) {
+	return api.list(req, true)
+}
+
+// list : Lists the items in a table, either all at once or a page at a time
+func (api MongoAPI) list(req types.Request, paginate bool) (types.Page[types.Record], error) {
+	collection := api.Client.Collection(api.Config.DataTable)
+
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return types.Page[types.Record]{}, err
+	}
+
+	limit := 0
+	if paginate {
+		if limit, err = base.PageLimit(req); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+	}
+
+	// Build filters
+	conditions, err := api.filtering.Filter(user, api.BuildParams(req), base.FilterActionRead)
+	if err != nil {
+		log.WithError(err).Error("Filtering failed")
+
+		if api.Config.ErrorFunc != nil {
+			api.Config.ErrorFunc(&req, user, err)
+		}
+
+		return types.Page[types.Record]{}, err
+	}
+
+	// Query the data
+	page, err := find[types.Record](req.Context(), collection, toSelector(conditions), false, limit, req.Next)
+	if err != nil {
+		log.WithError(err).Error("Failed to list records")
+
+		if api.Config.ErrorFunc != nil {
+			api.Config.ErrorFunc(&req, user, err)
+		}
+
+		return types.Page[types.Record]{}, err
+	}
+
+	// Filter the response
+	api.PostProcess(page.Items, user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionList, req, user, nil, nil); err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	return page, nil
+}
+
+// ListUniqueValues : Lists unique values in a table
+func (api MongoAPI) ListUniqueValues(req types.Request, uniqueKey string) ([]string, error) {
+	collection := api.Client.Collection(api.Config.DataTable)
+
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return nil, err
+	}
+
+	// Copy queryParams into params
+	params := make(map[string][]string)
+	for key, values := range req.QueryParams {
+		params[key] = append(params[key], values...)
+	}
+
+	// Build filters
+	conditions, err := api.filtering.Filter(user, params, base.FilterActionRead)
+	if err != nil {
+		log.WithError(err).Error("Filtering failed")
+
+		if api.Config.ErrorFunc != nil {
+			api.Config.ErrorFunc(&req, user, err)
+		}
+
+		return nil, base.ContextError(req.Context(), err)
+	}
+
+	// Excluded fields are never returned to the user
+	for _, field := range user.ExcludeFields {
+		if field == uniqueKey {
+			if err := api.WriteAuditLog(base.AuditActionList, req, user, nil, nil); err != nil {
+				return nil, err
+			}
+			return []string{}, nil
+		}
+	}
+
+	// Query the distinct values
+	data, err := collection.Distinct(req.Context(), uniqueKey, toSelector(conditions))
+	if err != nil {
+		log.WithError(err).Error("Failed to list records")
+
+		if api.Config.ErrorFunc != nil {
+			api.Config.ErrorFunc(&req, user, err)
+		}
+
+		return nil, base.ContextError(req.Context(), err)
+	}
+
+	// Only string values are supported
+	values := []string{}
+	for _, item := range data {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+
+	// Sort the data
+	sort.Strings(values)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionList, req, user, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
@@ -1,57 +1,74 @@
 package mongo
 
-// import (
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	"github.com/globalsign/mgo/bson"
-// 	log "github.com/sirupsen/logrus"
-// )
-
-// // Search : Search items in the table
-// func (api MongoAPI) Search(req types.Request, key string, values []string) ([]types.Record, error) {
-// 	records := []types.Record{}
-// 	collection := api.Client.C(api.Config.DataTable)
-
-// 	// Get the user
-// 	user, err := api.InitializeRequest(req)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return nil, err
-// 	}
-
-// 	// Build filters
-// 	rawFilters, err := api.Filtering.MultiFilter(user, key, values)
-// 	if err != nil {
-// 		log.Errorln("Error encountered during filtering", err)
-// 		return nil, err
-// 	}
-
-// 	// Make sure filters are cast as bson.D
-// 	var filters bson.D
-// 	if rawFilters == nil {
-// 		// No filters
-// 		filters = nil
-// 	} else if _, ok := rawFilters.(bson.DocElem); ok {
-// 		// Single filter
-// 		filters = bson.D{rawFilters.(bson.DocElem)}
-// 	} else {
-// 		// Multiple filters
-// 		filters = rawFilters.(bson.D)
-// 	}
-
-// 	// Query the data
-// 	err = collection.Find(filters).All(&records)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	// Filter the response
-// 	api.PostProcess(records, user)
-
-// 	// Create audit log
-// 	if err := api.auditLog(base.AuditActionSearch, req, *user, nil, nil); err != nil {
-// 		log.Warnf("Failed to create audit log: %v", err)
-// 	}
-
-// 	return records, nil
-// }
+import (
+	"context"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Search : Search items in the table
+func (api MongoAPI) Search(req types.Request, key string, values []string) ([]types.Record, error) {
+	page, err := api.search(req, key, values, false)
+	return page.Items, err
+}
+
+// SearchPage : Search a page of items in the table
+func (api MongoAPI) SearchPage(req types.Request, key string, values []string) (types.Page[types.Record], error) {
+	return api.search(req, key, values, true)
+}
+
+// search : Search items in the table, either all at once or a page at a time
+func (api MongoAPI) search(req types.Request, key string, values []string, paginate bool) (types.Page[types.Record], error) {
+	collection := api.Client.Collection(api.Config.DataTable)
+
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return types.Page[types.Record]{}, err
+	}
+
+	limit := 0
+	if paginate {
+		if limit, err = base.PageLimit(req); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+	}
+
+	// Build filters
+	conditions, err := api.filtering.MultiFilter(user, key, values)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return types.Page[types.Record]{}, err
+	}
+
+	// Query the data
+	page, err := find[types.Record](req.Context(), collection, toSelector(conditions), false, limit, req.Next)
+	if err != nil {
+		log.Errorln("Error while attempting to search records", err)
+		return types.Page[types.Record]{}, err
+	}
+
+	// Filter the response
+	api.PostProcess(page.Items, user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionSearch, req, user, nil, nil); err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	return page, nil
+}
+
+// FindItems : Find the items with one of the values in a field, without checking the user's permissions
+func (api MongoAPI) FindItems(ctx context.Context, key string, values []string) ([]types.Record, error) {
+	conditions, err := api.filtering.MultiFilter(nil, key, values)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := find[types.Record](ctx, api.Client.Collection(api.Config.DataTable), toSelector(conditions), false, 0, "")
+	return page.Items, err
+}
@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	"errors"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Transaction : Apply a set of operations in a single MongoDB transaction, which requires a replica set. Every item is
+// read with the user's filters and the expected version in its selector, and nothing is committed unless every
+// operation passes.
+func (api MongoAPI) Transaction(req types.Request, operations []types.TransactionOperation, validation map[string]types.FieldValidation) ([]types.Record, error) {
+	transaction, err := api.PrepareTransaction(req, operations, validation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build pre-condition filters for each operation
+	conditions := make([]interface{}, len(operations))
+	for i := range operations {
+		filters, err := api.filtering.Filter(transaction.User, nil, transaction.FilterAction(i))
+		if err != nil {
+			log.Errorln("Error encountered during filtering", err)
+			return nil, types.NewTransactionFailed(i, err)
+		}
+		conditions[i] = api.filtering.And(filters, api.keySelector(transaction.Keys[i]))
+	}
+
+	session, err := api.Client.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(req.Context())
+
+	var previous, outputs []types.Record
+	collection := api.Client.Collection(api.Config.DataTable)
+	projection := options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 0}})
+	_, err = session.WithTransaction(req.Context(), func(sc mongo.SessionContext) (interface{}, error) {
+		previous = make([]types.Record, len(operations))
+		outputs = make([]types.Record, len(operations))
+
+		for i, operation := range operations {
+			request := transaction.OperationRequest(i).WithContext(sc)
+
+			if operation.Action == types.TransactionCreate {
+				outputs[i] = api.ApplyOperation(transaction, i, nil)
+				if _, err := collection.InsertOne(sc, outputs[i]); err != nil {
+					if mongo.IsDuplicateKeyError(err) {
+						return nil, types.NewTransactionFailed(i, &types.BadRequest{Message: transaction.Failure(i)})
+					}
+					return nil, types.NewTransactionFailed(i, err)
+				}
+				continue
+			}
+
+			// Make sure the item exists, the user is permitted to use it and it is at the expected version
+			selector, err := api.versionSelector(request, conditions[i])
+			if err != nil {
+				return nil, types.NewTransactionFailed(i, err)
+			}
+			if err := collection.FindOne(sc, selector, projection).Decode(&previous[i]); err != nil {
+				if errors.Is(err, mongo.ErrNoDocuments) {
+					err = api.writeFailure(request, conditions[i], transaction.Failure(i))
+				}
+				return nil, types.NewTransactionFailed(i, err)
+			}
+
+			outputs[i] = api.ApplyOperation(transaction, i, previous[i])
+			switch {
+			case operation.Action == types.TransactionCheck:
+				continue
+			case outputs[i] == nil:
+				_, err = collection.DeleteOne(sc, api.keySelector(transaction.Keys[i]))
+			default:
+				_, err = collection.ReplaceOne(sc, api.keySelector(transaction.Keys[i]), outputs[i])
+			}
+			if err != nil {
+				return nil, types.NewTransactionFailed(i, err)
+			}
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to apply transaction", err)
+		return nil, base.ContextError(req.Context(), err)
+	}
+
+	// Create audit logs
+	if err := api.AuditTransaction(transaction, previous, outputs); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
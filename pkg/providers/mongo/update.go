@@ -1,130 +1,219 @@
 package mongo
 
-// import (
-// 	"fmt"
-
-// 	"github.com/globalsign/mgo/bson"
-
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	log "github.com/sirupsen/logrus"
-// )
-
-// // Update : Update an item
-// func (api MongoAPI) Update(request types.Request, partitionKey map[string]interface{}, item map[string]string, validation map[string]types.FieldValidation, auditAction string) (interface{}, error) {
-// 	var output interface{}
-// 	collection := api.Client.C(api.Config.DataTable)
-
-// 	// Get the user
-// 	user, err := api.InitializeRequest(request)
-// 	if err != nil {
-// 		// Bad user - pass the error through
-// 		return nil, err
-// 	}
-
-// 	// Run data validation
-// 	if validation != nil {
-// 		err := api.ValidateFields(validation, item, nil, true)
-// 		if err != nil {
-// 			log.Errorln("Field validation error", err)
-// 			return nil, err
-// 		}
-// 	}
-
-// 	// Make sure user is not trying to update restricted fields
-// 	for _, field := range user.UpdateFieldsRestricted {
-// 		if _, ok := item[field]; ok {
-// 			return nil, &types.BadRequest{
-// 				Message: fmt.Sprintf("Not authorized to update field '%s'", field),
-// 			}
-// 		}
-// 	}
-
-// 	// Check all keys of the update input
-// 	for _, key := range item {
-// 		// Make sure fields being updated are not excluded from user
-// 		for _, field := range user.ExcludeFields {
-// 			if field == key {
-// 				return nil, &types.BadRequest{
-// 					Message: fmt.Sprintf("Not authorized to update field '%s'", key),
-// 				}
-// 			}
-// 		}
-
-// 		// Make sure the user is only updating permitted fields
-// 		if len(user.UpdateFieldsPermitted) > 0 {
-// 			found := false
-// 			for _, field := range user.UpdateFieldsPermitted {
-// 				if field == key {
-// 					found = true
-// 					break
-// 				}
-// 			}
-
-// 			if !found {
-// 				return nil, &types.BadRequest{
-// 					Message: fmt.Sprintf("Not authorized to update field '%s'", key),
-// 				}
-// 			}
-// 		}
-// 	}
-
-// 	// Build update expression
-// 	var updates bson.D
-// 	for key, value := range item {
-// 		updates = append(updates, bson.DocElem{
-// 			Name: "$set",
-// 			Value: bson.D{{
-// 				Name:  key,
-// 				Value: value,
-// 			}},
-// 		})
-// 	}
-
-// 	// Build pre-condition filters. This will apply all the filter criteria for the user to this selector query and
-// 	// throw a not found error if the user is not permitted to view the item
-// 	rawFilters, _, err := api.Filter(
-// 		&api.Filtering,
-// 		user,
-// 		map[string]string{api.Config.PrimaryKey: partitionKey[api.Config.PrimaryKey]},
-// 	)
-// 	if err != nil {
-// 		log.Errorf("Error generating rawFilters: %v", err)
-// 		return nil, err
-// 	}
-
-// 	// Make sure filters are cast as bson.D
-// 	var selector bson.D
-// 	if _, ok := rawFilters.(bson.DocElem); ok {
-// 		// Single filter
-// 		selector = bson.D{rawFilters.(bson.DocElem)}
-// 	} else {
-// 		// Multiple filters
-// 		selector = rawFilters.(bson.D)
-// 	}
-
-// 	// Update the item
-// 	err = collection.Update(selector, updates)
-// 	if err != nil {
-// 		if err.Error() == "not found" {
-// 			return nil, &types.NotFound{
-// 				Message: "Item does not exist or you do not have permission to view it",
-// 			}
-// 		} else {
-// 			log.Errorln("Error while attempting to update item", err)
-// 			return nil, err
-// 		}
-// 	}
-
-// 	// Pull the updated item to show as a result
-// 	err = collection.Find(bson.M{api.Config.PrimaryKey: partitionKey[api.Config.PrimaryKey]}).One(&output)
-// 	if err != nil {
-// 		log.Errorln("Failed to fetch updated item to show in results")
-// 	}
-
-// 	// Create audit log
-// 	if err := api.auditLog(auditAction, request, *user, &partitionKey, &item); err != nil {
-// 		log.Warnf("Failed to create audit log: %v", err)
-// 	}
-
-// 	return output, nil
-// }
+import (
+	"errors"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// updateAttempts : Number of times an update is tried when the item changes between reading and writing it
+const updateAttempts = 5
+
+// Update : Update an item
+func (api MongoAPI) Update(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, requiredFields)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.update(request, user, partitionKey, item, api.updateDocument(partitionKey, item, false), auditAction)
+}
+
+// Patch : Partially update an item. Fields with a null value are removed from the record.
+func (api MongoAPI) Patch(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.update(request, user, partitionKey, item, api.updateDocument(partitionKey, item, true), auditAction)
+}
+
+// updateDocument : Build the update document setting the fields of an item. The key of an item cannot be changed,
+// so key fields are skipped. If unsetNulls is true, fields with a null value are removed rather than set to null.
+func (api MongoAPI) updateDocument(partitionKey types.Key, item map[string]interface{}, unsetNulls bool) bson.D {
+	var set, unset bson.D
+	for key, value := range item {
+		if api.isKeyField(key) {
+			continue
+		} else if value == nil && unsetNulls {
+			unset = append(unset, bson.E{Key: key, Value: ""})
+		} else {
+			set = append(set, bson.E{Key: key, Value: value})
+		}
+	}
+
+	// MongoDB rejects an empty update, so an update that only names the key sets it to the value it already has
+	if len(set) == 0 && len(unset) == 0 {
+		for key, value := range partitionKey {
+			set = append(set, bson.E{Key: key, Value: value})
+		}
+	}
+
+	updates := bson.D{}
+	if len(set) > 0 {
+		updates = append(updates, bson.E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		updates = append(updates, bson.E{Key: "$unset", Value: unset})
+	}
+
+	return updates
+}
+
+// isKeyField : Check if a field is one of the key attributes of the data table
+func (api MongoAPI) isKeyField(field string) bool {
+	for _, key := range api.KeyFields() {
+		if key == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// update : Apply an update document to the item matching the partition key and the user's update filters. Returns
+// the new version of the item, as stored by MongoDB. The item is read first for its audit log, and the update only
+// applies if the item has not changed since, so the previous version is the one that was updated. If another request
+// changes the item in between, it is read again.
+func (api MongoAPI) update(request types.Request, user *types.User, partitionKey types.Key, item map[string]interface{}, updates bson.D, auditAction string) (interface{}, error) {
+	const message = "Item does not exist or you do not have permission to update it"
+
+	// Build pre-condition filters. This will apply all the filter criteria for the user to this selector query and
+	// throw an error if the user is not permitted to update the item
+	conditions, err := api.filtering.Filter(user, nil, base.FilterActionUpdate)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return nil, err
+	}
+	conditions = api.filtering.And(conditions, api.keySelector(partitionKey))
+	if api.Versioned() {
+		updates = append(updates, bson.E{Key: "$inc", Value: bson.D{{Key: api.Config.VersionField, Value: int64(1)}}})
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.D{{Key: "_id", Value: 0}})
+
+	collection := api.Client.Collection(api.Config.DataTable)
+	for attempt := 1; ; attempt++ {
+		// Read the previous version of the item
+		result := collection.FindOne(request.Context(), api.keySelector(partitionKey))
+		raw, err := result.Raw()
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, &types.BadRequest{Message: message}
+			}
+
+			log.Errorln("Error while attempting to read item", err)
+			return nil, base.ContextError(request.Context(), err)
+		}
+
+		var previous types.Record
+		if err := result.Decode(&previous); err != nil {
+			return nil, err
+		}
+		delete(previous, "_id")
+
+		// Update the item, as long as it has not changed since it was read, and return the new version of it
+		unchanged := api.unchangedSelector(raw)
+		selector, err := api.versionSelector(request, api.filtering.And(conditions, unchanged))
+		if err != nil {
+			return nil, err
+		}
+
+		var output types.Record
+		if err := collection.FindOneAndUpdate(request.Context(), selector, updates, opts).Decode(&output); err != nil {
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				log.Errorln("Error while attempting to update item", err)
+				return nil, base.ContextError(request.Context(), err)
+			}
+
+			// Read the item again if it changed, rather than failing the conditions
+			if attempt < updateAttempts {
+				count, err := collection.CountDocuments(request.Context(), toSelector(api.filtering.And(api.keySelector(partitionKey), unchanged)))
+				if err != nil {
+					return nil, base.ContextError(request.Context(), err)
+				} else if count == 0 {
+					continue
+				}
+			}
+
+			return nil, api.writeFailure(request, conditions, message)
+		}
+
+		// Create audit log
+		if err := api.WriteChangeAuditLog(auditAction, request, user, partitionKey, item, previous, output); err != nil {
+			return nil, err
+		}
+
+		return output, nil
+	}
+}
+
+// unchangedSelector : Selector matching an item only if it has not changed since it was read. Versioned items are
+// matched by their version, and other items by the value of each field that was read.
+func (api MongoAPI) unchangedSelector(item bson.Raw) bson.D {
+	if api.Versioned() {
+		version, err := item.LookupErr(api.Config.VersionField)
+		if err != nil {
+			return bson.D{{Key: api.Config.VersionField, Value: bson.D{{Key: "$exists", Value: false}}}}
+		}
+
+		return bson.D{{Key: api.Config.VersionField, Value: bson.D{{Key: "$eq", Value: version}}}}
+	}
+
+	selector := bson.D{}
+	elements, _ := item.Elements()
+	for _, element := range elements {
+		if element.Key() != "_id" {
+			selector = append(selector, bson.E{Key: element.Key(), Value: bson.D{{Key: "$eq", Value: element.Value()}}})
+		}
+	}
+
+	return selector
+}
+
+// versionSelector : Selector matching the conditions and, if the request expects one, the version of the item
+func (api MongoAPI) versionSelector(request types.Request, conditions interface{}) (bson.D, error) {
+	expected, ok, err := api.ExpectedVersion(request)
+	if err != nil || !ok {
+		return toSelector(conditions), err
+	}
+
+	version, err := api.filtering.Equals(api.Config.VersionField, expected)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSelector(api.filtering.And(conditions, version)), nil
+}
+
+// writeFailure : Work out why a write matched no item. If the item satisfies every condition except the version,
+// it was modified since the version the request expected.
+func (api MongoAPI) writeFailure(request types.Request, conditions interface{}, message string) error {
+	expected, ok, _ := api.ExpectedVersion(request)
+	if !ok {
+		return &types.BadRequest{Message: message}
+	}
+
+	var existing types.Record
+	collection := api.Client.Collection(api.Config.DataTable)
+	if err := collection.FindOne(request.Context(), toSelector(conditions)).Decode(&existing); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &types.BadRequest{Message: message}
+		}
+
+		return base.ContextError(request.Context(), err)
+	}
+
+	version, _ := base.RecordVersion(existing, api.Config.VersionField)
+	return api.VersionConflict(expected, version)
+}
@@ -1,75 +1,89 @@
 package mongo
 
-// import (
-// 	"encoding/json"
-
-// 	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
-// 	"github.com/globalsign/mgo/bson"
-// 	log "github.com/sirupsen/logrus"
-// )
-
-// // GetAuth : Fetch an auth identity from the collection
-// // Responses:
-// //   - nil, nil: user does not exist
-// //   - nil, error: error while fetching user
-// //   - user, nil: found user
-// func (api MongoAPI) GetAuth(id string) (*types.User, error) {
-// 	collection := api.Client.C(api.Config.AuthTable)
-// 	var user *types.User
-
-// 	// Try to find user in the auth table
-// 	var result interface{}
-// 	if err := collection.Find(bson.M{"id": id}).One(&result); err != nil {
-// 		if err.Error() == "not found" {
-// 			// Failed to find user in the table
-// 			return nil, nil
-// 		} else {
-// 			log.Infof("Failed to get user: %v", err)
-// 			return nil, err
-// 		}
-// 	} else {
-// 		// Found a user, unmarshal into user object
-// 		data, err := json.Marshal(result)
-// 		if err != nil {
-// 			log.Errorf("Failed to marshal to json: %v", err)
-// 			return nil, err
-// 		}
-// 		err = json.Unmarshal(data, &user)
-// 		if err != nil {
-// 			log.Errorf("Failed to unmarshal from json: %v", err)
-// 			return nil, err
-// 		}
-// 	}
-
-// 	return user, nil
-// }
-
-// // GetGroup : Fetch a group from the collection
-// // Responses:
-// //   - nil, nil: group does not exist
-// //   - nil, error: error while fetching group
-// //   - user, nil: found group
-// func (api MongoAPI) GetGroup(id string) (*types.Group, error) {
-// 	collection := api.Client.C(api.Config.GroupTable)
-// 	var group *types.Group
-
-// 	// Try to find group in the group table
-// 	var result interface{}
-// 	if err := collection.Find(bson.M{"group_id": id}).One(&result); err != nil {
-// 		return nil, err
-// 	} else {
-// 		// Found a group, unmarshal into user object
-// 		data, err := json.Marshal(result)
-// 		if err != nil {
-// 			log.Errorf("Failed to marshal to json: %v", err)
-// 			return nil, err
-// 		}
-// 		err = json.Unmarshal(data, &group)
-// 		if err != nil {
-// 			log.Errorf("Failed to unmarshal from json: %v", err)
-// 			return nil, err
-// 		}
-// 	}
-
-// 	return group, nil
-// }
+import (
+	"context"
+	"errors"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GetAuth : Fetch an auth identity from the collection
+// Responses:
+//   - nil, nil: user does not exist
+//   - nil, error: error while fetching user
+//   - user, nil: found user
+func (api MongoAPI) GetAuth(ctx context.Context, id string) (*types.User, error) {
+	collection := api.Client.Collection(api.Config.AuthTable)
+
+	// Try to find user in the auth table
+	var result bson.M
+	if err := collection.FindOne(ctx, bson.M{"id": id}).Decode(&result); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Failed to find user in the table
+			return nil, nil
+		}
+
+		log.WithError(err).Error("Failed to get user")
+		return nil, base.ContextError(ctx, err)
+	}
+
+	return decode[types.User](result)
+}
+
+// GetGroup : Fetch a group from the collection
+// Responses:
+//   - nil, nil: group does not exist
+//   - nil, error: error while fetching group
+//   - user, nil: found group
+func (api MongoAPI) GetGroup(ctx context.Context, id string) (*types.Group, error) {
+	collection := api.Client.Collection(api.Config.GroupTable)
+
+	// Try to find group in the group table
+	var result bson.M
+	if err := collection.FindOne(ctx, bson.M{"group_id": id}).Decode(&result); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Group is not in the table
+			return nil, nil
+		}
+
+		log.WithError(err).Error("Failed to get group")
+		return nil, base.ContextError(ctx, err)
+	}
+
+	return decode[types.Group](result)
+}
+
+// GetEntitlements: Fetch entitlements from the database
+func (api MongoAPI) GetEntitlements(ctx context.Context, entitlementIDs []string) ([]types.User, error) {
+	if len(entitlementIDs) == 0 {
+		return nil, nil
+	}
+
+	collection := api.Client.Collection(api.Config.AuthTable)
+
+	// Search for the entitlement ids
+	cursor, err := collection.Find(ctx, bson.M{"id": bson.M{"$in": entitlementIDs}})
+	if err != nil {
+		return nil, base.ContextError(ctx, err)
+	}
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, base.ContextError(ctx, err)
+	}
+
+	var entitlements []types.User
+	for _, result := range results {
+		entitlement, err := decode[types.User](result)
+		if err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, *entitlement)
+	}
+
+	return entitlements, nil
+}
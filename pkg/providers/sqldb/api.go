@@ -0,0 +1,355 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// keyColumn : Column holding the key of each record
+const keyColumn = `"key"`
+
+// SQLAPI : API, based off of Scoutr, used to talk to SQL databases. Records are stored as JSON documents.
+type SQLAPI struct {
+	*base.Scoutr
+	DB        *sql.DB
+	Dialect   Dialect
+	filtering SQLFiltering
+}
+
+// queryer : Methods shared by *sql.DB and *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// NewSQLAPI : Connect to the database, create any missing tables and initialize the API
+func NewSQLAPI(sqlConfig config.SQLConfig) (SQLAPI, error) {
+	dialectName := sqlConfig.Dialect
+	if dialectName == "" {
+		dialectName = sqlConfig.Driver
+	}
+
+	dialect, err := GetDialect(dialectName)
+	if err != nil {
+		return SQLAPI{}, err
+	}
+
+	db, err := sql.Open(sqlConfig.Driver, sqlConfig.DataSourceName)
+	if err != nil {
+		return SQLAPI{}, err
+	}
+
+	// Make sure the server is reachable
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return SQLAPI{}, err
+	}
+
+	api := SQLAPI{
+		DB:        db,
+		Dialect:   dialect,
+		filtering: NewFilter(dialect),
+		Scoutr: &base.Scoutr{
+			Config: sqlConfig.Config,
+		},
+	}
+
+	// Select the audit sink
+	sink, err := base.NewAuditSink(api.Config, map[string]base.AuditSink{
+		base.AuditSinkTable: api.AuditTableSink(),
+	}, api.AuditChainStore())
+	if err != nil {
+		db.Close()
+		return SQLAPI{}, err
+	}
+	api.AuditSink = sink
+
+	api.ScoutrBase = api
+
+	if err := api.createTables(); err != nil {
+		db.Close()
+		return SQLAPI{}, err
+	}
+
+	return api, nil
+}
+
+// Close : Close the connection with the database, after writing any queued audit logs
+func (api SQLAPI) Close() error {
+	// Queued audit logs may be written to the database, so they are flushed first
+	auditErr := api.CloseAuditLogs(context.Background())
+
+	return errors.Join(auditErr, api.DB.Close())
+}
+
+// createTables : Create the record and audit tables, and the audit chain table when audit logs are signed, if they
+// do not exist
+func (api SQLAPI) createTables() error {
+	for _, name := range []string{api.Config.DataTable, api.Config.AuthTable, api.Config.GroupTable} {
+		if name == "" {
+			continue
+		}
+
+		_, err := api.DB.Exec(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s (%s TEXT PRIMARY KEY, %s %s NOT NULL)",
+			quoteIdent(name), keyColumn, dataColumn, api.Dialect.JSONType(),
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	if api.Config.AuditTable != "" {
+		_, err := api.DB.Exec(fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s ("id" %s, %s %s NOT NULL)`,
+			quoteIdent(api.Config.AuditTable), api.Dialect.SerialType(), dataColumn, api.Dialect.JSONType(),
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	if api.Config.AuditTable != "" && len(api.Config.AuditHMACKey) > 0 {
+		_, err := api.DB.Exec(fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s ("name" TEXT PRIMARY KEY, "sequence" BIGINT NOT NULL, "hash" TEXT NOT NULL)`,
+			quoteIdent(api.Config.AuditTable+chainSuffix),
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keyFields : The fields of a record that make up the key of a table
+func (api SQLAPI) keyFields(tableName string) []string {
+	switch tableName {
+	case api.Config.AuthTable:
+		return []string{"id"}
+	case api.Config.GroupTable:
+		return []string{"group_id"}
+	}
+
+	return api.KeyFields()
+}
+
+// key : Get the key of a record in a table. Composite keys are stored as a JSON list of their values.
+func (api SQLAPI) key(tableName string, record map[string]interface{}) (string, error) {
+	var values []string
+	var missing []string
+	for _, field := range api.keyFields(tableName) {
+		value, ok := record[field]
+		if !ok || value == nil {
+			missing = append(missing, field)
+			continue
+		}
+		values = append(values, fmt.Sprint(value))
+	}
+
+	if len(missing) > 0 {
+		return "", &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: %v", missing),
+		}
+	} else if len(values) == 1 {
+		return values[0], nil
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// keyOf : Extract the key attributes from a record
+func (api SQLAPI) keyOf(tableName string, record map[string]interface{}) types.Key {
+	key := make(types.Key)
+	for _, field := range api.keyFields(tableName) {
+		key[field] = record[field]
+	}
+
+	return key
+}
+
+// PutItem : Store an item in a table, replacing any existing item with the same key. The item may be
+// a map or any struct that can be converted to JSON, such as a types.User or types.Group.
+func (api SQLAPI) PutItem(tableName string, item interface{}) error {
+	record, err := clone(item)
+	if err != nil {
+		return err
+	}
+
+	id, err := api.key(tableName, record)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = api.DB.Exec(api.Dialect.Rebind(fmt.Sprintf(
+		"INSERT INTO %[1]s (%[2]s, %[3]s) VALUES (?, CAST(? AS %[4]s)) ON CONFLICT (%[2]s) DO UPDATE SET %[3]s = excluded.%[3]s",
+		quoteIdent(tableName), keyColumn, dataColumn, api.Dialect.JSONType(),
+	)), id, string(data))
+
+	return err
+}
+
+// query : Select the records in a table that match a set of conditions, ordered by key
+func (api SQLAPI) query(ctx context.Context, q queryer, tableName string, conditions interface{}, suffix string) ([]types.Record, error) {
+	page, err := api.page(ctx, q, tableName, keyColumn, false, conditions, 0, "", suffix)
+	return page.Items, err
+}
+
+// page : Select up to limit documents in a table that match a set of conditions, ordered by column. Pages
+// continue after the value of the column in the continuation token. A limit of 0 returns all matching documents.
+func (api SQLAPI) page(ctx context.Context, q queryer, tableName string, column string, descending bool, conditions interface{}, limit int, next string, suffix string) (types.Page[types.Record], error) {
+	page := types.Page[types.Record]{Items: []types.Record{}}
+
+	order, op := "ASC", ">"
+	if descending {
+		order, op = "DESC", "<"
+	}
+
+	// Continue after the last row of the previous page
+	var after interface{}
+	if ok, err := base.DecodeToken(next, &after); err != nil {
+		return types.Page[types.Record]{}, err
+	} else if ok {
+		// JSON numbers decode as floats, but serial columns are integers
+		if n, isFloat := after.(float64); isFloat && n == math.Trunc(n) {
+			after = int64(n)
+		}
+
+		conditions = api.filtering.And(&SQLCondition{
+			Query: fmt.Sprintf("%s %s ?", column, op),
+			Args:  []interface{}{after},
+		}, conditions)
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s FROM %s", column, dataColumn, quoteIdent(tableName))
+
+	var args []interface{}
+	if condition := toCondition(conditions); condition != nil {
+		query += " WHERE " + condition.Query
+		args = condition.Args
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s", column, order)
+
+	// Fetch an extra row to find out if there is a following page
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit+1)
+	}
+	query += suffix
+
+	rows, err := q.QueryContext(ctx, api.Dialect.Rebind(query), args...)
+	if err != nil {
+		return types.Page[types.Record]{}, base.ContextError(ctx, err)
+	}
+	defer rows.Close()
+
+	var last interface{}
+	for rows.Next() {
+		var cursor interface{}
+		var data []byte
+		if err := rows.Scan(&cursor, &data); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+
+		if limit > 0 && len(page.Items) == limit {
+			page.Next, err = base.EncodeToken(last)
+			if err != nil {
+				return types.Page[types.Record]{}, err
+			}
+			break
+		}
+
+		var record types.Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+		page.Items = append(page.Items, record)
+
+		// Drivers may return text columns as bytes
+		if b, ok := cursor.([]byte); ok {
+			cursor = string(b)
+		}
+		last = cursor
+	}
+
+	if err := rows.Err(); err != nil {
+		return types.Page[types.Record]{}, base.ContextError(ctx, err)
+	}
+
+	return page, nil
+}
+
+// keyCondition : Build a condition that matches the keys of records
+func keyCondition(ids ...string) *SQLCondition {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	return &SQLCondition{
+		Query: fmt.Sprintf("%s IN (%s)", keyColumn, strings.Join(placeholders, ", ")),
+		Args:  args,
+	}
+}
+
+// getItem : Fetch a single item by its key. Returns nil if the item does not exist.
+func (api SQLAPI) getItem(ctx context.Context, tableName string, id string) (types.Record, error) {
+	records, err := api.query(ctx, api.DB, tableName, keyCondition(id), "")
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+
+	return records[0], nil
+}
+
+// clone : Deep copy a value into a record, normalizing it the same way it is stored in the database
+func clone(value interface{}) (types.Record, error) {
+	var output types.Record
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// decode : Convert a record into a struct using its JSON tags
+func decode[T any](record types.Record) (*T, error) {
+	var output *T
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
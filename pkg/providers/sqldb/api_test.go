@@ -0,0 +1,394 @@
+package sqldb_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/config"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/sqldb"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newAPI : Create an API backed by a new SQLite database, with a user permitted to call every endpoint
+func newAPI(t *testing.T) sqldb.SQLAPI {
+	return newAPIWithConfig(t, config.Config{})
+}
+
+// newAPIWithConfig : Create an API with extra settings. The tables and primary key are always set.
+func newAPIWithConfig(t *testing.T, cfg config.Config) sqldb.SQLAPI {
+	cfg.DataTable = "data"
+	cfg.AuthTable = "auth"
+	cfg.GroupTable = "groups"
+	cfg.AuditTable = "audit"
+	cfg.PrimaryKey = "id"
+
+	api, err := sqldb.NewSQLAPI(config.SQLConfig{
+		Driver:         "sqlite3",
+		DataSourceName: filepath.Join(t.TempDir(), "scoutr.db"),
+		Config:         cfg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { api.Close() })
+
+	user := types.User{
+		ID:       "user1",
+		Username: "user1",
+		Name:     "User One",
+		Email:    "user1@example.com",
+		Permissions: types.Permissions{
+			PermittedEndpoints: []types.PermittedEndpoint{
+				{Endpoint: ".*", Method: "GET"},
+				{Endpoint: ".*", Method: "POST"},
+				{Endpoint: ".*", Method: "PUT"},
+				{Endpoint: ".*", Method: "PATCH"},
+				{Endpoint: ".*", Method: "DELETE"},
+			},
+			UpdateFilters: []types.FilterField{{Field: "status", Operator: base.OperationNotEqual, Value: "locked"}},
+		},
+	}
+	if err := api.PutItem("auth", user); err != nil {
+		t.Fatal(err)
+	}
+
+	return api
+}
+
+func request(method string, path string) types.Request {
+	return types.Request{
+		User:   types.RequestUser{ID: "user1"},
+		Method: method,
+		Path:   path,
+	}
+}
+
+func TestCrud(t *testing.T) {
+	api := newAPI(t)
+
+	// Create items
+	items := []map[string]interface{}{
+		{"id": "1", "name": "alpha", "status": "active", "count": 1},
+		{"id": "2", "name": "beta", "status": "locked", "count": 2},
+		{"id": "3", "name": "gamma", "status": "active", "count": 3},
+	}
+	for _, item := range items {
+		req := request("POST", "/items/")
+		req.Body = item
+		if err := api.Create(req, item, nil, nil, base.AuditActionCreate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Duplicates are rejected
+	err := api.Create(request("POST", "/items/"), map[string]interface{}{"id": "1"}, nil, nil, base.AuditActionCreate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	// Get
+	record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	} else if record["name"] != "alpha" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	if _, err := api.Get(request("GET", "/item/4"), types.Key{"id": "4"}); err == nil {
+		t.Error("Expected not found error")
+	}
+
+	// List
+	req := request("GET", "/items/")
+	req.QueryParams = map[string][]string{"status": {"active"}, "count__gt": {"1"}}
+	records, err := api.List(req)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 1 || records[0]["id"] != "3" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+
+	// Search
+	records, err = api.Search(request("POST", "/search/name"), "name", []string{"alpha", "beta"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(records) != 2 {
+		t.Errorf("Expected 2 records, got %d", len(records))
+	}
+
+	// Unique values
+	values, err := api.ListUniqueValues(request("GET", "/items/status"), "status")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(values) != 2 || values[0] != "active" || values[1] != "locked" {
+		t.Errorf("Unexpected values %+v", values)
+	}
+
+	// Update
+	output, err := api.Update(request("PUT", "/item/1"), map[string]interface{}{"id": "1"}, map[string]interface{}{"name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if output.(types.Record)["name"] != "delta" {
+		t.Errorf("Unexpected output %+v", output)
+	}
+
+	// Update filters are enforced
+	_, err = api.Update(request("PUT", "/item/2"), map[string]interface{}{"id": "2"}, map[string]interface{}{"name": "delta"}, nil, nil, base.AuditActionUpdate)
+	if _, ok := err.(*types.BadRequest); !ok {
+		t.Errorf("Expected bad request, got %v", err)
+	}
+
+	// Patch removes null fields
+	output, err = api.Patch(request("PATCH", "/item/1"), map[string]interface{}{"id": "1"}, map[string]interface{}{"count": nil}, nil, base.AuditActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := output.(types.Record)["count"]; ok {
+		t.Errorf("Field should have been removed: %+v", output)
+	}
+
+	record, err = api.Get(request("GET", "/item/1"), types.Key{"id": "1"})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := record["count"]; ok || record["name"] != "delta" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+
+	// Delete
+	if err := api.Delete(request("DELETE", "/item/3"), map[string]interface{}{"id": "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Delete(request("DELETE", "/item/3"), map[string]interface{}{"id": "3"}); err == nil {
+		t.Error("Expected error deleting missing item")
+	}
+}
+
+func TestPagination(t *testing.T) {
+	api := newAPI(t)
+
+	for i := 0; i < 5; i++ {
+		item := map[string]interface{}{"id": string(rune('a' + i)), "even": i%2 == 0}
+		if err := api.PutItem("data", item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Filters are applied to each page
+	var ids []string
+	req := request("GET", "/items/")
+	req.QueryParams = map[string][]string{"even": {"true"}}
+	req.Limit = 2
+	for pages := 0; ; pages++ {
+		page, err := api.ListPage(req)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(page.Items) > 2 || pages > 2 {
+			t.Fatalf("Unexpected page %+v", page)
+		}
+
+		for _, record := range page.Items {
+			ids = append(ids, record["id"].(string))
+		}
+
+		if page.Next == "" {
+			break
+		}
+		req.Next = page.Next
+	}
+	if len(ids) != 3 || ids[0] != "a" || ids[1] != "c" || ids[2] != "e" {
+		t.Errorf("Unexpected records %v", ids)
+	}
+
+	// Audit logs are paged newest first
+	var logs []types.AuditLog
+	req = request("GET", "/audit/")
+	req.Limit = 1
+	for {
+		page, err := api.ListAuditLogsPage(req, map[string]string{}, map[string][]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		logs = append(logs, page.Items...)
+		if page.Next == "" {
+			break
+		}
+		req.Next = page.Next
+	}
+	if len(logs) < 2 || logs[0].Time < logs[len(logs)-1].Time {
+		t.Errorf("Unexpected audit logs %+v", logs)
+	}
+
+	req = request("GET", "/items/")
+	req.Next = "invalid"
+	if _, err := api.ListPage(req); err == nil {
+		t.Error("Expected an error for an invalid token")
+	}
+}
+
+func TestGroupsAndEntitlements(t *testing.T) {
+	api := newAPI(t)
+
+	if err := api.PutItem("groups", map[string]interface{}{"group_id": "readers"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.PutItem("auth", map[string]interface{}{"id": "ent1", "read_filters": []interface{}{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	group, err := api.GetGroup(context.Background(), "readers")
+	if err != nil {
+		t.Fatal(err)
+	} else if group == nil || group.ID != "readers" {
+		t.Errorf("Unexpected group %+v", group)
+	}
+
+	if group, err := api.GetGroup(context.Background(), "missing"); err != nil || group != nil {
+		t.Errorf("Expected no group, got %+v, %v", group, err)
+	}
+
+	entitlements, err := api.GetEntitlements(context.Background(), []string{"user1", "ent1", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(entitlements) != 2 {
+		t.Errorf("Expected 2 entitlements, got %d", len(entitlements))
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	api := newAPI(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	// Requests that run past their deadline fail with a timeout error
+	_, err := api.List(request("GET", "/items/").WithContext(ctx))
+	if _, ok := err.(*types.Timeout); !ok {
+		t.Errorf("Expected a timeout error, got %T: %v", err, err)
+	}
+
+	// Cancelled requests fail without being reported as a timeout
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := api.List(request("GET", "/items/").WithContext(ctx)); err == nil {
+		t.Error("Expected an error for a cancelled request")
+	} else if _, ok := err.(*types.Timeout); ok {
+		t.Errorf("Expected a cancellation error, got %v", err)
+	}
+}
+
+func TestAuditAndHistory(t *testing.T) {
+	api := newAPI(t)
+
+	req := request("POST", "/items/")
+	req.Body = map[string]interface{}{"id": "1", "name": "alpha", "status": "active"}
+	if err := api.Create(req, req.Body.(map[string]interface{}), nil, nil, base.AuditActionCreate); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"beta", "gamma"} {
+		_, err := api.Update(request("PUT", "/item/1"), map[string]interface{}{"id": "1"}, map[string]interface{}{"name": name}, nil, nil, base.AuditActionUpdate)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Audit logs are listed newest first
+	logs, err := api.ListAuditLogs(request("GET", "/audit/"), map[string]string{"resource.id": "1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 3 || logs[0].Action != base.AuditActionUpdate || logs[2].Action != base.AuditActionCreate {
+		t.Errorf("Unexpected audit logs %+v", logs)
+	}
+
+	// Logs written again by a retry are only stored once
+	if err := api.AuditSink.Write(context.Background(), logs[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err = api.ListAuditLogs(request("GET", "/audit/"), nil, map[string][]string{"action": {base.AuditActionCreate}})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 1 {
+		t.Errorf("Expected 1 audit log, got %d", len(logs))
+	}
+
+	history, err := api.History(request("GET", "/history/1"), types.Key{"id": "1"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(history) != 3 || history[0].Data["name"] != "gamma" || history[2].Data["name"] != "alpha" {
+		t.Errorf("Unexpected history %+v", history)
+	}
+
+	// Updates and deletes record the previous state of the item and what changed
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err = api.ListAuditLogs(request("GET", "/audit/"), map[string]string{"resource.id": "1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(logs) != 5 || logs[1].Action != base.AuditActionGet {
+		t.Fatalf("Expected 5 audit logs, including reading the history, got %+v", logs)
+	}
+
+	deleted, updated := logs[0], logs[2]
+	if updated.Previous["name"] != "beta" || len(updated.Changes) != 1 {
+		t.Errorf("Unexpected update log %+v", updated)
+	} else if change := updated.Changes["name"]; change.Old != "beta" || change.New != "gamma" {
+		t.Errorf("Unexpected change %+v", change)
+	}
+	if deleted.Previous["name"] != "gamma" || len(deleted.Changes) != 3 {
+		t.Errorf("Unexpected delete log %+v", deleted)
+	} else if change := deleted.Changes["status"]; change.Old != "active" || change.New != nil {
+		t.Errorf("Unexpected change %+v", change)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	api := newAPIWithConfig(t, config.Config{SoftDelete: true})
+
+	for _, item := range []map[string]interface{}{{"id": "1", "name": "alpha", "status": "active"}, {"id": "2", "name": "beta", "status": "active"}} {
+		if err := api.Create(request("POST", "/items/"), item, nil, nil, base.AuditActionCreate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deleted items move to the trash and are hidden from everything else
+	if err := api.Delete(request("DELETE", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err == nil {
+		t.Error("Expected an error getting a deleted item")
+	}
+	if records, err := api.List(request("GET", "/items/")); err != nil {
+		t.Fatal(err)
+	} else if len(records) != 1 || records[0]["id"] != "2" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+
+	if trash, err := api.ListTrash(request("GET", "/trash/")); err != nil {
+		t.Fatal(err)
+	} else if len(trash) != 1 || trash[0][base.DeletedByField] != "user1" {
+		t.Errorf("Unexpected trash %+v", trash)
+	}
+
+	// Recently deleted items are not purged
+	if purged, err := api.PurgeTrash(request("DELETE", "/trash/")); err != nil {
+		t.Fatal(err)
+	} else if purged != 0 {
+		t.Errorf("Expected nothing to be purged, got %d", purged)
+	}
+
+	if _, err := api.RestoreFromTrash(request("POST", "/trash/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := api.Get(request("GET", "/item/1"), types.Key{"id": "1"}); err != nil {
+		t.Fatal(err)
+	} else if _, ok := record[base.DeletedAtField]; ok {
+		t.Errorf("Unexpected record %+v", record)
+	}
+}
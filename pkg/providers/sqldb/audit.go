@@ -0,0 +1,138 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// chainSuffix : Suffix of the table that holds the head of the audit log chain
+const chainSuffix = "_chain"
+
+// chainHeadName : Name of the row that holds the head of the audit log chain
+const chainHeadName = "head"
+
+// auditTable : Audit sink that stores audit logs as JSON documents in a table
+type auditTable struct {
+	api  SQLAPI
+	name string
+}
+
+// AuditTableSink : Sink that stores audit logs in the audit table of the database
+func (api SQLAPI) AuditTableSink() base.AuditSink {
+	return auditTable{api: api, name: api.Config.AuditTable}
+}
+
+// Write : Add an audit log to the table. Logs that are already in the table are skipped.
+func (sink auditTable) Write(ctx context.Context, auditLog types.AuditLog) error {
+	data, err := json.Marshal(auditLog)
+	if err != nil {
+		return err
+	}
+
+	if auditLog.ID != "" {
+		id, args := sink.api.Dialect.Text(dataColumn, []string{"id"})
+		_, err = sink.api.DB.ExecContext(ctx, sink.api.Dialect.Rebind(fmt.Sprintf(
+			"INSERT INTO %[1]s (%[2]s) SELECT CAST(? AS %[3]s) WHERE NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[4]s = ?)",
+			quoteIdent(sink.name), dataColumn, sink.api.Dialect.JSONType(), id,
+		)), append(append([]interface{}{string(data)}, args...), auditLog.ID)...)
+
+		return base.ContextError(ctx, err)
+	}
+
+	_, err = sink.api.DB.ExecContext(ctx, sink.api.Dialect.Rebind(fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (CAST(? AS %s))",
+		quoteIdent(sink.name), dataColumn, sink.api.Dialect.JSONType(),
+	)), string(data))
+
+	return base.ContextError(ctx, err)
+}
+
+// Query : List the audit logs in the table, newest first
+func (sink auditTable) Query(ctx context.Context, filters map[string][]string, limit int, next string) (types.Page[types.AuditLog], error) {
+	// Filter using the JSON field names of the audit log
+	conditions, err := sink.api.filtering.Filter(nil, filters, "")
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	records, err := sink.api.page(ctx, sink.api.DB, sink.name, `"id"`, true, conditions, limit, next, "")
+	if err != nil {
+		return types.Page[types.AuditLog]{}, err
+	}
+
+	page := types.Page[types.AuditLog]{Items: []types.AuditLog{}, Next: records.Next}
+	for _, record := range records.Items {
+		auditLog, err := decode[types.AuditLog](record)
+		if err != nil {
+			return types.Page[types.AuditLog]{}, err
+		}
+		page.Items = append(page.Items, *auditLog)
+	}
+
+	return page, nil
+}
+
+// auditChain : Head of the audit log chain, kept in a single row of the chain table
+type auditChain struct {
+	api  SQLAPI
+	name string
+}
+
+// AuditChainStore : Store that keeps the head of the audit log chain in a table next to the audit table. Returns nil
+// if there is no audit table.
+func (api SQLAPI) AuditChainStore() base.AuditChainStore {
+	if api.Config.AuditTable == "" {
+		return nil
+	}
+
+	return auditChain{api: api, name: api.Config.AuditTable + chainSuffix}
+}
+
+// Head : Read the head of the chain
+func (chain auditChain) Head(ctx context.Context) (base.AuditChainHead, error) {
+	var head base.AuditChainHead
+	err := chain.api.DB.QueryRowContext(ctx, chain.api.Dialect.Rebind(fmt.Sprintf(
+		`SELECT "sequence", "hash" FROM %s WHERE "name" = ?`, quoteIdent(chain.name),
+	)), chainHeadName).Scan(&head.Sequence, &head.Hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return base.AuditChainHead{}, nil
+	}
+
+	return head, base.ContextError(ctx, err)
+}
+
+// Advance : Move the head of the chain on, if it has not moved since it was read. The first log of the chain inserts
+// the row, and every log after that updates it.
+func (chain auditChain) Advance(ctx context.Context, from base.AuditChainHead, to base.AuditChainHead) (bool, error) {
+	var result sql.Result
+	var err error
+	if from == (base.AuditChainHead{}) {
+		result, err = chain.api.DB.ExecContext(ctx, chain.api.Dialect.Rebind(fmt.Sprintf(
+			`INSERT INTO %s ("name", "sequence", "hash") VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+			quoteIdent(chain.name),
+		)), chainHeadName, to.Sequence, to.Hash)
+	} else {
+		result, err = chain.api.DB.ExecContext(ctx, chain.api.Dialect.Rebind(fmt.Sprintf(
+			`UPDATE %s SET "sequence" = ?, "hash" = ? WHERE "name" = ? AND "sequence" = ? AND "hash" = ?`,
+			quoteIdent(chain.name),
+		)), to.Sequence, to.Hash, chainHeadName, from.Sequence, from.Hash)
+	}
+	if err != nil {
+		return false, base.ContextError(ctx, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
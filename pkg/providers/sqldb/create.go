@@ -0,0 +1,61 @@
+package sqldb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Create : Create an item
+func (api SQLAPI) Create(req types.Request, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) error {
+	// Get the user
+	user, err := api.PrepareCreate(req, item, validation, requiredFields)
+	if err != nil {
+		// Bad user - pass the error through
+		return err
+	}
+
+	// Make sure the primary key was provided
+	item = api.InitialVersion(item)
+	id, err := api.key(api.Config.DataTable, item)
+	if err != nil {
+		return err
+	}
+
+	// Insert the item, unless the key is already in use
+	if inserted, err := api.insertRow(req.Context(), api.DB, id, item); err != nil {
+		log.Errorln("Encountered error while attempting to create record", err)
+		return err
+	} else if !inserted {
+		log.Errorln("Encountered error while attempting to create record: item already exists")
+		return &types.BadRequest{
+			Message: "Item already exists or you do not have permission to create it",
+		}
+	}
+
+	// Create audit log, recording every field as a change
+	return api.WriteChangeAuditLog(auditAction, req, user, api.keyOf(api.Config.DataTable, item), nil, nil, item)
+}
+
+// insertRow : Add an item to the data table. Returns false if its key is already in use.
+func (api SQLAPI) insertRow(ctx context.Context, q queryer, id string, record map[string]interface{}) (bool, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := q.ExecContext(ctx, api.Dialect.Rebind(fmt.Sprintf(
+		"INSERT INTO %[1]s (%[2]s, %[3]s) VALUES (?, CAST(? AS %[4]s)) ON CONFLICT (%[2]s) DO NOTHING",
+		quoteIdent(api.Config.DataTable), keyColumn, dataColumn, api.Dialect.JSONType(),
+	)), id, string(data))
+	if err != nil {
+		return false, base.ContextError(ctx, err)
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
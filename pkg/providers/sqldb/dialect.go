@@ -0,0 +1,194 @@
+package sqldb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect : Differences between the SQL databases supported by the SQLAPI. Queries are written with ?
+// placeholders and converted with Rebind before they are executed.
+type Dialect interface {
+	// Rebind : Convert a query written with ? placeholders into the placeholder style of the database
+	Rebind(query string) string
+
+	// JSONType : Column type used to store JSON documents
+	JSONType() string
+
+	// SerialType : Column type of an auto-incrementing primary key
+	SerialType() string
+
+	// Text : Expression that extracts a JSON field as text. Strings are returned without quotes.
+	Text(column string, path []string) (string, []interface{})
+
+	// Number : Expression that extracts a JSON field as a number, or NULL if the field is not a number
+	Number(column string, path []string) (string, []interface{})
+
+	// Exists : Expression that is true if a JSON field exists
+	Exists(column string, path []string) (string, []interface{})
+
+	// Position : Expression for the 1-based position of needle in haystack, or 0 if it is not found
+	Position(haystack string, needle string) string
+
+	// LockClause : Suffix added to a SELECT to lock the selected rows until the transaction ends
+	LockClause() string
+}
+
+// dialects : Dialects by name, and by the name of the database/sql drivers that use them
+var dialects = map[string]Dialect{
+	"sqlite":   SQLite{},
+	"sqlite3":  SQLite{},
+	"postgres": Postgres{},
+	"pgx":      Postgres{},
+}
+
+// GetDialect : Look up a dialect by name. Driver names such as "sqlite3" and "pgx" are also accepted.
+func GetDialect(name string) (Dialect, error) {
+	dialect, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL dialect '%s'", name)
+	}
+
+	return dialect, nil
+}
+
+// quoteIdent : Quote a table or column name
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// SQLite : Dialect for SQLite, using its built in JSON functions. Documents are stored as text.
+type SQLite struct{}
+
+// Rebind : SQLite uses ? placeholders natively
+func (SQLite) Rebind(query string) string {
+	return query
+}
+
+// JSONType : Documents are stored as text
+func (SQLite) JSONType() string {
+	return "TEXT"
+}
+
+// SerialType : Auto-incrementing integer key
+func (SQLite) SerialType() string {
+	return "INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+
+// path : Build a JSON path such as $."a"."b"
+func (SQLite) path(path []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, part := range path {
+		b.WriteString(`."`)
+		b.WriteString(strings.ReplaceAll(part, `"`, `\"`))
+		b.WriteString(`"`)
+	}
+
+	return b.String()
+}
+
+// Text : Booleans are extracted as true/false rather than 1/0 so they compare the same as the other providers
+func (d SQLite) Text(column string, path []string) (string, []interface{}) {
+	p := d.path(path)
+	expr := fmt.Sprintf(
+		"(CASE json_type(%[1]s, ?) WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE CAST(json_extract(%[1]s, ?) AS TEXT) END)",
+		column,
+	)
+
+	return expr, []interface{}{p, p}
+}
+
+// Number : Extract integer and real values
+func (d SQLite) Number(column string, path []string) (string, []interface{}) {
+	p := d.path(path)
+	expr := fmt.Sprintf("(CASE WHEN json_type(%[1]s, ?) IN ('integer', 'real') THEN json_extract(%[1]s, ?) END)", column)
+
+	return expr, []interface{}{p, p}
+}
+
+// Exists : json_type is NULL only when the path does not exist
+func (d SQLite) Exists(column string, path []string) (string, []interface{}) {
+	return fmt.Sprintf("(json_type(%s, ?) IS NOT NULL)", column), []interface{}{d.path(path)}
+}
+
+// Position : Position of a substring
+func (SQLite) Position(haystack string, needle string) string {
+	return fmt.Sprintf("instr(%s, %s)", haystack, needle)
+}
+
+// LockClause : SQLite locks the whole database on write, so no row locks are needed
+func (SQLite) LockClause() string {
+	return ""
+}
+
+// Postgres : Dialect for PostgreSQL. Documents are stored as JSONB.
+type Postgres struct{}
+
+// Rebind : Convert ? placeholders into $1, $2, ...
+func (Postgres) Rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// JSONType : Documents are stored as JSONB
+func (Postgres) JSONType() string {
+	return "JSONB"
+}
+
+// SerialType : Auto-incrementing integer key
+func (Postgres) SerialType() string {
+	return "BIGSERIAL PRIMARY KEY"
+}
+
+// path : Build a text array literal such as {"a","b"}, so the path can be passed as a single argument
+func (Postgres) path(path []string) string {
+	parts := make([]string, len(path))
+	for i, part := range path {
+		part = strings.ReplaceAll(part, `\`, `\\`)
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `\"`) + `"`
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// Text : The #>> operator extracts a field as text
+func (d Postgres) Text(column string, path []string) (string, []interface{}) {
+	return fmt.Sprintf("(%s #>> CAST(? AS TEXT[]))", column), []interface{}{d.path(path)}
+}
+
+// Number : Extract numeric values
+func (d Postgres) Number(column string, path []string) (string, []interface{}) {
+	p := d.path(path)
+	expr := fmt.Sprintf(
+		"(CASE WHEN jsonb_typeof(%[1]s #> CAST(? AS TEXT[])) = 'number' THEN CAST(%[1]s #>> CAST(? AS TEXT[]) AS NUMERIC) END)",
+		column,
+	)
+
+	return expr, []interface{}{p, p}
+}
+
+// Exists : The #> operator returns NULL only when the path does not exist
+func (d Postgres) Exists(column string, path []string) (string, []interface{}) {
+	return fmt.Sprintf("(%s #> CAST(? AS TEXT[]) IS NOT NULL)", column), []interface{}{d.path(path)}
+}
+
+// Position : Position of a substring
+func (Postgres) Position(haystack string, needle string) string {
+	return fmt.Sprintf("strpos(%s, %s)", haystack, needle)
+}
+
+// LockClause : Lock the selected rows
+func (Postgres) LockClause() string {
+	return " FOR UPDATE"
+}
@@ -0,0 +1,331 @@
+package sqldb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+)
+
+// dataColumn : Column holding the JSON document of each record
+const dataColumn = `"data"`
+
+// SQLCondition : A parameterised fragment of a WHERE clause. Placeholders are written as ? and are
+// converted by the dialect when the query is executed.
+type SQLCondition struct {
+	Query string
+	Args  []interface{}
+}
+
+// SQLFiltering : Used by the SQLAPI to translate filters into WHERE clauses on the JSON document of each record
+type SQLFiltering struct {
+	base.Filtering
+	dialect Dialect
+}
+
+func NewFilter(dialect Dialect) SQLFiltering {
+	f := SQLFiltering{dialect: dialect}
+	f.FilterBase = &f
+	f.ScoutrFilters = &f
+	return f
+}
+
+// Operations : Map of supported operations for this filter provider
+func (f *SQLFiltering) Operations() base.OperationMap {
+	return base.OperationMap{
+		base.OperationStartsWith:       f.StartsWith,
+		base.OperationEqual:            f.Equals,
+		base.OperationNotEqual:         f.NotEqual,
+		base.OperationContains:         f.Contains,
+		base.OperationNotContains:      f.NotContains,
+		base.OperationExists:           f.Exists,
+		base.OperationGreaterThan:      f.GreaterThan,
+		base.OperationLessThan:         f.LessThan,
+		base.OperationGreaterThanEqual: f.GreaterThanEqual,
+		base.OperationLessThanEqual:    f.LessThanEqual,
+		base.OperationBetween:          f.Between,
+		base.OperationIn:               f.In,
+		base.OperationNotIn:            f.NotIn,
+	}
+}
+
+// combine : Join two conditions with a boolean operator
+func combine(op string, condition1, condition2 interface{}) interface{} {
+	cond1, ok1 := condition1.(*SQLCondition)
+	cond2, ok2 := condition2.(*SQLCondition)
+
+	if ok1 && cond1 != nil && ok2 && cond2 != nil {
+		args := make([]interface{}, 0, len(cond1.Args)+len(cond2.Args))
+		args = append(args, cond1.Args...)
+		args = append(args, cond2.Args...)
+
+		return &SQLCondition{
+			Query: fmt.Sprintf("(%s %s %s)", cond1.Query, op, cond2.Query),
+			Args:  args,
+		}
+	} else if ok1 && cond1 != nil {
+		return cond1
+	} else if ok2 && cond2 != nil {
+		return cond2
+	}
+
+	return nil
+}
+
+// And : Takes two conditions and performs an AND operation on them
+func (f *SQLFiltering) And(condition1, condition2 interface{}) interface{} {
+	return combine("AND", condition1, condition2)
+}
+
+// Or : Takes two conditions and performs an OR operation on them
+func (f *SQLFiltering) Or(condition1, condition2 interface{}) interface{} {
+	return combine("OR", condition1, condition2)
+}
+
+// not : Negate a condition. Conditions on missing fields evaluate to NULL, which is treated as false.
+func not(condition interface{}) *SQLCondition {
+	cond := condition.(*SQLCondition)
+	return &SQLCondition{
+		Query: fmt.Sprintf("(NOT COALESCE(%s, FALSE))", cond.Query),
+		Args:  cond.Args,
+	}
+}
+
+// toCondition : Extract the SQL condition from a set of conditions
+func toCondition(conditions interface{}) *SQLCondition {
+	if condition, ok := conditions.(*SQLCondition); ok {
+		return condition
+	}
+
+	return nil
+}
+
+// validate : Check the value of an operation the same way base.LocalFiltering does, so that all
+// providers reject the same filters
+func validate(operation string, key string, value interface{}) error {
+	_, err := base.NewLocalFilter(nil).Operations()[operation](key, value)
+	return err
+}
+
+// toFloat : Convert a filter value into a number, if possible
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+
+	return 0, false
+}
+
+// field : Expressions for a field of the document as text and as a number. Dots in the key refer to nested fields.
+func (f *SQLFiltering) field(key string) (text *SQLCondition, number *SQLCondition) {
+	path := strings.Split(key, ".")
+
+	text = &SQLCondition{}
+	text.Query, text.Args = f.dialect.Text(dataColumn, path)
+
+	number = &SQLCondition{}
+	number.Query, number.Args = f.dialect.Number(dataColumn, path)
+
+	return text, number
+}
+
+// compare : Compare a field with a value. Numeric fields are compared numerically, while strings and
+// booleans are compared as text, matching the behavior of base.LocalFiltering.
+func (f *SQLFiltering) compare(key string, op string, value interface{}) *SQLCondition {
+	text, number := f.field(key)
+
+	condition := &SQLCondition{
+		Query: fmt.Sprintf("(%s IS NULL AND %s %s ?)", number.Query, text.Query, op),
+		Args:  append(append(number.Args, text.Args...), fmt.Sprint(value)),
+	}
+
+	if n, ok := toFloat(value); ok {
+		_, number := f.field(key)
+		numeric := &SQLCondition{
+			Query: fmt.Sprintf("%s %s ?", number.Query, op),
+			Args:  append(number.Args, n),
+		}
+		return f.Or(numeric, condition).(*SQLCondition)
+	}
+
+	return condition
+}
+
+// text : Build a condition on the text of a field that is not a number. format contains the text
+// expression as %s, followed by placeholders for args.
+func (f *SQLFiltering) text(key string, format string, args ...interface{}) *SQLCondition {
+	text, number := f.field(key)
+
+	return &SQLCondition{
+		Query: fmt.Sprintf("(%s IS NULL AND %s)", number.Query, fmt.Sprintf(format, text.Query)),
+		Args:  append(append(number.Args, text.Args...), args...),
+	}
+}
+
+// unmarshalValues : Convert a JSON-encoded list of values into a slice
+func unmarshalValues(values interface{}) ([]interface{}, error) {
+	var valueList []interface{}
+
+	s, ok := values.(string)
+	if !ok {
+		return nil, fmt.Errorf("%+v could not be cast as a string", values)
+	}
+
+	if err := json.Unmarshal([]byte(s), &valueList); err != nil {
+		return nil, err
+	}
+
+	return valueList, nil
+}
+
+// Equals : Standard equals operation
+func (f *SQLFiltering) Equals(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationEqual, key, value); err != nil {
+		return nil, err
+	}
+
+	return f.compare(key, "=", value), nil
+}
+
+// NotEqual : Standard not equals operation. Records without the field do not match.
+func (f *SQLFiltering) NotEqual(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationNotEqual, key, value); err != nil {
+		return nil, err
+	}
+
+	exists, _ := f.Exists(key, "true")
+	return f.And(exists, not(f.compare(key, "=", value))), nil
+}
+
+// Contains : Check if a string contains a value
+func (f *SQLFiltering) Contains(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationContains, key, value); err != nil {
+		return nil, err
+	}
+
+	format := f.dialect.Position("%s", "CAST(? AS TEXT)") + " > 0"
+	return f.text(key, format, fmt.Sprint(value)), nil
+}
+
+// NotContains : Check for values that do not contain a string
+func (f *SQLFiltering) NotContains(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationNotContains, key, value); err != nil {
+		return nil, err
+	}
+
+	condition, _ := f.Contains(key, value)
+	return not(condition), nil
+}
+
+// StartsWith : Check if a string starts with a value
+func (f *SQLFiltering) StartsWith(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationStartsWith, key, value); err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprint(value)
+	return f.text(key, "substr(%s, 1, length(CAST(? AS TEXT))) = CAST(? AS TEXT)", prefix, prefix), nil
+}
+
+// Exists : Checks if an attribute exists. Only accepts true/false values.
+func (f *SQLFiltering) Exists(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationExists, key, value); err != nil {
+		return nil, err
+	}
+
+	condition := &SQLCondition{}
+	condition.Query, condition.Args = f.dialect.Exists(dataColumn, strings.Split(key, "."))
+
+	if value == "false" || value == false {
+		return not(condition), nil
+	}
+
+	return condition, nil
+}
+
+// GreaterThan : Check if a value is greater than another value
+func (f *SQLFiltering) GreaterThan(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationGreaterThan, key, value); err != nil {
+		return nil, err
+	}
+
+	return f.compare(key, ">", value), nil
+}
+
+// LessThan : Check if a value is less than another value
+func (f *SQLFiltering) LessThan(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationLessThan, key, value); err != nil {
+		return nil, err
+	}
+
+	return f.compare(key, "<", value), nil
+}
+
+// GreaterThanEqual : Check if a value is greater than or equal to another value
+func (f *SQLFiltering) GreaterThanEqual(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationGreaterThanEqual, key, value); err != nil {
+		return nil, err
+	}
+
+	return f.compare(key, ">=", value), nil
+}
+
+// LessThanEqual : Check if a value is less than or equal to another value
+func (f *SQLFiltering) LessThanEqual(key string, value interface{}) (interface{}, error) {
+	if err := validate(base.OperationLessThanEqual, key, value); err != nil {
+		return nil, err
+	}
+
+	return f.compare(key, "<=", value), nil
+}
+
+// Between : Check for records that are between a low and high value
+//
+// Operator: key__between=["1", "2"]
+func (f *SQLFiltering) Between(key string, values interface{}) (interface{}, error) {
+	if err := validate(base.OperationBetween, key, values); err != nil {
+		return nil, err
+	}
+
+	valueList, _ := unmarshalValues(values)
+	return f.And(f.compare(key, ">=", valueList[0]), f.compare(key, "<=", valueList[1])), nil
+}
+
+// In : Find all records with a list of values
+func (f *SQLFiltering) In(key string, values interface{}) (interface{}, error) {
+	if err := validate(base.OperationIn, key, values); err != nil {
+		return nil, err
+	}
+
+	valueList, _ := unmarshalValues(values)
+	if len(valueList) == 0 {
+		return &SQLCondition{Query: "(1 = 0)"}, nil
+	}
+
+	var conditions interface{}
+	for _, value := range valueList {
+		conditions = f.Or(conditions, f.compare(key, "=", value))
+	}
+
+	return conditions, nil
+}
+
+// NotIn : Find all records without a list of values
+func (f *SQLFiltering) NotIn(key string, values interface{}) (interface{}, error) {
+	if err := validate(base.OperationNotIn, key, values); err != nil {
+		return nil, err
+	}
+
+	condition, _ := f.In(key, values)
+	return not(condition), nil
+}
@@ -0,0 +1,179 @@
+package sqldb_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/sqldb"
+)
+
+func TestOperations(t *testing.T) {
+	f := sqldb.NewFilter(sqldb.SQLite{})
+
+	operationMap := f.Operations()
+
+	if len(operationMap) != 13 {
+		t.Errorf("Expected 13 operations, but got %d", len(operationMap))
+	}
+}
+
+func TestAndOr(t *testing.T) {
+	f := sqldb.NewFilter(sqldb.SQLite{})
+
+	cond1 := &sqldb.SQLCondition{Query: "a = ?", Args: []interface{}{1}}
+	cond2 := &sqldb.SQLCondition{Query: "b = ?", Args: []interface{}{2}}
+
+	tests := []struct {
+		name     string
+		output   interface{}
+		expected interface{}
+	}{
+		{"And", f.And(cond1, cond2), &sqldb.SQLCondition{Query: "(a = ? AND b = ?)", Args: []interface{}{1, 2}}},
+		{"Or", f.Or(cond1, cond2), &sqldb.SQLCondition{Query: "(a = ? OR b = ?)", Args: []interface{}{1, 2}}},
+		{"AndCond1", f.And(cond1, nil), cond1},
+		{"OrCond2", f.Or(nil, cond2), cond2},
+		{"Nil", f.And(nil, nil), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !reflect.DeepEqual(test.output, test.expected) {
+				t.Errorf("Expected %+v, got %+v", test.expected, test.output)
+			}
+		})
+	}
+}
+
+func TestPostgresRebind(t *testing.T) {
+	query := sqldb.Postgres{}.Rebind(`SELECT "data" FROM "t" WHERE a = ? AND (b = ? OR c = ?)`)
+
+	expected := `SELECT "data" FROM "t" WHERE a = $1 AND (b = $2 OR c = $3)`
+	if query != expected {
+		t.Errorf("Expected %s, got %s", expected, query)
+	}
+}
+
+func TestPostgresPath(t *testing.T) {
+	query, args := sqldb.Postgres{}.Text(`"data"`, []string{"a", `b"c`})
+
+	if query != `("data" #>> CAST(? AS TEXT[]))` {
+		t.Errorf("Unexpected query %s", query)
+	}
+
+	if !reflect.DeepEqual(args, []interface{}{`{"a","b\"c"}`}) {
+		t.Errorf("Unexpected args %+v", args)
+	}
+}
+
+func TestGetDialect(t *testing.T) {
+	for name, expected := range map[string]sqldb.Dialect{
+		"sqlite3":  sqldb.SQLite{},
+		"postgres": sqldb.Postgres{},
+		"pgx":      sqldb.Postgres{},
+	} {
+		if dialect, err := sqldb.GetDialect(name); err != nil {
+			t.Error(err)
+		} else if dialect != expected {
+			t.Errorf("Unexpected dialect for %s: %T", name, dialect)
+		}
+	}
+
+	if _, err := sqldb.GetDialect("oracle"); err == nil {
+		t.Error("Expected an error for an unsupported dialect")
+	}
+}
+
+func TestOperatorErrors(t *testing.T) {
+	f := sqldb.NewFilter(sqldb.SQLite{})
+
+	tests := []struct {
+		operation string
+		value     interface{}
+	}{
+		{base.OperationExists, "maybe"},
+		{base.OperationBetween, `["1"]`},
+		{base.OperationBetween, "1"},
+		{base.OperationIn, `{"a": 1}`},
+		{base.OperationNotIn, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.operation, func(t *testing.T) {
+			if _, err := f.Operations()[test.operation]("key", test.value); err == nil {
+				t.Errorf("Expected an error for %v", test.value)
+			}
+		})
+	}
+}
+
+func TestOperatorsMatchLocalFiltering(t *testing.T) {
+	api := newAPI(t)
+
+	records := []map[string]interface{}{
+		{"id": "1", "name": "alpha", "count": 1, "enabled": true, "meta": map[string]interface{}{"tier": "gold"}},
+		{"id": "2", "name": "beta", "count": 10, "enabled": false, "meta": map[string]interface{}{"tier": "silver"}},
+		{"id": "3", "name": "Alphabet", "count": 2.5, "code": "10"},
+		{"id": "4", "name": "gamma", "count": "9", "code": "9"},
+		{"id": "5", "name": nil},
+	}
+	for _, record := range records {
+		if err := api.PutItem("data", record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filters := []map[string][]string{
+		{"name": {"alpha"}},
+		{"name__ne": {"alpha"}},
+		{"name__startswith": {"Alpha"}},
+		{"name__contains": {"lph"}},
+		{"name__notcontains": {"lph"}},
+		{"code__exists": {"true"}},
+		{"code__exists": {"false"}},
+		{"meta.tier": {"gold"}},
+		{"count__gt": {"2"}},
+		{"count__lt": {"10"}},
+		{"count__ge": {"2.5"}},
+		{"count__le": {"1"}},
+		{"count__between": {`[1, 3]`}},
+		{"code__gt": {"2"}},
+		{"count": {"10"}},
+		{"enabled": {"true"}},
+		{"name__in": {`["alpha", "beta"]`}},
+		{"name__notin": {`["alpha", "beta"]`}},
+		{"count__in": {`[1, 10]`}},
+		{"name__in": {`[]`}},
+	}
+
+	for _, filter := range filters {
+		// Determine the expected results using local filtering
+		var expected []string
+		for _, record := range records {
+			f := base.NewLocalFilter(record)
+			conditions, err := f.Filter(nil, filter, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Matches(conditions) {
+				expected = append(expected, record["id"].(string))
+			}
+		}
+
+		req := request("GET", "/items/")
+		req.QueryParams = filter
+		output, err := api.List(req)
+		if err != nil {
+			t.Fatalf("%v: %v", filter, err)
+		}
+
+		var ids []string
+		for _, record := range output {
+			ids = append(ids, record["id"].(string))
+		}
+
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("%v: expected %v, got %v", filter, expected, ids)
+		}
+	}
+}
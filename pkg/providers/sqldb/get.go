@@ -0,0 +1,82 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Get : Get an item from the table. Tables with a sort key can also be looked up by partition key alone, in
+// which case the partition must contain a single item.
+func (api SQLAPI) Get(req types.Request, key types.Key) (types.Record, error) {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return nil, err
+	}
+
+	// Apply all the filter criteria for the user. Items the user is not permitted to view are treated
+	// as though they do not exist
+	conditions, err := api.filtering.Filter(user, nil, base.FilterActionRead)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return nil, err
+	}
+
+	// Items are looked up by the key column when the whole key is known
+	if partitionKey := api.KeyFields()[0]; key[partitionKey] == nil {
+		return nil, &types.BadRequest{
+			Message: fmt.Sprintf("Missing required key fields: [%s]", partitionKey),
+		}
+	} else if id, err := api.key(api.Config.DataTable, key); err == nil {
+		conditions = api.filtering.And(keyCondition(id), conditions)
+	} else {
+		for field, value := range key {
+			condition, err := api.filtering.Equals(field, value)
+			if err != nil {
+				return nil, err
+			}
+			conditions = api.filtering.And(conditions, condition)
+		}
+	}
+
+	records, err := api.query(req.Context(), api.DB, api.Config.DataTable, conditions, "")
+	if err != nil {
+		log.Errorln("Error while attempting to get item", err)
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, &types.NotFound{
+			Message: "Item does not exist or you do not have permission to view it",
+		}
+	} else if len(records) > 1 {
+		return nil, &types.BadRequest{
+			Message: "Multiple items returned",
+		}
+	}
+
+	// Filter the response
+	api.PostProcess(records[:1], user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionGet, req, user, key, nil); err != nil {
+		return nil, err
+	}
+
+	return records[0], nil
+}
+
+// FetchItem : Fetch an item by its full key, without checking the user's permissions
+func (api SQLAPI) FetchItem(ctx context.Context, key types.Key) (types.Record, error) {
+	id, err := api.key(api.Config.DataTable, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.getItem(ctx, api.Config.DataTable, id)
+}
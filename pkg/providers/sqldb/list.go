@@ -0,0 +1,128 @@
+package sqldb
+
+import (
+	"sort"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// List : Lists all items in a table
+func (api SQLAPI) List(req types.Request) ([]types.Record, error) {
+	page, err := api.list(req, false)
+	return page.Items, err
+}
+
+// ListPage : Lists a page of items in a table
+func (api SQLAPI) ListPage(req types.Request) (types.Page[types.Record], error) {
+	return api.list(req, true)
+}
+
+// list : Lists the items in a table, either all at once or a page at a time
+func (api SQLAPI) list(req types.Request, paginate bool) (types.Page[types.Record], error) {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return types.Page[types.Record]{}, err
+	}
+
+	limit := 0
+	if paginate {
+		if limit, err = base.PageLimit(req); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+	}
+
+	// Build the filters
+	params := api.BuildParams(req)
+	conditions, err := api.filtering.Filter(user, params, base.FilterActionRead)
+	if err != nil {
+		log.WithError(err).Error("Filtering failed")
+
+		if api.Config.ErrorFunc != nil {
+			api.Config.ErrorFunc(&req, user, err)
+		}
+
+		return types.Page[types.Record]{}, err
+	}
+
+	// Find matching records
+	page, err := api.page(req.Context(), api.DB, api.Config.DataTable, keyColumn, false, conditions, limit, req.Next, "")
+	if err != nil {
+		log.WithError(err).Error("Query failed")
+		return types.Page[types.Record]{}, err
+	}
+
+	// Filter the response
+	api.PostProcess(page.Items, user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionList, req, user, nil, nil); err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	return page, nil
+}
+
+// ListUniqueValues : Lists unique values in a table
+func (api SQLAPI) ListUniqueValues(req types.Request, uniqueKey string) ([]string, error) {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return nil, err
+	}
+
+	// Copy queryParams into params
+	params := make(map[string][]string)
+	for key, values := range req.QueryParams {
+		params[key] = append(params[key], values...)
+	}
+
+	// Build the filters
+	conditions, err := api.filtering.Filter(user, params, base.FilterActionRead)
+	if err != nil {
+		log.WithError(err).Error("Filtering failed")
+
+		if api.Config.ErrorFunc != nil {
+			api.Config.ErrorFunc(&req, user, err)
+		}
+
+		return nil, err
+	}
+
+	// Find matching records
+	records, err := api.query(req.Context(), api.DB, api.Config.DataTable, conditions, "")
+	if err != nil {
+		log.WithError(err).Error("Query failed")
+		return nil, err
+	}
+
+	// Filter the response
+	api.PostProcess(records, user)
+
+	// Collect the unique values. Only string values are supported
+	unique := make(map[string]bool)
+	for _, item := range records {
+		if value, ok := item[uniqueKey].(string); ok {
+			unique[value] = true
+		}
+	}
+
+	values := []string{}
+	for value := range unique {
+		values = append(values, value)
+	}
+
+	// Sort the data
+	sort.Strings(values)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionList, req, user, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
@@ -0,0 +1,71 @@
+package sqldb
+
+import (
+	"context"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Search : Search items in the table
+func (api SQLAPI) Search(req types.Request, key string, values []string) ([]types.Record, error) {
+	page, err := api.search(req, key, values, false)
+	return page.Items, err
+}
+
+// SearchPage : Search a page of items in the table
+func (api SQLAPI) SearchPage(req types.Request, key string, values []string) (types.Page[types.Record], error) {
+	return api.search(req, key, values, true)
+}
+
+// search : Search items in the table, either all at once or a page at a time
+func (api SQLAPI) search(req types.Request, key string, values []string, paginate bool) (types.Page[types.Record], error) {
+	// Get the user
+	user, err := api.InitializeRequest(req)
+	if err != nil {
+		// Bad user - pass the error through
+		return types.Page[types.Record]{}, err
+	}
+
+	limit := 0
+	if paginate {
+		if limit, err = base.PageLimit(req); err != nil {
+			return types.Page[types.Record]{}, err
+		}
+	}
+
+	// Find matching records
+	conditions, err := api.filtering.MultiFilter(user, key, values)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return types.Page[types.Record]{}, err
+	}
+
+	page, err := api.page(req.Context(), api.DB, api.Config.DataTable, keyColumn, false, conditions, limit, req.Next, "")
+	if err != nil {
+		log.Errorln("Error encountered while querying", err)
+		return types.Page[types.Record]{}, err
+	}
+
+	// Filter the response
+	api.PostProcess(page.Items, user)
+
+	// Create audit log
+	if err := api.WriteAuditLog(base.AuditActionSearch, req, user, nil, nil); err != nil {
+		return types.Page[types.Record]{}, err
+	}
+
+	return page, nil
+}
+
+// FindItems : Find the items with one of the values in a field, without checking the user's permissions
+func (api SQLAPI) FindItems(ctx context.Context, key string, values []string) ([]types.Record, error) {
+	conditions, err := api.filtering.MultiFilter(nil, key, values)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := api.page(ctx, api.DB, api.Config.DataTable, keyColumn, false, conditions, 0, "", "")
+	return page.Items, err
+}
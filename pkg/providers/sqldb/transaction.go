@@ -0,0 +1,86 @@
+package sqldb
+
+import (
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Transaction : Apply a set of operations in a single database transaction. Existing items are locked and checked
+// against the user's filters and the expected versions as they are read, and nothing is committed unless every
+// operation passes.
+func (api SQLAPI) Transaction(req types.Request, operations []types.TransactionOperation, validation map[string]types.FieldValidation) ([]types.Record, error) {
+	transaction, err := api.PrepareTransaction(req, operations, validation)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := req.Context()
+	tx, err := api.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, base.ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	previous := make([]types.Record, len(operations))
+	outputs := make([]types.Record, len(operations))
+	for i, operation := range operations {
+		id, err := api.key(api.Config.DataTable, transaction.Keys[i])
+		if err != nil {
+			return nil, types.NewTransactionFailed(i, err)
+		}
+
+		if operation.Action == types.TransactionCreate {
+			outputs[i] = api.ApplyOperation(transaction, i, nil)
+			if inserted, err := api.insertRow(ctx, tx, id, outputs[i]); err != nil {
+				log.Errorln("Encountered error while attempting to create record", err)
+				return nil, types.NewTransactionFailed(i, err)
+			} else if !inserted {
+				return nil, types.NewTransactionFailed(i, &types.BadRequest{Message: transaction.Failure(i)})
+			}
+			continue
+		}
+
+		// Make sure the item exists and the user is permitted to use it
+		conditions, err := api.filtering.Filter(transaction.User, nil, transaction.FilterAction(i))
+		if err != nil {
+			log.Errorln("Error encountered during filtering", err)
+			return nil, types.NewTransactionFailed(i, err)
+		}
+		records, err := api.query(ctx, tx, api.Config.DataTable, api.filtering.And(keyCondition(id), conditions), api.Dialect.LockClause())
+		if err != nil {
+			return nil, types.NewTransactionFailed(i, err)
+		} else if len(records) == 0 {
+			return nil, types.NewTransactionFailed(i, &types.BadRequest{Message: transaction.Failure(i)})
+		}
+		if err := api.CheckVersion(transaction.OperationRequest(i), records[0]); err != nil {
+			return nil, types.NewTransactionFailed(i, err)
+		}
+
+		previous[i] = records[0]
+		outputs[i] = api.ApplyOperation(transaction, i, records[0])
+		switch {
+		case operation.Action == types.TransactionCheck:
+			continue
+		case outputs[i] == nil:
+			err = api.deleteRow(ctx, tx, id)
+		default:
+			err = api.updateRow(ctx, tx, id, outputs[i])
+		}
+		if err != nil {
+			log.Errorln("Error while attempting to apply transaction", err)
+			return nil, types.NewTransactionFailed(i, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, base.ContextError(ctx, err)
+	}
+
+	// Create audit logs
+	if err := api.AuditTransaction(transaction, previous, outputs); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
@@ -0,0 +1,171 @@
+package sqldb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/providers/base"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Update : Update an item
+func (api SQLAPI) Update(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, requiredFields []string, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, requiredFields)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := clone(item)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the item
+	previous, output, err := api.write(request, user, partitionKey, base.FilterActionUpdate, "Item does not exist or you do not have permission to update it", func(record types.Record) types.Record {
+		for key, value := range changes {
+			record[key] = value
+		}
+		return record
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to update item", err)
+		return nil, err
+	}
+
+	// Create audit log
+	if err := api.WriteChangeAuditLog(auditAction, request, user, partitionKey, item, previous, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// Patch : Partially update an item. Fields with a null value are removed from the record.
+func (api SQLAPI) Patch(request types.Request, partitionKey types.Key, item map[string]interface{}, validation map[string]types.FieldValidation, auditAction string) (interface{}, error) {
+	// Get the user, making sure they may update the fields and the fields are valid
+	user, err := api.PrepareUpdate(request, partitionKey, item, validation, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := clone(item)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the item
+	previous, output, err := api.write(request, user, partitionKey, base.FilterActionUpdate, "Item does not exist or you do not have permission to update it", func(record types.Record) types.Record {
+		for key, value := range changes {
+			if value == nil {
+				delete(record, key)
+			} else {
+				record[key] = value
+			}
+		}
+		return record
+	})
+	if err != nil {
+		log.Errorln("Error while attempting to update item", err)
+		return nil, err
+	}
+
+	// Create audit log
+	if err := api.WriteChangeAuditLog(auditAction, request, user, partitionKey, item, previous, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// write : Replace the item matching the key with the output of fn, as long as the item satisfies the
+// user's filters for the action and is at the version the request expects. If fn returns nil, the item is
+// deleted. Otherwise, its version is incremented. The item is read and written in a single transaction.
+// Returns the previous and new versions of the item.
+func (api SQLAPI) write(req types.Request, user *types.User, partitionKey types.Key, action string, message string, fn func(types.Record) types.Record) (types.Record, types.Record, error) {
+	ctx := req.Context()
+	conditions, err := api.filtering.Filter(user, nil, action)
+	if err != nil {
+		log.Errorln("Error encountered during filtering", err)
+		return nil, nil, err
+	}
+
+	id, err := api.key(api.Config.DataTable, partitionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := api.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, base.ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	// Make sure the item exists and the user is permitted to modify it
+	records, err := api.query(ctx, tx, api.Config.DataTable, api.filtering.And(keyCondition(id), conditions), api.Dialect.LockClause())
+	if err != nil {
+		return nil, nil, err
+	} else if len(records) == 0 {
+		return nil, nil, &types.BadRequest{Message: message}
+	}
+	if err := api.CheckVersion(req, records[0]); err != nil {
+		return nil, nil, err
+	}
+	key := api.keyOf(api.Config.DataTable, records[0])
+
+	previous, err := clone(records[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record := fn(records[0])
+	if record == nil {
+		if err := api.deleteRow(ctx, tx, id); err != nil {
+			return nil, nil, err
+		}
+
+		return previous, nil, base.ContextError(ctx, tx.Commit())
+	}
+
+	// The key of an item cannot be changed
+	for field, value := range key {
+		record[field] = value
+	}
+	api.IncrementVersion(record)
+
+	if err := api.updateRow(ctx, tx, id, record); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, base.ContextError(ctx, err)
+	}
+
+	return previous, record, nil
+}
+
+// updateRow : Replace the data of an item in the data table
+func (api SQLAPI) updateRow(ctx context.Context, q queryer, id string, record types.Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, api.Dialect.Rebind(fmt.Sprintf(
+		"UPDATE %s SET %s = CAST(? AS %s) WHERE %s = ?",
+		quoteIdent(api.Config.DataTable), dataColumn, api.Dialect.JSONType(), keyColumn,
+	)), string(data), id)
+
+	return base.ContextError(ctx, err)
+}
+
+// deleteRow : Remove an item from the data table
+func (api SQLAPI) deleteRow(ctx context.Context, q queryer, id string) error {
+	_, err := q.ExecContext(ctx, api.Dialect.Rebind(fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = ?", quoteIdent(api.Config.DataTable), keyColumn,
+	)), id)
+
+	return base.ContextError(ctx, err)
+}
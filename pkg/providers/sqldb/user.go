@@ -0,0 +1,59 @@
+package sqldb
+
+import (
+	"context"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// GetAuth : Fetch an auth identity from the table
+// Responses:
+//   - nil, nil: user does not exist
+//   - nil, error: error while fetching user
+//   - user, nil: found user
+func (api SQLAPI) GetAuth(ctx context.Context, id string) (*types.User, error) {
+	record, err := api.getItem(ctx, api.Config.AuthTable, id)
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	return decode[types.User](record)
+}
+
+// GetGroup : Fetch a group from the table
+// Responses:
+//   - nil, nil: group does not exist
+//   - nil, error: error while fetching group
+//   - user, nil: found group
+func (api SQLAPI) GetGroup(ctx context.Context, id string) (*types.Group, error) {
+	record, err := api.getItem(ctx, api.Config.GroupTable, id)
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	return decode[types.Group](record)
+}
+
+// GetEntitlements: Fetch entitlements from the database
+func (api SQLAPI) GetEntitlements(ctx context.Context, entitlementIDs []string) ([]types.User, error) {
+	var entitlements []types.User
+
+	if len(entitlementIDs) == 0 {
+		return entitlements, nil
+	}
+
+	records, err := api.query(ctx, api.DB, api.Config.AuthTable, keyCondition(entitlementIDs...), "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		entitlement, err := decode[types.User](record)
+		if err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, *entitlement)
+	}
+
+	return entitlements, nil
+}
@@ -4,25 +4,45 @@ import "time"
 
 // AuditUser : User object used in audit logs
 type AuditUser struct {
-	ID        string `json:"id"`
-	Username  string `json:"username"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	SourceIP  string `json:"source_ip,omitempty"`
-	UserAgent string `json:"user_agent,omitempty"`
+	ID        string `json:"id" dynamodbav:"id"`
+	Username  string `json:"username" dynamodbav:"username"`
+	Name      string `json:"name" dynamodbav:"name"`
+	Email     string `json:"email" dynamodbav:"email"`
+	SourceIP  string `json:"source_ip,omitempty" dynamodbav:"source_ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty" dynamodbav:"user_agent,omitempty"`
 }
 
-// AuditLog : Audit log object
+// AuditChange : Value of a field before and after a change. A nil value means the field was not set.
+type AuditChange struct {
+	Old interface{} `json:"old" dynamodbav:"old"`
+	New interface{} `json:"new" dynamodbav:"new"`
+}
+
+// AuditLog : Audit log object. Dynamo attributes have the same names as the JSON fields, so the audit table can be
+// keyed and filtered by them.
 type AuditLog struct {
-	Time        string                 `json:"time"`
-	User        AuditUser              `json:"user"`
-	Action      string                 `json:"action"`
-	Method      string                 `json:"method"`
-	Path        string                 `json:"path"`
-	ExpireTime  int64                  `json:"expire_time,omitempty"`
-	QueryParams map[string][]string    `json:"query_params,omitempty"`
-	Resource    map[string]interface{} `json:"resource,omitempty"`
-	Body        interface{}            `json:"body,omitempty"`
+	// ID : Unique identifier of the audit log, so sinks can recognise a log that is written again by a retry
+	ID          string                 `json:"id,omitempty" dynamodbav:"id,omitempty"`
+	Time        string                 `json:"time" dynamodbav:"time"`
+	User        AuditUser              `json:"user" dynamodbav:"user"`
+	Action      string                 `json:"action" dynamodbav:"action"`
+	Method      string                 `json:"method" dynamodbav:"method"`
+	Path        string                 `json:"path" dynamodbav:"path"`
+	ExpireTime  int64                  `json:"expire_time,omitempty" dynamodbav:"expire_time,omitempty"`
+	QueryParams map[string][]string    `json:"query_params,omitempty" dynamodbav:"query_params,omitempty"`
+	Resource    map[string]interface{} `json:"resource,omitempty" dynamodbav:"resource,omitempty"`
+	Body        interface{}            `json:"body,omitempty" dynamodbav:"body,omitempty"`
+
+	// Previous and Changes record the state of the item before an update or delete, and the old and new value of
+	// each field that changed
+	Previous map[string]interface{} `json:"previous,omitempty" dynamodbav:"previous,omitempty"`
+	Changes  map[string]AuditChange `json:"changes,omitempty" dynamodbav:"changes,omitempty"`
+
+	// Sequence, PreviousHash and Hash chain the audit logs together when an HMAC key is configured. Hash is an
+	// HMAC over the canonical JSON of the rest of the log, and PreviousHash is the Hash of the log before it.
+	Sequence     int64  `json:"sequence,omitempty" dynamodbav:"sequence,omitempty"`
+	PreviousHash string `json:"previous_hash,omitempty" dynamodbav:"previous_hash,omitempty"`
+	Hash         string `json:"hash,omitempty" dynamodbav:"hash,omitempty"`
 }
 
 type AuditEvent struct {
@@ -47,7 +67,7 @@ type AuditEventData struct {
 }
 
 type AuditEventUserIdentity struct {
-	Type        string `json:"type"`
-	PrincipalId string `json:"principalId"`
-	Details     *User  `json:"details"`
+	Type        string    `json:"type"`
+	PrincipalId string    `json:"principalId"`
+	Details     AuditUser `json:"details"`
 }
@@ -0,0 +1,40 @@
+package types
+
+const (
+	BatchStatusCreated = "created"
+	BatchStatusUpdated = "updated"
+	BatchStatusDeleted = "deleted"
+	BatchStatusFailed  = "failed"
+)
+
+// BatchUpdate : Changes to make to one item of a batch update
+type BatchUpdate struct {
+	Key  Key                    `json:"key"`
+	Item map[string]interface{} `json:"item"`
+}
+
+// BatchResult : Outcome of one item of a batch operation. Failed items carry the same error and errors fields as
+// the error that failed them, which is also kept in Err.
+type BatchResult struct {
+	Key      Key               `json:"key,omitempty"`
+	Status   string            `json:"status"`
+	Item     interface{}       `json:"item,omitempty"`
+	Message  string            `json:"error,omitempty"`
+	Messages map[string]string `json:"errors,omitempty"`
+	Err      error             `json:"-"`
+}
+
+// NewBatchResult : Build the result of one item of a batch operation, which failed if err is set
+func NewBatchResult(key Key, status string, item interface{}, err error) BatchResult {
+	if err == nil {
+		return BatchResult{Key: key, Status: status, Item: item}
+	}
+
+	message, messages := errorMessages(err)
+	return BatchResult{Key: key, Status: BatchStatusFailed, Message: message, Messages: messages, Err: err}
+}
+
+// Failed : Check if the item failed
+func (r BatchResult) Failed() bool {
+	return r.Status == BatchStatusFailed
+}
@@ -2,6 +2,8 @@ package types
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
 )
@@ -74,3 +76,86 @@ func (e *NotFound) Error() string {
 
 	return e.Message
 }
+
+// Timeout : Request was cancelled or did not complete before its deadline
+type Timeout baseError
+
+func (e *Timeout) Error() string {
+	if len(e.Messages) > 0 {
+		bs, err := json.Marshal(e.Messages)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal error data")
+		}
+
+		return string(bs)
+	}
+
+	return e.Message
+}
+
+// PreconditionFailed : Item was modified since the version the request expected
+type PreconditionFailed baseError
+
+func (e *PreconditionFailed) Error() string {
+	if len(e.Messages) > 0 {
+		bs, err := json.Marshal(e.Messages)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal error data")
+		}
+
+		return string(bs)
+	}
+
+	return e.Message
+}
+
+// TransactionFailed : Operation that stopped a transaction from being committed. Err is the error of the operation,
+// and decides how the failure is reported.
+type TransactionFailed struct {
+	Operation int               `json:"operation"`
+	Message   string            `json:"error,omitempty"`
+	Messages  map[string]string `json:"errors,omitempty"`
+	Err       error             `json:"-"`
+}
+
+// NewTransactionFailed : Build the error of a transaction stopped by one of its operations
+func NewTransactionFailed(operation int, err error) *TransactionFailed {
+	message, messages := errorMessages(err)
+	return &TransactionFailed{Operation: operation, Message: message, Messages: messages, Err: err}
+}
+
+func (e *TransactionFailed) Error() string {
+	return fmt.Sprintf("Operation %d: %s", e.Operation, e.Err)
+}
+
+func (e *TransactionFailed) Unwrap() error {
+	return e.Err
+}
+
+// errorMessages : Message and field messages of an error
+func errorMessages(err error) (string, map[string]string) {
+	var (
+		unauthorized *Unauthorized
+		forbidden    *Forbidden
+		badRequest   *BadRequest
+		notFound     *NotFound
+		conflict     *PreconditionFailed
+		timeout      *Timeout
+	)
+	switch {
+	case errors.As(err, &unauthorized):
+		return unauthorized.Message, unauthorized.Messages
+	case errors.As(err, &forbidden):
+		return forbidden.Message, forbidden.Messages
+	case errors.As(err, &badRequest):
+		return badRequest.Message, badRequest.Messages
+	case errors.As(err, &notFound):
+		return notFound.Message, notFound.Messages
+	case errors.As(err, &conflict):
+		return conflict.Message, conflict.Messages
+	case errors.As(err, &timeout):
+		return timeout.Message, timeout.Messages
+	}
+
+	return err.Error(), nil
+}
@@ -97,3 +97,23 @@ func TestNotFoundMessages(t *testing.T) {
 		t.Errorf("Expected error message %s' but got '%s'", expected, err.Error())
 	}
 }
+
+func TestTimeout(t *testing.T) {
+	err := types.Timeout{
+		Message: "timed out",
+	}
+
+	if err.Error() != "timed out" {
+		t.Errorf("Expected error message 'timed out' but got '%s'", err.Error())
+	}
+}
+
+func TestPreconditionFailed(t *testing.T) {
+	err := types.PreconditionFailed{
+		Message: "item was modified",
+	}
+
+	if err.Error() != "item was modified" {
+		t.Errorf("Expected error message 'item was modified' but got '%s'", err.Error())
+	}
+}
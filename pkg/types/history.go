@@ -1,7 +1,20 @@
 package types
 
-// History : History object
+import "time"
+
+// History : Change made to an item. Data is the state of the item after the change, and is nil once the item has
+// been deleted. Versions count the changes made to the item, starting at 1.
 type History struct {
-	Time string `json:"time"`
-	Data Record `json:"data"`
+	Version int                    `json:"version"`
+	Time    string                 `json:"time"`
+	Action  string                 `json:"action"`
+	User    AuditUser              `json:"user"`
+	Changes map[string]AuditChange `json:"changes,omitempty"`
+	Data    Record                 `json:"data"`
+}
+
+// HistoryPoint : Point in the history of an item, given either as a time or a version
+type HistoryPoint struct {
+	Time    time.Time
+	Version int
 }
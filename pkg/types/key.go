@@ -0,0 +1,17 @@
+package types
+
+import "fmt"
+
+// Key : Values of the key attributes that identify an item. Tables with a sort key are addressed by
+// both their partition key and sort key.
+type Key map[string]interface{}
+
+// AuditParams : Path params that match the audit logs of the item with this key
+func (key Key) AuditParams() map[string]string {
+	params := make(map[string]string)
+	for field, value := range key {
+		params["resource."+field] = fmt.Sprint(value)
+	}
+
+	return params
+}
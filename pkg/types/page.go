@@ -0,0 +1,8 @@
+package types
+
+// Page : A page of results. Next is the continuation token used to fetch the following page, and is
+// empty on the last page.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next,omitempty"`
+}
@@ -1,5 +1,7 @@
 package types
 
+import "context"
+
 // Record : Data record
 type Record map[string]interface{}
 
@@ -27,4 +29,36 @@ type Request struct {
 	UserAgent   string
 	PathParams  map[string]string
 	QueryParams map[string][]string
+
+	// Limit and Next select a page of results for the paginated operations. Next is the opaque
+	// continuation token returned with the previous page.
+	Limit int
+	Next  string
+
+	// IfMatch : Entity tag of the version of the item the request expects to modify. Writes fail with
+	// PreconditionFailed if the item has been modified since.
+	IfMatch string
+
+	ctx context.Context
+}
+
+// Context : Context of the request, used to cancel backend calls made on its behalf. Defaults to the background context.
+func (r Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+
+	return context.Background()
+}
+
+// WithContext : Copy of the request bound to a context
+func (r Request) WithContext(ctx context.Context) Request {
+	r.ctx = ctx
+	return r
+}
+
+// WithBody : Copy of the request with a different body, such as a single item of a batch
+func (r Request) WithBody(body interface{}) Request {
+	r.Body = body
+	return r
 }
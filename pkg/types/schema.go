@@ -0,0 +1,65 @@
+package types
+
+import "encoding/json"
+
+// JSONSchema : JSON Schema that the items of the data table must satisfy. The type, enum, pattern, minLength,
+// maxLength, minimum, maximum, exclusiveMinimum, exclusiveMaximum, required, properties, additionalProperties,
+// items, minItems, maxItems and uniqueItems keywords are supported. Schemas may also be true or false, as for
+// additionalProperties.
+type JSONSchema struct {
+	Type    JSONSchemaType `json:"type,omitempty"`
+	Enum    []interface{}  `json:"enum,omitempty"`
+	Pattern string         `json:"pattern,omitempty"`
+
+	MinLength *int `json:"minLength,omitempty"`
+	MaxLength *int `json:"maxLength,omitempty"`
+
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+
+	Required             []string               `json:"required,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+
+	Items       *JSONSchema `json:"items,omitempty"`
+	MinItems    *int        `json:"minItems,omitempty"`
+	MaxItems    *int        `json:"maxItems,omitempty"`
+	UniqueItems bool        `json:"uniqueItems,omitempty"`
+
+	// Reject : Set for the false schema, which no value satisfies
+	Reject bool `json:"-"`
+}
+
+// UnmarshalJSON : Decode a schema, which may be an object or a boolean
+func (s *JSONSchema) UnmarshalJSON(data []byte) error {
+	var accept bool
+	if err := json.Unmarshal(data, &accept); err == nil {
+		*s = JSONSchema{Reject: !accept}
+		return nil
+	}
+
+	type schema JSONSchema
+	return json.Unmarshal(data, (*schema)(s))
+}
+
+// JSONSchemaType : Types a value may have, given as a single type or a list of types
+type JSONSchemaType []string
+
+// UnmarshalJSON : Decode a type, which may be a string or a list of strings
+func (t *JSONSchemaType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = JSONSchemaType{name}
+		return nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*t = names
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package types
+
+const (
+	TransactionCreate = "create"
+	TransactionUpdate = "update"
+	TransactionDelete = "delete"
+	TransactionCheck  = "check"
+)
+
+// TransactionOperation : Operation of a transaction. Creates take the new item, updates take the key of the item and
+// the fields to set, and deletes take the key of the item. Checks write nothing, and only make sure the item with
+// the key exists and can be read by the user. IfMatch holds the entity tag of the version the item must be at, if any.
+type TransactionOperation struct {
+	Action  string                 `json:"action"`
+	Key     Key                    `json:"key,omitempty"`
+	Item    map[string]interface{} `json:"item,omitempty"`
+	IfMatch string                 `json:"if_match,omitempty"`
+}
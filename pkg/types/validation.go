@@ -1,10 +1,19 @@
 package types
 
+import "context"
+
 type ValidationInput struct {
 	Key          string
 	Value        interface{}
 	Item         map[string]interface{}
 	ExistingItem map[string]interface{}
+
+	// Context : Context of the request being validated
+	Context context.Context
+
+	// FindItems : Find the items of the data table with one of the values in a field, without checking the user's
+	// permissions or writing an audit log. Nil when the validators are not run by a provider.
+	FindItems func(ctx context.Context, key string, values []string) ([]Record, error)
 }
 
 type ValidationOutput struct {
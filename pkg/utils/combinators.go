@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// Validator : Build a field validator from a check of the input. The check returns an error message if the value is
+// invalid, or an error if validation could not be performed.
+func Validator(check func(input *types.ValidationInput) (string, error)) types.FieldValidation {
+	return func(input *types.ValidationInput, ch chan types.ValidationOutput) {
+		message, err := check(input)
+		ch <- types.ValidationOutput{Input: input, Result: message == "" && err == nil, Message: message, Error: err}
+	}
+}
+
+// Validate : Run a field validator against an input and wait for its result
+func Validate(fn types.FieldValidation, input *types.ValidationInput) types.ValidationOutput {
+	ch := make(chan types.ValidationOutput, 1)
+	go fn(input, ch)
+
+	return <-ch
+}
+
+// And : Validator that the value passes every validator, reporting the first that fails
+func And(validators ...types.FieldValidation) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		for _, fn := range validators {
+			if output := Validate(fn, input); output.Error != nil || !output.Result {
+				return output.Message, output.Error
+			}
+		}
+
+		return "", nil
+	})
+}
+
+// Or : Validator that the value passes at least one validator. If none pass, their messages are combined.
+func Or(validators ...types.FieldValidation) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		var messages []string
+		for _, fn := range validators {
+			output := Validate(fn, input)
+			if output.Error != nil {
+				return "", output.Error
+			} else if output.Result {
+				return "", nil
+			}
+			messages = append(messages, output.Message)
+		}
+
+		return strings.Join(messages, " or "), nil
+	})
+}
+
+// Not : Validator that the value fails a validator, reporting the message given
+func Not(fn types.FieldValidation, message string) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		output := Validate(fn, input)
+		if output.Error != nil {
+			return "", output.Error
+		} else if output.Result {
+			return message, nil
+		}
+
+		return "", nil
+	})
+}
+
+// Optional : Validator that the value passes a validator, unless it is null or an empty string
+func Optional(fn types.FieldValidation) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		if input.Value == nil || input.Value == "" {
+			return "", nil
+		}
+
+		output := Validate(fn, input)
+		return output.Message, output.Error
+	})
+}
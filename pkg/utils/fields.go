@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+// fieldValue : Value of another field of the item. Updates only hold the fields being changed, so the existing item
+// is used for fields that are not being changed.
+func fieldValue(input *types.ValidationInput, field string) (interface{}, bool) {
+	if value, ok := input.Item[field]; ok {
+		return value, value != nil
+	}
+
+	value, ok := input.ExistingItem[field]
+	return value, ok && value != nil
+}
+
+// RequiresFields : Validator that the other fields are set on the item whenever this field is
+func RequiresFields(fields ...string) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		var missing []string
+		for _, field := range fields {
+			if _, ok := fieldValue(input, field); !ok {
+				missing = append(missing, field)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Sprintf("Field requires %s to be set", strings.Join(missing, ", ")), nil
+		}
+
+		return "", nil
+	})
+}
+
+// ConflictsWith : Validator that none of the other fields are set on the item along with this field
+func ConflictsWith(fields ...string) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		if input.Value == nil {
+			return "", nil
+		}
+
+		var conflicts []string
+		for _, field := range fields {
+			if _, ok := fieldValue(input, field); ok {
+				conflicts = append(conflicts, field)
+			}
+		}
+		if len(conflicts) > 0 {
+			return fmt.Sprintf("Field cannot be set along with %s", strings.Join(conflicts, ", ")), nil
+		}
+
+		return "", nil
+	})
+}
+
+// GreaterThanField : Validator that the value is greater than another field of the item. Numbers are compared by
+// value, RFC 3339 timestamps by time and other strings alphabetically. Passes if the other field is not set.
+func GreaterThanField(field string) types.FieldValidation {
+	return compareField(field, 1, fmt.Sprintf("Value must be greater than %s", field))
+}
+
+// LessThanField : Validator that the value is less than another field of the item, compared as for GreaterThanField
+func LessThanField(field string) types.FieldValidation {
+	return compareField(field, -1, fmt.Sprintf("Value must be less than %s", field))
+}
+
+// compareField : Validator that the value compares to another field of the item in the expected direction
+func compareField(field string, expected int, message string) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		other, ok := fieldValue(input, field)
+		if !ok {
+			return "", nil
+		}
+
+		result, ok := compare(input.Value, other)
+		if !ok {
+			return fmt.Sprintf("Value cannot be compared with %s", field), nil
+		} else if result != expected {
+			return message, nil
+		}
+
+		return "", nil
+	})
+}
+
+// compare : Compare two numbers, timestamps or strings. Returns false if the values cannot be compared.
+func compare(v1 interface{}, v2 interface{}) (int, bool) {
+	if n1, ok := toFloat(v1); ok {
+		n2, ok := toFloat(v2)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(n1, n2), true
+	}
+
+	s1, ok1 := v1.(string)
+	s2, ok2 := v2.(string)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+
+	t1, err1 := time.Parse(time.RFC3339, s1)
+	t2, err2 := time.Parse(time.RFC3339, s2)
+	if err1 == nil && err2 == nil {
+		return t1.Compare(t2), true
+	}
+
+	return strings.Compare(s1, s2), true
+}
+
+// compareOrdered : Compare two numbers
+func compareOrdered(n1 float64, n2 float64) int {
+	if n1 < n2 {
+		return -1
+	} else if n1 > n2 {
+		return 1
+	}
+
+	return 0
+}
+
+// Immutable : Validator that the value is not changed once the item is created. Always passes on create, when there
+// is no existing item.
+func Immutable() types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		if input.ExistingItem == nil {
+			return "", nil
+		}
+
+		if existing, ok := input.ExistingItem[input.Key]; ok && !equal(existing, input.Value) {
+			return "Field cannot be changed once the item is created", nil
+		}
+
+		return "", nil
+	})
+}
+
+// Unique : Validator that no other item has the same value in this field. Every item of the data table is
+// considered, including those the user cannot read, and the lookup is not audited. Updates that leave the value
+// unchanged always pass.
+func Unique() types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		if input.Value == nil {
+			return "", nil
+		}
+		if existing, ok := input.ExistingItem[input.Key]; ok && equal(existing, input.Value) {
+			return "", nil
+		}
+		if input.FindItems == nil {
+			return "", errors.New("unique validation requires a provider to look up items")
+		}
+
+		ctx := input.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		records, err := input.FindItems(ctx, input.Key, []string{fmt.Sprint(input.Value)})
+		if err != nil {
+			return "", err
+		}
+
+		for _, record := range records {
+			if equal(record[input.Key], input.Value) {
+				return "Value is already in use", nil
+			}
+		}
+
+		return "", nil
+	})
+}
+
+// StateMachine : Validator that the value only moves between the states of a field along the allowed transitions,
+// given as the states each state can move to. New items must start in one of the initial states, or in any state if
+// none are given. Updates that leave the state unchanged always pass.
+func StateMachine(transitions map[string][]string, initial ...string) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		if input.Value == nil {
+			return "", nil
+		}
+
+		state, ok := input.Value.(string)
+		if !ok {
+			return "Value must be a string", nil
+		}
+
+		existing, ok := input.ExistingItem[input.Key]
+		if !ok || existing == nil {
+			if len(initial) > 0 && !containsState(initial, state) {
+				return fmt.Sprintf("Value must start as one of %s", strings.Join(initial, ", ")), nil
+			}
+			return "", nil
+		}
+
+		current := fmt.Sprint(existing)
+		if current == state {
+			return "", nil
+		}
+
+		allowed := transitions[current]
+		if !containsState(allowed, state) {
+			if len(allowed) == 0 {
+				return fmt.Sprintf("Value cannot be changed from %s", current), nil
+			}
+			return fmt.Sprintf("Value can only be changed from %s to %s", current, strings.Join(allowed, ", ")), nil
+		}
+
+		return "", nil
+	})
+}
+
+// containsState : Check if a state is in a list of states
+func containsState(states []string, state string) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
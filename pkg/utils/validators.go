@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+)
+
+var (
+	hostnamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*\.?$`)
+	uuidPattern     = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+// stringValidator : Build a validator of string values, which fails values of any other type
+func stringValidator(check func(value string) string) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		value, ok := input.Value.(string)
+		if !ok {
+			return "Value must be a string", nil
+		}
+
+		return check(value), nil
+	})
+}
+
+// MatchesPattern : Validator that the value is a string matching a regular expression. The message defaults to one
+// naming the pattern.
+func MatchesPattern(pattern string, message string) types.FieldValidation {
+	re := regexp.MustCompile(pattern)
+	if message == "" {
+		message = fmt.Sprintf("Value must match pattern %s", pattern)
+	}
+
+	return stringValidator(func(value string) string {
+		if !re.MatchString(value) {
+			return message
+		}
+		return ""
+	})
+}
+
+// Length : Validator that the value is a string of between min and max characters. A max of 0 sets no upper limit.
+func Length(min int, max int) types.FieldValidation {
+	return stringValidator(func(value string) string {
+		length := utf8.RuneCountInString(value)
+		if length < min {
+			return fmt.Sprintf("Value must be at least %d characters long", min)
+		} else if max > 0 && length > max {
+			return fmt.Sprintf("Value must be at most %d characters long", max)
+		}
+		return ""
+	})
+}
+
+// Range : Validator that the value is a number between min and max, inclusive. Use math.Inf for an open range.
+func Range(min float64, max float64) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		n, ok := toFloat(input.Value)
+		if !ok {
+			return "Value must be a number", nil
+		} else if n < min {
+			return fmt.Sprintf("Value must be at least %v", min), nil
+		} else if n > max {
+			return fmt.Sprintf("Value must be at most %v", max), nil
+		}
+
+		return "", nil
+	})
+}
+
+// Email : Validator that the value is a bare email address, such as user@example.com
+func Email() types.FieldValidation {
+	return stringValidator(func(value string) string {
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value || !strings.Contains(value, "@") {
+			return "Value must be an email address"
+		}
+		return ""
+	})
+}
+
+// IPAddress : Validator that the value is an IPv4 or IPv6 address
+func IPAddress() types.FieldValidation {
+	return stringValidator(func(value string) string {
+		if net.ParseIP(value) == nil {
+			return "Value must be an IP address"
+		}
+		return ""
+	})
+}
+
+// CIDR : Validator that the value is an IPv4 or IPv6 network in CIDR notation, such as 10.0.0.0/8
+func CIDR() types.FieldValidation {
+	return stringValidator(func(value string) string {
+		if _, _, err := net.ParseCIDR(value); err != nil {
+			return "Value must be a CIDR block"
+		}
+		return ""
+	})
+}
+
+// Hostname : Validator that the value is a DNS hostname
+func Hostname() types.FieldValidation {
+	return stringValidator(func(value string) string {
+		if len(value) > 253 || !hostnamePattern.MatchString(value) {
+			return "Value must be a hostname"
+		}
+		return ""
+	})
+}
+
+// URL : Validator that the value is an absolute URL with one of the schemes given, or any scheme if none are given
+func URL(schemes ...string) types.FieldValidation {
+	return stringValidator(func(value string) string {
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "Value must be a URL"
+		}
+		if len(schemes) == 0 {
+			return ""
+		}
+
+		for _, scheme := range schemes {
+			if strings.EqualFold(u.Scheme, scheme) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("URL scheme must be one of %v", schemes)
+	})
+}
+
+// RFC3339Date : Validator that the value is a timestamp in RFC 3339 format, such as 2006-01-02T15:04:05Z
+func RFC3339Date() types.FieldValidation {
+	return stringValidator(func(value string) string {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return "Value must be an RFC 3339 timestamp"
+		}
+		return ""
+	})
+}
+
+// UUID : Validator that the value is a UUID in its canonical form
+func UUID() types.FieldValidation {
+	return stringValidator(func(value string) string {
+		if !uuidPattern.MatchString(value) {
+			return "Value must be a UUID"
+		}
+		return ""
+	})
+}
+
+// ListSize : Validator that the value is a list of between min and max elements. A max of 0 sets no upper limit.
+func ListSize(min int, max int) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		list, ok := toList(input.Value)
+		if !ok {
+			return "Value must be a list", nil
+		} else if len(list) < min {
+			return fmt.Sprintf("List must have at least %d elements", min), nil
+		} else if max > 0 && len(list) > max {
+			return fmt.Sprintf("List must have at most %d elements", max), nil
+		}
+
+		return "", nil
+	})
+}
+
+// EachElement : Validator that every element of a list passes a validator, reporting the first element that fails
+func EachElement(fn types.FieldValidation) types.FieldValidation {
+	return Validator(func(input *types.ValidationInput) (string, error) {
+		list, ok := toList(input.Value)
+		if !ok {
+			return "Value must be a list", nil
+		}
+
+		for i, element := range list {
+			output := Validate(fn, &types.ValidationInput{
+				Key:          fmt.Sprintf("%s[%d]", input.Key, i),
+				Value:        element,
+				Item:         input.Item,
+				ExistingItem: input.ExistingItem,
+				Context:      input.Context,
+				FindItems:    input.FindItems,
+			})
+			if output.Error != nil {
+				return "", output.Error
+			} else if !output.Result {
+				return fmt.Sprintf("Element %d: %s", i, output.Message), nil
+			}
+		}
+
+		return "", nil
+	})
+}
+
+// toFloat : Convert a numeric value to a float. Strings are not numbers.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// toList : Convert a list into a slice, regardless of the slice type it was decoded as
+func toList(value interface{}) ([]interface{}, bool) {
+	if list, ok := value.([]interface{}); ok {
+		return list, true
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	list := make([]interface{}, v.Len())
+	for i := range list {
+		list[i] = v.Index(i).Interface()
+	}
+
+	return list, true
+}
+
+// equal : Compare two values. Numbers are compared by value, whatever their type.
+func equal(v1 interface{}, v2 interface{}) bool {
+	if n1, ok := toFloat(v1); ok {
+		n2, ok := toFloat(v2)
+		return ok && n1 == n2
+	}
+
+	return reflect.DeepEqual(v1, v2)
+}
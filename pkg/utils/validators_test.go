@@ -0,0 +1,174 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/MichaelPalmer1/scoutr-go/pkg/types"
+	"github.com/MichaelPalmer1/scoutr-go/pkg/utils"
+)
+
+type validatorTest struct {
+	name     string
+	fn       types.FieldValidation
+	input    types.ValidationInput
+	expected string
+}
+
+func runValidatorTests(t *testing.T, tests []validatorTest) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := test.input
+			if input.Key == "" {
+				input.Key = "key"
+			}
+
+			output := utils.Validate(test.fn, &input)
+			if output.Error != nil {
+				t.Fatal(output.Error)
+			}
+			if output.Result != (test.expected == "") || output.Message != test.expected {
+				t.Errorf("Expected message %q, got %+v", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestValidators(t *testing.T) {
+	runValidatorTests(t, []validatorTest{
+		{"PatternMatch", utils.MatchesPattern(`^[a-z]+$`, ""), types.ValidationInput{Value: "abc"}, ""},
+		{"PatternMismatch", utils.MatchesPattern(`^[a-z]+$`, ""), types.ValidationInput{Value: "ABC"}, "Value must match pattern ^[a-z]+$"},
+		{"PatternMessage", utils.MatchesPattern(`^[a-z]+$`, "Lowercase only"), types.ValidationInput{Value: "1"}, "Lowercase only"},
+		{"PatternNotString", utils.MatchesPattern(`.*`, ""), types.ValidationInput{Value: 1}, "Value must be a string"},
+		{"Length", utils.Length(2, 4), types.ValidationInput{Value: "äbc"}, ""},
+		{"LengthShort", utils.Length(2, 4), types.ValidationInput{Value: "a"}, "Value must be at least 2 characters long"},
+		{"LengthLong", utils.Length(2, 4), types.ValidationInput{Value: "abcde"}, "Value must be at most 4 characters long"},
+		{"LengthUnlimited", utils.Length(1, 0), types.ValidationInput{Value: "abcdefghij"}, ""},
+		{"Range", utils.Range(0, 10), types.ValidationInput{Value: 10}, ""},
+		{"RangeLow", utils.Range(0, 10), types.ValidationInput{Value: -0.5}, "Value must be at least 0"},
+		{"RangeHigh", utils.Range(math.Inf(-1), 10), types.ValidationInput{Value: int64(11)}, "Value must be at most 10"},
+		{"RangeNotNumber", utils.Range(0, 10), types.ValidationInput{Value: "5"}, "Value must be a number"},
+		{"Email", utils.Email(), types.ValidationInput{Value: "user@example.com"}, ""},
+		{"EmailName", utils.Email(), types.ValidationInput{Value: "User <user@example.com>"}, "Value must be an email address"},
+		{"EmailInvalid", utils.Email(), types.ValidationInput{Value: "user"}, "Value must be an email address"},
+		{"IPv4", utils.IPAddress(), types.ValidationInput{Value: "10.0.0.1"}, ""},
+		{"IPv6", utils.IPAddress(), types.ValidationInput{Value: "::1"}, ""},
+		{"IPInvalid", utils.IPAddress(), types.ValidationInput{Value: "10.0.0.256"}, "Value must be an IP address"},
+		{"CIDR", utils.CIDR(), types.ValidationInput{Value: "10.0.0.0/8"}, ""},
+		{"CIDRInvalid", utils.CIDR(), types.ValidationInput{Value: "10.0.0.1"}, "Value must be a CIDR block"},
+		{"Hostname", utils.Hostname(), types.ValidationInput{Value: "api.example.com"}, ""},
+		{"HostnameInvalid", utils.Hostname(), types.ValidationInput{Value: "-bad.example.com"}, "Value must be a hostname"},
+		{"URL", utils.URL(), types.ValidationInput{Value: "ftp://example.com/file"}, ""},
+		{"URLRelative", utils.URL(), types.ValidationInput{Value: "/path"}, "Value must be a URL"},
+		{"URLScheme", utils.URL("https"), types.ValidationInput{Value: "http://example.com"}, "URL scheme must be one of [https]"},
+		{"RFC3339Date", utils.RFC3339Date(), types.ValidationInput{Value: "2024-01-02T15:04:05Z"}, ""},
+		{"RFC3339DateInvalid", utils.RFC3339Date(), types.ValidationInput{Value: "2024-01-02"}, "Value must be an RFC 3339 timestamp"},
+		{"UUID", utils.UUID(), types.ValidationInput{Value: "0b5e8f4c-3c3a-4d3b-9f5e-1a2b3c4d5e6f"}, ""},
+		{"UUIDInvalid", utils.UUID(), types.ValidationInput{Value: "0b5e8f4c3c3a4d3b9f5e1a2b3c4d5e6f"}, "Value must be a UUID"},
+		{"ListSize", utils.ListSize(1, 2), types.ValidationInput{Value: []interface{}{"a"}}, ""},
+		{"ListSizeTyped", utils.ListSize(1, 2), types.ValidationInput{Value: []string{"a", "b", "c"}}, "List must have at most 2 elements"},
+		{"ListSizeEmpty", utils.ListSize(1, 0), types.ValidationInput{Value: []interface{}{}}, "List must have at least 1 elements"},
+		{"ListSizeNotList", utils.ListSize(1, 0), types.ValidationInput{Value: "a"}, "Value must be a list"},
+		{"EachElement", utils.EachElement(utils.IPAddress()), types.ValidationInput{Value: []interface{}{"10.0.0.1", "::1"}}, ""},
+		{"EachElementInvalid", utils.EachElement(utils.IPAddress()), types.ValidationInput{Value: []interface{}{"10.0.0.1", "x"}}, "Element 1: Value must be an IP address"},
+	})
+}
+
+func TestCombinators(t *testing.T) {
+	ip := utils.Or(utils.IPAddress(), utils.CIDR())
+
+	runValidatorTests(t, []validatorTest{
+		{"And", utils.And(utils.Length(1, 0), utils.Hostname()), types.ValidationInput{Value: "example.com"}, ""},
+		{"AndFirstFailure", utils.And(utils.Length(20, 0), utils.Hostname()), types.ValidationInput{Value: "-"}, "Value must be at least 20 characters long"},
+		{"Or", ip, types.ValidationInput{Value: "10.0.0.0/8"}, ""},
+		{"OrFailure", ip, types.ValidationInput{Value: "x"}, "Value must be an IP address or Value must be a CIDR block"},
+		{"Not", utils.Not(utils.ValueInArray([]string{"root"}, "", ""), "Name is reserved"), types.ValidationInput{Value: "root"}, "Name is reserved"},
+		{"NotPass", utils.Not(utils.ValueInArray([]string{"root"}, "", ""), "Name is reserved"), types.ValidationInput{Value: "user"}, ""},
+		{"OptionalNull", utils.Optional(utils.Email()), types.ValidationInput{Value: nil}, ""},
+		{"OptionalEmpty", utils.Optional(utils.Email()), types.ValidationInput{Value: ""}, ""},
+		{"OptionalSet", utils.Optional(utils.Email()), types.ValidationInput{Value: "user"}, "Value must be an email address"},
+	})
+
+	// Errors are passed through rather than treated as failures
+	failing := utils.Validator(func(input *types.ValidationInput) (string, error) {
+		return "", errors.New("lookup failed")
+	})
+	if output := utils.Validate(utils.Or(utils.Email(), failing), &types.ValidationInput{Value: "x"}); output.Error == nil {
+		t.Error("Expected the error of the validator to be returned")
+	}
+}
+
+func TestFieldValidators(t *testing.T) {
+	item := map[string]interface{}{"start": "2024-01-01T00:00:00Z", "end": "2024-01-02T00:00:00Z", "min": 5, "email": "user@example.com"}
+	existing := map[string]interface{}{"owner": "a", "phone": "555"}
+
+	runValidatorTests(t, []validatorTest{
+		{"RequiresFields", utils.RequiresFields("min", "phone"), types.ValidationInput{Value: 10, Item: item, ExistingItem: existing}, ""},
+		{"RequiresFieldsMissing", utils.RequiresFields("min", "max"), types.ValidationInput{Value: 10, Item: item}, "Field requires max to be set"},
+		{"ConflictsWith", utils.ConflictsWith("phone"), types.ValidationInput{Value: "x", Item: item}, ""},
+		{"ConflictsWithSet", utils.ConflictsWith("email", "phone"), types.ValidationInput{Value: "x", Item: item, ExistingItem: existing}, "Field cannot be set along with email, phone"},
+		{"ConflictsWithRemoved", utils.ConflictsWith("email"), types.ValidationInput{Value: nil, Item: item}, ""},
+		{"GreaterThanField", utils.GreaterThanField("min"), types.ValidationInput{Value: 6.5, Item: item}, ""},
+		{"GreaterThanFieldEqual", utils.GreaterThanField("min"), types.ValidationInput{Value: 5, Item: item}, "Value must be greater than min"},
+		{"GreaterThanFieldMissing", utils.GreaterThanField("max"), types.ValidationInput{Value: 1, Item: item}, ""},
+		{"LessThanFieldTime", utils.LessThanField("end"), types.ValidationInput{Value: "2024-01-01T12:00:00+02:00", Item: item}, ""},
+		{"LessThanFieldTimeAfter", utils.LessThanField("start"), types.ValidationInput{Value: "2024-01-01T00:00:00-01:00", Item: item}, "Value must be less than start"},
+		{"LessThanFieldMismatch", utils.LessThanField("min"), types.ValidationInput{Value: "a", Item: item}, "Value cannot be compared with min"},
+		{"ImmutableCreate", utils.Immutable(), types.ValidationInput{Key: "owner", Value: "b"}, ""},
+		{"ImmutableUnchanged", utils.Immutable(), types.ValidationInput{Key: "owner", Value: "a", ExistingItem: existing}, ""},
+		{"ImmutableChanged", utils.Immutable(), types.ValidationInput{Key: "owner", Value: "b", ExistingItem: existing}, "Field cannot be changed once the item is created"},
+	})
+}
+
+func TestStateMachine(t *testing.T) {
+	states := utils.StateMachine(map[string][]string{
+		"draft":     {"review"},
+		"review":    {"draft", "published"},
+		"published": {"archived"},
+	}, "draft")
+
+	runValidatorTests(t, []validatorTest{
+		{"Create", states, types.ValidationInput{Key: "state", Value: "draft"}, ""},
+		{"CreateNotInitial", states, types.ValidationInput{Key: "state", Value: "published"}, "Value must start as one of draft"},
+		{"CreateAnyState", utils.StateMachine(nil), types.ValidationInput{Key: "state", Value: "published"}, ""},
+		{"Unchanged", states, types.ValidationInput{Key: "state", Value: "review", ExistingItem: map[string]interface{}{"state": "review"}}, ""},
+		{"Allowed", states, types.ValidationInput{Key: "state", Value: "published", ExistingItem: map[string]interface{}{"state": "review"}}, ""},
+		{"NotAllowed", states, types.ValidationInput{Key: "state", Value: "published", ExistingItem: map[string]interface{}{"state": "draft"}}, "Value can only be changed from draft to review"},
+		{"FinalState", states, types.ValidationInput{Key: "state", Value: "draft", ExistingItem: map[string]interface{}{"state": "archived"}}, "Value cannot be changed from archived"},
+		{"NoExistingState", states, types.ValidationInput{Key: "state", Value: "draft", ExistingItem: map[string]interface{}{"name": "a"}}, ""},
+		{"NotString", states, types.ValidationInput{Key: "state", Value: 1}, "Value must be a string"},
+	})
+}
+
+func TestUnique(t *testing.T) {
+	searches := 0
+	records := []types.Record{{"id": "1", "name": "alpha"}}
+	findItems := func(ctx context.Context, key string, values []string) ([]types.Record, error) {
+		searches++
+
+		var found []types.Record
+		for _, record := range records {
+			for _, value := range values {
+				if record[key] == value {
+					found = append(found, record)
+				}
+			}
+		}
+
+		return found, nil
+	}
+	fn := utils.Unique()
+
+	runValidatorTests(t, []validatorTest{
+		{"Unused", fn, types.ValidationInput{Key: "name", Value: "beta", FindItems: findItems}, ""},
+		{"Used", fn, types.ValidationInput{Key: "name", Value: "alpha", FindItems: findItems}, "Value is already in use"},
+		{"Unchanged", fn, types.ValidationInput{Key: "name", Value: "alpha", ExistingItem: map[string]interface{}{"name": "alpha"}, FindItems: findItems}, ""},
+	})
+
+	// Unchanged values are not looked up
+	if searches != 2 {
+		t.Errorf("Expected 2 searches, got %d", searches)
+	}
+}